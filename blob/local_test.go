@@ -0,0 +1,80 @@
+package blob
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocal(t.TempDir())
+	require.NoError(t, err)
+
+	require.NoError(t, store.Put(ctx, "avatars/a.png", strings.NewReader("data")))
+
+	f, _, err := store.Open(ctx, "avatars/a.png")
+	require.NoError(t, err)
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	assert.Equal(t, "data", string(data))
+
+	_, _, err = store.Open(ctx, "avatars")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, store.Delete(ctx, "avatars/a.png"))
+	_, _, err = store.Open(ctx, "avatars/a.png")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, store.Delete(ctx, "avatars/a.png"), ErrNotFound)
+}
+
+func TestLocal_InvalidKeys(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocal(t.TempDir())
+	require.NoError(t, err)
+
+	for _, key := range []string{"", "/etc/passwd", "../secret", "a/../../secret", "a//b", ".."} {
+		assert.ErrorIs(t, store.Put(ctx, key, strings.NewReader("x")), ErrInvalidKey, key)
+		_, _, err = store.Open(ctx, key)
+		assert.ErrorIs(t, err, ErrInvalidKey, key)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocal(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, store.Put(ctx, "posts/a.png", strings.NewReader("\x89PNG\r\n\x1a\n")))
+
+	h := http.StripPrefix("/media/", Handler(store))
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{"Existing file", http.MethodGet, "/media/posts/a.png", http.StatusOK},
+		{"Missing file", http.MethodGet, "/media/posts/b.png", http.StatusNotFound},
+		{"Traversal", http.MethodGet, "/media/../go.mod", http.StatusNotFound},
+		{"Wrong method", http.MethodPost, "/media/posts/a.png", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+				assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+			}
+		})
+	}
+}
@@ -3,20 +3,50 @@ package domain
 import (
 	"context"
 	"errors"
+	"github.com/farid21ola/forum/middleware"
 	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/storage"
 	"log"
+	"time"
 )
 
 func (d *Domain) Login(ctx context.Context, input *model.LoginInput) (*model.AuthResponse, error) {
+	policy := d.loginPolicy()
+	ip := middleware.GetClientIPFromCtx(ctx)
+	now := time.Now()
+
+	failures, err := d.Storage.LoginFailures(ctx, input.Username, ip, now.Add(-policy.Window))
+	if err != nil {
+		log.Printf("can't count failed logins: %v", err)
+		return nil, errors.New("something went wrong")
+	}
+	if err := policy.check(failures, now); err != nil {
+		return nil, err
+	}
+
 	user, err := d.Storage.UserByUsername(ctx, input.Username)
 	if err != nil {
+		// spend the same time as a wrong password so usernames can't be probed by timing
+		(&model.User{}).ComparePassword(input.Password)
+		d.recordLogin(ctx, nil, input.Username, ip, false)
 		return nil, ErrBadCredentials
 	}
 
 	err = user.ComparePassword(input.Password)
 	if err != nil {
+		d.recordLogin(ctx, user, input.Username, ip, false)
+		if policy.locksOut(failures.ByUsername) && d.Notifier != nil {
+			if err = d.Notifier.AccountLocked(ctx, user, now.Add(policy.LockoutDuration)); err != nil {
+				log.Printf("can't notify about locked account: %v", err)
+			}
+		}
 		return nil, ErrBadCredentials
 	}
+	d.recordLogin(ctx, user, input.Username, ip, true)
+
+	if user.PasswordNeedsRehash() {
+		d.rehashPassword(ctx, user, input.Password)
+	}
 
 	token, err := user.GenToken()
 	if err != nil {
@@ -29,10 +59,16 @@ func (d *Domain) Login(ctx context.Context, input *model.LoginInput) (*model.Aut
 	}, nil
 }
 
+var errUsernameTaken = &storage.Error{Err: storage.ErrConflict, Message: "username is already in use"}
+
 func (d *Domain) Register(ctx context.Context, input *model.RegisterInput) (*model.AuthResponse, error) {
 	_, err := d.Storage.UserByUsername(ctx, input.Username)
 	if err == nil {
-		return nil, errors.New("username is already in use")
+		return nil, errUsernameTaken
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		log.Printf("error find a user by username: %v", err)
+		return nil, errors.New("something went wrong")
 	}
 
 	user := &model.User{
@@ -58,6 +94,10 @@ func (d *Domain) Register(ctx context.Context, input *model.RegisterInput) (*mod
 	}
 
 	if _, err = d.Storage.CreateUser(ctx, tx, user); err != nil {
+		// the username has been taken since the check above
+		if errors.Is(err, storage.ErrConflict) {
+			return nil, errUsernameTaken
+		}
 		log.Printf("error creating a user: %v", err)
 		return nil, err
 	}
@@ -80,3 +120,41 @@ func (d *Domain) Register(ctx context.Context, input *model.RegisterInput) (*mod
 		User:      user,
 	}, nil
 }
+
+func (d *Domain) loginPolicy() LoginPolicy {
+	if d.LoginPolicy == (LoginPolicy{}) {
+		return DefaultLoginPolicy
+	}
+	return d.LoginPolicy
+}
+
+func (d *Domain) recordLogin(ctx context.Context, user *model.User, username, ip string, success bool) {
+	event := &model.LoginEvent{
+		Username: username,
+		IP:       ip,
+		Success:  success,
+	}
+	if user != nil {
+		event.UserID = &user.ID
+	}
+
+	if err := d.Storage.AddLoginEvent(ctx, event); err != nil {
+		log.Printf("can't record login event: %v", err)
+	}
+}
+
+// rehashPassword upgrades the stored hash after a successful login, the only
+// moment the plain password is known. Failures are not fatal for the login.
+func (d *Domain) rehashPassword(ctx context.Context, user *model.User, password string) {
+	upgraded := *user
+	if err := upgraded.HashPassword(password); err != nil {
+		log.Printf("can't rehash password: %v", err)
+		return
+	}
+
+	if err := d.Storage.UpdatePassword(ctx, user.ID, upgraded.Password); err != nil {
+		log.Printf("can't store rehashed password: %v", err)
+		return
+	}
+	user.Password = upgraded.Password
+}
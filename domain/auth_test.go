@@ -3,10 +3,14 @@ package domain
 import (
 	"context"
 	"errors"
+	"github.com/farid21ola/forum/middleware"
 	"github.com/farid21ola/forum/mocks"
 	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"strings"
 	"testing"
 	"time"
 )
@@ -22,6 +26,9 @@ func TestDomain_Login(t *testing.T) {
 	}
 
 	mockStorage.On("UserByUsername", ctx, "user1").Return(user, nil)
+	mockStorage.On("LoginFailures", ctx, "user1", "", mock.Anything).Return(&model.LoginFailures{}, nil)
+	mockStorage.On("AddLoginEvent", ctx, mock.AnythingOfType("*model.LoginEvent")).Return(nil)
+	mockStorage.On("UpdatePassword", ctx, "", mock.AnythingOfType("string")).Return(nil)
 
 	d := &Domain{
 		Storage: mockStorage,
@@ -55,6 +62,203 @@ func TestDomain_Login(t *testing.T) {
 	}
 }
 
+type notifierMock struct {
+	locked []*model.User
+}
+
+func (n *notifierMock) AccountLocked(ctx context.Context, user *model.User, until time.Time) error {
+	n.locked = append(n.locked, user)
+	return nil
+}
+
+func TestDomain_Login_Throttling(t *testing.T) {
+	ctx := context.WithValue(context.Background(), middleware.ClientIPKey, "10.0.0.1")
+	user := &model.User{ID: "1", Username: "user1"}
+	require.NoError(t, user.HashPassword("correct_password"))
+
+	tests := []struct {
+		name         string
+		username     string
+		password     string
+		failures     *model.LoginFailures
+		mockSetup    func(s *mocks.Storage)
+		wantErr      error
+		wantThrottle *LoginThrottledError
+		wantLocked   int
+	}{
+		{
+			name:     "Free attempts left",
+			username: "user1",
+			password: "correct_password",
+			failures: &model.LoginFailures{ByUsername: 2, LastByUsername: time.Now()},
+			mockSetup: func(s *mocks.Storage) {
+				s.On("UserByUsername", ctx, "user1").Return(user, nil)
+				s.On("AddLoginEvent", ctx, mock.MatchedBy(func(e *model.LoginEvent) bool {
+					return e.Success && *e.UserID == "1" && e.IP == "10.0.0.1"
+				})).Return(nil)
+			},
+		},
+		{
+			name:         "Backoff after failures",
+			username:     "user1",
+			password:     "correct_password",
+			failures:     &model.LoginFailures{ByUsername: 4, LastByUsername: time.Now()},
+			mockSetup:    func(s *mocks.Storage) {},
+			wantThrottle: &LoginThrottledError{Locked: false},
+		},
+		{
+			name:         "Username locked out",
+			username:     "user1",
+			password:     "correct_password",
+			failures:     &model.LoginFailures{ByUsername: 10, LastByUsername: time.Now()},
+			mockSetup:    func(s *mocks.Storage) {},
+			wantThrottle: &LoginThrottledError{Locked: true},
+		},
+		{
+			name:         "IP locked out",
+			username:     "user1",
+			password:     "correct_password",
+			failures:     &model.LoginFailures{ByIP: 50, LastByIP: time.Now()},
+			mockSetup:    func(s *mocks.Storage) {},
+			wantThrottle: &LoginThrottledError{Locked: true},
+		},
+		{
+			name:     "Lockout expired",
+			username: "user1",
+			password: "correct_password",
+			failures: &model.LoginFailures{ByUsername: 10, LastByUsername: time.Now().Add(-time.Hour)},
+			mockSetup: func(s *mocks.Storage) {
+				s.On("UserByUsername", ctx, "user1").Return(user, nil)
+				s.On("AddLoginEvent", ctx, mock.AnythingOfType("*model.LoginEvent")).Return(nil)
+			},
+		},
+		{
+			name:     "Failure that locks the account notifies the owner",
+			username: "user1",
+			password: "wrong_password",
+			failures: &model.LoginFailures{ByUsername: 9, LastByUsername: time.Now().Add(-time.Hour)},
+			mockSetup: func(s *mocks.Storage) {
+				s.On("UserByUsername", ctx, "user1").Return(user, nil)
+				s.On("AddLoginEvent", ctx, mock.MatchedBy(func(e *model.LoginEvent) bool {
+					return !e.Success && *e.UserID == "1"
+				})).Return(nil)
+			},
+			wantErr:    ErrBadCredentials,
+			wantLocked: 1,
+		},
+		{
+			name:     "Unknown username is recorded",
+			username: "ghost",
+			password: "whatever",
+			failures: &model.LoginFailures{},
+			mockSetup: func(s *mocks.Storage) {
+				s.On("UserByUsername", ctx, "ghost").Return(nil, errors.New("not found"))
+				s.On("AddLoginEvent", ctx, mock.MatchedBy(func(e *model.LoginEvent) bool {
+					return !e.Success && e.UserID == nil && e.Username == "ghost"
+				})).Return(nil)
+			},
+			wantErr: ErrBadCredentials,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(mocks.Storage)
+			mockStorage.On("LoginFailures", ctx, tt.username, "10.0.0.1", mock.Anything).Return(tt.failures, nil)
+			tt.mockSetup(mockStorage)
+
+			notifier := &notifierMock{}
+			d := &Domain{Storage: mockStorage, Notifier: notifier}
+
+			resp, err := d.Login(ctx, &model.LoginInput{Username: tt.username, Password: tt.password})
+			switch {
+			case tt.wantThrottle != nil:
+				var throttled *LoginThrottledError
+				require.ErrorAs(t, err, &throttled)
+				assert.Equal(t, tt.wantThrottle.Locked, throttled.Locked)
+				assert.Greater(t, throttled.RetryAfter, time.Duration(0))
+			case tt.wantErr != nil:
+				assert.Equal(t, tt.wantErr, err)
+			default:
+				require.NoError(t, err)
+				assert.NotNil(t, resp)
+			}
+			assert.Len(t, notifier.locked, tt.wantLocked)
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDomain_Login_Rehash(t *testing.T) {
+	ctx := context.Background()
+	bcryptHash := "$2a$10$WfR582ps551unrHH9K7BZ.j8FyYQ5g16N/c8zGsKeHP2v583n0pQ." // hashed password for "correct_password"
+
+	current := &model.User{ID: "2", Username: "current"}
+	require.NoError(t, current.HashPassword("correct_password"))
+
+	tests := []struct {
+		name       string
+		user       *model.User
+		wantRehash bool
+	}{
+		{"Legacy bcrypt hash is upgraded", &model.User{ID: "1", Username: "legacy", Password: bcryptHash}, true},
+		{"Current hash is kept", current, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(mocks.Storage)
+			mockStorage.On("LoginFailures", ctx, tt.user.Username, "", mock.Anything).Return(&model.LoginFailures{}, nil)
+			mockStorage.On("UserByUsername", ctx, tt.user.Username).Return(tt.user, nil)
+			mockStorage.On("AddLoginEvent", ctx, mock.AnythingOfType("*model.LoginEvent")).Return(nil)
+			if tt.wantRehash {
+				mockStorage.On("UpdatePassword", ctx, tt.user.ID, mock.MatchedBy(func(hash string) bool {
+					return strings.HasPrefix(hash, "$argon2id$")
+				})).Return(nil)
+			}
+
+			d := &Domain{Storage: mockStorage}
+			_, err := d.Login(ctx, &model.LoginInput{Username: tt.user.Username, Password: "correct_password"})
+			require.NoError(t, err)
+			assert.NoError(t, tt.user.ComparePassword("correct_password"))
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLoginPolicy_check(t *testing.T) {
+	now := time.Now()
+	p := DefaultLoginPolicy
+
+	tests := []struct {
+		name       string
+		failures   model.LoginFailures
+		wantWait   time.Duration
+		wantLocked bool
+	}{
+		{"No failures", model.LoginFailures{}, 0, false},
+		{"First delay", model.LoginFailures{ByUsername: 3, LastByUsername: now}, time.Second, false},
+		{"Delay doubles", model.LoginFailures{ByUsername: 5, LastByUsername: now}, 4 * time.Second, false},
+		{"Longest wait wins", model.LoginFailures{ByUsername: 9, LastByUsername: now, ByIP: 40, LastByIP: now}, 64 * time.Second, false},
+		{"Delay already passed", model.LoginFailures{ByUsername: 3, LastByUsername: now.Add(-time.Minute)}, 0, false},
+		{"Lockout", model.LoginFailures{ByUsername: 10, LastByUsername: now}, 30 * time.Minute, true},
+		{"IP backoff is looser", model.LoginFailures{ByIP: 15, LastByIP: now}, time.Second, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.check(&tt.failures, now)
+			if tt.wantWait == 0 {
+				assert.Nil(t, err)
+				return
+			}
+			require.NotNil(t, err)
+			assert.Equal(t, tt.wantWait, err.RetryAfter)
+			assert.Equal(t, tt.wantLocked, err.Locked)
+		})
+	}
+}
+
 func TestDomain_Register(t *testing.T) {
 	ctx := context.Background()
 	mockTx := new(mocks.Tx)
@@ -92,6 +296,35 @@ func TestDomain_Register(t *testing.T) {
 			},
 			expectedError: errors.New("username is already in use"),
 		},
+		{
+			name: "Username taken while registering",
+			input: &model.RegisterInput{
+				Username:  "racer",
+				Password:  "password",
+				FirstName: "John",
+				LastName:  "Doe",
+			},
+			mockSetup: func() {
+				mockStorage.On("UserByUsername", ctx, "racer").Return(nil, storage.ErrNotFound)
+				mockStorage.On("CreateUser", mock.Anything, mock.Anything, mock.MatchedBy(func(u *model.User) bool {
+					return u.Username == "racer"
+				})).Return(nil, storage.ErrConflict)
+			},
+			expectedError: errors.New("username is already in use"),
+		},
+		{
+			name: "Storage failure",
+			input: &model.RegisterInput{
+				Username:  "unlucky",
+				Password:  "password",
+				FirstName: "John",
+				LastName:  "Doe",
+			},
+			mockSetup: func() {
+				mockStorage.On("UserByUsername", ctx, "unlucky").Return(nil, errors.New("connection refused"))
+			},
+			expectedError: errors.New("something went wrong"),
+		},
 		{
 			name: "Successful registration",
 			input: &model.RegisterInput{
@@ -101,7 +334,7 @@ func TestDomain_Register(t *testing.T) {
 				LastName:  "Doe",
 			},
 			mockSetup: func() {
-				mockStorage.On("UserByUsername", ctx, "newuser").Return(nil, errors.New("not found"))
+				mockStorage.On("UserByUsername", ctx, "newuser").Return(nil, storage.ErrNotFound)
 				mockStorage.On("CreateUser", mock.Anything, mock.Anything, mock.Anything).Return(&model.User{
 					ID:        "1",
 					Username:  "newuser",
@@ -0,0 +1,258 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"github.com/farid21ola/forum/middleware"
+	"github.com/farid21ola/forum/model"
+	"sync"
+)
+
+var errBlocked = errors.New("you have been blocked by this user")
+
+// BlockUser hides the posts and comments of a user from the current user and
+// keeps the user from replying to or mentioning the current user.
+func (d *Domain) BlockUser(ctx context.Context, id string) (*model.User, error) {
+	return d.setBlock(ctx, id, model.BlockKindBlock, d.Storage.AddBlock)
+}
+
+func (d *Domain) UnblockUser(ctx context.Context, id string) (*model.User, error) {
+	return d.setBlock(ctx, id, model.BlockKindBlock, d.Storage.RemoveBlock)
+}
+
+// MuteUser only hides the posts and comments of a user from the current
+// user.
+func (d *Domain) MuteUser(ctx context.Context, id string) (*model.User, error) {
+	return d.setBlock(ctx, id, model.BlockKindMute, d.Storage.AddBlock)
+}
+
+func (d *Domain) UnmuteUser(ctx context.Context, id string) (*model.User, error) {
+	return d.setBlock(ctx, id, model.BlockKindMute, d.Storage.RemoveBlock)
+}
+
+func (d *Domain) setBlock(ctx context.Context, id string, kind model.BlockKind, apply func(context.Context, *model.Block) error) (*model.User, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+	if id == currentUser.ID {
+		return nil, errors.New("you can't block or mute yourself")
+	}
+	user, err := d.Storage.UserByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err, errUserNotFound)
+	}
+	if err = apply(ctx, &model.Block{UserID: currentUser.ID, TargetID: user.ID, Kind: kind}); err != nil {
+		return nil, err
+	}
+	forgetHidden(ctx, currentUser.ID)
+	return user, nil
+}
+
+// BlockedUsers returns the users blocked by the current user, or muted when
+// kind is model.BlockKindMute.
+func (d *Domain) BlockedUsers(ctx context.Context, kind model.BlockKind) ([]*model.User, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+	blocks, err := d.Storage.Blocks(ctx, currentUser.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	for _, b := range blocks {
+		if b.Kind == kind {
+			ids = append(ids, b.TargetID)
+		}
+	}
+	if len(ids) == 0 {
+		return []*model.User{}, nil
+	}
+
+	return d.Storage.UsersByIDs(ctx, ids)
+}
+
+const hiddenCacheKey = "hiddencache"
+
+// hiddenCache keeps the hidden authors of every user for the duration of a
+// request, so a list of posts loads the blocks once rather than per post.
+type hiddenCache struct {
+	mu    sync.Mutex
+	users map[string]*hiddenEntry
+}
+
+type hiddenEntry struct {
+	once   sync.Once
+	hidden map[string]bool
+	err    error
+}
+
+// WithHiddenCache returns a context that caches the hidden authors of the
+// users it is used for.
+func WithHiddenCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, hiddenCacheKey, &hiddenCache{users: make(map[string]*hiddenEntry)})
+}
+
+// forgetHidden drops the cached authors of a user whose blocks have changed.
+func forgetHidden(ctx context.Context, userID string) {
+	if cache, ok := ctx.Value(hiddenCacheKey).(*hiddenCache); ok {
+		cache.mu.Lock()
+		delete(cache.users, userID)
+		cache.mu.Unlock()
+	}
+}
+
+// hiddenAuthors returns the users the current user has blocked or muted,
+// nothing is hidden from anonymous users. The filtering is done here rather
+// than in the queries so every storage hides the same content.
+func (d *Domain) hiddenAuthors(ctx context.Context) (map[string]bool, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, nil
+	}
+	cache, ok := ctx.Value(hiddenCacheKey).(*hiddenCache)
+	if !ok {
+		return d.loadHiddenAuthors(ctx, currentUser.ID)
+	}
+
+	cache.mu.Lock()
+	entry := cache.users[currentUser.ID]
+	if entry == nil {
+		entry = &hiddenEntry{}
+		cache.users[currentUser.ID] = entry
+	}
+	cache.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.hidden, entry.err = d.loadHiddenAuthors(ctx, currentUser.ID)
+	})
+	return entry.hidden, entry.err
+}
+
+// freshHiddenAuthors skips the cache, subscriptions outlive the request that
+// started them and a websocket connection shares one context.
+func (d *Domain) freshHiddenAuthors(ctx context.Context) (map[string]bool, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, nil
+	}
+	return d.loadHiddenAuthors(ctx, currentUser.ID)
+}
+
+func (d *Domain) loadHiddenAuthors(ctx context.Context, userID string) (map[string]bool, error) {
+	blocks, err := d.Storage.Blocks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	hidden := make(map[string]bool, len(blocks))
+	for _, b := range blocks {
+		hidden[b.TargetID] = true
+	}
+	return hidden, nil
+}
+
+// blockedBy returns the users among userIDs who have blocked the author.
+func (d *Domain) blockedBy(ctx context.Context, author *model.User, userIDs []string) (map[string]bool, error) {
+	var others []string
+	for _, id := range userIDs {
+		if id != author.ID {
+			others = append(others, id)
+		}
+	}
+	if len(others) == 0 {
+		return nil, nil
+	}
+
+	ids, err := d.Storage.BlockingUserIDs(ctx, author.ID, others)
+	if err != nil {
+		return nil, err
+	}
+
+	blocking := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		blocking[id] = true
+	}
+	return blocking, nil
+}
+
+// Posts returns a page of published posts without the ones of hidden
+// authors, so a page may hold fewer posts than asked.
+func (d *Domain) Posts(ctx context.Context, limit, offset *int) ([]*model.Post, error) {
+	if err := offsetPage(limit, offset); err != nil {
+		return nil, err
+	}
+	posts, err := d.Storage.Posts(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	hidden, err := d.hiddenAuthors(ctx)
+	if err != nil || len(hidden) == 0 {
+		return posts, err
+	}
+
+	visible := []*model.Post{}
+	for _, post := range posts {
+		if !hidden[post.UserID] {
+			visible = append(visible, post)
+		}
+	}
+	return visible, nil
+}
+
+// Comments returns comments of a post without the ones of hidden authors,
+// their replies are hidden with them.
+func (d *Domain) Comments(ctx context.Context, postID string, limit, offset *int) ([]*model.Comment, error) {
+	if err := offsetPage(limit, offset); err != nil {
+		return nil, err
+	}
+	comments, err := d.Storage.Comments(ctx, postID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	hidden, err := d.hiddenAuthors(ctx)
+	if err != nil || len(hidden) == 0 {
+		return comments, err
+	}
+
+	return visibleComments(comments, hidden), nil
+}
+
+// visibleComments filters a thread without modifying the stored comments.
+func visibleComments(comments []*model.Comment, hidden map[string]bool) []*model.Comment {
+	visible := []*model.Comment{}
+	for _, comment := range comments {
+		if hidden[comment.UserID] {
+			continue
+		}
+		if len(comment.Replies) > 0 {
+			copied := *comment
+			copied.Replies = visibleComments(comment.Replies, hidden)
+			comment = &copied
+		}
+		visible = append(visible, comment)
+	}
+	return visible
+}
+
+// keepVisible adds to the keep filter of a subscription the check that drops
+// content of hidden authors. Blocks set later only apply to new
+// subscriptions.
+func keepVisible[T any](hidden map[string]bool, authorOf func(*T) string, keep func(*T) bool) func(*T) bool {
+	if len(hidden) == 0 {
+		return keep
+	}
+	return func(v *T) bool {
+		return !hidden[authorOf(v)] && (keep == nil || keep(v))
+	}
+}
+
+func commentAuthor(c *model.Comment) string {
+	return c.UserID
+}
+
+func postAuthor(p *model.Post) string {
+	return p.UserID
+}
@@ -0,0 +1,192 @@
+package domain
+
+import (
+	"context"
+	"github.com/farid21ola/forum/mocks"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDomain_BlockUser(t *testing.T) {
+	userCtx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"})
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		id            string
+		mockSetup     func(m *mocks.Storage)
+		expectedError string
+	}{
+		{
+			name:          "Unauthenticated user",
+			ctx:           context.Background(),
+			id:            "2",
+			mockSetup:     func(m *mocks.Storage) {},
+			expectedError: "unauthenticated",
+		},
+		{
+			name:          "Yourself",
+			ctx:           userCtx,
+			id:            "1",
+			mockSetup:     func(m *mocks.Storage) {},
+			expectedError: "you can't block or mute yourself",
+		},
+		{
+			name: "Successful block",
+			ctx:  userCtx,
+			id:   "2",
+			mockSetup: func(m *mocks.Storage) {
+				m.On("UserByID", mock.Anything, "2").Return(&model.User{ID: "2"}, nil)
+				m.On("AddBlock", mock.Anything, &model.Block{UserID: "1", TargetID: "2", Kind: model.BlockKindBlock}).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(mocks.Storage)
+			tt.mockSetup(mockStorage)
+			d := &Domain{Storage: mockStorage}
+
+			_, err := d.BlockUser(tt.ctx, tt.id)
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.expectedError, err.Error())
+			} else {
+				require.NoError(t, err)
+			}
+
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDomain_Posts_HidesBlocked(t *testing.T) {
+	limit, offset := 10, 0
+	posts := []*model.Post{{ID: "1", UserID: "2"}, {ID: "2", UserID: "3"}, {ID: "3", UserID: "4"}}
+
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("Posts", mock.Anything, &limit, &offset).Return(posts, nil)
+	mockStorage.On("Blocks", mock.Anything, "1").Return([]*model.Block{
+		{UserID: "1", TargetID: "2", Kind: model.BlockKindBlock},
+		{UserID: "1", TargetID: "4", Kind: model.BlockKindMute},
+	}, nil)
+	d := &Domain{Storage: mockStorage}
+
+	ctx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"})
+	visible, err := d.Posts(ctx, &limit, &offset)
+	require.NoError(t, err)
+	assert.Equal(t, []*model.Post{{ID: "2", UserID: "3"}}, visible)
+
+	anonymous, err := d.Posts(context.Background(), &limit, &offset)
+	require.NoError(t, err)
+	assert.Len(t, anonymous, 3)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestDomain_Posts_NegativePage(t *testing.T) {
+	// storage isn't asked for pages it can't return
+	d := &Domain{Storage: new(mocks.Storage)}
+
+	_, err := d.Posts(context.Background(), intPtr(-1), nil)
+	assert.EqualError(t, err, "limit must not be negative")
+	_, err = d.Posts(context.Background(), nil, intPtr(-1))
+	assert.EqualError(t, err, "offset must not be negative")
+	_, err = d.Comments(context.Background(), "7", intPtr(10), intPtr(-5))
+	assert.EqualError(t, err, "offset must not be negative")
+}
+
+func TestDomain_Comments_HidesBlocked(t *testing.T) {
+	stored := []*model.Comment{
+		{ID: "1", UserID: "3", Replies: []*model.Comment{{ID: "2", UserID: "2"}, {ID: "3", UserID: "4"}}},
+		{ID: "4", UserID: "2", Replies: []*model.Comment{{ID: "5", UserID: "3"}}},
+	}
+
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("Comments", mock.Anything, "7", (*int)(nil), (*int)(nil)).Return(stored, nil)
+	mockStorage.On("Blocks", mock.Anything, "1").Return([]*model.Block{{UserID: "1", TargetID: "2", Kind: model.BlockKindMute}}, nil)
+	d := &Domain{Storage: mockStorage}
+
+	ctx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"})
+	comments, err := d.Comments(ctx, "7", nil, nil)
+	require.NoError(t, err)
+	require.Len(t, comments, 1, "replies of hidden comments are hidden with them")
+	assert.Equal(t, "1", comments[0].ID)
+	assert.Equal(t, []*model.Comment{{ID: "3", UserID: "4"}}, comments[0].Replies)
+	assert.Len(t, stored[0].Replies, 2, "the stored comments must not be modified")
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestDomain_HiddenCache(t *testing.T) {
+	mockStorage := new(mocks.Storage)
+	for _, postID := range []string{"7", "8", "9"} {
+		mockStorage.On("Comments", mock.Anything, postID, (*int)(nil), (*int)(nil)).Return([]*model.Comment{{ID: postID, UserID: "2"}}, nil)
+	}
+	mockStorage.On("Blocks", mock.Anything, "1").Return(nil, nil).Once()
+	mockStorage.On("UserByID", mock.Anything, "2").Return(&model.User{ID: "2"}, nil)
+	mockStorage.On("AddBlock", mock.Anything, &model.Block{UserID: "1", TargetID: "2", Kind: model.BlockKindMute}).Return(nil)
+	mockStorage.On("Blocks", mock.Anything, "1").Return([]*model.Block{{UserID: "1", TargetID: "2", Kind: model.BlockKindMute}}, nil).Once()
+	d := &Domain{Storage: mockStorage}
+
+	ctx := WithHiddenCache(context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"}))
+	for _, postID := range []string{"7", "8"} {
+		comments, err := d.Comments(ctx, postID, nil, nil)
+		require.NoError(t, err)
+		assert.Len(t, comments, 1)
+	}
+
+	// a change of the blocks in the same request isn't hidden by the cache
+	_, err := d.MuteUser(ctx, "2")
+	require.NoError(t, err)
+	comments, err := d.Comments(ctx, "9", nil, nil)
+	require.NoError(t, err)
+	assert.Empty(t, comments)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestDomain_AddComment_MentionBlocked(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1", Username: "alice"})
+
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("Post", mock.Anything, "3").Return(&model.Post{ID: "3", Title: "Hello", UserID: "1", CommentsEnabled: true}, nil)
+	mockStorage.On("AddComment", mock.Anything, mock.AnythingOfType("*model.Comment")).Return(
+		&model.Comment{ID: "8", PostID: "3", Content: "@bob @carol look", UserID: "1"}, nil)
+	mockStorage.On("UsersByUsernames", mock.Anything, []string{"bob", "carol"}).Return(
+		[]*model.User{{ID: "2", Username: "bob"}, {ID: "4", Username: "carol"}}, nil)
+	// carol blocked alice, she is neither mentioned nor notified
+	mockStorage.On("BlockingUserIDs", mock.Anything, "1", []string{"2", "4"}).Return([]string{"4"}, nil)
+	mockStorage.On("AddMentions", mock.Anything, []*model.Mention{{PostID: "3", CommentID: strPtr("8"), UserID: "2"}}).Return(nil)
+	mockStorage.On("AddNotification", mock.Anything, mock.MatchedBy(func(n *model.Notification) bool {
+		return n.UserID == "2"
+	})).Return(&model.Notification{ID: "1", UserID: "2"}, nil).Once()
+	d := &Domain{Storage: mockStorage}
+
+	_, err := d.AddComment(ctx, model.NewComment{PostID: "3", Content: "@bob @carol look"})
+	require.NoError(t, err)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestDomain_PostAdded_HidesMuted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"}))
+	defer cancel()
+
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("Blocks", mock.Anything, "1").Return([]*model.Block{{UserID: "1", TargetID: "2", Kind: model.BlockKindMute}}, nil)
+	d := &Domain{Storage: mockStorage, Broker: pubsub.NewMemory(pubsub.DefaultOptions)}
+
+	added, err := d.PostAdded(ctx, nil)
+	require.NoError(t, err)
+
+	d.publish(ctx, postsTopic, &model.Post{ID: "5", UserID: "2"})
+	d.publish(ctx, postsTopic, &model.Post{ID: "6", UserID: "3"})
+
+	assert.Equal(t, "6", receive(t, added).ID)
+}
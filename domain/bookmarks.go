@@ -0,0 +1,150 @@
+package domain
+
+import (
+	"context"
+	"github.com/farid21ola/forum/middleware"
+	"github.com/farid21ola/forum/model"
+)
+
+// SavePost adds a published post to the reading list of the current user,
+// saving it again does nothing.
+func (d *Domain) SavePost(ctx context.Context, id string) (*model.Post, error) {
+	return d.bookmarkPost(ctx, id, d.Storage.AddBookmark)
+}
+
+func (d *Domain) UnsavePost(ctx context.Context, id string) (*model.Post, error) {
+	return d.bookmarkPost(ctx, id, d.Storage.RemoveBookmark)
+}
+
+func (d *Domain) SaveComment(ctx context.Context, id string) (*model.Comment, error) {
+	return d.bookmarkComment(ctx, id, d.Storage.AddBookmark)
+}
+
+func (d *Domain) UnsaveComment(ctx context.Context, id string) (*model.Comment, error) {
+	return d.bookmarkComment(ctx, id, d.Storage.RemoveBookmark)
+}
+
+func (d *Domain) bookmarkPost(ctx context.Context, id string, apply func(context.Context, *model.Bookmark) error) (*model.Post, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+	post, err := d.Storage.Post(ctx, id)
+	if err != nil {
+		return nil, notFound(err, errPostNotFound)
+	}
+	if !post.IsPublished() {
+		return nil, errPostNotFound
+	}
+	if err = apply(ctx, &model.Bookmark{UserID: currentUser.ID, PostID: &post.ID}); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+func (d *Domain) bookmarkComment(ctx context.Context, id string, apply func(context.Context, *model.Bookmark) error) (*model.Comment, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+	comment, err := d.Storage.Comment(ctx, id)
+	if err != nil {
+		return nil, notFound(err, errCommentNotFound)
+	}
+	if err = apply(ctx, &model.Bookmark{UserID: currentUser.ID, CommentID: &comment.ID}); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// Saved returns a page of the current user's reading list. Bookmarks of
+// posts and comments that no longer exist are skipped, so a page may hold
+// fewer items than asked.
+func (d *Domain) Saved(ctx context.Context, savedType *model.SavedType, first *int, after *string) (*model.SavedConnection, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+	limit, err := pageLimit(first, after)
+	if err != nil {
+		return nil, err
+	}
+
+	// one more than asked tells whether there is a next page
+	bookmarks, err := d.Storage.Bookmarks(ctx, model.BookmarkFilter{
+		UserID: currentUser.ID,
+		Type:   savedType,
+		After:  after,
+		Limit:  limit + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &model.SavedConnection{
+		Edges:    []*model.SavedEdge{},
+		PageInfo: &model.PageInfo{},
+	}
+	if len(bookmarks) > limit {
+		bookmarks = bookmarks[:limit]
+		conn.PageInfo.HasNextPage = true
+	}
+	items, err := d.savedItems(ctx, bookmarks)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range bookmarks {
+		if node := items[savedKey(b)]; node != nil {
+			conn.Edges = append(conn.Edges, &model.SavedEdge{Cursor: b.ID, Node: node, SavedAt: b.CreatedAt})
+		}
+	}
+	if len(bookmarks) > 0 {
+		conn.PageInfo.EndCursor = &bookmarks[len(bookmarks)-1].ID
+	}
+
+	return conn, nil
+}
+
+// savedItems loads the posts and comments of a page of bookmarks with one
+// call each, keyed by savedKey. Drafts and removed items are left out.
+func (d *Domain) savedItems(ctx context.Context, bookmarks []*model.Bookmark) (map[string]model.SavedItem, error) {
+	var postIDs, commentIDs []string
+	for _, b := range bookmarks {
+		if b.PostID != nil {
+			postIDs = append(postIDs, *b.PostID)
+		} else {
+			commentIDs = append(commentIDs, *b.CommentID)
+		}
+	}
+
+	items := make(map[string]model.SavedItem, len(bookmarks))
+	if len(postIDs) > 0 {
+		posts, err := d.Storage.PostsByIDs(ctx, postIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, post := range posts {
+			if post.IsPublished() {
+				items["post:"+post.ID] = post
+			}
+		}
+	}
+	if len(commentIDs) > 0 {
+		comments, err := d.Storage.CommentsByIDs(ctx, commentIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, comment := range comments {
+			items["comment:"+comment.ID] = comment
+		}
+	}
+
+	return items, nil
+}
+
+func savedKey(b *model.Bookmark) string {
+	if b.PostID != nil {
+		return "post:" + *b.PostID
+	}
+	return "comment:" + *b.CommentID
+}
@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"context"
+	"github.com/farid21ola/forum/mocks"
+	"github.com/farid21ola/forum/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDomain_SavePost(t *testing.T) {
+	userCtx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"})
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		mockSetup     func(m *mocks.Storage)
+		expectedError string
+	}{
+		{
+			name:          "Unauthenticated user",
+			ctx:           context.Background(),
+			mockSetup:     func(m *mocks.Storage) {},
+			expectedError: "unauthenticated",
+		},
+		{
+			name: "Draft of another user",
+			ctx:  userCtx,
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Post", mock.Anything, "3").Return(&model.Post{ID: "3", UserID: "2", Status: model.PostStatusDraft}, nil)
+			},
+			expectedError: "post with this id don't exist",
+		},
+		{
+			name: "Published post",
+			ctx:  userCtx,
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Post", mock.Anything, "3").Return(&model.Post{ID: "3", UserID: "2", Status: model.PostStatusPublished}, nil)
+				m.On("AddBookmark", mock.Anything, &model.Bookmark{UserID: "1", PostID: strPtr("3")}).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(mocks.Storage)
+			tt.mockSetup(mockStorage)
+			d := &Domain{Storage: mockStorage}
+
+			post, err := d.SavePost(tt.ctx, "3")
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.expectedError, err.Error())
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "3", post.ID)
+			}
+
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDomain_UnsaveComment(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"})
+
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("Comment", mock.Anything, "5").Return(&model.Comment{ID: "5", PostID: "3"}, nil)
+	mockStorage.On("RemoveBookmark", mock.Anything, &model.Bookmark{UserID: "1", CommentID: strPtr("5")}).Return(nil)
+	d := &Domain{Storage: mockStorage}
+
+	comment, err := d.UnsaveComment(ctx, "5")
+	require.NoError(t, err)
+	assert.Equal(t, "5", comment.ID)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestDomain_Saved(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"})
+	postType := model.SavedTypePost
+
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("Bookmarks", mock.Anything, model.BookmarkFilter{UserID: "1", After: strPtr("10"), Limit: 3}).
+		Return([]*model.Bookmark{
+			{ID: "9", PostID: strPtr("3")},
+			{ID: "8", CommentID: strPtr("5")},
+			{ID: "6", PostID: strPtr("4")},
+		}, nil)
+	mockStorage.On("PostsByIDs", mock.Anything, []string{"3"}).Return([]*model.Post{{ID: "3", Status: model.PostStatusPublished}}, nil).Once()
+	mockStorage.On("CommentsByIDs", mock.Anything, []string{"5"}).Return(nil, nil).Once()
+	d := &Domain{Storage: mockStorage}
+
+	conn, err := d.Saved(ctx, nil, intPtr(2), strPtr("10"))
+	require.NoError(t, err)
+	require.Len(t, conn.Edges, 1, "the bookmark of a missing comment is skipped")
+	assert.Equal(t, "9", conn.Edges[0].Cursor)
+	assert.Equal(t, &model.Post{ID: "3", Status: model.PostStatusPublished}, conn.Edges[0].Node)
+	assert.Equal(t, strPtr("8"), conn.PageInfo.EndCursor)
+	assert.True(t, conn.PageInfo.HasNextPage)
+
+	mockStorage.On("Bookmarks", mock.Anything, model.BookmarkFilter{UserID: "1", Type: &postType, Limit: 21}).Return(nil, nil)
+	conn, err = d.Saved(ctx, &postType, nil, nil)
+	require.NoError(t, err)
+	assert.Empty(t, conn.Edges)
+	assert.Nil(t, conn.PageInfo.EndCursor)
+
+	mockStorage.On("Bookmarks", mock.Anything, model.BookmarkFilter{UserID: "1", Type: &postType, After: strPtr("9"), Limit: 21}).
+		Return([]*model.Bookmark{{ID: "6", PostID: strPtr("4")}}, nil)
+	mockStorage.On("PostsByIDs", mock.Anything, []string{"4"}).Return([]*model.Post{{ID: "4", Status: model.PostStatusDraft}}, nil)
+	conn, err = d.Saved(ctx, &postType, nil, strPtr("9"))
+	require.NoError(t, err)
+	assert.Empty(t, conn.Edges, "the bookmark of a post turned back into a draft is skipped")
+	assert.Equal(t, strPtr("6"), conn.PageInfo.EndCursor)
+
+	mockStorage.AssertExpectations(t)
+}
@@ -3,10 +3,24 @@ package domain
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/farid21ola/forum/middleware"
 	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/storage"
+	"log"
 )
 
+const maxContentLength = 2000
+
+// validateContent checks the body of a comment or of a message, what names
+// it in the error.
+func validateContent(content, what string) error {
+	if len(content) >= maxContentLength {
+		return fmt.Errorf("too big %s", what)
+	}
+	return nil
+}
+
 func (d *Domain) AddComment(ctx context.Context, input model.NewComment) (*model.Comment, error) {
 	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
 	if err != nil {
@@ -15,24 +29,169 @@ func (d *Domain) AddComment(ctx context.Context, input model.NewComment) (*model
 
 	post, err := d.Storage.Post(ctx, input.PostID)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err, errPostNotFound)
 	}
-	if post == nil {
-		return nil, errors.New("post with this id don't exist")
+	if !post.IsPublished() {
+		return nil, errPostNotFound
 	}
 	if post.CommentsEnabled == false {
 		return nil, errors.New("comments disabled for this post")
 	}
-	if len(input.Content) >= 2000 {
-		return nil, errors.New("too big comment")
+	if err = validateContent(input.Content, "comment"); err != nil {
+		return nil, err
+	}
+
+	var parent *model.Comment
+	repliedTo := []string{post.UserID}
+	if input.ParentID != nil {
+		parent, err = d.Storage.Comment(ctx, *input.ParentID)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return nil, err
+		}
+		if err != nil || parent.PostID != post.ID {
+			return nil, &storage.Error{Err: storage.ErrNotFound, Message: "parent comment with this id don't exist"}
+		}
+		repliedTo = append(repliedTo, parent.UserID)
+	}
+	blocking, err := d.blockedBy(ctx, currentUser, repliedTo)
+	if err != nil {
+		return nil, err
+	}
+	if len(blocking) > 0 {
+		return nil, errBlocked
 	}
 
 	comment := model.Comment{
-		PostID:  input.PostID,
-		Content: input.Content,
-		UserID:  currentUser.ID,
+		PostID:   input.PostID,
+		ParentID: input.ParentID,
+		Content:  input.Content,
+		UserID:   currentUser.ID,
 	}
 	newComment, err := d.Storage.AddComment(ctx, &comment)
+	if err != nil {
+		return nil, err
+	}
+	d.publish(ctx, commentsTopic(newComment.PostID), newComment)
+	notified := d.notifyReply(ctx, currentUser, post, parent, newComment)
+	d.mentionUsers(ctx, currentUser, post, newComment, notified)
+
+	return newComment, nil
+}
+
+func (d *Domain) UpdateComment(ctx context.Context, input model.UpdateComment) (*model.Comment, error) {
+	if err := validateContent(input.Content, "comment"); err != nil {
+		return nil, err
+	}
+
+	comment, err := d.ownComment(ctx, input.ID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.Deleted {
+		return nil, errors.New("comment is deleted")
+	}
+	comment.Content = input.Content
+	comment.Edited = true
+
+	updated, err := d.saveComment(ctx, comment)
+	if err != nil {
+		return nil, err
+	}
+	currentUser, _ := middleware.GetCurrentUserFromCtx(ctx)
+	d.updateCommentMentions(ctx, currentUser, updated)
+
+	return updated, nil
+}
+
+// DeleteComment removes the content of a comment, replies stay in the thread.
+// Besides the author, the author of the post may delete comments under it,
+// the comment author is notified then.
+func (d *Domain) DeleteComment(ctx context.Context, id string) (*model.Comment, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+
+	comment, err := d.commentCopy(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var moderated *model.Post
+	if comment.UserID != currentUser.ID {
+		moderated, err = d.Storage.Post(ctx, comment.PostID)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return nil, err
+		}
+		if err != nil || moderated.UserID != currentUser.ID {
+			return nil, ErrForbidden
+		}
+	}
+	if comment.Deleted {
+		return nil, errors.New("comment is already deleted")
+	}
+	comment.Content = ""
+	comment.Deleted = true
+
+	deleted, err := d.saveComment(ctx, comment)
+	if err != nil {
+		return nil, err
+	}
+	if err = d.Storage.RemoveCommentMentions(ctx, deleted.ID); err != nil {
+		log.Printf("error remove mentions of comment %s: %v", deleted.ID, err)
+	}
+
+	if moderated != nil {
+		err = d.notify(ctx, &model.Notification{
+			UserID:    deleted.UserID,
+			Kind:      model.NotificationKindModeration,
+			ActorID:   &currentUser.ID,
+			PostID:    &moderated.ID,
+			CommentID: &deleted.ID,
+			Message:   fmt.Sprintf("%s removed your comment on the post %q", currentUser.Username, moderated.Title),
+		})
+		if err != nil {
+			log.Printf("error notify user %s about removed comment %s: %v", deleted.UserID, deleted.ID, err)
+		}
+	}
+
+	return deleted, nil
+}
+
+// ownComment returns a copy of a comment written by the current user.
+func (d *Domain) ownComment(ctx context.Context, id string) (*model.Comment, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+
+	comment, err := d.commentCopy(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.UserID != currentUser.ID {
+		return nil, ErrForbidden
+	}
+
+	return comment, nil
+}
+
+func (d *Domain) commentCopy(ctx context.Context, id string) (*model.Comment, error) {
+	comment, err := d.Storage.Comment(ctx, id)
+	if err != nil {
+		return nil, notFound(err, errCommentNotFound)
+	}
+
+	// storage may hand out shared values, they must not change before saving
+	own := *comment
+	return &own, nil
+}
+
+func (d *Domain) saveComment(ctx context.Context, comment *model.Comment) (*model.Comment, error) {
+	updated, err := d.Storage.UpdateComment(ctx, comment)
+	if err != nil {
+		return nil, err
+	}
+	d.publish(ctx, commentUpdatesTopic(updated.PostID), updated)
 
-	return newComment, err
+	return updated, nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/farid21ola/forum/mocks"
 	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -34,7 +35,7 @@ func TestDomain_AddComment(t *testing.T) {
 			ctx:   context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"}),
 			input: model.NewComment{PostID: "1", Content: "Test comment"},
 			mockSetup: func() {
-				mockStorage.On("Post", mock.Anything, "1").Return(nil, nil)
+				mockStorage.On("Post", mock.Anything, "1").Return(nil, storage.ErrNotFound)
 			},
 			wantErr:       true,
 			expectedError: "post with this id don't exist",
@@ -64,12 +65,57 @@ func TestDomain_AddComment(t *testing.T) {
 			ctx:   context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"}),
 			input: model.NewComment{PostID: "1", Content: "Test comment"},
 			mockSetup: func() {
-				mockStorage.On("Post", mock.Anything, "1").Return(&model.Post{CommentsEnabled: true}, nil)
+				mockStorage.On("Post", mock.Anything, "1").Return(&model.Post{ID: "1", UserID: "1", CommentsEnabled: true}, nil)
 				mockStorage.On("AddComment", mock.Anything, mock.Anything).Return(&model.Comment{PostID: "1", Content: "Test comment", UserID: "1"}, nil)
 			},
 			wantErr:         false,
 			expectedComment: &model.Comment{PostID: "1", Content: "Test comment", UserID: "1"},
 		},
+		{
+			name:  "parent from another post",
+			ctx:   context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"}),
+			input: model.NewComment{PostID: "1", ParentID: strPtr("7"), Content: "Test comment"},
+			mockSetup: func() {
+				mockStorage.On("Post", mock.Anything, "1").Return(&model.Post{ID: "1", UserID: "1", CommentsEnabled: true}, nil)
+				mockStorage.On("Comment", mock.Anything, "7").Return(&model.Comment{ID: "7", PostID: "2"}, nil)
+			},
+			wantErr:       true,
+			expectedError: "parent comment with this id don't exist",
+		},
+		{
+			name:  "parent author blocked the user",
+			ctx:   context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"}),
+			input: model.NewComment{PostID: "1", ParentID: strPtr("7"), Content: "Test comment"},
+			mockSetup: func() {
+				mockStorage.On("Post", mock.Anything, "1").Return(&model.Post{ID: "1", UserID: "1", CommentsEnabled: true}, nil)
+				mockStorage.On("Comment", mock.Anything, "7").Return(&model.Comment{ID: "7", PostID: "1", UserID: "3"}, nil)
+				mockStorage.On("BlockingUserIDs", mock.Anything, "1", []string{"3"}).Return([]string{"3"}, nil)
+			},
+			wantErr:       true,
+			expectedError: "you have been blocked by this user",
+		},
+		{
+			name:  "reply notifies post and parent authors",
+			ctx:   context.WithValue(context.Background(), "currentUser", &model.User{ID: "1", Username: "alice"}),
+			input: model.NewComment{PostID: "1", ParentID: strPtr("7"), Content: "Test comment"},
+			mockSetup: func() {
+				mockStorage.On("Post", mock.Anything, "1").Return(&model.Post{ID: "1", UserID: "2", Title: "Hello", CommentsEnabled: true}, nil)
+				mockStorage.On("Comment", mock.Anything, "7").Return(&model.Comment{ID: "7", PostID: "1", UserID: "3"}, nil)
+				mockStorage.On("BlockingUserIDs", mock.Anything, "1", []string{"2", "3"}).Return(nil, nil)
+				mockStorage.On("AddComment", mock.Anything, &model.Comment{PostID: "1", ParentID: strPtr("7"), Content: "Test comment", UserID: "1"}).
+					Return(&model.Comment{ID: "8", PostID: "1", ParentID: strPtr("7"), Content: "Test comment", UserID: "1"}, nil)
+				mockStorage.On("AddNotification", mock.Anything, &model.Notification{
+					UserID: "3", Kind: model.NotificationKindReply, ActorID: strPtr("1"), PostID: strPtr("1"), CommentID: strPtr("8"),
+					Message: "alice replied to your comment",
+				}).Return(&model.Notification{ID: "1", UserID: "3"}, nil).Once()
+				mockStorage.On("AddNotification", mock.Anything, &model.Notification{
+					UserID: "2", Kind: model.NotificationKindReply, ActorID: strPtr("1"), PostID: strPtr("1"), CommentID: strPtr("8"),
+					Message: `alice commented on your post "Hello"`,
+				}).Return(&model.Notification{ID: "2", UserID: "2"}, nil).Once()
+			},
+			wantErr:         false,
+			expectedComment: &model.Comment{ID: "8", PostID: "1", ParentID: strPtr("7"), Content: "Test comment", UserID: "1"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -92,3 +138,141 @@ func TestDomain_AddComment(t *testing.T) {
 		})
 	}
 }
+
+func TestDomain_UpdateComment(t *testing.T) {
+	authorCtx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"})
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		input         model.UpdateComment
+		mockSetup     func(m *mocks.Storage)
+		expectedError string
+	}{
+		{
+			name:          "Unauthenticated user",
+			ctx:           context.Background(),
+			input:         model.UpdateComment{ID: "5", Content: "edited"},
+			mockSetup:     func(m *mocks.Storage) {},
+			expectedError: "unauthenticated",
+		},
+		{
+			name:  "Someone else's comment",
+			ctx:   context.WithValue(context.Background(), "currentUser", &model.User{ID: "2"}),
+			input: model.UpdateComment{ID: "5", Content: "edited"},
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Comment", mock.Anything, "5").Return(&model.Comment{ID: "5", UserID: "1"}, nil)
+			},
+			expectedError: "unauthorized",
+		},
+		{
+			name:  "Deleted comment",
+			ctx:   authorCtx,
+			input: model.UpdateComment{ID: "5", Content: "edited"},
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Comment", mock.Anything, "5").Return(&model.Comment{ID: "5", UserID: "1", Deleted: true}, nil)
+			},
+			expectedError: "comment is deleted",
+		},
+		{
+			name:  "Successful edit",
+			ctx:   authorCtx,
+			input: model.UpdateComment{ID: "5", Content: "edited"},
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Comment", mock.Anything, "5").Return(&model.Comment{ID: "5", UserID: "1", Content: "original"}, nil)
+				m.On("UpdateComment", mock.Anything, &model.Comment{ID: "5", UserID: "1", Content: "edited", Edited: true}).
+					Return(&model.Comment{ID: "5", UserID: "1", Content: "edited", Edited: true}, nil)
+				m.On("CommentsMentions", mock.Anything, []string{"5"}).Return(nil, nil)
+				m.On("RemoveCommentMentions", mock.Anything, "5").Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(mocks.Storage)
+			tt.mockSetup(mockStorage)
+			d := &Domain{Storage: mockStorage}
+
+			comment, err := d.UpdateComment(tt.ctx, tt.input)
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.expectedError, err.Error())
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "edited", comment.Content)
+				assert.True(t, comment.Edited)
+			}
+
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDomain_DeleteComment(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"})
+	stored := &model.Comment{ID: "5", PostID: "3", UserID: "1", Content: "original"}
+
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("Comment", mock.Anything, "5").Return(stored, nil)
+	mockStorage.On("UpdateComment", mock.Anything, &model.Comment{ID: "5", PostID: "3", UserID: "1", Deleted: true}).
+		Return(&model.Comment{ID: "5", PostID: "3", UserID: "1", Deleted: true}, nil)
+	mockStorage.On("RemoveCommentMentions", mock.Anything, "5").Return(nil)
+	d := &Domain{Storage: mockStorage}
+
+	comment, err := d.DeleteComment(ctx, "5")
+	require.NoError(t, err)
+	assert.True(t, comment.Deleted)
+	assert.Empty(t, comment.Content)
+	assert.Equal(t, "original", stored.Content, "the stored comment must not be modified")
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestDomain_DeleteComment_ByPostAuthor(t *testing.T) {
+	tests := []struct {
+		name          string
+		post          *model.Post
+		expectedError string
+	}{
+		{
+			name:          "Someone else's post",
+			post:          &model.Post{ID: "3", UserID: "4"},
+			expectedError: "unauthorized",
+		},
+		{
+			name: "Own post",
+			post: &model.Post{ID: "3", UserID: "2", Title: "Hello"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "2", Username: "bob"})
+			mockStorage := new(mocks.Storage)
+			mockStorage.On("Comment", mock.Anything, "5").Return(&model.Comment{ID: "5", PostID: "3", UserID: "1", Content: "spam"}, nil)
+			mockStorage.On("Post", mock.Anything, "3").Return(tt.post, nil)
+			if tt.expectedError == "" {
+				mockStorage.On("UpdateComment", mock.Anything, &model.Comment{ID: "5", PostID: "3", UserID: "1", Deleted: true}).
+					Return(&model.Comment{ID: "5", PostID: "3", UserID: "1", Deleted: true}, nil)
+				mockStorage.On("RemoveCommentMentions", mock.Anything, "5").Return(nil)
+				mockStorage.On("AddNotification", mock.Anything, &model.Notification{
+					UserID: "1", Kind: model.NotificationKindModeration, ActorID: strPtr("2"), PostID: strPtr("3"), CommentID: strPtr("5"),
+					Message: `bob removed your comment on the post "Hello"`,
+				}).Return(&model.Notification{ID: "1", UserID: "1"}, nil)
+			}
+			d := &Domain{Storage: mockStorage}
+
+			comment, err := d.DeleteComment(ctx, "5")
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.expectedError, err.Error())
+			} else {
+				require.NoError(t, err)
+				assert.True(t, comment.Deleted)
+			}
+
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
@@ -1,8 +1,13 @@
 package domain
 
 import (
+	"context"
 	"errors"
+	"github.com/farid21ola/forum/markdown"
+	"github.com/farid21ola/forum/media"
+	"github.com/farid21ola/forum/pubsub"
 	"github.com/farid21ola/forum/storage"
+	"sync"
 )
 
 var (
@@ -11,10 +16,71 @@ var (
 	ErrForbidden       = errors.New("unauthorized")
 )
 
+// Errors of rows that don't exist or that the user can't see, they match
+// storage.ErrNotFound.
+var (
+	errPostNotFound    = &storage.Error{Err: storage.ErrNotFound, Message: "post with this id don't exist"}
+	errCommentNotFound = &storage.Error{Err: storage.ErrNotFound, Message: "comment with this id don't exist"}
+	errUserNotFound    = &storage.Error{Err: storage.ErrNotFound, Message: "user with this id don't exist"}
+	errPollNotFound    = &storage.Error{Err: storage.ErrNotFound, Message: "poll with this id don't exist"}
+)
+
+// notFound replaces storage.ErrNotFound with an error that names the missing
+// row.
+func notFound(err, notFoundErr error) error {
+	if errors.Is(err, storage.ErrNotFound) {
+		return notFoundErr
+	}
+	return err
+}
+
+var (
+	ErrUploadsDisabled = errors.New("uploads are not configured")
+	ErrEventsDisabled  = errors.New("subscriptions are not configured")
+	ErrInvalidEventID  = errors.New("invalid event id")
+)
+
 type Domain struct {
-	Storage storage.Storage
+	Storage     storage.Storage
+	Notifier    Notifier
+	LoginPolicy LoginPolicy
+	// Media stores uploaded images, uploads are rejected when it is nil.
+	Media *media.Processor
+	// Broker delivers events to subscriptions, without it changes are not
+	// published.
+	Broker pubsub.Broker
+	// Markdown renders post and comment content to html.
+	Markdown *markdown.Renderer
+	// Reactions are the emojis users can react with.
+	Reactions []string
+
+	// tasks are the jobs started off the request path.
+	tasks sync.WaitGroup
+}
+
+// background runs f outside of the request, it gets a context of its own
+// since the request one is canceled once the response is sent.
+func (d *Domain) background(f func(ctx context.Context)) {
+	d.tasks.Add(1)
+	go func() {
+		defer d.tasks.Done()
+		f(context.Background())
+	}()
+}
+
+// Wait blocks until the jobs started in the background are done.
+func (d *Domain) Wait() {
+	d.tasks.Wait()
 }
 
 func NewDomain(storage storage.Storage) *Domain {
-	return &Domain{Storage: storage}
+	d := &Domain{
+		Storage:     storage,
+		LoginPolicy: DefaultLoginPolicy,
+		Markdown:    markdown.New(markdown.DefaultCacheSize),
+		Reactions:   DefaultReactions,
+	}
+	d.Notifier = InboxNotifier{Domain: d}
+
+	return d
 }
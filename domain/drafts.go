@@ -0,0 +1,205 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"github.com/farid21ola/forum/middleware"
+	"github.com/farid21ola/forum/model"
+	"log"
+	"time"
+)
+
+// schedulerBatch limits how many posts one scheduler run publishes, the rest
+// are published by the next runs.
+const schedulerBatch = 100
+
+var errAlreadyPublished = errors.New("post is already published")
+
+// SaveDraft creates a draft or updates a draft or a scheduled post of the
+// current user. A draft may be too short to publish but not too long, a
+// scheduled post must stay publishable.
+func (d *Domain) SaveDraft(ctx context.Context, input model.DraftInput) (*model.Post, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+	if err = validateDraftContent(input.Title, input.Content); err != nil {
+		return nil, err
+	}
+	tags, err := normalizeTags(input.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.ID == nil {
+		return d.Storage.CreatePost(ctx, &model.Post{
+			Title:   input.Title,
+			Content: input.Content,
+			UserID:  currentUser.ID,
+			Tags:    tags,
+			Status:  model.PostStatusDraft,
+		}, nil, nil)
+	}
+
+	post, err := d.ownDraft(ctx, currentUser, *input.ID)
+	if err != nil {
+		return nil, err
+	}
+	if post.Status == model.PostStatusScheduled {
+		if err = validatePostContent(input.Title, input.Content); err != nil {
+			return nil, err
+		}
+	}
+	post.Title = input.Title
+	post.Content = input.Content
+	post.Tags = tags
+
+	return d.updateDraft(ctx, post)
+}
+
+// PublishPost publishes a draft or a scheduled post right away.
+func (d *Domain) PublishPost(ctx context.Context, id string) (*model.Post, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+	post, err := d.ownDraft(ctx, currentUser, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = validatePostContent(post.Title, post.Content); err != nil {
+		return nil, err
+	}
+
+	published, err := d.Storage.PublishPost(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// the scheduler may have published it in the meantime
+	if published == nil {
+		return nil, errAlreadyPublished
+	}
+	d.postPublished(ctx, currentUser, published)
+
+	return published, nil
+}
+
+// SchedulePost makes a draft publish itself at the given time, the time of a
+// scheduled post can be moved.
+func (d *Domain) SchedulePost(ctx context.Context, id string, at time.Time) (*model.Post, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+	if !at.After(time.Now()) {
+		return nil, errors.New("publish time must be in the future")
+	}
+	post, err := d.ownDraft(ctx, currentUser, id)
+	if err != nil {
+		return nil, err
+	}
+	// the scheduler can't report errors to the author, so the post is
+	// checked now
+	if err = validatePostContent(post.Title, post.Content); err != nil {
+		return nil, err
+	}
+	post.Status = model.PostStatusScheduled
+	post.PublishAt = &at
+
+	return d.updateDraft(ctx, post)
+}
+
+func (d *Domain) MyDrafts(ctx context.Context) ([]*model.Post, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+
+	return d.Storage.UserDrafts(ctx, currentUser.ID)
+}
+
+// PublishDuePosts publishes scheduled posts whose time has come and returns
+// how many it published. Posts that can't be published any more, e.g. they
+// were saved before the checks got stricter, become drafts again.
+func (d *Domain) PublishDuePosts(ctx context.Context, now time.Time) (int, error) {
+	posts, err := d.Storage.PublishDuePosts(ctx, now, schedulerBatch, publishable)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, post := range posts {
+		author, err := d.Storage.UserByID(ctx, post.UserID)
+		if err != nil {
+			log.Printf("error load author of scheduled post %s: %v", post.ID, err)
+			continue
+		}
+		d.postPublished(ctx, author, post)
+	}
+
+	return len(posts), nil
+}
+
+func publishable(post *model.Post) bool {
+	if err := validatePostContent(post.Title, post.Content); err != nil {
+		log.Printf("scheduled post %s is a draft again: %v", post.ID, err)
+		return false
+	}
+	return true
+}
+
+// RunScheduler publishes due posts every interval until ctx is done. Every
+// server instance runs it, storage hands each due post to only one of them.
+func (d *Domain) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// a full batch means that more posts may be due already
+		for {
+			n, err := d.PublishDuePosts(ctx, time.Now())
+			if err != nil {
+				log.Printf("error publish scheduled posts: %v", err)
+				break
+			}
+			if n < schedulerBatch {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ownDraft returns a copy of an unpublished post of the user.
+func (d *Domain) ownDraft(ctx context.Context, user *model.User, id string) (*model.Post, error) {
+	post, err := d.Storage.Post(ctx, id)
+	if err != nil {
+		return nil, notFound(err, errPostNotFound)
+	}
+	if post.UserID != user.ID {
+		return nil, ErrForbidden
+	}
+	if post.IsPublished() {
+		return nil, errAlreadyPublished
+	}
+
+	// storage may hand out shared values, they must not change before saving
+	own := *post
+	return &own, nil
+}
+
+func (d *Domain) updateDraft(ctx context.Context, post *model.Post) (*model.Post, error) {
+	updated, err := d.Storage.UpdateDraft(ctx, post)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, errAlreadyPublished
+	}
+
+	return updated, nil
+}
@@ -0,0 +1,300 @@
+package domain
+
+import (
+	"context"
+	"github.com/farid21ola/forum/mocks"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/pubsub"
+	"github.com/farid21ola/forum/storage/inmemory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDomain_SaveDraft(t *testing.T) {
+	userCtx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"})
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		input         model.DraftInput
+		mockSetup     func(m *mocks.Storage)
+		expectedError string
+	}{
+		{
+			name:          "Unauthenticated user",
+			ctx:           context.Background(),
+			input:         model.DraftInput{Title: "T"},
+			mockSetup:     func(m *mocks.Storage) {},
+			expectedError: "unauthenticated",
+		},
+		{
+			name:  "New draft",
+			ctx:   userCtx,
+			input: model.DraftInput{Title: "T", Content: "", Tags: []string{"Go"}},
+			mockSetup: func(m *mocks.Storage) {
+				m.On("CreatePost", mock.Anything, &model.Post{
+					Title: "T", UserID: "1", Tags: []string{"go"}, Status: model.PostStatusDraft,
+				}, []*model.Image(nil), (*model.Poll)(nil)).Return(&model.Post{ID: "2", Status: model.PostStatusDraft}, nil)
+			},
+		},
+		{
+			name:          "New draft with a too long title",
+			ctx:           userCtx,
+			input:         model.DraftInput{Title: strings.Repeat("я", maxTitleLength+1)},
+			mockSetup:     func(m *mocks.Storage) {},
+			expectedError: "title is too long",
+		},
+		{
+			name:          "New draft with too big content",
+			ctx:           userCtx,
+			input:         model.DraftInput{Title: "T", Content: strings.Repeat("a", maxContentLength)},
+			mockSetup:     func(m *mocks.Storage) {},
+			expectedError: "too big post",
+		},
+		{
+			name:          "Draft updated with too big content",
+			ctx:           userCtx,
+			input:         model.DraftInput{ID: strPtr("2"), Title: "T", Content: strings.Repeat("a", maxContentLength)},
+			mockSetup:     func(m *mocks.Storage) {},
+			expectedError: "too big post",
+		},
+		{
+			name:  "Someone else's draft",
+			ctx:   userCtx,
+			input: model.DraftInput{ID: strPtr("2"), Title: "T"},
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Post", mock.Anything, "2").Return(&model.Post{ID: "2", UserID: "3", Status: model.PostStatusDraft}, nil)
+			},
+			expectedError: "unauthorized",
+		},
+		{
+			name:  "Published post",
+			ctx:   userCtx,
+			input: model.DraftInput{ID: strPtr("2"), Title: "T"},
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Post", mock.Anything, "2").Return(&model.Post{ID: "2", UserID: "1", Status: model.PostStatusPublished}, nil)
+			},
+			expectedError: "post is already published",
+		},
+		{
+			name:  "Invalid scheduled post",
+			ctx:   userCtx,
+			input: model.DraftInput{ID: strPtr("2"), Title: "New"},
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Post", mock.Anything, "2").Return(&model.Post{ID: "2", UserID: "1", Status: model.PostStatusScheduled}, nil)
+			},
+			expectedError: "content not long enough",
+		},
+		{
+			name:  "Published by the scheduler while saving",
+			ctx:   userCtx,
+			input: model.DraftInput{ID: strPtr("2"), Title: "New", Content: "Text"},
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Post", mock.Anything, "2").Return(&model.Post{ID: "2", UserID: "1", Status: model.PostStatusScheduled}, nil)
+				m.On("UpdateDraft", mock.Anything, mock.AnythingOfType("*model.Post")).Return(nil, nil)
+			},
+			expectedError: "post is already published",
+		},
+		{
+			name:  "Update draft",
+			ctx:   userCtx,
+			input: model.DraftInput{ID: strPtr("2"), Title: "New", Content: "Text"},
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Post", mock.Anything, "2").Return(&model.Post{ID: "2", UserID: "1", Title: "Old", Status: model.PostStatusDraft}, nil)
+				m.On("UpdateDraft", mock.Anything, &model.Post{
+					ID: "2", UserID: "1", Title: "New", Content: "Text", Tags: []string{}, Status: model.PostStatusDraft,
+				}).Return(&model.Post{ID: "2", Status: model.PostStatusDraft}, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(mocks.Storage)
+			tt.mockSetup(mockStorage)
+			d := &Domain{Storage: mockStorage}
+
+			post, err := d.SaveDraft(tt.ctx, tt.input)
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.expectedError, err.Error())
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, model.PostStatusDraft, post.Status)
+			}
+
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDomain_PublishPost(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"}))
+	defer cancel()
+
+	tests := []struct {
+		name          string
+		mockSetup     func(m *mocks.Storage)
+		expectedError string
+	}{
+		{
+			name: "Content not long enough",
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Post", mock.Anything, "2").Return(&model.Post{ID: "2", UserID: "1", Title: "Title", Status: model.PostStatusDraft}, nil)
+			},
+			expectedError: "content not long enough",
+		},
+		{
+			name: "Published by the scheduler first",
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Post", mock.Anything, "2").Return(&model.Post{ID: "2", UserID: "1", Title: "Title", Content: "Text", Status: model.PostStatusScheduled}, nil)
+				m.On("PublishPost", mock.Anything, "2").Return(nil, nil)
+			},
+			expectedError: "post is already published",
+		},
+		{
+			name: "Successful publish",
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Post", mock.Anything, "2").Return(&model.Post{ID: "2", UserID: "1", Title: "Title", Content: "Text", Status: model.PostStatusDraft}, nil)
+				m.On("PublishPost", mock.Anything, "2").Return(&model.Post{ID: "2", UserID: "1", Title: "Title", Content: "Text", Status: model.PostStatusPublished}, nil)
+				m.On("AddFollowerNotifications", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(mocks.Storage)
+			tt.mockSetup(mockStorage)
+			mockStorage.On("Blocks", mock.Anything, "1").Return(nil, nil)
+			d := &Domain{Storage: mockStorage, Broker: pubsub.NewMemory(pubsub.DefaultOptions)}
+			added, err := d.PostAdded(ctx, nil)
+			require.NoError(t, err)
+
+			post, err := d.PublishPost(ctx, "2")
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.expectedError, err.Error())
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, model.PostStatusPublished, post.Status)
+				assert.Equal(t, "2", receive(t, added).ID)
+			}
+
+			d.Wait()
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDomain_SchedulePost(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"})
+	at := time.Now().Add(time.Hour)
+
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("Post", mock.Anything, "2").Return(&model.Post{ID: "2", UserID: "1", Title: "Title", Content: "Text", Status: model.PostStatusDraft}, nil)
+	mockStorage.On("UpdateDraft", mock.Anything, &model.Post{
+		ID: "2", UserID: "1", Title: "Title", Content: "Text", Status: model.PostStatusScheduled, PublishAt: &at,
+	}).Return(&model.Post{ID: "2", Status: model.PostStatusScheduled, PublishAt: &at}, nil)
+	d := &Domain{Storage: mockStorage}
+
+	_, err := d.SchedulePost(ctx, "2", time.Now().Add(-time.Minute))
+	assert.EqualError(t, err, "publish time must be in the future")
+
+	post, err := d.SchedulePost(ctx, "2", at)
+	require.NoError(t, err)
+	assert.Equal(t, model.PostStatusScheduled, post.Status)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestDomain_PublishDuePosts(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	now := time.Now()
+
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("PublishDuePosts", mock.Anything, now, schedulerBatch, mock.Anything).Return([]*model.Post{
+		{ID: "2", UserID: "1", Title: "Title", Content: "hi @bob", Status: model.PostStatusPublished},
+	}, nil)
+	mockStorage.On("UserByID", mock.Anything, "1").Return(&model.User{ID: "1", Username: "alice"}, nil)
+	mockStorage.On("UsersByUsernames", mock.Anything, []string{"bob"}).Return([]*model.User{{ID: "3", Username: "bob"}}, nil)
+	mockStorage.On("BlockingUserIDs", mock.Anything, "1", []string{"3"}).Return(nil, nil)
+	mockStorage.On("AddMentions", mock.Anything, []*model.Mention{{PostID: "2", UserID: "3"}}).Return(nil)
+	mockStorage.On("AddNotification", mock.Anything, mock.AnythingOfType("*model.Notification")).Return(&model.Notification{ID: "1", UserID: "3"}, nil)
+	mockStorage.On("AddFollowerNotifications", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	d := &Domain{Storage: mockStorage, Broker: pubsub.NewMemory(pubsub.DefaultOptions)}
+	added, err := d.PostAdded(ctx, nil)
+	require.NoError(t, err)
+
+	n, err := d.PublishDuePosts(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, "2", receive(t, added).ID)
+
+	d.Wait()
+	mockStorage.AssertExpectations(t)
+}
+
+func TestDomain_ScheduledPostEdits(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"posts.json", "users.json"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte("[]"), 0o644))
+	}
+	st := inmemory.New(dir)
+	user, err := st.CreateUser(context.Background(), nil, &model.User{Username: "alice", Password: "hash"})
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), "currentUser", user)
+	d := &Domain{Storage: st, Broker: pubsub.NewMemory(pubsub.DefaultOptions)}
+
+	schedule := func(title string) *model.Post {
+		draft, err := d.SaveDraft(ctx, model.DraftInput{Title: title, Content: "Text"})
+		require.NoError(t, err)
+		post, err := d.SchedulePost(ctx, draft.ID, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		return post
+	}
+	valid := schedule("Valid")
+	stale := schedule("Stale")
+
+	_, err = d.SaveDraft(ctx, model.DraftInput{ID: &valid.ID, Title: "", Content: "Text"})
+	require.EqualError(t, err, "title not long enough")
+	// a post saved before the checks got stricter
+	stale.Content = strings.Repeat("a", maxContentLength)
+	_, err = st.UpdateDraft(ctx, stale)
+	require.NoError(t, err)
+
+	n, err := d.PublishDuePosts(ctx, time.Now().Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	post, err := st.Post(ctx, valid.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Valid", post.Title)
+	assert.Equal(t, model.PostStatusPublished, post.Status)
+	post, err = st.Post(ctx, stale.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.PostStatusDraft, post.Status)
+	assert.Nil(t, post.PublishAt)
+}
+
+func TestDomain_Post_Drafts(t *testing.T) {
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("Post", mock.Anything, "2").Return(&model.Post{ID: "2", UserID: "1", Status: model.PostStatusDraft}, nil)
+	d := &Domain{Storage: mockStorage}
+
+	post, err := d.Post(context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"}), "2")
+	require.NoError(t, err)
+	assert.Equal(t, "2", post.ID)
+
+	_, err = d.Post(context.WithValue(context.Background(), "currentUser", &model.User{ID: "3"}), "2")
+	assert.EqualError(t, err, "post with this id don't exist")
+	_, err = d.Post(context.Background(), "2")
+	assert.EqualError(t, err, "post with this id don't exist")
+}
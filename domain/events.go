@@ -0,0 +1,163 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/pubsub"
+	"log"
+	"strconv"
+)
+
+// eventIDSetter is implemented by values that carry the id of the event that
+// delivered them.
+type eventIDSetter interface {
+	SetEventID(id string)
+}
+
+const postsTopic = "posts"
+
+func postTopic(id string) string {
+	return "post:" + id
+}
+
+func commentsTopic(postID string) string {
+	return "comments:" + postID
+}
+
+func commentUpdatesTopic(postID string) string {
+	return "comment-updates:" + postID
+}
+
+func scoresTopic(postID string) string {
+	return "scores:" + postID
+}
+
+func notificationsTopic(userID string) string {
+	return "notifications:" + userID
+}
+
+func messagesTopic(userID string) string {
+	return "messages:" + userID
+}
+
+func reactionsTopic(postID string) string {
+	return "reactions:" + postID
+}
+
+func pollTopic(id string) string {
+	return "poll:" + id
+}
+
+// publish sends v to the subscribers of topic. Failures are only logged, the
+// change that caused the event has already been stored.
+func (d *Domain) publish(ctx context.Context, topic string, v interface{}) {
+	if d.Broker == nil {
+		return
+	}
+
+	payload, err := json.Marshal(v)
+	if err == nil {
+		err = d.Broker.Publish(ctx, topic, payload)
+	}
+	if err != nil {
+		log.Printf("error publish to %s: %v", topic, err)
+	}
+}
+
+// subscribe decodes the messages of topic into values of T and passes on the
+// ones accepted by keep. With since set, logged messages published after that
+// event come first. The channel is closed when the subscription ends.
+func subscribe[T any](ctx context.Context, b pubsub.Broker, topic string, since *string, keep func(*T) bool) (<-chan *T, error) {
+	if b == nil {
+		return nil, ErrEventsDisabled
+	}
+
+	var msgs <-chan pubsub.Message
+	var err error
+	if since != nil {
+		id, parseErr := strconv.ParseInt(*since, 10, 64)
+		if parseErr != nil || id < 0 {
+			return nil, ErrInvalidEventID
+		}
+		msgs, err = pubsub.Resume(ctx, b, topic, id)
+	} else {
+		msgs, err = b.Subscribe(ctx, topic)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan *T, 1)
+	go func() {
+		defer close(ch)
+		for msg := range msgs {
+			v := new(T)
+			if err := json.Unmarshal(msg.Payload, v); err != nil {
+				log.Printf("error decode message from %s: %v", topic, err)
+				continue
+			}
+			if keep != nil && !keep(v) {
+				continue
+			}
+			if setter, ok := any(v).(eventIDSetter); ok {
+				setter.SetEventID(strconv.FormatInt(msg.ID, 10))
+			}
+			select {
+			case ch <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// CommentAdded streams new comments of a post, the ones added after the
+// since event are replayed first.
+func (d *Domain) CommentAdded(ctx context.Context, postID string, since *string) (<-chan *model.Comment, error) {
+	hidden, err := d.freshHiddenAuthors(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return subscribe[model.Comment](ctx, d.Broker, commentsTopic(postID), since, keepVisible(hidden, commentAuthor, nil))
+}
+
+// CommentUpdated streams edited and deleted comments of a post.
+func (d *Domain) CommentUpdated(ctx context.Context, postID string) (<-chan *model.Comment, error) {
+	hidden, err := d.freshHiddenAuthors(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return subscribe[model.Comment](ctx, d.Broker, commentUpdatesTopic(postID), nil, keepVisible(hidden, commentAuthor, nil))
+}
+
+// PostAdded streams new posts, only the ones tagged with tag when it is set.
+func (d *Domain) PostAdded(ctx context.Context, tag *string) (<-chan *model.Post, error) {
+	var keep func(*model.Post) bool
+	if tag != nil {
+		want := normalizeTag(*tag)
+		keep = func(post *model.Post) bool {
+			for _, t := range post.Tags {
+				if t == want {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	hidden, err := d.freshHiddenAuthors(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return subscribe[model.Post](ctx, d.Broker, postsTopic, nil, keepVisible(hidden, postAuthor, keep))
+}
+
+func (d *Domain) PostUpdated(ctx context.Context, id string) (<-chan *model.Post, error) {
+	return subscribe[model.Post](ctx, d.Broker, postTopic(id), nil, nil)
+}
+
+func (d *Domain) ScoreChanged(ctx context.Context, postID string) (<-chan *model.ScoreChange, error) {
+	return subscribe[model.ScoreChange](ctx, d.Broker, scoresTopic(postID), nil, nil)
+}
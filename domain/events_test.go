@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"context"
+	"github.com/farid21ola/forum/mocks"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func receive[T any](t *testing.T, ch <-chan *T) *T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return nil
+	}
+}
+
+func TestDomain_PostAdded(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"}))
+	defer cancel()
+
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("CreatePost", mock.Anything, mock.AnythingOfType("*model.Post"), []*model.Image(nil), (*model.Poll)(nil)).Return(
+		func(ctx context.Context, post *model.Post, images []*model.Image, poll *model.Poll) *model.Post {
+			created := *post
+			created.ID = post.Title
+			return &created
+		}, nil)
+	mockStorage.On("AddFollowerNotifications", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	mockStorage.On("Blocks", mock.Anything, "1").Return(nil, nil)
+	d := &Domain{Storage: mockStorage, Broker: pubsub.NewMemory(pubsub.DefaultOptions)}
+
+	tag := "Go"
+	tagged, err := d.PostAdded(ctx, &tag)
+	require.NoError(t, err)
+	all, err := d.PostAdded(ctx, nil)
+	require.NoError(t, err)
+
+	_, err = d.CreatePost(ctx, model.NewPost{Title: "first", Content: "no tags"})
+	require.NoError(t, err)
+	_, err = d.CreatePost(ctx, model.NewPost{Title: "second", Content: "tagged", Tags: []string{"go"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "first", receive(t, all).ID)
+	assert.Equal(t, "second", receive(t, all).ID)
+	assert.Equal(t, "second", receive(t, tagged).ID)
+	d.Wait()
+}
+
+func TestDomain_CommentUpdated(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"}))
+	defer cancel()
+
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("Comment", mock.Anything, "5").Return(&model.Comment{ID: "5", PostID: "3", UserID: "1"}, nil)
+	mockStorage.On("UpdateComment", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, c *model.Comment) *model.Comment { return c }, nil)
+	mockStorage.On("RemoveCommentMentions", mock.Anything, "5").Return(nil)
+	mockStorage.On("Blocks", mock.Anything, "1").Return(nil, nil)
+	d := &Domain{Storage: mockStorage, Broker: pubsub.NewMemory(pubsub.DefaultOptions)}
+
+	updates, err := d.CommentUpdated(ctx, "3")
+	require.NoError(t, err)
+
+	_, err = d.DeleteComment(ctx, "5")
+	require.NoError(t, err)
+
+	comment := receive(t, updates)
+	assert.Equal(t, "5", comment.ID)
+	assert.True(t, comment.Deleted)
+}
+
+func TestDomain_ScoreChanged(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), "currentUser", &model.User{ID: "2"}))
+	defer cancel()
+
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("Post", mock.Anything, "1").Return(&model.Post{ID: "1"}, nil)
+	mockStorage.On("VotePost", mock.Anything, mock.Anything).Return(1, nil)
+	d := &Domain{Storage: mockStorage, Broker: pubsub.NewMemory(pubsub.DefaultOptions)}
+
+	scores, err := d.ScoreChanged(ctx, "1")
+	require.NoError(t, err)
+
+	_, err = d.VotePost(ctx, "1", 1)
+	require.NoError(t, err)
+
+	assert.Equal(t, &model.ScoreChange{PostID: "1", Score: 1}, receive(t, scores))
+}
+
+func TestDomain_SubscribeWithoutBroker(t *testing.T) {
+	d := &Domain{}
+
+	_, err := d.CommentAdded(context.Background(), "1", nil)
+	assert.ErrorIs(t, err, ErrEventsDisabled)
+}
+
+func TestDomain_CommentAdded_Since(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"}))
+	defer cancel()
+
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("Post", mock.Anything, "1").Return(&model.Post{ID: "1", UserID: "1", CommentsEnabled: true}, nil)
+	mockStorage.On("AddComment", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, c *model.Comment) *model.Comment { return c }, nil)
+	mockStorage.On("Blocks", mock.Anything, "1").Return(nil, nil)
+	d := &Domain{Storage: mockStorage, Broker: pubsub.NewMemory(pubsub.DefaultOptions)}
+
+	for _, content := range []string{"first", "missed", "also missed"} {
+		_, err := d.AddComment(ctx, model.NewComment{PostID: "1", Content: content})
+		require.NoError(t, err)
+	}
+
+	since := "1"
+	comments, err := d.CommentAdded(ctx, "1", &since)
+	require.NoError(t, err)
+
+	_, err = d.AddComment(ctx, model.NewComment{PostID: "1", Content: "live"})
+	require.NoError(t, err)
+
+	for _, want := range []struct{ content, eventID string }{
+		{"missed", "2"}, {"also missed", "3"}, {"live", "4"},
+	} {
+		comment := receive(t, comments)
+		assert.Equal(t, want.content, comment.Content)
+		require.NotNil(t, comment.EventID)
+		assert.Equal(t, want.eventID, *comment.EventID)
+	}
+
+	invalid := "abc"
+	_, err = d.CommentAdded(ctx, "1", &invalid)
+	assert.ErrorIs(t, err, ErrInvalidEventID)
+}
@@ -0,0 +1,188 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/farid21ola/forum/middleware"
+	"github.com/farid21ola/forum/model"
+	"log"
+)
+
+// FollowUser adds the posts of a user to the feed of the current user,
+// following someone again does nothing.
+func (d *Domain) FollowUser(ctx context.Context, id string) (*model.User, error) {
+	return d.follow(ctx, id, d.Storage.Follow)
+}
+
+func (d *Domain) UnfollowUser(ctx context.Context, id string) (*model.User, error) {
+	return d.follow(ctx, id, d.Storage.Unfollow)
+}
+
+func (d *Domain) follow(ctx context.Context, id string, apply func(context.Context, *model.Follow) error) (*model.User, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+	if id == currentUser.ID {
+		return nil, errors.New("you can't follow yourself")
+	}
+	user, err := d.Storage.UserByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err, errUserNotFound)
+	}
+	if err = apply(ctx, &model.Follow{FollowerID: currentUser.ID, FolloweeID: user.ID}); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Followers returns a page of the users following userID.
+func (d *Domain) Followers(ctx context.Context, userID string, first *int, after *string) (*model.FollowConnection, error) {
+	return d.follows(ctx, model.FollowFilter{FolloweeID: userID}, first, after)
+}
+
+// Following returns a page of the users userID follows.
+func (d *Domain) Following(ctx context.Context, userID string, first *int, after *string) (*model.FollowConnection, error) {
+	return d.follows(ctx, model.FollowFilter{FollowerID: userID}, first, after)
+}
+
+func (d *Domain) follows(ctx context.Context, filter model.FollowFilter, first *int, after *string) (*model.FollowConnection, error) {
+	limit, err := pageLimit(first, after)
+	if err != nil {
+		return nil, err
+	}
+
+	// one more than asked tells whether there is a next page
+	filter.After = after
+	filter.Limit = limit + 1
+	follows, err := d.Storage.Follows(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := d.Storage.FollowCount(ctx, model.FollowFilter{FollowerID: filter.FollowerID, FolloweeID: filter.FolloweeID})
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &model.FollowConnection{
+		Edges:      []*model.FollowEdge{},
+		PageInfo:   &model.PageInfo{},
+		TotalCount: count,
+	}
+	if len(follows) > limit {
+		follows = follows[:limit]
+		conn.PageInfo.HasNextPage = true
+	}
+
+	// the other side of every follow
+	ids := make([]string, 0, len(follows))
+	for _, f := range follows {
+		ids = append(ids, otherUser(f, filter))
+	}
+	users, err := d.Storage.UsersByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*model.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	for _, f := range follows {
+		if user := byID[otherUser(f, filter)]; user != nil {
+			conn.Edges = append(conn.Edges, &model.FollowEdge{Cursor: f.ID, Node: user, FollowedAt: f.CreatedAt})
+		}
+	}
+	if len(follows) > 0 {
+		conn.PageInfo.EndCursor = &follows[len(follows)-1].ID
+	}
+
+	return conn, nil
+}
+
+func otherUser(f *model.Follow, filter model.FollowFilter) string {
+	if filter.FolloweeID != "" {
+		return f.FollowerID
+	}
+	return f.FolloweeID
+}
+
+// Feed returns a page of the published posts of the users the current user
+// follows, after is the id of the last post of the previous page.
+func (d *Domain) Feed(ctx context.Context, first *int, after *string) (*model.PostConnection, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+	// the cursor is a publish time and an id rather than an id
+	limit, err := pageLimit(first, nil)
+	if err != nil {
+		return nil, err
+	}
+	if after != nil {
+		if _, _, ok := model.ParsePostCursor(*after); !ok {
+			return nil, errors.New("invalid cursor")
+		}
+	}
+
+	posts, err := d.Storage.Feed(ctx, model.FeedFilter{UserID: currentUser.ID, After: after, Limit: limit + 1})
+	if err != nil {
+		return nil, err
+	}
+	// muted users may still be followed, their posts are skipped but the
+	// cursor moves past them
+	hidden, err := d.hiddenAuthors(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &model.PostConnection{
+		Edges:    []*model.PostEdge{},
+		PageInfo: &model.PageInfo{},
+	}
+	if len(posts) > limit {
+		posts = posts[:limit]
+		conn.PageInfo.HasNextPage = true
+	}
+	for _, post := range posts {
+		if !hidden[post.UserID] {
+			conn.Edges = append(conn.Edges, &model.PostEdge{Cursor: post.Cursor(), Node: post})
+		}
+	}
+	if len(posts) > 0 {
+		cursor := posts[len(posts)-1].Cursor()
+		conn.PageInfo.EndCursor = &cursor
+	}
+
+	return conn, nil
+}
+
+// notifyFollowers tells the followers of the author about a new post, users
+// in notified have already been told about it and followers blocking the
+// author are skipped. It runs in the background, failures are only logged,
+// the post has already been published.
+func (d *Domain) notifyFollowers(author *model.User, post *model.Post, notified map[string]bool) {
+	skip := make([]string, 0, len(notified))
+	for userID := range notified {
+		skip = append(skip, userID)
+	}
+	n := &model.Notification{
+		Kind:    model.NotificationKindNewPost,
+		ActorID: &author.ID,
+		PostID:  &post.ID,
+		Message: fmt.Sprintf("%s published a new post %q", author.Username, post.Title),
+	}
+
+	d.background(func(ctx context.Context) {
+		notifications, err := d.Storage.AddFollowerNotifications(ctx, n, skip)
+		if err != nil {
+			log.Printf("error notify followers of user %s about post %s: %v", author.ID, post.ID, err)
+			return
+		}
+		for _, saved := range notifications {
+			d.publish(ctx, notificationsTopic(saved.UserID), saved)
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package domain
+
+import (
+	"context"
+	"github.com/farid21ola/forum/mocks"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/pubsub"
+	"github.com/farid21ola/forum/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestDomain_FollowUser(t *testing.T) {
+	userCtx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"})
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		id            string
+		mockSetup     func(m *mocks.Storage)
+		expectedError string
+	}{
+		{
+			name:          "Unauthenticated user",
+			ctx:           context.Background(),
+			id:            "2",
+			mockSetup:     func(m *mocks.Storage) {},
+			expectedError: "unauthenticated",
+		},
+		{
+			name:          "Yourself",
+			ctx:           userCtx,
+			id:            "1",
+			mockSetup:     func(m *mocks.Storage) {},
+			expectedError: "you can't follow yourself",
+		},
+		{
+			name: "Unknown user",
+			ctx:  userCtx,
+			id:   "2",
+			mockSetup: func(m *mocks.Storage) {
+				m.On("UserByID", mock.Anything, "2").Return(nil, storage.ErrNotFound)
+			},
+			expectedError: "user with this id don't exist",
+		},
+		{
+			name: "Successful follow",
+			ctx:  userCtx,
+			id:   "2",
+			mockSetup: func(m *mocks.Storage) {
+				m.On("UserByID", mock.Anything, "2").Return(&model.User{ID: "2", Username: "bob"}, nil)
+				m.On("Follow", mock.Anything, &model.Follow{FollowerID: "1", FolloweeID: "2"}).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(mocks.Storage)
+			tt.mockSetup(mockStorage)
+			d := &Domain{Storage: mockStorage}
+
+			user, err := d.FollowUser(tt.ctx, tt.id)
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.expectedError, err.Error())
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "bob", user.Username)
+			}
+
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDomain_Followers(t *testing.T) {
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("Follows", mock.Anything, model.FollowFilter{FolloweeID: "1", Limit: 3}).Return([]*model.Follow{
+		{ID: "9", FollowerID: "4", FolloweeID: "1"},
+		{ID: "7", FollowerID: "3", FolloweeID: "1"},
+		{ID: "5", FollowerID: "2", FolloweeID: "1"},
+	}, nil)
+	mockStorage.On("FollowCount", mock.Anything, model.FollowFilter{FolloweeID: "1"}).Return(3, nil)
+	// the user 3 has been deleted in between
+	mockStorage.On("UsersByIDs", mock.Anything, []string{"4", "3"}).Return([]*model.User{{ID: "4"}}, nil)
+	d := &Domain{Storage: mockStorage}
+
+	conn, err := d.Followers(context.Background(), "1", intPtr(2), nil)
+	require.NoError(t, err)
+	require.Len(t, conn.Edges, 1)
+	assert.Equal(t, "9", conn.Edges[0].Cursor)
+	assert.Equal(t, "4", conn.Edges[0].Node.ID)
+	assert.Equal(t, 3, conn.TotalCount)
+	assert.Equal(t, strPtr("7"), conn.PageInfo.EndCursor)
+	assert.True(t, conn.PageInfo.HasNextPage)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestDomain_Feed(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"})
+
+	at := time.Unix(1700000000, 0)
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("Feed", mock.Anything, model.FeedFilter{UserID: "1", After: strPtr("1700000010000000000:10"), Limit: 21}).
+		Return([]*model.Post{
+			{ID: "6", UserID: "2", PublishedAt: timePtr(at.Add(2 * time.Second))},
+			{ID: "8", UserID: "3", PublishedAt: timePtr(at.Add(time.Second))},
+			{ID: "7", UserID: "2", PublishedAt: timePtr(at)},
+		}, nil)
+	// the user follows and mutes 3
+	mockStorage.On("Blocks", mock.Anything, "1").Return([]*model.Block{{UserID: "1", TargetID: "3", Kind: model.BlockKindMute}}, nil)
+	d := &Domain{Storage: mockStorage}
+
+	conn, err := d.Feed(ctx, nil, strPtr("1700000010000000000:10"))
+	require.NoError(t, err)
+	require.Len(t, conn.Edges, 2)
+	assert.Equal(t, "6", conn.Edges[0].Node.ID)
+	assert.Equal(t, "1700000002000000000:6", conn.Edges[0].Cursor)
+	assert.Equal(t, "7", conn.Edges[1].Node.ID)
+	// the cursor is the publish time, drafts published later have lower ids
+	assert.Equal(t, strPtr("1700000000000000000:7"), conn.PageInfo.EndCursor)
+	assert.False(t, conn.PageInfo.HasNextPage)
+
+	_, err = d.Feed(ctx, nil, strPtr("10"))
+	assert.EqualError(t, err, "invalid cursor")
+
+	_, err = d.Feed(context.Background(), nil, nil)
+	assert.Equal(t, ErrUnauthenticated, err)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestDomain_CreatePost_NotifiesFollowers(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1", Username: "alice"})
+
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("CreatePost", mock.Anything, mock.AnythingOfType("*model.Post"), []*model.Image(nil), (*model.Poll)(nil)).Return(
+		&model.Post{ID: "3", Title: "Hello", Content: "hi @bob", UserID: "1"}, nil)
+	mockStorage.On("UsersByUsernames", mock.Anything, []string{"bob"}).Return([]*model.User{{ID: "2", Username: "bob"}}, nil)
+	mockStorage.On("BlockingUserIDs", mock.Anything, "1", []string{"2"}).Return(nil, nil)
+	mockStorage.On("AddMentions", mock.Anything, []*model.Mention{{PostID: "3", UserID: "2"}}).Return(nil)
+	mockStorage.On("AddNotification", mock.Anything, &model.Notification{
+		UserID: "2", Kind: model.NotificationKindMention, ActorID: strPtr("1"), PostID: strPtr("3"),
+		Message: `alice mentioned you in the post "Hello"`,
+	}).Return(&model.Notification{ID: "1", UserID: "2"}, nil).Once()
+	// bob follows alice too but has already been told about the post
+	mockStorage.On("AddFollowerNotifications", mock.Anything, &model.Notification{
+		Kind: model.NotificationKindNewPost, ActorID: strPtr("1"), PostID: strPtr("3"),
+		Message: `alice published a new post "Hello"`,
+	}, []string{"2"}).Return([]*model.Notification{{ID: "2", UserID: "4"}}, nil).Once()
+	d := &Domain{Storage: mockStorage, Broker: pubsub.NewMemory(pubsub.DefaultOptions)}
+	followerCtx, cancel := context.WithCancel(context.WithValue(context.Background(), "currentUser", &model.User{ID: "4"}))
+	defer cancel()
+	received, err := d.NotificationReceived(followerCtx)
+	require.NoError(t, err)
+
+	_, err = d.CreatePost(ctx, model.NewPost{Title: "Hello", Content: "hi @bob"})
+	require.NoError(t, err)
+	d.Wait()
+	assert.Equal(t, "2", receive(t, received).ID)
+
+	mockStorage.AssertExpectations(t)
+}
@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"fmt"
+	"github.com/farid21ola/forum/model"
+	"math"
+	"time"
+)
+
+// LoginPolicy controls how repeated failed logins are slowed down. Failures are
+// counted per username and per client ip since the last successful login.
+type LoginPolicy struct {
+	// Window is how long a failed attempt is remembered.
+	Window time.Duration
+	// FreeAttempts failures are allowed before any delay is applied.
+	FreeAttempts int
+	// BaseDelay is doubled for every failure after FreeAttempts, up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// After LockoutAfter failures logins are refused for LockoutDuration.
+	LockoutAfter    int
+	LockoutDuration time.Duration
+	// IPFactor multiplies the thresholds for failures counted per ip, since
+	// several people may share one address.
+	IPFactor int
+}
+
+var DefaultLoginPolicy = LoginPolicy{
+	Window:          time.Hour,
+	FreeAttempts:    3,
+	BaseDelay:       time.Second,
+	MaxDelay:        5 * time.Minute,
+	LockoutAfter:    10,
+	LockoutDuration: 30 * time.Minute,
+	IPFactor:        5,
+}
+
+// LoginThrottledError is returned by Login while further attempts are refused.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("too many failed login attempts, account is locked for %d seconds", e.RetrySeconds())
+	}
+	return fmt.Sprintf("too many failed login attempts, retry in %d seconds", e.RetrySeconds())
+}
+
+func (e *LoginThrottledError) RetrySeconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+// check returns an error when the next attempt has to wait.
+func (p LoginPolicy) check(f *model.LoginFailures, now time.Time) *LoginThrottledError {
+	userWait, userLocked := p.wait(f.ByUsername, f.LastByUsername, 1, now)
+	ipWait, ipLocked := p.wait(f.ByIP, f.LastByIP, p.IPFactor, now)
+
+	if userWait <= 0 && ipWait <= 0 {
+		return nil
+	}
+	if ipWait > userWait {
+		return &LoginThrottledError{RetryAfter: ipWait, Locked: ipLocked}
+	}
+	return &LoginThrottledError{RetryAfter: userWait, Locked: userLocked}
+}
+
+func (p LoginPolicy) wait(failures int, last time.Time, factor int, now time.Time) (time.Duration, bool) {
+	if factor < 1 {
+		factor = 1
+	}
+
+	if failures >= p.LockoutAfter*factor {
+		return last.Add(p.LockoutDuration).Sub(now), true
+	}
+	if failures < p.FreeAttempts*factor {
+		return 0, false
+	}
+
+	delay := p.MaxDelay
+	if exp := (failures - p.FreeAttempts*factor) / factor; exp < 32 {
+		delay = time.Duration(math.Min(float64(p.BaseDelay)*math.Pow(2, float64(exp)), float64(p.MaxDelay)))
+	}
+
+	return last.Add(delay).Sub(now), false
+}
+
+// locksOut reports whether one more failure for a username locks the account.
+func (p LoginPolicy) locksOut(failures int) bool {
+	return failures+1 == p.LockoutAfter
+}
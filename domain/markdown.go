@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"fmt"
+)
+
+// maxPreviewSize limits previewMarkdown, editors call it while the user types.
+const maxPreviewSize = 100 << 10
+
+// ContentHTML renders the Markdown content of a post or a comment to
+// sanitized html.
+func (d *Domain) ContentHTML(content string) string {
+	return d.Markdown.Render(content)
+}
+
+// PreviewMarkdown renders text the way it would look as post or comment
+// content.
+func (d *Domain) PreviewMarkdown(text string) (string, error) {
+	if len(text) > maxPreviewSize {
+		return "", fmt.Errorf("text is too long, at most %d bytes are allowed", maxPreviewSize)
+	}
+
+	return d.Markdown.Render(text), nil
+}
@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"github.com/farid21ola/forum/markdown"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestDomain_PreviewMarkdown(t *testing.T) {
+	d := &Domain{Markdown: markdown.New(10)}
+
+	html, err := d.PreviewMarkdown("**hi** @bob <script>alert(1)</script>")
+	require.NoError(t, err)
+	assert.Equal(t, "<p><strong>hi</strong> @bob alert(1)</p>\n", html)
+
+	_, err = d.PreviewMarkdown(strings.Repeat("a", maxPreviewSize+1))
+	assert.EqualError(t, err, "text is too long, at most 102400 bytes are allowed")
+}
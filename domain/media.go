@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/farid21ola/forum/media"
+	"github.com/farid21ola/forum/middleware"
+	"github.com/farid21ola/forum/model"
+	"log"
+)
+
+const maxPostImages = 10
+
+// UploadAvatar stores an image and makes its thumbnail the avatar of the current user.
+func (d *Domain) UploadAvatar(ctx context.Context, file graphql.Upload) (*model.User, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+	if d.Media == nil {
+		return nil, ErrUploadsDisabled
+	}
+
+	img, err := d.Media.SaveImage(ctx, "avatars", file.File)
+	if err != nil {
+		return nil, uploadError(err)
+	}
+
+	user := *currentUser
+	user.AvatarURL = d.Media.URL(img.ThumbnailKey)
+	user.AvatarKeys = []string{img.Key, img.ThumbnailKey}
+
+	updated, err := d.Storage.UpdateUser(ctx, &user)
+	if err != nil {
+		d.Media.Delete(ctx, img)
+		log.Printf("error updating a user: %v", err)
+		return nil, err
+	}
+	// only the files of an uploaded avatar are deleted, the url can be set
+	// to anything through the profile
+	d.Media.DeleteKeys(ctx, currentUser.AvatarKeys)
+
+	return updated, nil
+}
+
+// MediaURL returns the public url of a stored file.
+func (d *Domain) MediaURL(key string) string {
+	if d.Media == nil {
+		return ""
+	}
+	return d.Media.URL(key)
+}
+
+func (d *Domain) saveImages(ctx context.Context, files []*graphql.Upload) ([]*model.Image, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+	if d.Media == nil {
+		return nil, ErrUploadsDisabled
+	}
+
+	images := make([]*model.Image, 0, len(files))
+	for _, file := range files {
+		img, err := d.Media.SaveImage(ctx, "posts", file.File)
+		if err != nil {
+			d.deleteImages(ctx, images)
+			return nil, uploadError(err)
+		}
+		images = append(images, img)
+	}
+
+	return images, nil
+}
+
+func (d *Domain) deleteImages(ctx context.Context, images []*model.Image) {
+	for _, img := range images {
+		d.Media.Delete(ctx, img)
+	}
+}
+
+// uploadError hides storage failures from clients but keeps validation errors.
+func uploadError(err error) error {
+	switch err {
+	case media.ErrTooLarge, media.ErrUnsupportedType, media.ErrInvalidImage, media.ErrInvalidDimensions:
+		return err
+	}
+	log.Printf("error saving an upload: %v", err)
+	return errors.New("something went wrong")
+}
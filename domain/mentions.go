@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"github.com/farid21ola/forum/model"
+	"log"
+	"regexp"
+)
+
+// maxMentions limits how many users a single post or comment can notify.
+const maxMentions = 20
+
+// a mention starts a word, so addresses like user@example.com are not
+// mentions, and dots or dashes only count inside the username
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@])@([\p{L}\p{N}_]+(?:[.-][\p{L}\p{N}_]+)*)`)
+
+// parseMentions returns the distinct usernames mentioned in content in the
+// order they appear.
+func parseMentions(content string) []string {
+	var usernames []string
+	seen := make(map[string]bool)
+
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		username := match[1]
+		if seen[username] {
+			continue
+		}
+		seen[username] = true
+		usernames = append(usernames, username)
+		if len(usernames) == maxMentions {
+			break
+		}
+	}
+
+	return usernames
+}
+
+// mentionUsers stores the mentions of existing users in the content of the
+// post, or of the comment when it is set, and notifies them. Users in
+// notified have already been told about the content. It returns the users it
+// notified. Unknown usernames stay plain text and failures are only logged,
+// the content has already been stored.
+func (d *Domain) mentionUsers(ctx context.Context, author *model.User, post *model.Post, comment *model.Comment, notified map[string]bool) map[string]bool {
+	content := post.Content
+	if comment != nil {
+		content = comment.Content
+	}
+	usernames := parseMentions(content)
+	if len(usernames) == 0 {
+		return nil
+	}
+
+	users, err := d.Storage.UsersByUsernames(ctx, usernames)
+	if err != nil {
+		log.Printf("error resolve mentions of post %s: %v", post.ID, err)
+		return nil
+	}
+
+	// users who blocked the author are not mentioned
+	ids := make([]string, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	blocking, err := d.blockedBy(ctx, author, ids)
+	if err != nil {
+		log.Printf("error check blocks of mentions in post %s: %v", post.ID, err)
+		return nil
+	}
+
+	var mentions []*model.Mention
+	for _, user := range users {
+		if user.ID == author.ID || blocking[user.ID] {
+			continue
+		}
+		mention := &model.Mention{PostID: post.ID, UserID: user.ID}
+		if comment != nil {
+			mention.CommentID = &comment.ID
+		}
+		mentions = append(mentions, mention)
+	}
+	if len(mentions) == 0 {
+		return nil
+	}
+	if err = d.Storage.AddMentions(ctx, mentions); err != nil {
+		log.Printf("error store mentions of post %s: %v", post.ID, err)
+		return nil
+	}
+
+	message := fmt.Sprintf("%s mentioned you in the post %q", author.Username, post.Title)
+	if comment != nil {
+		message = fmt.Sprintf("%s mentioned you in a comment on the post %q", author.Username, post.Title)
+	}
+	mentioned := make(map[string]bool, len(mentions))
+	for _, mention := range mentions {
+		if notified[mention.UserID] {
+			continue
+		}
+		err = d.notify(ctx, &model.Notification{
+			UserID:    mention.UserID,
+			Kind:      model.NotificationKindMention,
+			ActorID:   &author.ID,
+			PostID:    &post.ID,
+			CommentID: mention.CommentID,
+			Message:   message,
+		})
+		if err != nil {
+			log.Printf("error notify user %s about mention in post %s: %v", mention.UserID, post.ID, err)
+			continue
+		}
+		mentioned[mention.UserID] = true
+	}
+
+	return mentioned
+}
+
+// updateCommentMentions replaces the mentions of an edited comment, only the
+// users mentioned for the first time are notified. Like mentionUsers it only
+// logs failures.
+func (d *Domain) updateCommentMentions(ctx context.Context, author *model.User, comment *model.Comment) {
+	previous, err := d.Storage.CommentsMentions(ctx, []string{comment.ID})
+	if err != nil {
+		log.Printf("error load mentions of comment %s: %v", comment.ID, err)
+		return
+	}
+	if err = d.Storage.RemoveCommentMentions(ctx, comment.ID); err != nil {
+		log.Printf("error remove mentions of comment %s: %v", comment.ID, err)
+		return
+	}
+	if len(parseMentions(comment.Content)) == 0 {
+		return
+	}
+	post, err := d.Storage.Post(ctx, comment.PostID)
+	if err != nil {
+		log.Printf("error load post of comment %s: %v", comment.ID, err)
+		return
+	}
+
+	notified := make(map[string]bool, len(previous))
+	for _, m := range previous {
+		notified[m.UserID] = true
+	}
+	d.mentionUsers(ctx, author, post, comment, notified)
+}
@@ -0,0 +1,160 @@
+package domain
+
+import (
+	"context"
+	"github.com/farid21ola/forum/mocks"
+	"github.com/farid21ola/forum/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{
+			name:     "No mentions",
+			content:  "just text",
+			expected: nil,
+		},
+		{
+			name:     "Start and middle of the text",
+			content:  "@alice thanks, cc @bob_1",
+			expected: []string{"alice", "bob_1"},
+		},
+		{
+			name:     "Punctuation after the username",
+			content:  "ask @alice. Or (@bob), @carol!",
+			expected: []string{"alice", "bob", "carol"},
+		},
+		{
+			name:     "Dots and dashes inside the username",
+			content:  "@john.doe and @mary-jane-",
+			expected: []string{"john.doe", "mary-jane"},
+		},
+		{
+			name:     "Email address",
+			content:  "write to alice@example.com",
+			expected: nil,
+		},
+		{
+			name:     "Repeated mention",
+			content:  "@alice @bob @alice",
+			expected: []string{"alice", "bob"},
+		},
+		{
+			name:     "Unicode username",
+			content:  "привет, @иван",
+			expected: []string{"иван"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseMentions(tt.content))
+		})
+	}
+}
+
+func TestParseMentions_Limit(t *testing.T) {
+	var content []string
+	for i := 0; i < maxMentions+5; i++ {
+		content = append(content, "@user"+strings.Repeat("x", i))
+	}
+
+	assert.Len(t, parseMentions(strings.Join(content, " ")), maxMentions)
+}
+
+func TestDomain_CreatePost_Mentions(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1", Username: "alice"})
+
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("CreatePost", mock.Anything, mock.AnythingOfType("*model.Post"), []*model.Image(nil), (*model.Poll)(nil)).Return(
+		&model.Post{ID: "3", Title: "Hello", Content: "hi @bob and @alice, @nobody", UserID: "1"}, nil)
+	// unknown usernames are not returned and stay plain text
+	mockStorage.On("UsersByUsernames", mock.Anything, []string{"bob", "alice", "nobody"}).Return(
+		[]*model.User{{ID: "1", Username: "alice"}, {ID: "2", Username: "bob"}}, nil)
+	mockStorage.On("BlockingUserIDs", mock.Anything, "1", []string{"2"}).Return(nil, nil)
+	mockStorage.On("AddMentions", mock.Anything, []*model.Mention{{PostID: "3", UserID: "2"}}).Return(nil)
+	mockStorage.On("AddNotification", mock.Anything, &model.Notification{
+		UserID: "2", Kind: model.NotificationKindMention, ActorID: strPtr("1"), PostID: strPtr("3"),
+		Message: `alice mentioned you in the post "Hello"`,
+	}).Return(&model.Notification{ID: "1", UserID: "2"}, nil)
+	mockStorage.On("AddFollowerNotifications", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	d := &Domain{Storage: mockStorage}
+
+	post, err := d.CreatePost(ctx, model.NewPost{Title: "Hello", Content: "hi @bob and @alice, @nobody"})
+	require.NoError(t, err)
+	assert.Equal(t, "hi @bob and @alice, @nobody", post.Content)
+
+	d.Wait()
+	mockStorage.AssertExpectations(t)
+}
+
+func TestDomain_AddComment_Mentions(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1", Username: "alice"})
+
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("Post", mock.Anything, "3").Return(&model.Post{ID: "3", Title: "Hello", UserID: "2", CommentsEnabled: true}, nil)
+	mockStorage.On("AddComment", mock.Anything, mock.AnythingOfType("*model.Comment")).Return(
+		&model.Comment{ID: "8", PostID: "3", Content: "@bob @carol look", UserID: "1"}, nil)
+	mockStorage.On("AddNotification", mock.Anything, &model.Notification{
+		UserID: "2", Kind: model.NotificationKindReply, ActorID: strPtr("1"), PostID: strPtr("3"), CommentID: strPtr("8"),
+		Message: `alice commented on your post "Hello"`,
+	}).Return(&model.Notification{ID: "1", UserID: "2"}, nil).Once()
+	mockStorage.On("UsersByUsernames", mock.Anything, []string{"bob", "carol"}).Return(
+		[]*model.User{{ID: "2", Username: "bob"}, {ID: "4", Username: "carol"}}, nil)
+	mockStorage.On("BlockingUserIDs", mock.Anything, "1", []string{"2"}).Return(nil, nil).Once()
+	mockStorage.On("BlockingUserIDs", mock.Anything, "1", []string{"2", "4"}).Return(nil, nil).Once()
+	mockStorage.On("AddMentions", mock.Anything, []*model.Mention{
+		{PostID: "3", CommentID: strPtr("8"), UserID: "2"},
+		{PostID: "3", CommentID: strPtr("8"), UserID: "4"},
+	}).Return(nil)
+	// the post author already got a reply notification for the same comment
+	mockStorage.On("AddNotification", mock.Anything, &model.Notification{
+		UserID: "4", Kind: model.NotificationKindMention, ActorID: strPtr("1"), PostID: strPtr("3"), CommentID: strPtr("8"),
+		Message: `alice mentioned you in a comment on the post "Hello"`,
+	}).Return(&model.Notification{ID: "2", UserID: "4"}, nil).Once()
+	d := &Domain{Storage: mockStorage}
+
+	_, err := d.AddComment(ctx, model.NewComment{PostID: "3", Content: "@bob @carol look"})
+	require.NoError(t, err)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestDomain_UpdateComment_Mentions(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1", Username: "alice"})
+
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("Comment", mock.Anything, "8").Return(&model.Comment{ID: "8", PostID: "3", Content: "@bob look", UserID: "1"}, nil)
+	mockStorage.On("UpdateComment", mock.Anything, mock.AnythingOfType("*model.Comment")).Return(
+		&model.Comment{ID: "8", PostID: "3", Content: "@bob @carol look", UserID: "1", Edited: true}, nil)
+	mockStorage.On("CommentsMentions", mock.Anything, []string{"8"}).Return(
+		[]*model.Mention{{PostID: "3", CommentID: strPtr("8"), UserID: "2"}}, nil)
+	mockStorage.On("RemoveCommentMentions", mock.Anything, "8").Return(nil)
+	mockStorage.On("Post", mock.Anything, "3").Return(&model.Post{ID: "3", Title: "Hello", UserID: "5"}, nil)
+	mockStorage.On("UsersByUsernames", mock.Anything, []string{"bob", "carol"}).Return(
+		[]*model.User{{ID: "2", Username: "bob"}, {ID: "4", Username: "carol"}}, nil)
+	mockStorage.On("BlockingUserIDs", mock.Anything, "1", []string{"2", "4"}).Return(nil, nil)
+	mockStorage.On("AddMentions", mock.Anything, []*model.Mention{
+		{PostID: "3", CommentID: strPtr("8"), UserID: "2"},
+		{PostID: "3", CommentID: strPtr("8"), UserID: "4"},
+	}).Return(nil)
+	// bob was mentioned before the edit and is not notified again
+	mockStorage.On("AddNotification", mock.Anything, &model.Notification{
+		UserID: "4", Kind: model.NotificationKindMention, ActorID: strPtr("1"), PostID: strPtr("3"), CommentID: strPtr("8"),
+		Message: `alice mentioned you in a comment on the post "Hello"`,
+	}).Return(&model.Notification{ID: "2", UserID: "4"}, nil).Once()
+	d := &Domain{Storage: mockStorage}
+
+	_, err := d.UpdateComment(ctx, model.UpdateComment{ID: "8", Content: "@bob @carol look"})
+	require.NoError(t, err)
+
+	mockStorage.AssertExpectations(t)
+}
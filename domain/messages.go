@@ -0,0 +1,235 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/farid21ola/forum/middleware"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/storage"
+	"strconv"
+	"strings"
+)
+
+const maxParticipants = 20
+
+var errNoConversation = &storage.Error{Err: storage.ErrNotFound, Message: "conversation with this id don't exist"}
+
+// SendMessage adds a message to a conversation of the current user, or to the
+// conversation with the recipients which is started when there is none.
+// Nobody can write to a conversation with a user who blocked them.
+func (d *Domain) SendMessage(ctx context.Context, input model.NewMessage) (*model.Message, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+	if strings.TrimSpace(input.Content) == "" {
+		return nil, errors.New("message is empty")
+	}
+	if err = validateContent(input.Content, "message"); err != nil {
+		return nil, err
+	}
+
+	var conversation *model.Conversation
+	if input.ConversationID != nil {
+		conversation, err = d.memberConversation(ctx, currentUser, *input.ConversationID)
+	} else {
+		conversation, err = d.startConversation(ctx, currentUser, input.RecipientIds)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// checked for existing conversations too, a block may come later
+	blocking, err := d.blockedBy(ctx, currentUser, conversation.ParticipantIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(blocking) > 0 {
+		return nil, errBlocked
+	}
+
+	message, err := d.Storage.AddMessage(ctx, &model.Message{
+		ConversationID: conversation.ID,
+		UserID:         currentUser.ID,
+		Content:        input.Content,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// the sender has read their own message
+	if err = d.Storage.MarkConversationRead(ctx, conversation.ID, currentUser.ID, message.ID); err != nil {
+		return nil, err
+	}
+	for _, userID := range conversation.ParticipantIDs {
+		d.publish(ctx, messagesTopic(userID), message)
+	}
+
+	return message, nil
+}
+
+func (d *Domain) startConversation(ctx context.Context, currentUser *model.User, recipientIDs []string) (*model.Conversation, error) {
+	participants := []string{currentUser.ID}
+	seen := map[string]bool{currentUser.ID: true}
+	for _, id := range recipientIDs {
+		if !seen[id] {
+			seen[id] = true
+			participants = append(participants, id)
+		}
+	}
+	if len(participants) < 2 {
+		return nil, errors.New("a message needs a conversation or recipients")
+	}
+	if len(participants) > maxParticipants {
+		return nil, fmt.Errorf("too many recipients, at most %d users can take part in a conversation", maxParticipants)
+	}
+
+	users, err := d.Storage.UsersByIDs(ctx, participants)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) != len(participants) {
+		return nil, errUserNotFound
+	}
+
+	return d.Storage.CreateConversation(ctx, &model.Conversation{ParticipantIDs: participants})
+}
+
+// memberConversation returns a conversation of the user, the ones of other
+// users don't exist for them.
+func (d *Domain) memberConversation(ctx context.Context, user *model.User, id string) (*model.Conversation, error) {
+	conversation, err := d.Storage.Conversation(ctx, id)
+	if err != nil {
+		return nil, notFound(err, errNoConversation)
+	}
+	if !isParticipant(conversation, user.ID) {
+		return nil, errNoConversation
+	}
+	return conversation, nil
+}
+
+func isParticipant(c *model.Conversation, userID string) bool {
+	for _, id := range c.ParticipantIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Domain) Conversations(ctx context.Context) ([]*model.Conversation, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+	return d.Storage.UserConversations(ctx, currentUser.ID)
+}
+
+// Messages returns a page of a conversation from the newest message, before
+// is the oldest message of the previous page.
+func (d *Domain) Messages(ctx context.Context, conversationID string, first *int, before *string) (*model.MessageConnection, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+	limit, err := pageLimit(first, before)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = d.memberConversation(ctx, currentUser, conversationID); err != nil {
+		return nil, err
+	}
+
+	// one more than asked tells whether there are older messages
+	messages, err := d.Storage.Messages(ctx, model.MessageFilter{ConversationID: conversationID, Before: before, Limit: limit + 1})
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &model.MessageConnection{
+		Edges:    []*model.MessageEdge{},
+		PageInfo: &model.PageInfo{},
+	}
+	if len(messages) > limit {
+		messages = messages[:limit]
+		conn.PageInfo.HasNextPage = true
+	}
+	for _, m := range messages {
+		conn.Edges = append(conn.Edges, &model.MessageEdge{Cursor: m.ID, Node: m})
+	}
+	if len(messages) > 0 {
+		conn.PageInfo.EndCursor = &messages[len(messages)-1].ID
+	}
+
+	return conn, nil
+}
+
+// MarkConversationRead moves the read receipt of the current user to
+// messageID, or to the last message when it is not set. A receipt never
+// moves back or past the last message.
+func (d *Domain) MarkConversationRead(ctx context.Context, conversationID string, messageID *string) (*model.Conversation, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+	conversation, err := d.memberConversation(ctx, currentUser, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	last, err := d.LastMessage(ctx, conversation)
+	if err != nil || last == nil {
+		return conversation, err
+	}
+
+	readID := last.ID
+	if messageID != nil {
+		id, err := strconv.ParseUint(*messageID, 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid message id")
+		}
+		if lastID, _ := strconv.ParseUint(last.ID, 10, 64); id < lastID {
+			readID = *messageID
+		}
+	}
+	if err = d.Storage.MarkConversationRead(ctx, conversation.ID, currentUser.ID, readID); err != nil {
+		return nil, err
+	}
+
+	return conversation, nil
+}
+
+func (d *Domain) LastMessage(ctx context.Context, c *model.Conversation) (*model.Message, error) {
+	messages, err := d.Storage.Messages(ctx, model.MessageFilter{ConversationID: c.ID, Limit: 1})
+	if err != nil || len(messages) == 0 {
+		return nil, err
+	}
+	return messages[0], nil
+}
+
+// MessageReadBy returns the ids of the members other than the sender whose
+// read receipt has reached the message, members are the ones of its
+// conversation.
+func MessageReadBy(m *model.Message, members []*model.ConversationMember) []string {
+	id, _ := strconv.ParseUint(m.ID, 10, 64)
+	readBy := []string{}
+	for _, member := range members {
+		if member.UserID == m.UserID || member.LastReadMessageID == nil {
+			continue
+		}
+		if lastRead, _ := strconv.ParseUint(*member.LastReadMessageID, 10, 64); lastRead >= id {
+			readBy = append(readBy, member.UserID)
+		}
+	}
+	return readBy
+}
+
+// MessageReceived streams new messages of all conversations of the current
+// user.
+func (d *Domain) MessageReceived(ctx context.Context) (<-chan *model.Message, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+
+	return subscribe[model.Message](ctx, d.Broker, messagesTopic(currentUser.ID), nil, nil)
+}
@@ -0,0 +1,197 @@
+package domain
+
+import (
+	"context"
+	"github.com/farid21ola/forum/mocks"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestDomain_SendMessage(t *testing.T) {
+	userCtx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"})
+	conversation := &model.Conversation{ID: "4", ParticipantIDs: []string{"1", "2"}}
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		input         model.NewMessage
+		mockSetup     func(m *mocks.Storage)
+		expectedError string
+	}{
+		{
+			name:          "Unauthenticated user",
+			ctx:           context.Background(),
+			input:         model.NewMessage{RecipientIds: []string{"2"}, Content: "hi"},
+			mockSetup:     func(m *mocks.Storage) {},
+			expectedError: "unauthenticated",
+		},
+		{
+			name:          "Empty message",
+			ctx:           userCtx,
+			input:         model.NewMessage{RecipientIds: []string{"2"}, Content: " \n"},
+			mockSetup:     func(m *mocks.Storage) {},
+			expectedError: "message is empty",
+		},
+		{
+			name:          "Too big message",
+			ctx:           userCtx,
+			input:         model.NewMessage{RecipientIds: []string{"2"}, Content: strings.Repeat("a", 2000)},
+			mockSetup:     func(m *mocks.Storage) {},
+			expectedError: "too big message",
+		},
+		{
+			name:          "Only yourself",
+			ctx:           userCtx,
+			input:         model.NewMessage{RecipientIds: []string{"1"}, Content: "hi"},
+			mockSetup:     func(m *mocks.Storage) {},
+			expectedError: "a message needs a conversation or recipients",
+		},
+		{
+			name:  "Unknown recipient",
+			ctx:   userCtx,
+			input: model.NewMessage{RecipientIds: []string{"2", "9"}, Content: "hi"},
+			mockSetup: func(m *mocks.Storage) {
+				m.On("UsersByIDs", mock.Anything, []string{"1", "2", "9"}).Return([]*model.User{{ID: "1"}, {ID: "2"}}, nil)
+			},
+			expectedError: "user with this id don't exist",
+		},
+		{
+			name:  "Conversation of other users",
+			ctx:   userCtx,
+			input: model.NewMessage{ConversationID: strPtr("5"), Content: "hi"},
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Conversation", mock.Anything, "5").Return(&model.Conversation{ID: "5", ParticipantIDs: []string{"2", "3"}}, nil)
+			},
+			expectedError: "conversation with this id don't exist",
+		},
+		{
+			name:  "Blocked by the recipient",
+			ctx:   userCtx,
+			input: model.NewMessage{ConversationID: strPtr("4"), Content: "hi"},
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Conversation", mock.Anything, "4").Return(conversation, nil)
+				m.On("BlockingUserIDs", mock.Anything, "1", []string{"2"}).Return([]string{"2"}, nil)
+			},
+			expectedError: "you have been blocked by this user",
+		},
+		{
+			name:  "New conversation",
+			ctx:   userCtx,
+			input: model.NewMessage{RecipientIds: []string{"2", "2"}, Content: "hi"},
+			mockSetup: func(m *mocks.Storage) {
+				m.On("UsersByIDs", mock.Anything, []string{"1", "2"}).Return([]*model.User{{ID: "1"}, {ID: "2"}}, nil)
+				m.On("CreateConversation", mock.Anything, &model.Conversation{ParticipantIDs: []string{"1", "2"}}).Return(conversation, nil)
+				m.On("BlockingUserIDs", mock.Anything, "1", []string{"2"}).Return(nil, nil)
+				m.On("AddMessage", mock.Anything, &model.Message{ConversationID: "4", UserID: "1", Content: "hi"}).
+					Return(&model.Message{ID: "7", ConversationID: "4", UserID: "1", Content: "hi"}, nil)
+				m.On("MarkConversationRead", mock.Anything, "4", "1", "7").Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(mocks.Storage)
+			tt.mockSetup(mockStorage)
+			d := &Domain{Storage: mockStorage}
+
+			message, err := d.SendMessage(tt.ctx, tt.input)
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.expectedError, err.Error())
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "7", message.ID)
+			}
+
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDomain_Messages(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"})
+
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("Conversation", mock.Anything, "4").Return(&model.Conversation{ID: "4", ParticipantIDs: []string{"1", "2"}}, nil)
+	mockStorage.On("Messages", mock.Anything, model.MessageFilter{ConversationID: "4", Before: strPtr("9"), Limit: 3}).
+		Return([]*model.Message{{ID: "8"}, {ID: "6"}, {ID: "3"}}, nil)
+	d := &Domain{Storage: mockStorage}
+
+	conn, err := d.Messages(ctx, "4", intPtr(2), strPtr("9"))
+	require.NoError(t, err)
+	require.Len(t, conn.Edges, 2)
+	assert.Equal(t, "8", conn.Edges[0].Cursor)
+	assert.Equal(t, strPtr("6"), conn.PageInfo.EndCursor)
+	assert.True(t, conn.PageInfo.HasNextPage)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestDomain_MarkConversationRead(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"})
+
+	tests := []struct {
+		name      string
+		messageID *string
+		expected  string
+	}{
+		{name: "Up to the last message", expected: "8"},
+		{name: "Up to a message", messageID: strPtr("6"), expected: "6"},
+		{name: "Past the last message", messageID: strPtr("100"), expected: "8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(mocks.Storage)
+			mockStorage.On("Conversation", mock.Anything, "4").Return(&model.Conversation{ID: "4", ParticipantIDs: []string{"1", "2"}}, nil)
+			mockStorage.On("Messages", mock.Anything, model.MessageFilter{ConversationID: "4", Limit: 1}).Return([]*model.Message{{ID: "8"}}, nil)
+			mockStorage.On("MarkConversationRead", mock.Anything, "4", "1", tt.expected).Return(nil)
+			d := &Domain{Storage: mockStorage}
+
+			_, err := d.MarkConversationRead(ctx, "4", tt.messageID)
+			require.NoError(t, err)
+
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMessageReadBy(t *testing.T) {
+	members := []*model.ConversationMember{
+		{ConversationID: "4", UserID: "1", LastReadMessageID: strPtr("10")},
+		{ConversationID: "4", UserID: "2", LastReadMessageID: strPtr("10")},
+		{ConversationID: "4", UserID: "3", LastReadMessageID: strPtr("9")},
+		{ConversationID: "4", UserID: "5"},
+	}
+
+	readBy := MessageReadBy(&model.Message{ID: "10", ConversationID: "4", UserID: "1"}, members)
+	assert.Equal(t, []string{"2"}, readBy, "ids are compared as numbers and the sender is left out")
+}
+
+func TestDomain_MessageReceived(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"}))
+	defer cancel()
+
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("Conversation", mock.Anything, "4").Return(&model.Conversation{ID: "4", ParticipantIDs: []string{"1", "2"}}, nil)
+	mockStorage.On("BlockingUserIDs", mock.Anything, "1", []string{"2"}).Return(nil, nil)
+	mockStorage.On("AddMessage", mock.Anything, mock.AnythingOfType("*model.Message")).Return(
+		&model.Message{ID: "7", ConversationID: "4", UserID: "1", Content: "hi"}, nil)
+	mockStorage.On("MarkConversationRead", mock.Anything, "4", "1", "7").Return(nil)
+	d := &Domain{Storage: mockStorage, Broker: pubsub.NewMemory(pubsub.DefaultOptions)}
+
+	bobCtx := context.WithValue(ctx, "currentUser", &model.User{ID: "2"})
+	received, err := d.MessageReceived(bobCtx)
+	require.NoError(t, err)
+
+	_, err = d.SendMessage(ctx, model.NewMessage{ConversationID: strPtr("4"), Content: "hi"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "hi", receive(t, received).Content)
+}
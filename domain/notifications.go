@@ -0,0 +1,153 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"github.com/farid21ola/forum/middleware"
+	"github.com/farid21ola/forum/model"
+	"log"
+	"time"
+)
+
+// InboxNotifier stores security notifications in the inbox of the account
+// owner.
+type InboxNotifier struct {
+	Domain *Domain
+}
+
+func (n InboxNotifier) AccountLocked(ctx context.Context, user *model.User, until time.Time) error {
+	return n.Domain.notify(ctx, &model.Notification{
+		UserID:  user.ID,
+		Kind:    model.NotificationKindSecurity,
+		Message: fmt.Sprintf("Your account is locked until %s after repeated failed logins", until.Format(time.RFC3339)),
+	})
+}
+
+// notify stores a notification and delivers it to the subscription of the
+// recipient.
+func (d *Domain) notify(ctx context.Context, n *model.Notification) error {
+	saved, err := d.Storage.AddNotification(ctx, n)
+	if err != nil {
+		return err
+	}
+	d.publish(ctx, notificationsTopic(saved.UserID), saved)
+
+	return nil
+}
+
+// notifyReply tells the authors of the post and of the parent comment about a
+// new comment and returns the users it notified. Nobody is notified about
+// their own comments and failures are only logged, the comment has already
+// been stored.
+func (d *Domain) notifyReply(ctx context.Context, author *model.User, post *model.Post, parent *model.Comment, comment *model.Comment) map[string]bool {
+	var notifications []*model.Notification
+	if parent != nil && parent.UserID != author.ID {
+		notifications = append(notifications, &model.Notification{
+			UserID:  parent.UserID,
+			Message: fmt.Sprintf("%s replied to your comment", author.Username),
+		})
+	}
+	if post.UserID != author.ID && (parent == nil || parent.UserID != post.UserID) {
+		notifications = append(notifications, &model.Notification{
+			UserID:  post.UserID,
+			Message: fmt.Sprintf("%s commented on your post %q", author.Username, post.Title),
+		})
+	}
+
+	notified := make(map[string]bool, len(notifications))
+	for _, n := range notifications {
+		n.Kind = model.NotificationKindReply
+		n.ActorID = &author.ID
+		n.PostID = &post.ID
+		n.CommentID = &comment.ID
+		if err := d.notify(ctx, n); err != nil {
+			log.Printf("error notify user %s about comment %s: %v", n.UserID, comment.ID, err)
+			continue
+		}
+		notified[n.UserID] = true
+	}
+
+	return notified
+}
+
+// Notifications returns a page of the current user's notifications, after is
+// the cursor of the last notification of the previous page.
+func (d *Domain) Notifications(ctx context.Context, unreadOnly *bool, first *int, after *string) (*model.NotificationConnection, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+
+	limit, err := pageLimit(first, after)
+	if err != nil {
+		return nil, err
+	}
+
+	// one more than asked tells whether there is a next page
+	notifications, err := d.Storage.Notifications(ctx, model.NotificationFilter{
+		UserID:     currentUser.ID,
+		UnreadOnly: unreadOnly != nil && *unreadOnly,
+		After:      after,
+		Limit:      limit + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &model.NotificationConnection{
+		Edges:    []*model.NotificationEdge{},
+		PageInfo: &model.PageInfo{},
+	}
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+		conn.PageInfo.HasNextPage = true
+	}
+	for _, n := range notifications {
+		conn.Edges = append(conn.Edges, &model.NotificationEdge{Cursor: n.ID, Node: n})
+	}
+	if len(notifications) > 0 {
+		conn.PageInfo.EndCursor = &notifications[len(notifications)-1].ID
+	}
+
+	return conn, nil
+}
+
+// MarkNotificationsRead marks notifications of the current user as read, all
+// of them when ids is nil, and returns the number of unread ones left.
+func (d *Domain) MarkNotificationsRead(ctx context.Context, ids []string) (int, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return 0, ErrUnauthenticated
+	}
+
+	if err = d.Storage.MarkNotificationsRead(ctx, currentUser.ID, ids); err != nil {
+		return 0, err
+	}
+
+	return d.Storage.UnreadNotificationCount(ctx, currentUser.ID)
+}
+
+// UnreadNotificationCount is only available to the owner of the inbox, it is
+// nil for everyone else, so lists of users don't fail.
+func (d *Domain) UnreadNotificationCount(ctx context.Context, userID string) (*int, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil || currentUser.ID != userID {
+		return nil, nil
+	}
+
+	count, err := d.Storage.UnreadNotificationCount(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &count, nil
+}
+
+// NotificationReceived streams new notifications of the current user.
+func (d *Domain) NotificationReceived(ctx context.Context) (<-chan *model.Notification, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+
+	return subscribe[model.Notification](ctx, d.Broker, notificationsTopic(currentUser.ID), nil, nil)
+}
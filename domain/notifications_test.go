@@ -0,0 +1,188 @@
+package domain
+
+import (
+	"context"
+	"github.com/farid21ola/forum/mocks"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestDomain_Notifications(t *testing.T) {
+	userCtx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"})
+	stored := []*model.Notification{{ID: "9"}, {ID: "7"}, {ID: "4"}}
+
+	tests := []struct {
+		name            string
+		ctx             context.Context
+		unreadOnly      *bool
+		first           *int
+		after           *string
+		mockSetup       func(m *mocks.Storage)
+		expectedCursors []string
+		expectedEnd     *string
+		expectedNext    bool
+		expectedError   string
+	}{
+		{
+			name:          "Unauthenticated user",
+			ctx:           context.Background(),
+			mockSetup:     func(m *mocks.Storage) {},
+			expectedError: "unauthenticated",
+		},
+		{
+			name:          "Page too big",
+			ctx:           userCtx,
+			first:         intPtr(101),
+			mockSetup:     func(m *mocks.Storage) {},
+			expectedError: "first must be between 1 and 100",
+		},
+		{
+			name:          "Invalid cursor",
+			ctx:           userCtx,
+			after:         strPtr("abc"),
+			mockSetup:     func(m *mocks.Storage) {},
+			expectedError: "invalid cursor",
+		},
+		{
+			name:  "Has next page",
+			ctx:   userCtx,
+			first: intPtr(2),
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Notifications", mock.Anything, model.NotificationFilter{UserID: "1", Limit: 3}).Return(stored, nil)
+			},
+			expectedCursors: []string{"9", "7"},
+			expectedEnd:     strPtr("7"),
+			expectedNext:    true,
+		},
+		{
+			name:       "Last page",
+			ctx:        userCtx,
+			unreadOnly: new(bool),
+			after:      strPtr("7"),
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Notifications", mock.Anything, model.NotificationFilter{UserID: "1", After: strPtr("7"), Limit: 21}).
+					Return(stored[2:], nil)
+			},
+			expectedCursors: []string{"4"},
+			expectedEnd:     strPtr("4"),
+		},
+		{
+			name: "Empty inbox",
+			ctx:  userCtx,
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Notifications", mock.Anything, model.NotificationFilter{UserID: "1", Limit: 21}).Return(nil, nil)
+			},
+			expectedCursors: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(mocks.Storage)
+			tt.mockSetup(mockStorage)
+			d := &Domain{Storage: mockStorage}
+
+			conn, err := d.Notifications(tt.ctx, tt.unreadOnly, tt.first, tt.after)
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.expectedError, err.Error())
+			} else {
+				require.NoError(t, err)
+				cursors := []string{}
+				for _, edge := range conn.Edges {
+					cursors = append(cursors, edge.Cursor)
+				}
+				assert.Equal(t, tt.expectedCursors, cursors)
+				assert.Equal(t, tt.expectedEnd, conn.PageInfo.EndCursor)
+				assert.Equal(t, tt.expectedNext, conn.PageInfo.HasNextPage)
+			}
+
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDomain_MarkNotificationsRead(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"})
+
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("MarkNotificationsRead", mock.Anything, "1", []string{"4", "7"}).Return(nil)
+	mockStorage.On("UnreadNotificationCount", mock.Anything, "1").Return(1, nil)
+	d := &Domain{Storage: mockStorage}
+
+	unread, err := d.MarkNotificationsRead(ctx, []string{"4", "7"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, unread)
+
+	_, err = d.MarkNotificationsRead(context.Background(), nil)
+	assert.Equal(t, ErrUnauthenticated, err)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestDomain_UnreadNotificationCount(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"})
+
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("UnreadNotificationCount", mock.Anything, "1").Return(3, nil)
+	d := &Domain{Storage: mockStorage}
+
+	count, err := d.UnreadNotificationCount(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, intPtr(3), count)
+
+	count, err = d.UnreadNotificationCount(ctx, "2")
+	require.NoError(t, err)
+	assert.Nil(t, count)
+
+	count, err = d.UnreadNotificationCount(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Nil(t, count)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestDomain_NotificationReceived(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"}))
+	defer cancel()
+
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("AddNotification", mock.Anything, mock.AnythingOfType("*model.Notification")).Return(
+		func(ctx context.Context, n *model.Notification) *model.Notification {
+			stored := *n
+			stored.ID = "1"
+			return &stored
+		}, nil)
+	d := &Domain{Storage: mockStorage, Broker: pubsub.NewMemory(pubsub.DefaultOptions)}
+
+	_, err := d.NotificationReceived(context.Background())
+	assert.Equal(t, ErrUnauthenticated, err)
+
+	received, err := d.NotificationReceived(ctx)
+	require.NoError(t, err)
+
+	notifier := InboxNotifier{Domain: d}
+	require.NoError(t, notifier.AccountLocked(ctx, &model.User{ID: "2"}, time.Now()))
+	require.NoError(t, notifier.AccountLocked(ctx, &model.User{ID: "1"}, time.Now()))
+
+	n := receive(t, received)
+	assert.Equal(t, "1", n.UserID)
+	assert.Equal(t, model.NotificationKindSecurity, n.Kind)
+}
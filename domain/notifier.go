@@ -0,0 +1,21 @@
+package domain
+
+import (
+	"context"
+	"github.com/farid21ola/forum/model"
+	"log"
+	"time"
+)
+
+// Notifier tells account owners about security related events.
+type Notifier interface {
+	AccountLocked(ctx context.Context, user *model.User, until time.Time) error
+}
+
+// LogNotifier only writes notifications to the server log.
+type LogNotifier struct{}
+
+func (LogNotifier) AccountLocked(ctx context.Context, user *model.User, until time.Time) error {
+	log.Printf("account %s is locked until %s after repeated failed logins", user.Username, until.Format(time.RFC3339))
+	return nil
+}
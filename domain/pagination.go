@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pageLimit checks the arguments of a cursor connection and returns the page
+// size, cursors are ids of the last item of the previous page.
+func pageLimit(first *int, after *string) (int, error) {
+	limit := defaultPageSize
+	if first != nil {
+		if *first < 1 || *first > maxPageSize {
+			return 0, fmt.Errorf("first must be between 1 and %d", maxPageSize)
+		}
+		limit = *first
+	}
+	if after != nil {
+		if _, err := strconv.ParseUint(*after, 10, 64); err != nil {
+			return 0, errors.New("invalid cursor")
+		}
+	}
+	return limit, nil
+}
+
+// offsetPage checks the arguments of a limit and offset page, nil means no
+// limit and no offset.
+func offsetPage(limit, offset *int) error {
+	if limit != nil && *limit < 0 {
+		return errors.New("limit must not be negative")
+	}
+	if offset != nil && *offset < 0 {
+		return errors.New("offset must not be negative")
+	}
+	return nil
+}
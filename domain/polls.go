@@ -0,0 +1,173 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/farid21ola/forum/middleware"
+	"github.com/farid21ola/forum/model"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	minPollOptions      = 2
+	maxPollOptions      = 10
+	maxPollQuestion     = 300
+	maxPollOptionLength = 100
+)
+
+// newPoll checks the poll of a new post, the options are trimmed and must be
+// distinct.
+func newPoll(input *model.NewPoll) (*model.Poll, error) {
+	question := strings.TrimSpace(input.Question)
+	if question == "" {
+		return nil, errors.New("poll question is empty")
+	}
+	if utf8.RuneCountInString(question) > maxPollQuestion {
+		return nil, errors.New("too big poll question")
+	}
+	if len(input.Options) < minPollOptions || len(input.Options) > maxPollOptions {
+		return nil, fmt.Errorf("a poll must have between %d and %d options", minPollOptions, maxPollOptions)
+	}
+	if input.ClosesAt != nil && !input.ClosesAt.After(time.Now()) {
+		return nil, errors.New("poll must close in the future")
+	}
+
+	poll := &model.Poll{
+		Question: question,
+		Multiple: input.MultipleChoice != nil && *input.MultipleChoice,
+		ClosesAt: input.ClosesAt,
+	}
+	seen := make(map[string]bool, len(input.Options))
+	for _, text := range input.Options {
+		text = strings.TrimSpace(text)
+		if text == "" || utf8.RuneCountInString(text) > maxPollOptionLength {
+			return nil, fmt.Errorf("poll options must be between 1 and %d characters long", maxPollOptionLength)
+		}
+		if seen[text] {
+			return nil, errors.New("poll options must be distinct")
+		}
+		seen[text] = true
+		poll.Options = append(poll.Options, &model.PollOption{Text: text})
+	}
+	return poll, nil
+}
+
+// viewPoll shows the poll to the current user.
+func (d *Domain) viewPoll(ctx context.Context, poll *model.Poll) (*model.Poll, error) {
+	var votes []string
+	if currentUser, err := middleware.GetCurrentUserFromCtx(ctx); err == nil {
+		if votes, err = d.Storage.PollVote(ctx, poll.ID, currentUser.ID); err != nil {
+			return nil, err
+		}
+	}
+	return ViewPoll(poll, votes), nil
+}
+
+// ViewPoll returns the poll as seen by a user who has chosen votes, the
+// counts are hidden while the poll is open and the user hasn't voted.
+func ViewPoll(poll *model.Poll, votes []string) *model.Poll {
+	viewed := *poll
+	viewed.MyVotes = votes
+	if votes != nil || poll.IsClosed(time.Now()) {
+		return &viewed
+	}
+
+	viewed.Options = make([]*model.PollOption, 0, len(poll.Options))
+	for _, option := range poll.Options {
+		viewed.Options = append(viewed.Options, &model.PollOption{ID: option.ID, Text: option.Text})
+	}
+	return &viewed
+}
+
+// VotePoll stores the vote of the current user, a user votes once.
+func (d *Domain) VotePoll(ctx context.Context, pollID string, optionIDs []string) (*model.Poll, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+
+	poll, err := d.Storage.Poll(ctx, pollID)
+	if err != nil {
+		return nil, notFound(err, errPollNotFound)
+	}
+	// polls of drafts don't exist for others, and a vote can't be cast on
+	// the post of someone who blocked the voter, like a comment or a reaction
+	post, err := d.Storage.Post(ctx, poll.PostID)
+	if err != nil {
+		return nil, notFound(err, errPollNotFound)
+	}
+	if !post.IsPublished() {
+		return nil, errPollNotFound
+	}
+	blocking, err := d.blockedBy(ctx, currentUser, []string{post.UserID})
+	if err != nil {
+		return nil, err
+	}
+	if len(blocking) > 0 {
+		return nil, errBlocked
+	}
+	if poll.IsClosed(time.Now()) {
+		return nil, errors.New("poll is closed")
+	}
+
+	chosen, err := pollChoice(poll, optionIDs)
+	if err != nil {
+		return nil, err
+	}
+	added, err := d.Storage.AddPollVote(ctx, &model.PollVote{PollID: poll.ID, UserID: currentUser.ID, OptionIDs: chosen})
+	if err != nil {
+		return nil, err
+	}
+	if !added {
+		return nil, errors.New("you have already voted")
+	}
+
+	updated, err := d.Storage.Poll(ctx, poll.ID)
+	if err != nil {
+		return nil, err
+	}
+	d.publish(ctx, pollTopic(poll.ID), updated)
+	voted := *updated
+	voted.MyVotes = chosen
+	return &voted, nil
+}
+
+// pollChoice checks that the options belong to the poll and returns them
+// without duplicates in the order of the poll.
+func pollChoice(poll *model.Poll, optionIDs []string) ([]string, error) {
+	if len(optionIDs) == 0 {
+		return nil, errors.New("choose at least one option")
+	}
+	picked := make(map[string]bool, len(optionIDs))
+	for _, id := range optionIDs {
+		picked[id] = true
+	}
+
+	var chosen []string
+	for _, option := range poll.Options {
+		if picked[option.ID] {
+			chosen = append(chosen, option.ID)
+		}
+	}
+	if len(chosen) != len(picked) {
+		return nil, errors.New("option with this id don't exist")
+	}
+	if len(chosen) > 1 && !poll.Multiple {
+		return nil, errors.New("only one option can be chosen")
+	}
+	return chosen, nil
+}
+
+func (d *Domain) PollUpdated(ctx context.Context, pollID string) (<-chan *model.Poll, error) {
+	return subscribe[model.Poll](ctx, d.Broker, pollTopic(pollID), nil, func(poll *model.Poll) bool {
+		viewed, err := d.viewPoll(ctx, poll)
+		if err != nil {
+			return false
+		}
+		*poll = *viewed
+		return true
+	})
+}
@@ -0,0 +1,315 @@
+package domain
+
+import (
+	"context"
+	"github.com/farid21ola/forum/mocks"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/pubsub"
+	"github.com/farid21ola/forum/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestNewPoll(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name          string
+		input         model.NewPoll
+		expectedError string
+	}{
+		{
+			name:          "Empty question",
+			input:         model.NewPoll{Question: "  ", Options: []string{"a", "b"}},
+			expectedError: "poll question is empty",
+		},
+		{
+			name:          "One option",
+			input:         model.NewPoll{Question: "Why?", Options: []string{"a"}},
+			expectedError: "a poll must have between 2 and 10 options",
+		},
+		{
+			name:          "Too many options",
+			input:         model.NewPoll{Question: "Why?", Options: make([]string, 11)},
+			expectedError: "a poll must have between 2 and 10 options",
+		},
+		{
+			name:          "Empty option",
+			input:         model.NewPoll{Question: "Why?", Options: []string{"a", " "}},
+			expectedError: "poll options must be between 1 and 100 characters long",
+		},
+		{
+			name:          "Same options",
+			input:         model.NewPoll{Question: "Why?", Options: []string{"a", " a"}},
+			expectedError: "poll options must be distinct",
+		},
+		{
+			name:          "Closed already",
+			input:         model.NewPoll{Question: "Why?", Options: []string{"a", "b"}, ClosesAt: &past},
+			expectedError: "poll must close in the future",
+		},
+		{
+			name:  "Valid poll",
+			input: model.NewPoll{Question: " Why? ", Options: []string{"a ", "b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poll, err := newPoll(&tt.input)
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.expectedError, err.Error())
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "Why?", poll.Question)
+				assert.Equal(t, []*model.PollOption{{Text: "a"}, {Text: "b"}}, poll.Options)
+			}
+		})
+	}
+}
+
+func TestDomain_CreatePost_Poll(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"})
+	multiple := true
+
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("CreatePost", mock.Anything, mock.AnythingOfType("*model.Post"), []*model.Image(nil), &model.Poll{
+		Question: "Why?",
+		Multiple: true,
+		Options:  []*model.PollOption{{Text: "a"}, {Text: "b"}},
+	}).Return(&model.Post{ID: "3", UserID: "1"}, nil)
+	mockStorage.On("AddFollowerNotifications", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	d := &Domain{Storage: mockStorage}
+
+	post, err := d.CreatePost(ctx, model.NewPost{
+		Title:   "Valid Title",
+		Content: "Valid Content",
+		Poll:    &model.NewPoll{Question: "Why?", Options: []string{"a", "b"}, MultipleChoice: &multiple},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "3", post.ID)
+
+	d.Wait()
+	mockStorage.AssertExpectations(t)
+}
+
+func testPoll(multiple bool, closesAt *time.Time) *model.Poll {
+	return &model.Poll{
+		ID:       "7",
+		PostID:   "3",
+		Question: "Why?",
+		Multiple: multiple,
+		ClosesAt: closesAt,
+		Options: []*model.PollOption{
+			{ID: "1", Text: "a", Votes: intPtr(2)},
+			{ID: "2", Text: "b", Votes: intPtr(1)},
+		},
+	}
+}
+
+func TestDomain_VotePoll(t *testing.T) {
+	userCtx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"})
+	past := time.Now().Add(-time.Minute)
+	// the post of the poll is published and its author hasn't blocked the voter
+	votable := func(m *mocks.Storage) {
+		m.On("Post", mock.Anything, "3").Return(&model.Post{ID: "3", UserID: "2", Status: model.PostStatusPublished}, nil)
+		m.On("BlockingUserIDs", mock.Anything, "1", []string{"2"}).Return([]string{}, nil)
+	}
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		optionIDs     []string
+		mockSetup     func(m *mocks.Storage)
+		expectedError string
+		expectedVotes []string
+	}{
+		{
+			name:          "Unauthenticated user",
+			ctx:           context.Background(),
+			optionIDs:     []string{"1"},
+			mockSetup:     func(m *mocks.Storage) {},
+			expectedError: "unauthenticated",
+		},
+		{
+			name:      "Poll not found",
+			ctx:       userCtx,
+			optionIDs: []string{"1"},
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Poll", mock.Anything, "7").Return(nil, storage.ErrNotFound)
+			},
+			expectedError: "poll with this id don't exist",
+		},
+		{
+			name:      "Poll of a draft",
+			ctx:       userCtx,
+			optionIDs: []string{"1"},
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Poll", mock.Anything, "7").Return(testPoll(false, nil), nil)
+				m.On("Post", mock.Anything, "3").Return(&model.Post{ID: "3", UserID: "2", Status: model.PostStatusDraft}, nil)
+			},
+			expectedError: "poll with this id don't exist",
+		},
+		{
+			name:      "Blocked by the author",
+			ctx:       userCtx,
+			optionIDs: []string{"1"},
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Poll", mock.Anything, "7").Return(testPoll(false, nil), nil)
+				m.On("Post", mock.Anything, "3").Return(&model.Post{ID: "3", UserID: "2", Status: model.PostStatusPublished}, nil)
+				m.On("BlockingUserIDs", mock.Anything, "1", []string{"2"}).Return([]string{"2"}, nil)
+			},
+			expectedError: errBlocked.Error(),
+		},
+		{
+			name:      "Closed poll",
+			ctx:       userCtx,
+			optionIDs: []string{"1"},
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Poll", mock.Anything, "7").Return(testPoll(false, &past), nil)
+				votable(m)
+			},
+			expectedError: "poll is closed",
+		},
+		{
+			name:      "Option of another poll",
+			ctx:       userCtx,
+			optionIDs: []string{"1", "5"},
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Poll", mock.Anything, "7").Return(testPoll(true, nil), nil)
+				votable(m)
+			},
+			expectedError: "option with this id don't exist",
+		},
+		{
+			name:      "Two options of a single choice poll",
+			ctx:       userCtx,
+			optionIDs: []string{"2", "1"},
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Poll", mock.Anything, "7").Return(testPoll(false, nil), nil)
+				votable(m)
+			},
+			expectedError: "only one option can be chosen",
+		},
+		{
+			name:      "Voted already",
+			ctx:       userCtx,
+			optionIDs: []string{"1"},
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Poll", mock.Anything, "7").Return(testPoll(false, nil), nil)
+				votable(m)
+				m.On("AddPollVote", mock.Anything, &model.PollVote{PollID: "7", UserID: "1", OptionIDs: []string{"1"}}).Return(false, nil)
+			},
+			expectedError: "you have already voted",
+		},
+		{
+			name:      "Multiple choice",
+			ctx:       userCtx,
+			optionIDs: []string{"2", "1", "2"},
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Poll", mock.Anything, "7").Return(testPoll(true, nil), nil)
+				votable(m)
+				m.On("AddPollVote", mock.Anything, &model.PollVote{PollID: "7", UserID: "1", OptionIDs: []string{"1", "2"}}).Return(true, nil)
+			},
+			expectedVotes: []string{"1", "2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(mocks.Storage)
+			tt.mockSetup(mockStorage)
+			d := &Domain{Storage: mockStorage}
+
+			poll, err := d.VotePoll(tt.ctx, "7", tt.optionIDs)
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.expectedError, err.Error())
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedVotes, poll.MyVotes)
+				assert.Equal(t, 2, *poll.Options[0].Votes)
+			}
+
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestViewPoll(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name         string
+		poll         *model.Poll
+		votes        []string
+		hiddenCounts bool
+	}{
+		{
+			name:         "Not voted",
+			poll:         testPoll(false, nil),
+			hiddenCounts: true,
+		},
+		{
+			name:  "Voted",
+			poll:  testPoll(false, nil),
+			votes: []string{"2"},
+		},
+		{
+			name: "Closed poll",
+			poll: testPoll(false, &past),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poll := ViewPoll(tt.poll, tt.votes)
+			if tt.hiddenCounts {
+				assert.Nil(t, poll.Options[0].Votes)
+				assert.Nil(t, poll.Options[1].Votes)
+			} else {
+				assert.Equal(t, 2, *poll.Options[0].Votes)
+				assert.Equal(t, 1, *poll.Options[1].Votes)
+			}
+			assert.Equal(t, tt.votes, poll.MyVotes)
+			// the stored poll isn't changed
+			assert.Equal(t, 2, *tt.poll.Options[0].Votes)
+		})
+	}
+}
+
+func TestDomain_PollUpdated(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), "currentUser", &model.User{ID: "2"}))
+	defer cancel()
+	voterCtx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"})
+
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("Poll", mock.Anything, "7").Return(testPoll(false, nil), nil)
+	mockStorage.On("Post", mock.Anything, "3").Return(&model.Post{ID: "3", UserID: "2", Status: model.PostStatusPublished}, nil)
+	mockStorage.On("BlockingUserIDs", mock.Anything, "1", []string{"2"}).Return([]string{}, nil)
+	mockStorage.On("AddPollVote", mock.Anything, mock.AnythingOfType("*model.PollVote")).Return(true, nil)
+	mockStorage.On("PollVote", mock.Anything, "7", "2").Return(nil, nil).Once()
+	mockStorage.On("PollVote", mock.Anything, "7", "2").Return([]string{"1"}, nil).Once()
+	d := &Domain{Storage: mockStorage, Broker: pubsub.NewMemory(pubsub.DefaultOptions)}
+
+	updates, err := d.PollUpdated(ctx, "7")
+	require.NoError(t, err)
+
+	_, err = d.VotePoll(voterCtx, "7", []string{"1"})
+	require.NoError(t, err)
+	poll := receive(t, updates)
+	assert.Nil(t, poll.Options[0].Votes)
+	assert.Empty(t, poll.MyVotes)
+
+	_, err = d.VotePoll(voterCtx, "7", []string{"1"})
+	require.NoError(t, err)
+	poll = receive(t, updates)
+	assert.Equal(t, 2, *poll.Options[0].Votes)
+	assert.Equal(t, []string{"1"}, poll.MyVotes)
+
+	mockStorage.AssertExpectations(t)
+}
@@ -3,8 +3,12 @@ package domain
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/farid21ola/forum/middleware"
 	"github.com/farid21ola/forum/model"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 func (d *Domain) CreatePost(ctx context.Context, input model.NewPost) (*model.Post, error) {
@@ -12,18 +16,98 @@ func (d *Domain) CreatePost(ctx context.Context, input model.NewPost) (*model.Po
 	if err != nil {
 		return nil, ErrUnauthenticated
 	}
-	if len(input.Title) < 2 {
-		return nil, errors.New("title not long enough")
+	if err = validatePostContent(input.Title, input.Content); err != nil {
+		return nil, err
+	}
+	tags, err := normalizeTags(input.Tags)
+	if err != nil {
+		return nil, err
 	}
-	if len(input.Content) < 2 {
-		return nil, errors.New("content not long enough")
+	if len(input.Images) > maxPostImages {
+		return nil, fmt.Errorf("too many images, at most %d are allowed", maxPostImages)
+	}
+	var poll *model.Poll
+	if input.Poll != nil {
+		if poll, err = newPoll(input.Poll); err != nil {
+			return nil, err
+		}
+	}
+	// images are checked before the post exists so a bad file doesn't leave a
+	// post without its pictures
+	images, err := d.saveImages(ctx, input.Images)
+	if err != nil {
+		return nil, err
 	}
 	post := model.Post{
 		Title:   input.Title,
 		Content: input.Content,
 		UserID:  currentUser.ID,
+		Tags:    tags,
+		Status:  model.PostStatusPublished,
+	}
+	for _, img := range images {
+		img.UserID = currentUser.ID
+	}
+	newPost, err := d.Storage.CreatePost(ctx, &post, images, poll)
+	if err != nil {
+		d.deleteImages(ctx, images)
+		return nil, err
+	}
+	d.postPublished(ctx, currentUser, newPost)
+	return newPost, nil
+}
+
+// Post returns a published post, drafts are only returned to their author.
+func (d *Domain) Post(ctx context.Context, id string) (*model.Post, error) {
+	post, err := d.Storage.Post(ctx, id)
+	if err != nil {
+		return nil, notFound(err, errPostNotFound)
+	}
+	if !PostVisible(ctx, post) {
+		return nil, errPostNotFound
+	}
+	return post, nil
+}
+
+// PostVisible tells whether the current user can see the post, drafts are
+// only shown to their author.
+func PostVisible(ctx context.Context, post *model.Post) bool {
+	return post.IsPublished() || isCurrentUser(ctx, post.UserID)
+}
+
+// maxTitleLength matches the posts.title column.
+const maxTitleLength = 255
+
+func validatePostContent(title, content string) error {
+	if len(title) < 2 {
+		return errors.New("title not long enough")
 	}
-	return d.Storage.CreatePost(ctx, &post)
+	if len(content) < 2 {
+		return errors.New("content not long enough")
+	}
+	return validateDraftContent(title, content)
+}
+
+// validateDraftContent checks the limits that don't depend on the post
+// being finished, drafts are checked with it on every save.
+func validateDraftContent(title, content string) error {
+	if utf8.RuneCountInString(title) > maxTitleLength {
+		return errors.New("title is too long")
+	}
+	return validateContent(content, "post")
+}
+
+// postPublished tells subscribers, mentioned users and followers of the
+// author about a post that has just become visible.
+func (d *Domain) postPublished(ctx context.Context, author *model.User, post *model.Post) {
+	d.publish(ctx, postsTopic, post)
+	mentioned := d.mentionUsers(ctx, author, post, nil, nil)
+	d.notifyFollowers(author, post, mentioned)
+}
+
+func isCurrentUser(ctx context.Context, userID string) bool {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	return err == nil && currentUser.ID == userID
 }
 
 // UpdatePost is the resolver for the updatePost field.
@@ -34,10 +118,7 @@ func (d *Domain) UpdatePost(ctx context.Context, input *model.UpdatePost) (*mode
 	}
 	post, err := d.Storage.Post(ctx, input.PostID)
 	if err != nil {
-		return nil, err
-	}
-	if post == nil {
-		return nil, errors.New("post with this id don't exist")
+		return nil, notFound(err, errPostNotFound)
 	}
 	if post.UserID != currentUser.ID {
 		return nil, ErrForbidden
@@ -53,5 +134,73 @@ func (d *Domain) UpdatePost(ctx context.Context, input *model.UpdatePost) (*mode
 			return nil, errors.New("comments already disabled")
 		}
 	}
-	return d.Storage.UpdatePost(ctx, upd)
+	updated, err := d.Storage.UpdatePost(ctx, upd)
+	if err != nil {
+		return nil, err
+	}
+	if updated.IsPublished() {
+		d.publish(ctx, postTopic(updated.ID), updated)
+	}
+	return updated, nil
+}
+
+// VotePost sets the vote of the current user for a post, 0 removes it.
+func (d *Domain) VotePost(ctx context.Context, postID string, value int) (*model.Post, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+	if value < -1 || value > 1 {
+		return nil, errors.New("vote must be 1, -1 or 0")
+	}
+	post, err := d.Storage.Post(ctx, postID)
+	if err != nil {
+		return nil, notFound(err, errPostNotFound)
+	}
+	if !post.IsPublished() {
+		return nil, errPostNotFound
+	}
+	score, err := d.Storage.VotePost(ctx, &model.Vote{PostID: postID, UserID: currentUser.ID, Value: value})
+	if err != nil {
+		return nil, err
+	}
+	voted := *post
+	voted.Score = score
+	d.publish(ctx, scoresTopic(postID), &model.ScoreChange{PostID: postID, Score: score})
+	return &voted, nil
+}
+
+const (
+	maxTags      = 5
+	maxTagLength = 30
+)
+
+// normalizeTags lowercases and deduplicates tags, a tag may only contain
+// letters, digits and dashes.
+func normalizeTags(tags []string) ([]string, error) {
+	normalized := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		if tag == "" || utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, fmt.Errorf("tags must be between 1 and %d characters long", maxTagLength)
+		}
+		for _, r := range tag {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' {
+				return nil, errors.New("tags may only contain letters, digits and dashes")
+			}
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	if len(normalized) > maxTags {
+		return nil, fmt.Errorf("too many tags, at most %d are allowed", maxTags)
+	}
+	return normalized, nil
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
 }
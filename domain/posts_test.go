@@ -2,8 +2,10 @@ package domain
 
 import (
 	"context"
+	"github.com/99designs/gqlgen/graphql"
 	"github.com/farid21ola/forum/mocks"
 	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -52,6 +54,50 @@ func TestDomain_CreatePost(t *testing.T) {
 			},
 			expectedError: "content not long enough",
 		},
+		{
+			name: "Too many images",
+			ctx:  context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"}),
+			input: model.NewPost{
+				Title:   "Valid Title",
+				Content: "Valid Content",
+				Images:  make([]*graphql.Upload, 11),
+			},
+			mockSetup:     func() {},
+			expectedError: "too many images, at most 10 are allowed",
+		},
+		{
+			name: "Uploads not configured",
+			ctx:  context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"}),
+			input: model.NewPost{
+				Title:   "Valid Title",
+				Content: "Valid Content",
+				Images:  []*graphql.Upload{{Filename: "a.png"}},
+			},
+			mockSetup:     func() {},
+			expectedError: "uploads are not configured",
+		},
+		{
+			name: "Invalid tag",
+			ctx:  context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"}),
+			input: model.NewPost{
+				Title:   "Valid Title",
+				Content: "Valid Content",
+				Tags:    []string{"go lang"},
+			},
+			mockSetup:     func() {},
+			expectedError: "tags may only contain letters, digits and dashes",
+		},
+		{
+			name: "Too many tags",
+			ctx:  context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"}),
+			input: model.NewPost{
+				Title:   "Valid Title",
+				Content: "Valid Content",
+				Tags:    []string{"a", "b", "c", "d", "e", "f"},
+			},
+			mockSetup:     func() {},
+			expectedError: "too many tags, at most 5 are allowed",
+		},
 		{
 			name: "Successful creation",
 			ctx:  context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"}),
@@ -60,11 +106,12 @@ func TestDomain_CreatePost(t *testing.T) {
 				Content: "Valid Content",
 			},
 			mockSetup: func() {
-				mockStorage.On("CreatePost", mock.Anything, mock.AnythingOfType("*model.Post")).Return(&model.Post{
+				mockStorage.On("CreatePost", mock.Anything, mock.AnythingOfType("*model.Post"), []*model.Image(nil), (*model.Poll)(nil)).Return(&model.Post{
 					Title:   "Valid Title",
 					Content: "Valid Content",
 					UserID:  "1",
 				}, nil)
+				mockStorage.On("AddFollowerNotifications", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
 			},
 			expectedError: "",
 		},
@@ -88,6 +135,7 @@ func TestDomain_CreatePost(t *testing.T) {
 				assert.Equal(t, tt.input.Title, resp.Title)
 				assert.Equal(t, tt.input.Content, resp.Content)
 			}
+			d.Wait()
 		})
 	}
 }
@@ -118,7 +166,7 @@ func TestDomain_UpdatePost(t *testing.T) {
 			ctx:   context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"}),
 			input: &model.UpdatePost{PostID: "1"},
 			setup: func() {
-				mockStorage.On("Post", mock.Anything, "1").Return(nil, nil)
+				mockStorage.On("Post", mock.Anything, "1").Return(nil, storage.ErrNotFound)
 			},
 			wantErr:       true,
 			expectedError: "post with this id don't exist",
@@ -183,3 +231,84 @@ func TestDomain_UpdatePost(t *testing.T) {
 		})
 	}
 }
+
+func TestDomain_VotePost(t *testing.T) {
+	userCtx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "2"})
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		value         int
+		mockSetup     func(m *mocks.Storage)
+		expectedScore int
+		expectedError string
+	}{
+		{
+			name:          "Unauthenticated user",
+			ctx:           context.Background(),
+			value:         1,
+			mockSetup:     func(m *mocks.Storage) {},
+			expectedError: "unauthenticated",
+		},
+		{
+			name:          "Invalid value",
+			ctx:           userCtx,
+			value:         2,
+			mockSetup:     func(m *mocks.Storage) {},
+			expectedError: "vote must be 1, -1 or 0",
+		},
+		{
+			name:  "Post not found",
+			ctx:   userCtx,
+			value: 1,
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Post", mock.Anything, "1").Return(nil, storage.ErrNotFound)
+			},
+			expectedError: "post with this id don't exist",
+		},
+		{
+			name:  "Successful vote",
+			ctx:   userCtx,
+			value: -1,
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Post", mock.Anything, "1").Return(&model.Post{ID: "1", Score: 3}, nil)
+				m.On("VotePost", mock.Anything, &model.Vote{PostID: "1", UserID: "2", Value: -1}).Return(2, nil)
+			},
+			expectedScore: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(mocks.Storage)
+			tt.mockSetup(mockStorage)
+			d := &Domain{Storage: mockStorage}
+
+			post, err := d.VotePost(tt.ctx, "1", tt.value)
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.expectedError, err.Error())
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedScore, post.Score)
+			}
+
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := normalizeTags([]string{" Go ", "go", "graph-ql", "тест"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go", "graph-ql", "тест"}, tags)
+
+	tags, err = normalizeTags(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, tags)
+
+	_, err = normalizeTags([]string{""})
+	assert.Error(t, err)
+	_, err = normalizeTags([]string{"a123456789012345678901234567890"})
+	assert.Error(t, err)
+}
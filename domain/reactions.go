@@ -0,0 +1,155 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"github.com/farid21ola/forum/middleware"
+	"github.com/farid21ola/forum/model"
+	"strings"
+)
+
+// DefaultReactions are the emojis users can react with unless the forum is
+// configured with others.
+var DefaultReactions = []string{"👍", "👎", "❤️", "😂", "🎉", "😮", "😢"}
+
+// ParseReactions reads a comma separated list of emojis, duplicates are
+// dropped. An empty list gives DefaultReactions.
+func ParseReactions(s string) []string {
+	var reactions []string
+	seen := make(map[string]bool)
+	for _, emoji := range strings.Split(s, ",") {
+		if emoji = strings.TrimSpace(emoji); emoji != "" && !seen[emoji] {
+			seen[emoji] = true
+			reactions = append(reactions, emoji)
+		}
+	}
+	if len(reactions) == 0 {
+		return DefaultReactions
+	}
+	return reactions
+}
+
+func (d *Domain) AvailableReactions() []string {
+	return d.Reactions
+}
+
+// React adds a reaction of the current user to a post or a comment and
+// returns the reactions of the target.
+func (d *Domain) React(ctx context.Context, target model.ReactionTarget, id, emoji string) ([]*model.ReactionCount, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+	if !d.isReaction(emoji) {
+		return nil, errors.New("unknown reaction")
+	}
+	reaction, postID, authorID, err := d.reactionTarget(ctx, target, id)
+	if err != nil {
+		return nil, err
+	}
+	blocking, err := d.blockedBy(ctx, currentUser, []string{authorID})
+	if err != nil {
+		return nil, err
+	}
+	if len(blocking) > 0 {
+		return nil, errBlocked
+	}
+
+	reaction.UserID = currentUser.ID
+	reaction.Emoji = emoji
+	if err = d.Storage.AddReaction(ctx, reaction); err != nil {
+		return nil, err
+	}
+	return d.reactionsChanged(ctx, currentUser, target, id, postID)
+}
+
+// Unreact removes a reaction of the current user, emojis that are no longer
+// available can still be taken back.
+func (d *Domain) Unreact(ctx context.Context, target model.ReactionTarget, id, emoji string) ([]*model.ReactionCount, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+	reaction, postID, _, err := d.reactionTarget(ctx, target, id)
+	if err != nil {
+		return nil, err
+	}
+
+	reaction.UserID = currentUser.ID
+	reaction.Emoji = emoji
+	if err = d.Storage.RemoveReaction(ctx, reaction); err != nil {
+		return nil, err
+	}
+	return d.reactionsChanged(ctx, currentUser, target, id, postID)
+}
+
+func (d *Domain) isReaction(emoji string) bool {
+	for _, r := range d.Reactions {
+		if r == emoji {
+			return true
+		}
+	}
+	return false
+}
+
+// reactionTarget finds a published post or a comment that isn't deleted and
+// returns a reaction to it, the post the target belongs to and its author.
+func (d *Domain) reactionTarget(ctx context.Context, target model.ReactionTarget, id string) (*model.Reaction, string, string, error) {
+	switch target {
+	case model.ReactionTargetPost:
+		post, err := d.Storage.Post(ctx, id)
+		if err != nil {
+			return nil, "", "", notFound(err, errPostNotFound)
+		}
+		if !post.IsPublished() {
+			return nil, "", "", errPostNotFound
+		}
+		return &model.Reaction{PostID: &post.ID}, post.ID, post.UserID, nil
+	case model.ReactionTargetComment:
+		comment, err := d.Storage.Comment(ctx, id)
+		if err != nil {
+			return nil, "", "", notFound(err, errCommentNotFound)
+		}
+		if comment.Deleted {
+			return nil, "", "", errCommentNotFound
+		}
+		return &model.Reaction{CommentID: &comment.ID}, comment.PostID, comment.UserID, nil
+	}
+	return nil, "", "", errors.New("unknown reaction target")
+}
+
+// reactionsChanged tells the subscribers of the post about the new reactions
+// of the target and returns them as the user sees them.
+func (d *Domain) reactionsChanged(ctx context.Context, user *model.User, target model.ReactionTarget, id, postID string) ([]*model.ReactionCount, error) {
+	counts, err := d.Storage.ReactionCounts(ctx, target, []string{id}, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// ReactedByMe is filled in for every subscriber
+	published := make([]*model.ReactionCount, 0, len(counts))
+	for _, c := range counts {
+		p := *c
+		p.ReactedByMe = false
+		published = append(published, &p)
+	}
+	d.publish(ctx, reactionsTopic(postID), &model.ReactionsUpdate{TargetType: target, TargetID: id, Reactions: published})
+	return counts, nil
+}
+
+// ReactionsUpdated streams the reactions of a post and of its comments after
+// every change.
+func (d *Domain) ReactionsUpdated(ctx context.Context, postID string) (<-chan *model.ReactionsUpdate, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return subscribe[model.ReactionsUpdate](ctx, d.Broker, reactionsTopic(postID), nil, nil)
+	}
+	return subscribe[model.ReactionsUpdate](ctx, d.Broker, reactionsTopic(postID), nil, func(u *model.ReactionsUpdate) bool {
+		counts, err := d.Storage.ReactionCounts(ctx, u.TargetType, []string{u.TargetID}, currentUser.ID)
+		if err != nil {
+			return false
+		}
+		u.Reactions = counts
+		return true
+	})
+}
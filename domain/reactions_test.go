@@ -0,0 +1,176 @@
+package domain
+
+import (
+	"context"
+	"github.com/farid21ola/forum/mocks"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseReactions(t *testing.T) {
+	assert.Equal(t, DefaultReactions, ParseReactions(""))
+	assert.Equal(t, DefaultReactions, ParseReactions(" , "))
+	assert.Equal(t, []string{"👍", "🚀"}, ParseReactions("👍, 🚀,👍"))
+}
+
+func TestDomain_React(t *testing.T) {
+	userCtx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"})
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		target        model.ReactionTarget
+		emoji         string
+		mockSetup     func(m *mocks.Storage)
+		expectedError string
+	}{
+		{
+			name:          "Unauthenticated user",
+			ctx:           context.Background(),
+			target:        model.ReactionTargetPost,
+			emoji:         "👍",
+			mockSetup:     func(m *mocks.Storage) {},
+			expectedError: "unauthenticated",
+		},
+		{
+			name:          "Unknown emoji",
+			ctx:           userCtx,
+			target:        model.ReactionTargetPost,
+			emoji:         "🚀",
+			mockSetup:     func(m *mocks.Storage) {},
+			expectedError: "unknown reaction",
+		},
+		{
+			name:   "Draft",
+			ctx:    userCtx,
+			target: model.ReactionTargetPost,
+			emoji:  "👍",
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Post", mock.Anything, "3").Return(&model.Post{ID: "3", UserID: "2", Status: model.PostStatusDraft}, nil)
+			},
+			expectedError: "post with this id don't exist",
+		},
+		{
+			name:   "Deleted comment",
+			ctx:    userCtx,
+			target: model.ReactionTargetComment,
+			emoji:  "👍",
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Comment", mock.Anything, "3").Return(&model.Comment{ID: "3", PostID: "5", UserID: "2", Deleted: true}, nil)
+			},
+			expectedError: "comment with this id don't exist",
+		},
+		{
+			name:   "Blocked by the author",
+			ctx:    userCtx,
+			target: model.ReactionTargetComment,
+			emoji:  "👍",
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Comment", mock.Anything, "3").Return(&model.Comment{ID: "3", PostID: "5", UserID: "2"}, nil)
+				m.On("BlockingUserIDs", mock.Anything, "1", []string{"2"}).Return([]string{"2"}, nil)
+			},
+			expectedError: "you have been blocked by this user",
+		},
+		{
+			name:   "Own post",
+			ctx:    userCtx,
+			target: model.ReactionTargetPost,
+			emoji:  "👍",
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Post", mock.Anything, "3").Return(&model.Post{ID: "3", UserID: "1", Status: model.PostStatusPublished}, nil)
+				m.On("AddReaction", mock.Anything, &model.Reaction{UserID: "1", PostID: strPtr("3"), Emoji: "👍"}).Return(nil)
+				m.On("ReactionCounts", mock.Anything, model.ReactionTargetPost, []string{"3"}, "1").
+					Return([]*model.ReactionCount{{TargetID: "3", Emoji: "👍", Count: 2, ReactedByMe: true}}, nil)
+			},
+		},
+		{
+			name:   "Comment of another user",
+			ctx:    userCtx,
+			target: model.ReactionTargetComment,
+			emoji:  "👍",
+			mockSetup: func(m *mocks.Storage) {
+				m.On("Comment", mock.Anything, "3").Return(&model.Comment{ID: "3", PostID: "5", UserID: "2"}, nil)
+				m.On("BlockingUserIDs", mock.Anything, "1", []string{"2"}).Return(nil, nil)
+				m.On("AddReaction", mock.Anything, &model.Reaction{UserID: "1", CommentID: strPtr("3"), Emoji: "👍"}).Return(nil)
+				m.On("ReactionCounts", mock.Anything, model.ReactionTargetComment, []string{"3"}, "1").
+					Return([]*model.ReactionCount{{TargetID: "3", Emoji: "👍", Count: 2, ReactedByMe: true}}, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(mocks.Storage)
+			tt.mockSetup(mockStorage)
+			d := &Domain{Storage: mockStorage, Reactions: DefaultReactions}
+
+			reactions, err := d.React(tt.ctx, tt.target, "3", tt.emoji)
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.expectedError, err.Error())
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, []*model.ReactionCount{{TargetID: "3", Emoji: "👍", Count: 2, ReactedByMe: true}}, reactions)
+			}
+
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDomain_Unreact(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"})
+
+	// the emoji was removed from the available ones after the user reacted
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("Post", mock.Anything, "3").Return(&model.Post{ID: "3", UserID: "2", Status: model.PostStatusPublished}, nil)
+	mockStorage.On("RemoveReaction", mock.Anything, &model.Reaction{UserID: "1", PostID: strPtr("3"), Emoji: "🚀"}).Return(nil)
+	mockStorage.On("ReactionCounts", mock.Anything, model.ReactionTargetPost, []string{"3"}, "1").Return(nil, nil)
+	d := &Domain{Storage: mockStorage, Reactions: DefaultReactions}
+
+	reactions, err := d.Unreact(ctx, model.ReactionTargetPost, "3", "🚀")
+	require.NoError(t, err)
+	assert.Empty(t, reactions)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestDomain_ReactionsUpdated(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), "currentUser", &model.User{ID: "2"}))
+	defer cancel()
+	anonymousCtx, cancelAnonymous := context.WithCancel(context.Background())
+	defer cancelAnonymous()
+	reactorCtx := context.WithValue(context.Background(), "currentUser", &model.User{ID: "1"})
+
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("Comment", mock.Anything, "3").Return(&model.Comment{ID: "3", PostID: "5", UserID: "1"}, nil)
+	mockStorage.On("AddReaction", mock.Anything, mock.AnythingOfType("*model.Reaction")).Return(nil)
+	mockStorage.On("ReactionCounts", mock.Anything, model.ReactionTargetComment, []string{"3"}, "1").
+		Return([]*model.ReactionCount{{TargetID: "3", Emoji: "🎉", Count: 2, ReactedByMe: true}}, nil)
+	mockStorage.On("ReactionCounts", mock.Anything, model.ReactionTargetComment, []string{"3"}, "2").
+		Return([]*model.ReactionCount{{TargetID: "3", Emoji: "🎉", Count: 2, ReactedByMe: true}}, nil)
+	d := &Domain{Storage: mockStorage, Broker: pubsub.NewMemory(pubsub.DefaultOptions), Reactions: DefaultReactions}
+
+	updates, err := d.ReactionsUpdated(ctx, "5")
+	require.NoError(t, err)
+	anonymousUpdates, err := d.ReactionsUpdated(anonymousCtx, "5")
+	require.NoError(t, err)
+
+	_, err = d.React(reactorCtx, model.ReactionTargetComment, "3", "🎉")
+	require.NoError(t, err)
+
+	update := receive(t, updates)
+	assert.Equal(t, model.ReactionTargetComment, update.TargetType)
+	assert.Equal(t, "3", update.TargetID)
+	assert.True(t, update.Reactions[0].ReactedByMe)
+
+	update = receive(t, anonymousUpdates)
+	assert.Equal(t, 2, update.Reactions[0].Count)
+	assert.False(t, update.Reactions[0].ReactedByMe)
+
+	mockStorage.AssertExpectations(t)
+}
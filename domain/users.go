@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"github.com/farid21ola/forum/middleware"
+	"github.com/farid21ola/forum/model"
+	"log"
+)
+
+// UpdateProfile changes the fields of the current user that are set in input.
+func (d *Domain) UpdateProfile(ctx context.Context, input model.UpdateProfile) (*model.User, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+
+	user := *currentUser
+	if input.FirstName != nil {
+		user.FirstName = *input.FirstName
+	}
+	if input.LastName != nil {
+		user.LastName = *input.LastName
+	}
+	if input.Bio != nil {
+		user.Bio = *input.Bio
+	}
+	if input.Website != nil {
+		user.Website = *input.Website
+	}
+	avatarReplaced := input.AvatarURL != nil && *input.AvatarURL != currentUser.AvatarURL
+	if avatarReplaced {
+		if d.Media != nil && d.Media.Serves(*input.AvatarURL) {
+			return nil, errors.New("avatarUrl can't point to uploaded files, use uploadAvatar")
+		}
+		user.AvatarURL = *input.AvatarURL
+		user.AvatarKeys = nil
+	}
+
+	updated, err := d.Storage.UpdateUser(ctx, &user)
+	if err != nil {
+		log.Printf("error updating a user: %v", err)
+		return nil, err
+	}
+	if avatarReplaced && d.Media != nil {
+		d.Media.DeleteKeys(ctx, currentUser.AvatarKeys)
+	}
+
+	return updated, nil
+}
@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"github.com/farid21ola/forum/blob"
+	"github.com/farid21ola/forum/media"
+	"github.com/farid21ola/forum/mocks"
+	"github.com/farid21ola/forum/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestDomain_UpdateProfile(t *testing.T) {
+	str := func(s string) *string { return &s }
+	currentUser := &model.User{ID: "1", Username: "user1", FirstName: "John", LastName: "Doe", Bio: "old bio"}
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		input         model.UpdateProfile
+		mockSetup     func(s *mocks.Storage)
+		expectedError string
+		expectedUser  *model.User
+	}{
+		{
+			name:          "Unauthenticated user",
+			ctx:           context.Background(),
+			input:         model.UpdateProfile{Bio: str("new bio")},
+			mockSetup:     func(s *mocks.Storage) {},
+			expectedError: "unauthenticated",
+		},
+		{
+			name:  "Only given fields change",
+			ctx:   context.WithValue(context.Background(), "currentUser", currentUser),
+			input: model.UpdateProfile{Bio: str("new bio"), Website: str("https://example.com")},
+			mockSetup: func(s *mocks.Storage) {
+				s.On("UpdateUser", mock.Anything, mock.AnythingOfType("*model.User")).Return(
+					func(ctx context.Context, u *model.User) (*model.User, error) { return u, nil })
+			},
+			expectedUser: &model.User{
+				ID: "1", Username: "user1", FirstName: "John", LastName: "Doe",
+				Bio: "new bio", Website: "https://example.com",
+			},
+		},
+		{
+			name:  "Storage error",
+			ctx:   context.WithValue(context.Background(), "currentUser", currentUser),
+			input: model.UpdateProfile{FirstName: str("Jack")},
+			mockSetup: func(s *mocks.Storage) {
+				s.On("UpdateUser", mock.Anything, mock.AnythingOfType("*model.User")).Return(nil, errors.New("db is down"))
+			},
+			expectedError: "db is down",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(mocks.Storage)
+			tt.mockSetup(mockStorage)
+			d := &Domain{Storage: mockStorage}
+
+			user, err := d.UpdateProfile(tt.ctx, tt.input)
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.expectedError, err.Error())
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedUser, user)
+				assert.Equal(t, "old bio", currentUser.Bio, "context user must not be modified")
+			}
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDomain_UpdateProfile_Avatar(t *testing.T) {
+	ctx := context.Background()
+	store, err := blob.NewLocal(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, store.Put(ctx, "avatars/a.png", strings.NewReader("a")))
+	require.NoError(t, store.Put(ctx, "avatars/a_thumb.png", strings.NewReader("a")))
+	currentUser := &model.User{
+		ID: "1", AvatarURL: "https://example.com/media/avatars/a_thumb.png",
+		AvatarKeys: []string{"avatars/a.png", "avatars/a_thumb.png"},
+	}
+	ctx = context.WithValue(ctx, "currentUser", currentUser)
+
+	mockStorage := new(mocks.Storage)
+	d := &Domain{Storage: mockStorage, Media: media.New(store, media.DefaultLimits, "https://example.com/media/")}
+
+	// someone else's upload can't become the avatar, replacing it would
+	// delete the files
+	other := "https://example.com/media/posts/b_thumb.png"
+	_, err = d.UpdateProfile(ctx, model.UpdateProfile{AvatarURL: &other})
+	assert.EqualError(t, err, "avatarUrl can't point to uploaded files, use uploadAvatar")
+
+	mockStorage.On("UpdateUser", mock.Anything, mock.AnythingOfType("*model.User")).Return(
+		func(ctx context.Context, u *model.User) (*model.User, error) { return u, nil })
+	external := "https://example.org/me.png"
+	user, err := d.UpdateProfile(ctx, model.UpdateProfile{AvatarURL: &external})
+	require.NoError(t, err)
+	assert.Equal(t, external, user.AvatarURL)
+	assert.Nil(t, user.AvatarKeys)
+	for _, key := range currentUser.AvatarKeys {
+		_, _, err = store.Open(ctx, key)
+		assert.ErrorIs(t, err, blob.ErrNotFound, key)
+	}
+
+	mockStorage.AssertExpectations(t)
+}
@@ -2,62 +2,528 @@ package graph
 
 import (
 	"context"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/farid21ola/forum/domain"
+	"github.com/farid21ola/forum/middleware"
 	"github.com/farid21ola/forum/model"
-	"github.com/jackc/pgx/v5/pgxpool"
-	"net/http"
+	"github.com/farid21ola/forum/storage"
+	"strings"
 	"time"
 )
 
 const (
-	userloaderKey = "userloader"
+	userloaderKey        = "userloader"
+	userstatsloaderKey   = "userstatsloader"
+	savedloaderKey       = "savedloader"
+	reactionloaderKey    = "reactionloader"
+	membersloaderKey     = "membersloader"
+	lastmessageloaderKey = "lastmessageloader"
+	unreadloaderKey      = "unreadloader"
+	mentionloaderKey     = "mentionloader"
+	pollloaderKey        = "pollloader"
+	postloaderKey        = "postloader"
+	commentloaderKey     = "commentloader"
+	imagesloaderKey      = "imagesloader"
 )
 
-func DataloaderMiddleware(db *pgxpool.Pool, next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		userLoader := UserLoader{
-			maxBatch: 100,
-			wait:     1 * time.Millisecond,
-			fetch: func(ids []string) ([]*model.User, []error) {
-				var users []*model.User
-				query := `SELECT * FROM users WHERE id = ANY($1)`
-				rows, err := db.Query(context.Background(), query, ids)
+// DataloaderMiddleware gives every response its own loaders and hidden
+// cache. A subscription sends a response for every event, so the data of the
+// current user is loaded again for each event rather than once for the
+// websocket connection.
+func DataloaderMiddleware(s storage.Storage) graphql.ResponseMiddleware {
+	return func(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+		return next(withLoaders(ctx, s))
+	}
+}
+
+func withLoaders(ctx context.Context, s storage.Storage) context.Context {
+	userLoader := UserLoader{
+		maxBatch: 100,
+		wait:     1 * time.Millisecond,
+		fetch: func(ids []string) ([]*model.User, []error) {
+			users, err := s.UsersByIDs(ctx, ids)
+			if err != nil {
+				return nil, []error{err}
+			}
+
+			u := make(map[string]*model.User, len(users))
+			for _, user := range users {
+				u[user.ID] = user
+			}
+
+			result := make([]*model.User, len(ids))
+			for i, id := range ids {
+				result[i] = u[id]
+			}
+			return result, nil
+		},
+	}
+
+	userStatsLoader := UserStatsLoader{
+		maxBatch: 100,
+		wait:     1 * time.Millisecond,
+		fetch: func(ids []string) ([]*model.UserStats, []error) {
+			stats, err := s.UsersStats(ctx, ids)
+			if err != nil {
+				return nil, []error{err}
+			}
+			return stats, nil
+		},
+	}
+
+	savedLoader := SavedLoader{
+		maxBatch: 100,
+		wait:     1 * time.Millisecond,
+		fetch: func(keys []string) ([]bool, []error) {
+			// a request usually has a single user, subscriptions over
+			// websockets have no user in the request context so it is
+			// part of the key
+			postIDs := make(map[string][]string)
+			for _, key := range keys {
+				userID, postID, _ := strings.Cut(key, ":")
+				postIDs[userID] = append(postIDs[userID], postID)
+			}
+
+			saved := make(map[string]bool, len(keys))
+			for userID, ids := range postIDs {
+				savedIDs, err := s.SavedPostIDs(ctx, userID, ids)
 				if err != nil {
 					return nil, []error{err}
 				}
-				defer rows.Close()
+				for _, id := range savedIDs {
+					saved[savedKey(userID, id)] = true
+				}
+			}
 
-				for rows.Next() {
-					var user model.User
-					err = rows.Scan(&user.ID, &user.Username)
-					if err != nil {
-						return nil, []error{err}
-					}
-					users = append(users, &user)
+			result := make([]bool, len(keys))
+			for i, key := range keys {
+				result[i] = saved[key]
+			}
+			return result, nil
+		},
+	}
+
+	reactionLoader := ReactionLoader{
+		maxBatch: 100,
+		wait:     1 * time.Millisecond,
+		fetch: func(keys []string) ([][]*model.ReactionCount, []error) {
+			// posts and comments are counted separately, like saved posts
+			// the counts depend on the user
+			type group struct {
+				userID string
+				target model.ReactionTarget
+			}
+			targetIDs := make(map[group][]string)
+			for _, key := range keys {
+				userID, target, id := splitReactionKey(key)
+				g := group{userID: userID, target: target}
+				targetIDs[g] = append(targetIDs[g], id)
+			}
+
+			reactions := make(map[string][]*model.ReactionCount, len(keys))
+			for g, ids := range targetIDs {
+				counts, err := s.ReactionCounts(ctx, g.target, ids, g.userID)
+				if err != nil {
+					return nil, []error{err}
+				}
+				for _, c := range counts {
+					key := reactionKey(g.userID, g.target, c.TargetID)
+					reactions[key] = append(reactions[key], c)
 				}
+			}
+
+			result := make([][]*model.ReactionCount, len(keys))
+			for i, key := range keys {
+				result[i] = reactions[key]
+			}
+			return result, nil
+		},
+	}
+
+	membersLoader := MembersLoader{
+		maxBatch: 100,
+		wait:     1 * time.Millisecond,
+		fetch: func(ids []string) ([][]*model.ConversationMember, []error) {
+			members, err := s.ConversationsMembers(ctx, ids)
+			if err != nil {
+				return nil, []error{err}
+			}
+
+			byConversation := make(map[string][]*model.ConversationMember, len(ids))
+			for _, m := range members {
+				byConversation[m.ConversationID] = append(byConversation[m.ConversationID], m)
+			}
+
+			result := make([][]*model.ConversationMember, len(ids))
+			for i, id := range ids {
+				result[i] = byConversation[id]
+			}
+			return result, nil
+		},
+	}
+
+	lastMessageLoader := LastMessageLoader{
+		maxBatch: 100,
+		wait:     1 * time.Millisecond,
+		fetch: func(ids []string) ([]*model.Message, []error) {
+			messages, err := s.LastMessages(ctx, ids)
+			if err != nil {
+				return nil, []error{err}
+			}
 
-				if rows.Err() != nil {
+			last := make(map[string]*model.Message, len(messages))
+			for _, m := range messages {
+				last[m.ConversationID] = m
+			}
+
+			result := make([]*model.Message, len(ids))
+			for i, id := range ids {
+				result[i] = last[id]
+			}
+			return result, nil
+		},
+	}
+
+	unreadLoader := UnreadLoader{
+		maxBatch: 100,
+		wait:     1 * time.Millisecond,
+		fetch: func(keys []string) ([]int, []error) {
+			// the counts depend on the user, like saved posts
+			conversationIDs := make(map[string][]string)
+			for _, key := range keys {
+				userID, conversationID, _ := strings.Cut(key, ":")
+				conversationIDs[userID] = append(conversationIDs[userID], conversationID)
+			}
+
+			unread := make(map[string]int, len(keys))
+			for userID, ids := range conversationIDs {
+				counts, err := s.UnreadMessageCounts(ctx, userID, ids)
+				if err != nil {
 					return nil, []error{err}
 				}
+				for i, id := range ids {
+					unread[unreadKey(userID, id)] = counts[i]
+				}
+			}
 
-				u := make(map[string]*model.User, len(users))
-				for _, user := range users {
-					u[user.ID] = user
+			result := make([]int, len(keys))
+			for i, key := range keys {
+				result[i] = unread[key]
+			}
+			return result, nil
+		},
+	}
+
+	mentionLoader := MentionLoader{
+		maxBatch: 100,
+		wait:     1 * time.Millisecond,
+		fetch: func(keys []string) ([][]*model.Mention, []error) {
+			ids := make(map[model.ReactionTarget][]string)
+			for _, key := range keys {
+				target, id, _ := strings.Cut(key, ":")
+				ids[model.ReactionTarget(target)] = append(ids[model.ReactionTarget(target)], id)
+			}
+
+			mentions := make(map[string][]*model.Mention, len(keys))
+			if postIDs := ids[model.ReactionTargetPost]; len(postIDs) > 0 {
+				loaded, err := s.PostsMentions(ctx, postIDs)
+				if err != nil {
+					return nil, []error{err}
 				}
+				for _, m := range loaded {
+					key := mentionKey(model.ReactionTargetPost, m.PostID)
+					mentions[key] = append(mentions[key], m)
+				}
+			}
+			if commentIDs := ids[model.ReactionTargetComment]; len(commentIDs) > 0 {
+				loaded, err := s.CommentsMentions(ctx, commentIDs)
+				if err != nil {
+					return nil, []error{err}
+				}
+				for _, m := range loaded {
+					key := mentionKey(model.ReactionTargetComment, *m.CommentID)
+					mentions[key] = append(mentions[key], m)
+				}
+			}
 
-				result := make([]*model.User, len(ids))
-				for i, id := range ids {
-					result[i] = u[id]
+			result := make([][]*model.Mention, len(keys))
+			for i, key := range keys {
+				result[i] = mentions[key]
+			}
+			return result, nil
+		},
+	}
+
+	pollLoader := PollLoader{
+		maxBatch: 100,
+		wait:     1 * time.Millisecond,
+		fetch: func(keys []string) ([]*model.Poll, []error) {
+			// the counts are hidden from users who haven't voted, like
+			// saved posts the poll depends on the user
+			postIDs := make(map[string][]string)
+			for _, key := range keys {
+				userID, postID, _ := strings.Cut(key, ":")
+				postIDs[userID] = append(postIDs[userID], postID)
+			}
+
+			viewed := make(map[string]*model.Poll, len(keys))
+			for userID, ids := range postIDs {
+				polls, err := s.PostsPolls(ctx, ids)
+				if err != nil {
+					return nil, []error{err}
 				}
-				return result, nil
-			},
-		}
+				votes := make(map[string][]string)
+				if userID != "" && len(polls) > 0 {
+					pollIDs := make([]string, 0, len(polls))
+					for _, poll := range polls {
+						pollIDs = append(pollIDs, poll.ID)
+					}
+					loaded, err := s.PollsVotes(ctx, userID, pollIDs)
+					if err != nil {
+						return nil, []error{err}
+					}
+					for _, vote := range loaded {
+						votes[vote.PollID] = vote.OptionIDs
+					}
+				}
+				for _, poll := range polls {
+					viewed[pollKey(userID, poll.PostID)] = domain.ViewPoll(poll, votes[poll.ID])
+				}
+			}
+
+			result := make([]*model.Poll, len(keys))
+			for i, key := range keys {
+				result[i] = viewed[key]
+			}
+			return result, nil
+		},
+	}
+
+	postLoader := PostLoader{
+		maxBatch: 100,
+		wait:     1 * time.Millisecond,
+		fetch: func(ids []string) ([]*model.Post, []error) {
+			posts, err := s.PostsByIDs(ctx, ids)
+			if err != nil {
+				return nil, []error{err}
+			}
+
+			p := make(map[string]*model.Post, len(posts))
+			for _, post := range posts {
+				p[post.ID] = post
+			}
+
+			result := make([]*model.Post, len(ids))
+			for i, id := range ids {
+				result[i] = p[id]
+			}
+			return result, nil
+		},
+	}
 
-		ctx := context.WithValue(r.Context(), userloaderKey, &userLoader)
+	commentLoader := CommentLoader{
+		maxBatch: 100,
+		wait:     1 * time.Millisecond,
+		fetch: func(ids []string) ([]*model.Comment, []error) {
+			comments, err := s.CommentsByIDs(ctx, ids)
+			if err != nil {
+				return nil, []error{err}
+			}
 
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+			c := make(map[string]*model.Comment, len(comments))
+			for _, comment := range comments {
+				c[comment.ID] = comment
+			}
+
+			result := make([]*model.Comment, len(ids))
+			for i, id := range ids {
+				result[i] = c[id]
+			}
+			return result, nil
+		},
+	}
+
+	imagesLoader := ImagesLoader{
+		maxBatch: 100,
+		wait:     1 * time.Millisecond,
+		fetch: func(ids []string) ([][]*model.Image, []error) {
+			images, err := s.PostsImages(ctx, ids)
+			if err != nil {
+				return nil, []error{err}
+			}
+
+			byPost := make(map[string][]*model.Image, len(ids))
+			for _, image := range images {
+				byPost[image.PostID] = append(byPost[image.PostID], image)
+			}
+
+			result := make([][]*model.Image, len(ids))
+			for i, id := range ids {
+				result[i] = byPost[id]
+			}
+			return result, nil
+		},
+	}
+
+	ctx = context.WithValue(ctx, userloaderKey, &userLoader)
+	ctx = context.WithValue(ctx, userstatsloaderKey, &userStatsLoader)
+	ctx = context.WithValue(ctx, savedloaderKey, &savedLoader)
+	ctx = context.WithValue(ctx, reactionloaderKey, &reactionLoader)
+	ctx = context.WithValue(ctx, membersloaderKey, &membersLoader)
+	ctx = context.WithValue(ctx, lastmessageloaderKey, &lastMessageLoader)
+	ctx = context.WithValue(ctx, unreadloaderKey, &unreadLoader)
+	ctx = context.WithValue(ctx, mentionloaderKey, &mentionLoader)
+	ctx = context.WithValue(ctx, pollloaderKey, &pollLoader)
+	ctx = context.WithValue(ctx, postloaderKey, &postLoader)
+	ctx = context.WithValue(ctx, commentloaderKey, &commentLoader)
+	ctx = context.WithValue(ctx, imagesloaderKey, &imagesLoader)
+	ctx = domain.WithHiddenCache(ctx)
+
+	return ctx
 }
 
 func getUserLoader(ctx context.Context) *UserLoader {
 	return ctx.Value(userloaderKey).(*UserLoader)
 }
+
+// loadUsers loads users through the user loader, users that no longer exist
+// are skipped.
+func loadUsers(ctx context.Context, ids []string) ([]*model.User, error) {
+	loaded, errs := getUserLoader(ctx).LoadAll(ids)
+	users := make([]*model.User, 0, len(loaded))
+	for i, user := range loaded {
+		if errs != nil && errs[i] != nil {
+			return nil, errs[i]
+		}
+		if user != nil {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func getUserStatsLoader(ctx context.Context) *UserStatsLoader {
+	return ctx.Value(userstatsloaderKey).(*UserStatsLoader)
+}
+
+func getSavedLoader(ctx context.Context) *SavedLoader {
+	return ctx.Value(savedloaderKey).(*SavedLoader)
+}
+
+// savedKey is the key of the saved loader for a post and a user.
+func savedKey(userID, postID string) string {
+	return userID + ":" + postID
+}
+
+func getReactionLoader(ctx context.Context) *ReactionLoader {
+	return ctx.Value(reactionloaderKey).(*ReactionLoader)
+}
+
+// reactionKey is the key of the reaction loader for a post or a comment and
+// a user, the user is empty for anonymous requests.
+func reactionKey(userID string, target model.ReactionTarget, id string) string {
+	return userID + ":" + string(target) + ":" + id
+}
+
+func getMembersLoader(ctx context.Context) *MembersLoader {
+	return ctx.Value(membersloaderKey).(*MembersLoader)
+}
+
+func getLastMessageLoader(ctx context.Context) *LastMessageLoader {
+	return ctx.Value(lastmessageloaderKey).(*LastMessageLoader)
+}
+
+func getUnreadLoader(ctx context.Context) *UnreadLoader {
+	return ctx.Value(unreadloaderKey).(*UnreadLoader)
+}
+
+// unreadKey is the key of the unread loader for a conversation and a user.
+func unreadKey(userID, conversationID string) string {
+	return userID + ":" + conversationID
+}
+
+func getMentionLoader(ctx context.Context) *MentionLoader {
+	return ctx.Value(mentionloaderKey).(*MentionLoader)
+}
+
+// loadMentions returns the users mentioned in a post or a comment in the
+// order of their usernames.
+func loadMentions(ctx context.Context, target model.ReactionTarget, id string) ([]*model.User, error) {
+	mentions, err := getMentionLoader(ctx).Load(mentionKey(target, id))
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(mentions))
+	for _, m := range mentions {
+		ids = append(ids, m.UserID)
+	}
+	return loadUsers(ctx, ids)
+}
+
+// mentionKey is the key of the mention loader for a post or a comment.
+func mentionKey(target model.ReactionTarget, id string) string {
+	return string(target) + ":" + id
+}
+
+// currentUserID returns the id of the current user, empty for anonymous
+// requests.
+func currentUserID(ctx context.Context) string {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return ""
+	}
+	return currentUser.ID
+}
+
+func splitReactionKey(key string) (userID string, target model.ReactionTarget, id string) {
+	userID, rest, _ := strings.Cut(key, ":")
+	t, id, _ := strings.Cut(rest, ":")
+	return userID, model.ReactionTarget(t), id
+}
+
+func getPollLoader(ctx context.Context) *PollLoader {
+	return ctx.Value(pollloaderKey).(*PollLoader)
+}
+
+// pollKey is the key of the poll loader for a post and a user, the user is
+// empty for anonymous requests.
+func pollKey(userID, postID string) string {
+	return userID + ":" + postID
+}
+
+func getPostLoader(ctx context.Context) *PostLoader {
+	return ctx.Value(postloaderKey).(*PostLoader)
+}
+
+// loadPost returns nil when the post doesn't exist or the current user can't
+// see it.
+func loadPost(ctx context.Context, id string) (*model.Post, error) {
+	post, err := getPostLoader(ctx).Load(id)
+	if err != nil || post == nil || !domain.PostVisible(ctx, post) {
+		return nil, err
+	}
+	return post, nil
+}
+
+func getCommentLoader(ctx context.Context) *CommentLoader {
+	return ctx.Value(commentloaderKey).(*CommentLoader)
+}
+
+// loadComment returns nil when the comment doesn't exist or the current user
+// can't see its post.
+func loadComment(ctx context.Context, id string) (*model.Comment, error) {
+	comment, err := getCommentLoader(ctx).Load(id)
+	if err != nil || comment == nil {
+		return nil, err
+	}
+	post, err := loadPost(ctx, comment.PostID)
+	if err != nil || post == nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+func getImagesLoader(ctx context.Context) *ImagesLoader {
+	return ctx.Value(imagesloaderKey).(*ImagesLoader)
+}
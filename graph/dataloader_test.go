@@ -0,0 +1,34 @@
+package graph
+
+import (
+	"context"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/farid21ola/forum/mocks"
+	"github.com/farid21ola/forum/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDataloaderMiddleware(t *testing.T) {
+	s := mocks.NewStorage(t)
+	s.On("UsersByIDs", mock.Anything, []string{"1"}).
+		Return([]*model.User{{ID: "1", Username: "alice"}}, nil).Once()
+	s.On("UsersByIDs", mock.Anything, []string{"1"}).
+		Return([]*model.User{{ID: "1", Username: "bob"}}, nil).Once()
+
+	// a subscription runs the middleware for every event on the same context
+	ctx := context.Background()
+	respond := DataloaderMiddleware(s)
+	for _, want := range []string{"alice", "bob"} {
+		respond(ctx, func(ctx context.Context) *graphql.Response {
+			for i := 0; i < 2; i++ {
+				user, err := getUserLoader(ctx).Load("1")
+				require.NoError(t, err)
+				assert.Equal(t, want, user.Username)
+			}
+			return nil
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/farid21ola/forum/domain"
+	"github.com/farid21ola/forum/ratelimit"
+	"github.com/farid21ola/forum/storage"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// ErrorPresenter adds extensions.code to errors that clients are expected to
+// handle, together with the details they need for it.
+func ErrorPresenter(ctx context.Context, err error) *gqlerror.Error {
+	gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+
+	var limitErr *ratelimit.LimitError
+	var loginErr *domain.LoginThrottledError
+
+	switch {
+	case errors.As(err, &limitErr):
+		setExtensions(gqlErr, map[string]interface{}{
+			"code":       "RATE_LIMITED",
+			"retryAfter": limitErr.RetrySeconds(),
+		})
+	case errors.As(err, &loginErr):
+		code := "LOGIN_THROTTLED"
+		if loginErr.Locked {
+			code = "ACCOUNT_LOCKED"
+		}
+		setExtensions(gqlErr, map[string]interface{}{
+			"code":       code,
+			"retryAfter": loginErr.RetrySeconds(),
+		})
+	case errors.Is(err, storage.ErrNotFound):
+		setExtensions(gqlErr, map[string]interface{}{"code": "NOT_FOUND"})
+	case errors.Is(err, storage.ErrConflict):
+		setExtensions(gqlErr, map[string]interface{}{"code": "CONFLICT"})
+	case errors.Is(err, storage.ErrInvalidID):
+		setExtensions(gqlErr, map[string]interface{}{"code": "INVALID_ID"})
+	}
+
+	return gqlErr
+}
+
+func setExtensions(gqlErr *gqlerror.Error, ext map[string]interface{}) {
+	if gqlErr.Extensions == nil {
+		gqlErr.Extensions = make(map[string]interface{}, len(ext))
+	}
+	for k, v := range ext {
+		gqlErr.Extensions[k] = v
+	}
+}
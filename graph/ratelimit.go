@@ -0,0 +1,30 @@
+package graph
+
+import (
+	"context"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/farid21ola/forum/middleware"
+	"github.com/farid21ola/forum/ratelimit"
+)
+
+// RateLimit throttles mutations per authenticated user, or per client ip for
+// anonymous requests.
+func RateLimit(l *ratelimit.Limiter) graphql.FieldMiddleware {
+	return func(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+		fc := graphql.GetFieldContext(ctx)
+		if fc == nil || fc.Object != "Mutation" {
+			return next(ctx)
+		}
+
+		key := "ip:" + middleware.GetClientIPFromCtx(ctx)
+		if user, err := middleware.GetCurrentUserFromCtx(ctx); err == nil {
+			key = "user:" + user.ID
+		}
+
+		if err := l.Allow(ctx, fc.Field.Name, key); err != nil {
+			return nil, err
+		}
+
+		return next(ctx)
+	}
+}
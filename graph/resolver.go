@@ -4,8 +4,6 @@ package graph
 
 import (
 	"github.com/farid21ola/forum/domain"
-	"github.com/farid21ola/forum/model"
-	"sync"
 )
 
 // This file will not be regenerated automatically.
@@ -14,7 +12,4 @@ import (
 
 type Resolver struct {
 	Domain *domain.Domain
-	// All active subscriptions
-	CommentsObservers map[string][]chan *model.Comment
-	mu                sync.Mutex
 }
@@ -7,14 +7,77 @@ package graph
 import (
 	"context"
 	"errors"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/farid21ola/forum/domain"
+	"github.com/farid21ola/forum/middleware"
 	"github.com/farid21ola/forum/model"
+	"time"
 )
 
+// ContentHTML is the resolver for the contentHtml field.
+func (r *commentResolver) ContentHTML(ctx context.Context, obj *model.Comment) (string, error) {
+	return r.Domain.ContentHTML(obj.Content), nil
+}
+
 // User is the resolver for the user field.
 func (r *commentResolver) User(ctx context.Context, obj *model.Comment) (*model.User, error) {
 	return r.Domain.Storage.UserByID(ctx, obj.UserID)
 }
 
+// Mentions is the resolver for the mentions field.
+func (r *commentResolver) Mentions(ctx context.Context, obj *model.Comment) ([]*model.User, error) {
+	return loadMentions(ctx, model.ReactionTargetComment, obj.ID)
+}
+
+// Reactions is the resolver for the reactions field.
+func (r *commentResolver) Reactions(ctx context.Context, obj *model.Comment) ([]*model.ReactionCount, error) {
+	return getReactionLoader(ctx).Load(reactionKey(currentUserID(ctx), model.ReactionTargetComment, obj.ID))
+}
+
+// Participants is the resolver for the participants field.
+func (r *conversationResolver) Participants(ctx context.Context, obj *model.Conversation) ([]*model.User, error) {
+	return loadUsers(ctx, obj.ParticipantIDs)
+}
+
+// LastMessage is the resolver for the lastMessage field.
+func (r *conversationResolver) LastMessage(ctx context.Context, obj *model.Conversation) (*model.Message, error) {
+	return getLastMessageLoader(ctx).Load(obj.ID)
+}
+
+// UnreadCount is the resolver for the unreadCount field.
+func (r *conversationResolver) UnreadCount(ctx context.Context, obj *model.Conversation) (int, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return 0, domain.ErrUnauthenticated
+	}
+	return getUnreadLoader(ctx).Load(unreadKey(currentUser.ID, obj.ID))
+}
+
+// URL is the resolver for the url field.
+func (r *imageResolver) URL(ctx context.Context, obj *model.Image) (string, error) {
+	return r.Domain.MediaURL(obj.Key), nil
+}
+
+// ThumbnailURL is the resolver for the thumbnailUrl field.
+func (r *imageResolver) ThumbnailURL(ctx context.Context, obj *model.Image) (string, error) {
+	return r.Domain.MediaURL(obj.ThumbnailKey), nil
+}
+
+// Sender is the resolver for the sender field.
+func (r *messageResolver) Sender(ctx context.Context, obj *model.Message) (*model.User, error) {
+	return getUserLoader(ctx).Load(obj.UserID)
+}
+
+// ReadBy is the resolver for the readBy field.
+func (r *messageResolver) ReadBy(ctx context.Context, obj *model.Message) ([]*model.User, error) {
+	members, err := getMembersLoader(ctx).Load(obj.ConversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return loadUsers(ctx, domain.MessageReadBy(obj, members))
+}
+
 // Login is the resolver for the login field.
 func (r *mutationResolver) Login(ctx context.Context, input *model.LoginInput) (*model.AuthResponse, error) {
 	IsValid := validation(ctx, input)
@@ -35,6 +98,21 @@ func (r *mutationResolver) Register(ctx context.Context, input *model.RegisterIn
 	return r.Domain.Register(ctx, input)
 }
 
+// UpdateProfile is the resolver for the updateProfile field.
+func (r *mutationResolver) UpdateProfile(ctx context.Context, input model.UpdateProfile) (*model.User, error) {
+	IsValid := validation(ctx, input)
+	if !IsValid {
+		return nil, ErrInput
+	}
+
+	return r.Domain.UpdateProfile(ctx, input)
+}
+
+// UploadAvatar is the resolver for the uploadAvatar field.
+func (r *mutationResolver) UploadAvatar(ctx context.Context, file graphql.Upload) (*model.User, error) {
+	return r.Domain.UploadAvatar(ctx, file)
+}
+
 // CreatePost is the resolver for the createPost field.
 func (r *mutationResolver) CreatePost(ctx context.Context, input model.NewPost) (*model.Post, error) {
 	return r.Domain.CreatePost(ctx, input)
@@ -45,25 +123,166 @@ func (r *mutationResolver) UpdatePost(ctx context.Context, input *model.UpdatePo
 	return r.Domain.UpdatePost(ctx, input)
 }
 
+// SaveDraft is the resolver for the saveDraft field.
+func (r *mutationResolver) SaveDraft(ctx context.Context, input model.DraftInput) (*model.Post, error) {
+	return r.Domain.SaveDraft(ctx, input)
+}
+
+// PublishPost is the resolver for the publishPost field.
+func (r *mutationResolver) PublishPost(ctx context.Context, id string) (*model.Post, error) {
+	return r.Domain.PublishPost(ctx, id)
+}
+
+// SchedulePost is the resolver for the schedulePost field.
+func (r *mutationResolver) SchedulePost(ctx context.Context, id string, at time.Time) (*model.Post, error) {
+	return r.Domain.SchedulePost(ctx, id, at)
+}
+
 // AddComment is the resolver for the addComment field.
 func (r *mutationResolver) AddComment(ctx context.Context, input model.NewComment) (*model.Comment, error) {
-	comment, err := r.Domain.AddComment(ctx, input)
-	if err != nil {
-		return nil, err
+	return r.Domain.AddComment(ctx, input)
+}
+
+// UpdateComment is the resolver for the updateComment field.
+func (r *mutationResolver) UpdateComment(ctx context.Context, input model.UpdateComment) (*model.Comment, error) {
+	return r.Domain.UpdateComment(ctx, input)
+}
+
+// DeleteComment is the resolver for the deleteComment field.
+func (r *mutationResolver) DeleteComment(ctx context.Context, id string) (*model.Comment, error) {
+	return r.Domain.DeleteComment(ctx, id)
+}
+
+// VotePost is the resolver for the votePost field.
+func (r *mutationResolver) VotePost(ctx context.Context, postID string, value int) (*model.Post, error) {
+	return r.Domain.VotePost(ctx, postID, value)
+}
+
+// VotePoll is the resolver for the votePoll field.
+func (r *mutationResolver) VotePoll(ctx context.Context, pollID string, optionIds []string) (*model.Poll, error) {
+	return r.Domain.VotePoll(ctx, pollID, optionIds)
+}
+
+// React is the resolver for the react field.
+func (r *mutationResolver) React(ctx context.Context, targetType model.ReactionTarget, targetID string, emoji string) ([]*model.ReactionCount, error) {
+	return r.Domain.React(ctx, targetType, targetID, emoji)
+}
+
+// Unreact is the resolver for the unreact field.
+func (r *mutationResolver) Unreact(ctx context.Context, targetType model.ReactionTarget, targetID string, emoji string) ([]*model.ReactionCount, error) {
+	return r.Domain.Unreact(ctx, targetType, targetID, emoji)
+}
+
+// SavePost is the resolver for the savePost field.
+func (r *mutationResolver) SavePost(ctx context.Context, id string) (*model.Post, error) {
+	return r.Domain.SavePost(ctx, id)
+}
+
+// UnsavePost is the resolver for the unsavePost field.
+func (r *mutationResolver) UnsavePost(ctx context.Context, id string) (*model.Post, error) {
+	return r.Domain.UnsavePost(ctx, id)
+}
+
+// SaveComment is the resolver for the saveComment field.
+func (r *mutationResolver) SaveComment(ctx context.Context, id string) (*model.Comment, error) {
+	return r.Domain.SaveComment(ctx, id)
+}
+
+// UnsaveComment is the resolver for the unsaveComment field.
+func (r *mutationResolver) UnsaveComment(ctx context.Context, id string) (*model.Comment, error) {
+	return r.Domain.UnsaveComment(ctx, id)
+}
+
+// FollowUser is the resolver for the followUser field.
+func (r *mutationResolver) FollowUser(ctx context.Context, id string) (*model.User, error) {
+	return r.Domain.FollowUser(ctx, id)
+}
+
+// UnfollowUser is the resolver for the unfollowUser field.
+func (r *mutationResolver) UnfollowUser(ctx context.Context, id string) (*model.User, error) {
+	return r.Domain.UnfollowUser(ctx, id)
+}
+
+// BlockUser is the resolver for the blockUser field.
+func (r *mutationResolver) BlockUser(ctx context.Context, id string) (*model.User, error) {
+	return r.Domain.BlockUser(ctx, id)
+}
+
+// UnblockUser is the resolver for the unblockUser field.
+func (r *mutationResolver) UnblockUser(ctx context.Context, id string) (*model.User, error) {
+	return r.Domain.UnblockUser(ctx, id)
+}
+
+// MuteUser is the resolver for the muteUser field.
+func (r *mutationResolver) MuteUser(ctx context.Context, id string) (*model.User, error) {
+	return r.Domain.MuteUser(ctx, id)
+}
+
+// UnmuteUser is the resolver for the unmuteUser field.
+func (r *mutationResolver) UnmuteUser(ctx context.Context, id string) (*model.User, error) {
+	return r.Domain.UnmuteUser(ctx, id)
+}
+
+// SendMessage is the resolver for the sendMessage field.
+func (r *mutationResolver) SendMessage(ctx context.Context, input model.NewMessage) (*model.Message, error) {
+	return r.Domain.SendMessage(ctx, input)
+}
+
+// MarkConversationRead is the resolver for the markConversationRead field.
+func (r *mutationResolver) MarkConversationRead(ctx context.Context, conversationID string, messageID *string) (*model.Conversation, error) {
+	return r.Domain.MarkConversationRead(ctx, conversationID, messageID)
+}
+
+// MarkNotificationsRead is the resolver for the markNotificationsRead field.
+func (r *mutationResolver) MarkNotificationsRead(ctx context.Context, ids []string) (int, error) {
+	return r.Domain.MarkNotificationsRead(ctx, ids)
+}
+
+// Actor is the resolver for the actor field.
+func (r *notificationResolver) Actor(ctx context.Context, obj *model.Notification) (*model.User, error) {
+	if obj.ActorID == nil {
+		return nil, nil
 	}
-	r.mu.Lock()
-	if chans, ok := r.CommentsObservers[input.PostID]; ok {
-		for _, ch := range chans {
-			ch <- comment
-		}
+
+	return getUserLoader(ctx).Load(*obj.ActorID)
+}
+
+// Post is the resolver for the post field.
+func (r *notificationResolver) Post(ctx context.Context, obj *model.Notification) (*model.Post, error) {
+	if obj.PostID == nil {
+		return nil, nil
+	}
+
+	return loadPost(ctx, *obj.PostID)
+}
+
+// Comment is the resolver for the comment field.
+func (r *notificationResolver) Comment(ctx context.Context, obj *model.Notification) (*model.Comment, error) {
+	if obj.CommentID == nil {
+		return nil, nil
 	}
-	r.mu.Unlock()
-	return comment, nil
+
+	return loadComment(ctx, *obj.CommentID)
+}
+
+// Closed is the resolver for the closed field.
+func (r *pollResolver) Closed(ctx context.Context, obj *model.Poll) (bool, error) {
+	return obj.IsClosed(time.Now()), nil
+}
+
+// ContentHTML is the resolver for the contentHtml field.
+func (r *postResolver) ContentHTML(ctx context.Context, obj *model.Post) (string, error) {
+	return r.Domain.ContentHTML(obj.Content), nil
 }
 
 // Comments is the resolver for the comments field.
 func (r *postResolver) Comments(ctx context.Context, obj *model.Post, limit *int, offset *int) ([]*model.Comment, error) {
-	return r.Domain.Storage.Comments(ctx, obj.ID, limit, offset)
+	return r.Domain.Comments(ctx, obj.ID, limit, offset)
+}
+
+// Images is the resolver for the images field.
+func (r *postResolver) Images(ctx context.Context, obj *model.Post) ([]*model.Image, error) {
+	return getImagesLoader(ctx).Load(obj.ID)
 }
 
 // User is the resolver for the user field.
@@ -71,14 +290,38 @@ func (r *postResolver) User(ctx context.Context, obj *model.Post) (*model.User,
 	return getUserLoader(ctx).Load(obj.UserID)
 }
 
+// Mentions is the resolver for the mentions field.
+func (r *postResolver) Mentions(ctx context.Context, obj *model.Post) ([]*model.User, error) {
+	return loadMentions(ctx, model.ReactionTargetPost, obj.ID)
+}
+
+// IsSaved is the resolver for the isSaved field.
+func (r *postResolver) IsSaved(ctx context.Context, obj *model.Post) (bool, error) {
+	currentUser, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return false, nil
+	}
+	return getSavedLoader(ctx).Load(savedKey(currentUser.ID, obj.ID))
+}
+
+// Poll is the resolver for the poll field.
+func (r *postResolver) Poll(ctx context.Context, obj *model.Post) (*model.Poll, error) {
+	return getPollLoader(ctx).Load(pollKey(currentUserID(ctx), obj.ID))
+}
+
+// Reactions is the resolver for the reactions field.
+func (r *postResolver) Reactions(ctx context.Context, obj *model.Post) ([]*model.ReactionCount, error) {
+	return getReactionLoader(ctx).Load(reactionKey(currentUserID(ctx), model.ReactionTargetPost, obj.ID))
+}
+
 // Posts is the resolver for the posts field.
 func (r *queryResolver) Posts(ctx context.Context, limit *int, offset *int) ([]*model.Post, error) {
-	return r.Domain.Storage.Posts(ctx, limit, offset)
+	return r.Domain.Posts(ctx, limit, offset)
 }
 
 // Post is the resolver for the post field.
 func (r *queryResolver) Post(ctx context.Context, id string) (*model.Post, error) {
-	return r.Domain.Storage.Post(ctx, id)
+	return r.Domain.Post(ctx, id)
 }
 
 // Users is the resolver for the users field.
@@ -91,26 +334,109 @@ func (r *queryResolver) User(ctx context.Context, id string) (*model.User, error
 	return r.Domain.Storage.UserByID(ctx, id)
 }
 
+// Me is the resolver for the me field.
+func (r *queryResolver) Me(ctx context.Context) (*model.User, error) {
+	user, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return nil, nil
+	}
+
+	return user, nil
+}
+
+// Notifications is the resolver for the notifications field.
+func (r *queryResolver) Notifications(ctx context.Context, unreadOnly *bool, first *int, after *string) (*model.NotificationConnection, error) {
+	return r.Domain.Notifications(ctx, unreadOnly, first, after)
+}
+
+// MyDrafts is the resolver for the myDrafts field.
+func (r *queryResolver) MyDrafts(ctx context.Context) ([]*model.Post, error) {
+	return r.Domain.MyDrafts(ctx)
+}
+
+// Saved is the resolver for the saved field.
+func (r *queryResolver) Saved(ctx context.Context, typeArg *model.SavedType, first *int, after *string) (*model.SavedConnection, error) {
+	return r.Domain.Saved(ctx, typeArg, first, after)
+}
+
+// Conversations is the resolver for the conversations field.
+func (r *queryResolver) Conversations(ctx context.Context) ([]*model.Conversation, error) {
+	return r.Domain.Conversations(ctx)
+}
+
+// Messages is the resolver for the messages field.
+func (r *queryResolver) Messages(ctx context.Context, conversationID string, first *int, before *string) (*model.MessageConnection, error) {
+	return r.Domain.Messages(ctx, conversationID, first, before)
+}
+
+// BlockedUsers is the resolver for the blockedUsers field.
+func (r *queryResolver) BlockedUsers(ctx context.Context) ([]*model.User, error) {
+	return r.Domain.BlockedUsers(ctx, model.BlockKindBlock)
+}
+
+// MutedUsers is the resolver for the mutedUsers field.
+func (r *queryResolver) MutedUsers(ctx context.Context) ([]*model.User, error) {
+	return r.Domain.BlockedUsers(ctx, model.BlockKindMute)
+}
+
+// Feed is the resolver for the feed field.
+func (r *queryResolver) Feed(ctx context.Context, first *int, after *string) (*model.PostConnection, error) {
+	return r.Domain.Feed(ctx, first, after)
+}
+
+// AvailableReactions is the resolver for the availableReactions field.
+func (r *queryResolver) AvailableReactions(ctx context.Context) ([]string, error) {
+	return r.Domain.AvailableReactions(), nil
+}
+
+// PreviewMarkdown is the resolver for the previewMarkdown field.
+func (r *queryResolver) PreviewMarkdown(ctx context.Context, text string) (string, error) {
+	return r.Domain.PreviewMarkdown(text)
+}
+
 // CommentAdded is the resolver for the commentAdded field.
-func (r *subscriptionResolver) CommentAdded(ctx context.Context, postID string) (<-chan *model.Comment, error) {
-	//_, err := middleware.GetCurrentUserFromCtx(ctx)
-	//if err != nil {
-	//	return nil, domain.ErrUnauthenticated
-	//}
-	r.mu.Lock()
-	defer r.mu.Unlock()
+func (r *subscriptionResolver) CommentAdded(ctx context.Context, postID string, since *string) (<-chan *model.Comment, error) {
+	return r.Domain.CommentAdded(ctx, postID, since)
+}
+
+// PostAdded is the resolver for the postAdded field.
+func (r *subscriptionResolver) PostAdded(ctx context.Context, tag *string) (<-chan *model.Post, error) {
+	return r.Domain.PostAdded(ctx, tag)
+}
+
+// PostUpdated is the resolver for the postUpdated field.
+func (r *subscriptionResolver) PostUpdated(ctx context.Context, id string) (<-chan *model.Post, error) {
+	return r.Domain.PostUpdated(ctx, id)
+}
+
+// CommentUpdated is the resolver for the commentUpdated field.
+func (r *subscriptionResolver) CommentUpdated(ctx context.Context, postID string) (<-chan *model.Comment, error) {
+	return r.Domain.CommentUpdated(ctx, postID)
+}
 
-	ch := make(chan *model.Comment, 1)
-	r.CommentsObservers[postID] = append(r.CommentsObservers[postID], ch)
+// ScoreChanged is the resolver for the scoreChanged field.
+func (r *subscriptionResolver) ScoreChanged(ctx context.Context, postID string) (<-chan *model.ScoreChange, error) {
+	return r.Domain.ScoreChanged(ctx, postID)
+}
+
+// ReactionsUpdated is the resolver for the reactionsUpdated field.
+func (r *subscriptionResolver) ReactionsUpdated(ctx context.Context, postID string) (<-chan *model.ReactionsUpdate, error) {
+	return r.Domain.ReactionsUpdated(ctx, postID)
+}
 
-	go func() {
-		<-ctx.Done()
-		r.mu.Lock()
-		delete(r.CommentsObservers, postID)
-		r.mu.Unlock()
-	}()
+// PollUpdated is the resolver for the pollUpdated field.
+func (r *subscriptionResolver) PollUpdated(ctx context.Context, pollID string) (<-chan *model.Poll, error) {
+	return r.Domain.PollUpdated(ctx, pollID)
+}
+
+// NotificationReceived is the resolver for the notificationReceived field.
+func (r *subscriptionResolver) NotificationReceived(ctx context.Context) (<-chan *model.Notification, error) {
+	return r.Domain.NotificationReceived(ctx)
+}
 
-	return ch, nil
+// MessageReceived is the resolver for the messageReceived field.
+func (r *subscriptionResolver) MessageReceived(ctx context.Context) (<-chan *model.Message, error) {
+	return r.Domain.MessageReceived(ctx)
 }
 
 // Posts is the resolver for the posts field.
@@ -118,12 +444,72 @@ func (r *userResolver) Posts(ctx context.Context, obj *model.User) ([]*model.Pos
 	return r.Domain.Storage.UsersPost(ctx, obj.ID)
 }
 
+// PostCount is the resolver for the postCount field.
+func (r *userResolver) PostCount(ctx context.Context, obj *model.User) (int, error) {
+	stats, err := getUserStatsLoader(ctx).Load(obj.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return stats.PostCount, nil
+}
+
+// CommentCount is the resolver for the commentCount field.
+func (r *userResolver) CommentCount(ctx context.Context, obj *model.User) (int, error) {
+	stats, err := getUserStatsLoader(ctx).Load(obj.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return stats.CommentCount, nil
+}
+
+// Karma is the resolver for the karma field.
+func (r *userResolver) Karma(ctx context.Context, obj *model.User) (int, error) {
+	stats, err := getUserStatsLoader(ctx).Load(obj.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return stats.Karma, nil
+}
+
+// UnreadNotificationCount is the resolver for the unreadNotificationCount field.
+func (r *userResolver) UnreadNotificationCount(ctx context.Context, obj *model.User) (*int, error) {
+	return r.Domain.UnreadNotificationCount(ctx, obj.ID)
+}
+
+// Followers is the resolver for the followers field.
+func (r *userResolver) Followers(ctx context.Context, obj *model.User, first *int, after *string) (*model.FollowConnection, error) {
+	return r.Domain.Followers(ctx, obj.ID, first, after)
+}
+
+// Following is the resolver for the following field.
+func (r *userResolver) Following(ctx context.Context, obj *model.User, first *int, after *string) (*model.FollowConnection, error) {
+	return r.Domain.Following(ctx, obj.ID, first, after)
+}
+
 // Comment returns CommentResolver implementation.
 func (r *Resolver) Comment() CommentResolver { return &commentResolver{r} }
 
+// Conversation returns ConversationResolver implementation.
+func (r *Resolver) Conversation() ConversationResolver { return &conversationResolver{r} }
+
+// Image returns ImageResolver implementation.
+func (r *Resolver) Image() ImageResolver { return &imageResolver{r} }
+
+// Message returns MessageResolver implementation.
+func (r *Resolver) Message() MessageResolver { return &messageResolver{r} }
+
 // Mutation returns MutationResolver implementation.
 func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 
+// Notification returns NotificationResolver implementation.
+func (r *Resolver) Notification() NotificationResolver { return &notificationResolver{r} }
+
+// Poll returns PollResolver implementation.
+func (r *Resolver) Poll() PollResolver { return &pollResolver{r} }
+
 // Post returns PostResolver implementation.
 func (r *Resolver) Post() PostResolver { return &postResolver{r} }
 
@@ -137,7 +523,12 @@ func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionRes
 func (r *Resolver) User() UserResolver { return &userResolver{r} }
 
 type commentResolver struct{ *Resolver }
+type conversationResolver struct{ *Resolver }
+type imageResolver struct{ *Resolver }
+type messageResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
+type notificationResolver struct{ *Resolver }
+type pollResolver struct{ *Resolver }
 type postResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
 type subscriptionResolver struct{ *Resolver }
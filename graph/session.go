@@ -0,0 +1,96 @@
+package graph
+
+import (
+	"context"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/farid21ola/forum/middleware"
+	"github.com/farid21ola/forum/storage"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WebsocketInit authenticates websocket connections with the token sent in
+// the connection_init payload, browsers can't set headers on websockets. A
+// token passed with the handshake request is used when the payload has none.
+// The connection is closed when the token expires or, checked every recheck
+// interval, when its user no longer exists.
+func WebsocketInit(s storage.Storage, recheck time.Duration) transport.WebsocketInitFunc {
+	return func(ctx context.Context, payload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+		if token := payload.Authorization(); token != "" {
+			user, expiresAt, err := middleware.Authenticate(ctx, s, token)
+			if err != nil {
+				return nil, nil, err
+			}
+			ctx = context.WithValue(ctx, middleware.CurrentUserKey, user)
+			ctx = context.WithValue(ctx, middleware.TokenExpiryKey, expiresAt)
+		}
+
+		if _, err := middleware.GetCurrentUserFromCtx(ctx); err != nil {
+			// anonymous connections can still subscribe to public events
+			return ctx, nil, nil
+		}
+
+		// the watcher cancels the context when the session ends
+		ctx, _ = sessionContext(transport.AppendCloseReason(ctx, "session expired"), s, recheck)
+		return ctx, nil, nil
+	}
+}
+
+// StreamSessions ends streaming http subscriptions (server-sent events and
+// multipart responses) the same way as websocket connections: when the token
+// expires or its user no longer exists.
+func StreamSessions(s storage.Storage, recheck time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			accept := r.Header.Get("Accept")
+			if !strings.Contains(accept, "text/event-stream") && !strings.Contains(accept, "multipart/mixed") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := sessionContext(r.Context(), s, recheck)
+			defer cancel()
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// sessionContext returns a context that is cancelled when the session of the
+// current user ends. Anonymous contexts are returned as they are, they can
+// still subscribe to public events.
+func sessionContext(ctx context.Context, s storage.Storage, recheck time.Duration) (context.Context, context.CancelFunc) {
+	user, err := middleware.GetCurrentUserFromCtx(ctx)
+	if err != nil {
+		return ctx, func() {}
+	}
+	expiresAt, _ := middleware.GetTokenExpiryFromCtx(ctx)
+
+	ctx, cancel := context.WithCancel(ctx)
+	go watchSession(ctx, cancel, s, user.ID, expiresAt, recheck)
+
+	return ctx, cancel
+}
+
+func watchSession(ctx context.Context, cancel context.CancelFunc, s storage.Storage, userID string, expiresAt time.Time, recheck time.Duration) {
+	defer cancel()
+
+	expired := time.NewTimer(time.Until(expiresAt))
+	defer expired.Stop()
+	ticker := time.NewTicker(recheck)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-expired.C:
+			return
+		case <-ticker.C:
+			if user, err := s.UserByID(ctx, userID); err != nil || user == nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/farid21ola/forum/middleware"
+	"github.com/farid21ola/forum/mocks"
+	"github.com/farid21ola/forum/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func token(t *testing.T, expiresAt time.Time) string {
+	t.Helper()
+	s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{Id: "1", ExpiresAt: expiresAt.Unix()}).
+		SignedString([]byte("secret"))
+	require.NoError(t, err)
+	return s
+}
+
+func assertClosed(t *testing.T, ctx context.Context) {
+	t.Helper()
+	select {
+	case <-ctx.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("connection was not closed")
+	}
+}
+
+func TestWebsocketInit(t *testing.T) {
+	t.Setenv("JWT_SECRET", "secret")
+
+	t.Run("Anonymous connection", func(t *testing.T) {
+		init := WebsocketInit(new(mocks.Storage), time.Minute)
+
+		ctx, _, err := init(context.Background(), transport.InitPayload{})
+		require.NoError(t, err)
+		_, err = middleware.GetCurrentUserFromCtx(ctx)
+		assert.Error(t, err)
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		init := WebsocketInit(new(mocks.Storage), time.Minute)
+
+		_, _, err := init(context.Background(), transport.InitPayload{"Authorization": "Bearer garbage"})
+		assert.ErrorIs(t, err, middleware.ErrInvalidToken)
+	})
+
+	t.Run("Closed when the token expires", func(t *testing.T) {
+		mockStorage := new(mocks.Storage)
+		mockStorage.On("UserByID", mock.Anything, "1").Return(&model.User{ID: "1"}, nil)
+		init := WebsocketInit(mockStorage, time.Minute)
+
+		ctx, _, err := init(context.Background(), transport.InitPayload{"Authorization": token(t, time.Now().Add(time.Second))})
+		require.NoError(t, err)
+		user, err := middleware.GetCurrentUserFromCtx(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "1", user.ID)
+
+		assertClosed(t, ctx)
+	})
+
+	t.Run("Closed when the user is removed", func(t *testing.T) {
+		mockStorage := new(mocks.Storage)
+		mockStorage.On("UserByID", mock.Anything, "1").Return(&model.User{ID: "1"}, nil).Once()
+		mockStorage.On("UserByID", mock.Anything, "1").Return(nil, errors.New("user with id not exists"))
+		init := WebsocketInit(mockStorage, 10*time.Millisecond)
+
+		ctx, _, err := init(context.Background(), transport.InitPayload{"Authorization": token(t, time.Now().Add(time.Hour))})
+		require.NoError(t, err)
+
+		assertClosed(t, ctx)
+	})
+}
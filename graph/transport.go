@@ -0,0 +1,207 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SSE is transport.SSE with keepalive comments, proxies tend to close
+// streams that stay idle.
+type SSE struct {
+	KeepAlive time.Duration
+}
+
+var _ graphql.Transport = SSE{}
+
+func (t SSE) Supports(r *http.Request) bool {
+	return transport.SSE{}.Supports(r)
+}
+
+func (t SSE) Do(w http.ResponseWriter, r *http.Request, exec graphql.GraphExecutor) {
+	withKeepAlive(w, t.KeepAlive, []byte(":\n\n"), func(w http.ResponseWriter) {
+		transport.SSE{}.Do(w, r, exec)
+	})
+}
+
+const multipartBoundary = "graphql"
+
+// MultipartMixed streams subscription events as parts of a multipart/mixed
+// response, following the multipart subscription protocol of Apollo:
+// every event is sent as {"payload": <response>} and an empty object is sent
+// as a heartbeat. Each part is followed by the boundary right away, clients
+// only process a part once they see the delimiter after it.
+type MultipartMixed struct {
+	KeepAlive time.Duration
+}
+
+var _ graphql.Transport = MultipartMixed{}
+
+func (t MultipartMixed) Supports(r *http.Request) bool {
+	if !strings.Contains(r.Header.Get("Accept"), "multipart/mixed") {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return r.Method == http.MethodPost && mediaType == "application/json"
+}
+
+func (t MultipartMixed) Do(w http.ResponseWriter, r *http.Request, exec graphql.GraphExecutor) {
+	ctx := r.Context()
+	if _, ok := w.(http.Flusher); !ok {
+		transport.SendErrorf(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	start := graphql.Now()
+	params := &graphql.RawParams{Headers: r.Header}
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(params); err != nil {
+		transport.SendErrorf(w, http.StatusBadRequest, "json request body could not be decoded: %v", err)
+		return
+	}
+	params.ReadTime = graphql.TraceTiming{Start: start, End: graphql.Now()}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Content-Type", fmt.Sprintf(`multipart/mixed;boundary="%s";subscriptionSpec="1.0"`, multipartBoundary))
+
+	withKeepAlive(w, t.KeepAlive, multipartPart([]byte("{}")), func(w http.ResponseWriter) {
+		flusher := w.(http.Flusher)
+		writeResponse := func(resp *graphql.Response) {
+			b, err := json.Marshal(struct {
+				Payload *graphql.Response `json:"payload"`
+			}{resp})
+			if err != nil {
+				b, _ = json.Marshal(struct {
+					Payload interface{}   `json:"payload"`
+					Errors  gqlerror.List `json:"errors"`
+				}{nil, gqlerror.List{gqlerror.Errorf("could not encode response")}})
+			}
+			w.Write(multipartPart(b))
+			flusher.Flush()
+		}
+
+		// the first part goes out right away so clients know the stream is open
+		w.Write(append([]byte("\r\n--"+multipartBoundary), multipartPart([]byte("{}"))...))
+		flusher.Flush()
+
+		rc, opErr := exec.CreateOperationContext(ctx, params)
+		ctx := graphql.WithOperationContext(ctx, rc)
+		if opErr != nil {
+			writeResponse(exec.DispatchError(ctx, opErr))
+		} else {
+			responses, ctx := exec.DispatchOperation(ctx, rc)
+			for {
+				resp := responses(ctx)
+				if resp == nil {
+					break
+				}
+				writeResponse(resp)
+			}
+		}
+
+		// turns the last delimiter into the closing one
+		fmt.Fprint(w, "--\r\n")
+	})
+}
+
+func multipartPart(body []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("\r\nContent-Type: application/json; charset=utf-8\r\n\r\n")
+	buf.Write(body)
+	buf.WriteString("\r\n--" + multipartBoundary)
+	return buf.Bytes()
+}
+
+// withKeepAlive runs do with a writer that sends ping when the response has
+// been idle for interval. Writes are serialized, so every Write of do must be
+// a complete event.
+func withKeepAlive(w http.ResponseWriter, interval time.Duration, ping []byte, do func(w http.ResponseWriter)) {
+	if interval <= 0 {
+		do(w)
+		return
+	}
+
+	kw := &keepAliveWriter{ResponseWriter: w, wrote: make(chan struct{}, 1)}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		timer := time.NewTimer(interval)
+		defer timer.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-kw.wrote:
+				if !timer.Stop() {
+					<-timer.C
+				}
+			case <-timer.C:
+				kw.ping(ping)
+			}
+			timer.Reset(interval)
+		}
+	}()
+
+	do(kw)
+	kw.stop()
+	close(done)
+	<-stopped
+}
+
+type keepAliveWriter struct {
+	http.ResponseWriter
+	// wrote restarts the idle interval
+	wrote chan struct{}
+	mu    sync.Mutex
+	// started is set by the first write, pings before it would commit the
+	// headers before the transport has set them
+	started bool
+	// done is set when do returns, the response may already be finished
+	done bool
+}
+
+func (w *keepAliveWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.started = true
+	select {
+	case w.wrote <- struct{}{}:
+	default:
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *keepAliveWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (w *keepAliveWriter) ping(b []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.started || w.done {
+		return
+	}
+	w.ResponseWriter.Write(b)
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (w *keepAliveWriter) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.done = true
+}
@@ -0,0 +1,60 @@
+package graph
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pingRecorder reports every ping written to the recorder.
+type pingRecorder struct {
+	*httptest.ResponseRecorder
+	pings chan struct{}
+}
+
+func (r *pingRecorder) Write(b []byte) (int, error) {
+	if string(b) == "ping\n" {
+		r.pings <- struct{}{}
+	}
+	return r.ResponseRecorder.Write(b)
+}
+
+func TestWithKeepAlive(t *testing.T) {
+	rec := &pingRecorder{ResponseRecorder: httptest.NewRecorder(), pings: make(chan struct{}, 100)}
+
+	withKeepAlive(rec, 10*time.Millisecond, []byte("ping\n"), func(w http.ResponseWriter) {
+		// pings are skipped until the transport writes the headers
+		<-time.After(30 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("start\n"))
+		select {
+		case <-rec.pings:
+		case <-time.After(5 * time.Second):
+			t.Error("no ping on an idle response")
+		}
+		w.Write([]byte("end\n"))
+	})
+
+	body := rec.Body.String()
+	assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(body, "start\nping\n"), body)
+	assert.True(t, strings.HasSuffix(body, "end\n"), body)
+}
+
+func TestWithKeepAlive_Writes(t *testing.T) {
+	rec := &pingRecorder{ResponseRecorder: httptest.NewRecorder(), pings: make(chan struct{}, 100)}
+
+	withKeepAlive(rec, 100*time.Millisecond, []byte("ping\n"), func(w http.ResponseWriter) {
+		// every write restarts the interval, so writes more frequent than
+		// it are never interleaved with pings
+		for i := 0; i < 30; i++ {
+			w.Write([]byte("event\n"))
+			<-time.After(5 * time.Millisecond)
+		}
+	})
+
+	assert.Equal(t, strings.Repeat("event\n", 30), rec.Body.String())
+}
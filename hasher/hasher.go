@@ -0,0 +1,116 @@
+package hasher
+
+import (
+	"errors"
+	"golang.org/x/crypto/bcrypt"
+	"sync"
+)
+
+var (
+	ErrMismatch    = errors.New("password doesn't match")
+	ErrUnknownHash = errors.New("unknown password hash format")
+	ErrInvalidHash = errors.New("invalid password hash")
+)
+
+// Hasher turns passwords into encoded strings that record the algorithm and
+// its parameters, so hashes made with older settings can still be verified.
+type Hasher interface {
+	Hash(password string) (string, error)
+	// Verify returns ErrMismatch when password doesn't match the encoded hash.
+	Verify(password, encoded string) error
+	// Handles reports whether encoded was produced by this algorithm.
+	Handles(encoded string) bool
+	// NeedsRehash reports whether encoded was made with weaker settings than
+	// the hasher currently uses.
+	NeedsRehash(encoded string) bool
+}
+
+// Default is used by model.User to hash new passwords with argon2id while
+// still accepting bcrypt hashes of existing accounts.
+var Default = New(NewArgon2id(DefaultArgon2idParams), NewBcrypt(bcrypt.DefaultCost))
+
+// Chain hashes new passwords with the preferred hasher and verifies stored
+// hashes with whichever hasher understands them.
+type Chain struct {
+	preferred Hasher
+	legacy    []Hasher
+
+	decoysOnce sync.Once
+	// decoys hold a hash made by every hasher in the order of hashers
+	decoys []string
+}
+
+func New(preferred Hasher, legacy ...Hasher) *Chain {
+	return &Chain{preferred: preferred, legacy: legacy}
+}
+
+func (c *Chain) Hash(password string) (string, error) {
+	return c.preferred.Hash(password)
+}
+
+func (c *Chain) Verify(password, encoded string) error {
+	h := c.find(encoded)
+	if h == nil {
+		return ErrUnknownHash
+	}
+	return h.Verify(password, encoded)
+}
+
+// VerifyEvenly is Verify that, when the password doesn't match, also checks
+// it against a hash made by every other hasher of the chain. A failed check
+// then takes as long whether encoded is argon2id, bcrypt or empty, so the
+// time doesn't tell which accounts exist or which ones use a legacy hash.
+func (c *Chain) VerifyEvenly(password, encoded string) error {
+	own := c.find(encoded)
+	err := ErrUnknownHash
+	if own != nil {
+		if err = own.Verify(password, encoded); err == nil {
+			return nil
+		}
+	}
+
+	c.decoysOnce.Do(c.hashDecoys)
+	for i, h := range c.hashers() {
+		if h != own && c.decoys[i] != "" {
+			_ = h.Verify(password, c.decoys[i])
+		}
+	}
+	return err
+}
+
+// hashDecoys skips the hashers that fail, their checks are then not padded.
+func (c *Chain) hashDecoys() {
+	for _, h := range c.hashers() {
+		decoy, _ := h.Hash("decoy password")
+		c.decoys = append(c.decoys, decoy)
+	}
+}
+
+func (c *Chain) hashers() []Hasher {
+	return append([]Hasher{c.preferred}, c.legacy...)
+}
+
+func (c *Chain) Handles(encoded string) bool {
+	return c.find(encoded) != nil
+}
+
+// NeedsRehash is true for hashes made by a legacy algorithm as well as for
+// outdated parameters of the preferred one.
+func (c *Chain) NeedsRehash(encoded string) bool {
+	if !c.preferred.Handles(encoded) {
+		return true
+	}
+	return c.preferred.NeedsRehash(encoded)
+}
+
+func (c *Chain) find(encoded string) Hasher {
+	if c.preferred.Handles(encoded) {
+		return c.preferred
+	}
+	for _, h := range c.legacy {
+		if h.Handles(encoded) {
+			return h
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package hasher
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"testing"
+)
+
+var testArgon2idParams = Argon2idParams{
+	Memory:      64,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+func TestHashers(t *testing.T) {
+	tests := []struct {
+		name   string
+		hasher Hasher
+		prefix string
+	}{
+		{"argon2id", NewArgon2id(testArgon2idParams), "$argon2id$v=19$m=64,t=1,p=1$"},
+		{"bcrypt", NewBcrypt(bcrypt.MinCost), "$2a$04$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := tt.hasher.Hash("secret")
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(encoded, tt.prefix), encoded)
+			assert.True(t, tt.hasher.Handles(encoded))
+			assert.False(t, tt.hasher.NeedsRehash(encoded))
+
+			assert.NoError(t, tt.hasher.Verify("secret", encoded))
+			assert.ErrorIs(t, tt.hasher.Verify("wrong", encoded), ErrMismatch)
+
+			other, err := tt.hasher.Hash("secret")
+			require.NoError(t, err)
+			assert.NotEqual(t, encoded, other, "hashes must be salted")
+		})
+	}
+}
+
+func TestArgon2id_NeedsRehash(t *testing.T) {
+	weak, err := NewArgon2id(testArgon2idParams).Hash("secret")
+	require.NoError(t, err)
+
+	stronger := testArgon2idParams
+	stronger.Iterations = 2
+	assert.True(t, NewArgon2id(stronger).NeedsRehash(weak))
+
+	assert.True(t, NewArgon2id(testArgon2idParams).NeedsRehash("$argon2id$garbage"))
+}
+
+func TestArgon2id_VerifyInvalid(t *testing.T) {
+	a := NewArgon2id(testArgon2idParams)
+
+	for _, encoded := range []string{
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdA",
+		"$argon2id$v=18$m=64,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=x,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$!!!$a2V5",
+	} {
+		assert.ErrorIs(t, a.Verify("secret", encoded), ErrInvalidHash, encoded)
+	}
+}
+
+func TestChain(t *testing.T) {
+	legacy := NewBcrypt(bcrypt.MinCost)
+	chain := New(NewArgon2id(testArgon2idParams), legacy)
+
+	oldHash, err := legacy.Hash("secret")
+	require.NoError(t, err)
+	assert.NoError(t, chain.Verify("secret", oldHash))
+	assert.ErrorIs(t, chain.Verify("wrong", oldHash), ErrMismatch)
+	assert.True(t, chain.NeedsRehash(oldHash))
+
+	newHash, err := chain.Hash("secret")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(newHash, "$argon2id$"))
+	assert.NoError(t, chain.Verify("secret", newHash))
+	assert.False(t, chain.NeedsRehash(newHash))
+
+	assert.ErrorIs(t, chain.Verify("secret", "plain text"), ErrUnknownHash)
+	assert.False(t, chain.Handles("plain text"))
+}
+
+// countingHasher counts the checks of the wrapped hasher.
+type countingHasher struct {
+	Hasher
+	verified int
+}
+
+func (h *countingHasher) Verify(password, encoded string) error {
+	h.verified++
+	return h.Hasher.Verify(password, encoded)
+}
+
+func TestChain_VerifyEvenly(t *testing.T) {
+	preferred := &countingHasher{Hasher: NewArgon2id(testArgon2idParams)}
+	legacy := &countingHasher{Hasher: NewBcrypt(bcrypt.MinCost)}
+	chain := New(preferred, legacy)
+
+	oldHash, err := legacy.Hash("secret")
+	require.NoError(t, err)
+	newHash, err := preferred.Hash("secret")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name             string
+		password         string
+		encoded          string
+		expectedErr      error
+		expectedVerified [2]int
+	}{
+		{
+			name:             "Match",
+			password:         "secret",
+			encoded:          newHash,
+			expectedVerified: [2]int{1, 0},
+		},
+		{
+			name:             "Mismatch",
+			password:         "wrong",
+			encoded:          newHash,
+			expectedErr:      ErrMismatch,
+			expectedVerified: [2]int{1, 1},
+		},
+		{
+			name:             "Legacy mismatch",
+			password:         "wrong",
+			encoded:          oldHash,
+			expectedErr:      ErrMismatch,
+			expectedVerified: [2]int{1, 1},
+		},
+		{
+			name:             "No hash",
+			password:         "secret",
+			encoded:          "",
+			expectedErr:      ErrUnknownHash,
+			expectedVerified: [2]int{1, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preferred.verified, legacy.verified = 0, 0
+			err := chain.VerifyEvenly(tt.password, tt.encoded)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedVerified, [2]int{preferred.verified, legacy.verified})
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package markdown
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"github.com/hashicorp/golang-lru/v2"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"log"
+)
+
+// DefaultCacheSize is the number of rendered texts kept by a Renderer.
+const DefaultCacheSize = 1000
+
+// Renderer turns Markdown into sanitized HTML. Rendered texts are cached by
+// the hash of their source, so a Renderer is meant to be shared.
+type Renderer struct {
+	md     goldmark.Markdown
+	policy *bluemonday.Policy
+	cache  *lru.Cache[[sha256.Size]byte, string]
+}
+
+func New(cacheSize int) *Renderer {
+	cache, err := lru.New[[sha256.Size]byte, string](cacheSize)
+	if err != nil {
+		log.Fatalf("can't create markdown cache: %v", err)
+	}
+
+	return &Renderer{
+		// raw html in the source is dropped by goldmark, the policy catches
+		// anything dangerous that markdown itself can express
+		md: goldmark.New(goldmark.WithExtensions(
+			extension.Table,
+			extension.Strikethrough,
+			extension.Linkify,
+		)),
+		policy: Policy(),
+		cache:  cache,
+	}
+}
+
+// Policy is the allow-list applied to rendered html: formatting, links, images
+// and tables, links get rel="nofollow noreferrer".
+func Policy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.RequireNoFollowOnLinks(true)
+	p.RequireNoReferrerOnLinks(true)
+	p.AllowURLSchemes("http", "https", "mailto")
+	return p
+}
+
+// Render returns the sanitized html of a Markdown source.
+func (r *Renderer) Render(source string) string {
+	key := sha256.Sum256([]byte(source))
+	if html, ok := r.cache.Get(key); ok {
+		return html
+	}
+
+	var buf bytes.Buffer
+	if err := r.md.Convert([]byte(source), &buf); err != nil {
+		// goldmark only fails on writer errors, a buffer doesn't return them
+		log.Printf("error render markdown: %v", err)
+		return ""
+	}
+	html := r.policy.SanitizeReader(&buf).String()
+
+	r.cache.Add(key, html)
+	return html
+}
@@ -0,0 +1,144 @@
+package markdown
+
+import (
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRenderer_Render(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{
+			name:     "Formatting",
+			source:   "**bold** _italic_ ~~gone~~ `code`",
+			expected: "<p><strong>bold</strong> <em>italic</em> <del>gone</del> <code>code</code></p>\n",
+		},
+		{
+			name:     "Links get nofollow",
+			source:   "[site](https://example.com) and https://example.org",
+			expected: `<p><a href="https://example.com" rel="nofollow noreferrer">site</a> and <a href="https://example.org" rel="nofollow noreferrer">https://example.org</a></p>` + "\n",
+		},
+		{
+			name:     "Raw html is dropped",
+			source:   "<script>alert(1)</script>",
+			expected: "\n",
+		},
+		{
+			name:     "Inline html is dropped",
+			source:   `hi <img src=x onerror="alert(1)"> there`,
+			expected: "<p>hi  there</p>\n",
+		},
+		{
+			name:     "Javascript links lose the href",
+			source:   "[click](javascript:alert(1))",
+			expected: "<p>click</p>\n",
+		},
+		{
+			name:     "Code block",
+			source:   "```\n<b>not bold</b>\n```",
+			expected: "<pre><code>&lt;b&gt;not bold&lt;/b&gt;\n</code></pre>\n",
+		},
+		{
+			name:     "Empty source",
+			source:   "",
+			expected: "",
+		},
+	}
+
+	r := New(10)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, r.Render(tt.source))
+			// the second call is served from the cache
+			assert.Equal(t, tt.expected, r.Render(tt.source))
+		})
+	}
+}
+
+func TestRenderer_Cache(t *testing.T) {
+	r := New(1)
+
+	r.Render("first")
+	assert.Equal(t, 1, r.cache.Len())
+	r.Render("second")
+	assert.Equal(t, 1, r.cache.Len())
+}
+
+// FuzzRenderer_Render checks that whatever the source, the html only contains
+// allowed elements: no scripts, no event handlers, no dangerous urls and
+// every link is nofollow.
+func FuzzRenderer_Render(f *testing.F) {
+	seeds := []string{
+		"plain text",
+		"**bold** [link](https://example.com)",
+		"<script>alert(1)</script>",
+		`<a href="javascript:alert(1)">x</a>`,
+		"[x](javascript:alert(1))",
+		"[x](JaVaScRiPt:alert(1))",
+		"![x](data:text/html;base64,PHNjcmlwdD4=)",
+		`<img src=x onerror=alert(1)>`,
+		"<style>body{}</style>",
+		"<iframe src=https://example.com></iframe>",
+		"[x](https://example.com \"title\" onclick=alert(1))",
+		"```\n</code><script>alert(1)</script>\n```",
+		"<<script>script>alert(1)<</script>/script>",
+		"| a |\n|---|\n| <svg onload=alert(1)> |",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	r := New(10)
+	f.Fuzz(func(t *testing.T, source string) {
+		checkSafe(t, r.Render(source))
+	})
+}
+
+var allowedSchemes = map[string]bool{"": true, "http": true, "https": true, "mailto": true}
+
+func checkSafe(t *testing.T, rendered string) {
+	t.Helper()
+
+	z := html.NewTokenizer(strings.NewReader(rendered))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+
+		token := z.Token()
+		switch token.Data {
+		case "script", "style", "iframe", "object", "embed", "form", "input", "svg", "math":
+			t.Fatalf("element %s in %q", token.Data, rendered)
+		}
+
+		var rel string
+		for _, attr := range token.Attr {
+			key := strings.ToLower(attr.Key)
+			if strings.HasPrefix(key, "on") || key == "style" {
+				t.Fatalf("attribute %s in %q", attr.Key, rendered)
+			}
+			if key == "href" || key == "src" {
+				u, err := url.Parse(attr.Val)
+				if err != nil || !allowedSchemes[strings.ToLower(u.Scheme)] {
+					t.Fatalf("url %s in %q", attr.Val, rendered)
+				}
+			}
+			if key == "rel" {
+				rel = attr.Val
+			}
+		}
+		if token.Data == "a" && !strings.Contains(rel, "nofollow") {
+			t.Fatalf("link without nofollow in %q", rendered)
+		}
+	}
+}
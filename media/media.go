@@ -0,0 +1,205 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/farid21ola/forum/blob"
+	"github.com/farid21ola/forum/model"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrTooLarge          = errors.New("file is too large")
+	ErrUnsupportedType   = errors.New("unsupported file type, only png, jpeg, gif and webp images are allowed")
+	ErrInvalidImage      = errors.New("file is not a valid image")
+	ErrInvalidDimensions = errors.New("image dimensions are out of bounds")
+)
+
+var extensions = map[string]string{
+	"image/png":  "png",
+	"image/jpeg": "jpg",
+	"image/gif":  "gif",
+	"image/webp": "webp",
+}
+
+// formats maps names reported by the image package to content types.
+var formats = map[string]string{
+	"png":  "image/png",
+	"jpeg": "image/jpeg",
+	"gif":  "image/gif",
+	"webp": "image/webp",
+}
+
+type Limits struct {
+	// MaxSize of an uploaded file in bytes.
+	MaxSize int64
+	// Images must be between MinSide and MaxSide pixels on each side.
+	MinSide int
+	MaxSide int
+	// Thumbnails fit into a ThumbSide x ThumbSide square.
+	ThumbSide int
+}
+
+var DefaultLimits = Limits{
+	MaxSize:   10 << 20,
+	MinSide:   16,
+	MaxSide:   8000,
+	ThumbSide: 320,
+}
+
+// Processor validates uploaded images, generates thumbnails and stores both
+// in a blob store.
+type Processor struct {
+	store   blob.Store
+	limits  Limits
+	baseURL string
+}
+
+// New creates a processor serving files under baseURL, e.g. "/media/".
+func New(store blob.Store, limits Limits, baseURL string) *Processor {
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+	return &Processor{store: store, limits: limits, baseURL: baseURL}
+}
+
+func (p *Processor) URL(key string) string {
+	return p.baseURL + key
+}
+
+// SaveImage stores an image and its thumbnail under dir. The returned image
+// has keys and metadata filled in, ids and owners are up to the caller.
+func (p *Processor) SaveImage(ctx context.Context, dir string, r io.Reader) (*model.Image, error) {
+	data, err := io.ReadAll(io.LimitReader(r, p.limits.MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > p.limits.MaxSize {
+		return nil, ErrTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := extensions[contentType]
+	if !ok {
+		return nil, ErrUnsupportedType
+	}
+
+	// check the header before decoding so huge images never get allocated
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || formats[format] != contentType {
+		return nil, ErrInvalidImage
+	}
+	if cfg.Width < p.limits.MinSide || cfg.Height < p.limits.MinSide ||
+		cfg.Width > p.limits.MaxSide || cfg.Height > p.limits.MaxSide {
+		return nil, ErrInvalidDimensions
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	thumb, thumbExt, err := p.thumbnail(src, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := randomName()
+	if err != nil {
+		return nil, err
+	}
+
+	img := &model.Image{
+		Key:          fmt.Sprintf("%s/%s.%s", dir, name, ext),
+		ThumbnailKey: fmt.Sprintf("%s/%s_thumb.%s", dir, name, thumbExt),
+		ContentType:  contentType,
+		Width:        cfg.Width,
+		Height:       cfg.Height,
+		Size:         int64(len(data)),
+	}
+
+	if err = p.store.Put(ctx, img.Key, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	if err = p.store.Put(ctx, img.ThumbnailKey, bytes.NewReader(thumb)); err != nil {
+		p.store.Delete(ctx, img.Key)
+		return nil, err
+	}
+
+	return img, nil
+}
+
+// Delete removes the blobs of an image, it is used to clean up after failures.
+func (p *Processor) Delete(ctx context.Context, img *model.Image) {
+	p.store.Delete(ctx, img.Key)
+	p.store.Delete(ctx, img.ThumbnailKey)
+}
+
+// DeleteKeys removes stored files, like Delete it ignores failures.
+func (p *Processor) DeleteKeys(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		p.store.Delete(ctx, key)
+	}
+}
+
+// Serves tells whether url points to a file of the processor.
+func (p *Processor) Serves(url string) bool {
+	return strings.HasPrefix(url, p.baseURL)
+}
+
+// thumbnail scales src down to fit the thumbnail square. Photos are encoded as
+// jpeg, everything else as png to keep transparency.
+func (p *Processor) thumbnail(src image.Image, contentType string) ([]byte, string, error) {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if side := p.limits.ThumbSide; w > side || h > side {
+		if w >= h {
+			w, h = side, atLeastOne(h*side/w)
+		} else {
+			w, h = atLeastOne(w*side/h), side
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if contentType == "image/jpeg" {
+		if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "jpg", nil
+	}
+
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "png", nil
+}
+
+func randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func atLeastOne(n int) int {
+	if n < 1 {
+		return 1
+	}
+	return n
+}
@@ -0,0 +1,154 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"github.com/farid21ola/forum/blob"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, w, h int) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)), nil))
+	return buf.Bytes()
+}
+
+func TestProcessor_SaveImage(t *testing.T) {
+	ctx := context.Background()
+	limits := Limits{MaxSize: 1 << 20, MinSide: 16, MaxSide: 2000, ThumbSide: 100}
+
+	tests := []struct {
+		name            string
+		data            func(t *testing.T) []byte
+		wantErr         error
+		wantType        string
+		wantWidth       int
+		wantHeight      int
+		wantThumbSuffix string
+		wantThumbBounds image.Rectangle
+	}{
+		{
+			name:            "Wide png",
+			data:            func(t *testing.T) []byte { return encodePNG(t, 400, 200) },
+			wantType:        "image/png",
+			wantWidth:       400,
+			wantHeight:      200,
+			wantThumbSuffix: "_thumb.png",
+			wantThumbBounds: image.Rect(0, 0, 100, 50),
+		},
+		{
+			name:            "Tall jpeg",
+			data:            func(t *testing.T) []byte { return encodeJPEG(t, 50, 300) },
+			wantType:        "image/jpeg",
+			wantWidth:       50,
+			wantHeight:      300,
+			wantThumbSuffix: "_thumb.jpg",
+			wantThumbBounds: image.Rect(0, 0, 16, 100),
+		},
+		{
+			name:            "Small image is not upscaled",
+			data:            func(t *testing.T) []byte { return encodePNG(t, 20, 30) },
+			wantType:        "image/png",
+			wantWidth:       20,
+			wantHeight:      30,
+			wantThumbSuffix: "_thumb.png",
+			wantThumbBounds: image.Rect(0, 0, 20, 30),
+		},
+		{
+			name:    "Not an image",
+			data:    func(t *testing.T) []byte { return []byte("<script>alert(1)</script>") },
+			wantErr: ErrUnsupportedType,
+		},
+		{
+			name:    "Truncated image",
+			data:    func(t *testing.T) []byte { return encodePNG(t, 100, 100)[:20] },
+			wantErr: ErrInvalidImage,
+		},
+		{
+			name:    "Too small",
+			data:    func(t *testing.T) []byte { return encodePNG(t, 8, 100) },
+			wantErr: ErrInvalidDimensions,
+		},
+		{
+			name:    "Too large dimensions",
+			data:    func(t *testing.T) []byte { return encodePNG(t, 2001, 16) },
+			wantErr: ErrInvalidDimensions,
+		},
+		{
+			name:    "Too large file",
+			data:    func(t *testing.T) []byte { return append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 1<<20)...) },
+			wantErr: ErrTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := blob.NewLocal(t.TempDir())
+			require.NoError(t, err)
+			p := New(store, limits, "/media")
+
+			img, err := p.SaveImage(ctx, "posts", bytes.NewReader(tt.data(t)))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantType, img.ContentType)
+			assert.Equal(t, tt.wantWidth, img.Width)
+			assert.Equal(t, tt.wantHeight, img.Height)
+			assert.True(t, strings.HasPrefix(img.Key, "posts/"))
+			assert.True(t, strings.HasSuffix(img.ThumbnailKey, tt.wantThumbSuffix))
+			assert.Equal(t, "/media/"+img.Key, p.URL(img.Key))
+
+			f, _, err := store.Open(ctx, img.ThumbnailKey)
+			require.NoError(t, err)
+			defer f.Close()
+			thumb, _, err := image.Decode(f)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantThumbBounds, thumb.Bounds())
+		})
+	}
+}
+
+func TestProcessor_DeleteKeys(t *testing.T) {
+	ctx := context.Background()
+	store, err := blob.NewLocal(t.TempDir())
+	require.NoError(t, err)
+	p := New(store, DefaultLimits, "https://example.com/media")
+
+	img, err := p.SaveImage(ctx, "avatars", bytes.NewReader(encodePNG(t, 100, 100)))
+	require.NoError(t, err)
+	other, err := p.SaveImage(ctx, "avatars", bytes.NewReader(encodePNG(t, 100, 100)))
+	require.NoError(t, err)
+
+	p.DeleteKeys(ctx, []string{img.Key, img.ThumbnailKey})
+
+	for _, key := range []string{img.Key, img.ThumbnailKey} {
+		_, _, err = store.Open(ctx, key)
+		assert.ErrorIs(t, err, blob.ErrNotFound, key)
+	}
+	for _, key := range []string{other.Key, other.ThumbnailKey} {
+		f, _, err := store.Open(ctx, key)
+		require.NoError(t, err, key)
+		f.Close()
+	}
+
+	assert.True(t, p.Serves(p.URL(other.ThumbnailKey)))
+	assert.False(t, p.Serves("https://example.org/media/"+other.ThumbnailKey))
+}
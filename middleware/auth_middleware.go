@@ -3,6 +3,7 @@ package middleware
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/dgrijalva/jwt-go/request"
 	"github.com/farid21ola/forum/model"
@@ -10,51 +11,93 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 )
 
-const CurrentUserKey = "currentUser"
+const (
+	CurrentUserKey = "currentUser"
+	TokenExpiryKey = "tokenExpiry"
+)
+
+var ErrInvalidToken = errors.New("invalid access token")
 
 func AuthMiddleware(s storage.Storage) func(handler http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			token, err := parseToken(r)
+			token, err := authExtractor.ExtractToken(r)
 			if err != nil {
 				next.ServeHTTP(w, r)
 				return
 			}
 
-			claims, ok := token.Claims.(jwt.MapClaims)
-			if !ok || !token.Valid {
-				next.ServeHTTP(w, r)
-				return
-			}
-
-			user, err := s.UserByID(context.Background(), claims["jti"].(string))
+			user, expiresAt, err := Authenticate(r.Context(), s, token)
 			if err != nil {
 				next.ServeHTTP(w, r)
 				return
 			}
 
 			ctx := context.WithValue(r.Context(), CurrentUserKey, user)
+			ctx = context.WithValue(ctx, TokenExpiryKey, expiresAt)
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// Authenticate validates an access token and loads its user. The expiry of
+// the token is returned so that long-lived connections can end with it.
+func Authenticate(ctx context.Context, s storage.Storage, token string) (*model.User, time.Time, error) {
+	parsed, err := jwt.Parse(StripBearerPrefix(token), keyFunc)
+	if err != nil {
+		return nil, time.Time{}, ErrInvalidToken
+	}
+
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok || !parsed.Valid {
+		return nil, time.Time{}, ErrInvalidToken
+	}
+	id, ok := claims["jti"].(string)
+	if !ok {
+		return nil, time.Time{}, ErrInvalidToken
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, time.Time{}, ErrInvalidToken
+	}
+
+	user, err := s.UserByID(ctx, id)
+	if err != nil || user == nil {
+		return nil, time.Time{}, ErrInvalidToken
+	}
+
+	return user, time.Unix(int64(exp), 0), nil
+}
+
+func keyFunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+	}
+	return []byte(os.Getenv("JWT_SECRET")), nil
+}
+
 var authHeaderExtractor = &request.PostExtractionFilter{
 	Extractor: request.HeaderExtractor{"Authorization"},
 	Filter:    stripBearerPrefixFromToken,
 }
 
 func stripBearerPrefixFromToken(token string) (string, error) {
+	return StripBearerPrefix(token), nil
+}
+
+// StripBearerPrefix removes the "Bearer " prefix of an Authorization value.
+func StripBearerPrefix(token string) string {
 	bearer := "BEARER"
 
 	if len(token) > len(bearer) && strings.ToUpper(token[0:len(bearer)]) == bearer {
-		return token[len(bearer)+1:], nil
+		return token[len(bearer)+1:]
 	}
 
-	return token, nil
+	return token
 }
 
 var authExtractor = &request.MultiExtractor{
@@ -62,14 +105,6 @@ var authExtractor = &request.MultiExtractor{
 	request.ArgumentExtractor{"access_token"},
 }
 
-func parseToken(r *http.Request) (*jwt.Token, error) {
-	jwtToken, err := request.ParseFromRequest(r, authExtractor, func(token *jwt.Token) (interface{}, error) {
-		t := []byte(os.Getenv("JWT_SECRET"))
-		return t, nil
-	})
-	return jwtToken, err
-}
-
 func GetCurrentUserFromCtx(ctx context.Context) (*model.User, error) {
 	errNoUserInContext := errors.New("no user in contex")
 	if ctx.Value(CurrentUserKey) == nil {
@@ -83,3 +118,9 @@ func GetCurrentUserFromCtx(ctx context.Context) (*model.User, error) {
 
 	return user, nil
 }
+
+// GetTokenExpiryFromCtx returns when the token of the current user expires.
+func GetTokenExpiryFromCtx(ctx context.Context) (time.Time, bool) {
+	expiresAt, ok := ctx.Value(TokenExpiryKey).(time.Time)
+	return expiresAt, ok
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/farid21ola/forum/mocks"
+	"github.com/farid21ola/forum/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func signToken(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.StandardClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	require.NoError(t, err)
+	return token
+}
+
+func TestAuthenticate(t *testing.T) {
+	t.Setenv("JWT_SECRET", "secret")
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	valid := jwt.StandardClaims{Id: "1", ExpiresAt: expiresAt.Unix()}
+
+	tests := []struct {
+		name      string
+		token     string
+		mockSetup func(m *mocks.Storage)
+		wantErr   bool
+	}{
+		{
+			name:  "Valid token",
+			token: signToken(t, jwt.SigningMethodHS256, []byte("secret"), valid),
+			mockSetup: func(m *mocks.Storage) {
+				m.On("UserByID", mock.Anything, "1").Return(&model.User{ID: "1"}, nil)
+			},
+		},
+		{
+			name:  "Bearer prefix",
+			token: "Bearer " + signToken(t, jwt.SigningMethodHS256, []byte("secret"), valid),
+			mockSetup: func(m *mocks.Storage) {
+				m.On("UserByID", mock.Anything, "1").Return(&model.User{ID: "1"}, nil)
+			},
+		},
+		{
+			name:      "Wrong secret",
+			token:     signToken(t, jwt.SigningMethodHS256, []byte("other"), valid),
+			mockSetup: func(m *mocks.Storage) {},
+			wantErr:   true,
+		},
+		{
+			name:      "Unsigned token",
+			token:     signToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid),
+			mockSetup: func(m *mocks.Storage) {},
+			wantErr:   true,
+		},
+		{
+			name:      "Expired token",
+			token:     signToken(t, jwt.SigningMethodHS256, []byte("secret"), jwt.StandardClaims{Id: "1", ExpiresAt: time.Now().Add(-time.Minute).Unix()}),
+			mockSetup: func(m *mocks.Storage) {},
+			wantErr:   true,
+		},
+		{
+			name:      "Token without expiry",
+			token:     signToken(t, jwt.SigningMethodHS256, []byte("secret"), jwt.StandardClaims{Id: "1"}),
+			mockSetup: func(m *mocks.Storage) {},
+			wantErr:   true,
+		},
+		{
+			name:  "Deleted user",
+			token: signToken(t, jwt.SigningMethodHS256, []byte("secret"), valid),
+			mockSetup: func(m *mocks.Storage) {
+				m.On("UserByID", mock.Anything, "1").Return(nil, errors.New("user with id not exists"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(mocks.Storage)
+			tt.mockSetup(mockStorage)
+
+			user, exp, err := Authenticate(context.Background(), mockStorage, tt.token)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidToken)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "1", user.ID)
+				assert.True(t, expiresAt.Equal(exp))
+			}
+
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	t.Setenv("JWT_SECRET", "secret")
+	token := signToken(t, jwt.SigningMethodHS256, []byte("secret"), jwt.StandardClaims{Id: "1", ExpiresAt: time.Now().Add(time.Hour).Unix()})
+
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("UserByID", mock.Anything, "1").Return(&model.User{ID: "1"}, nil)
+
+	var user *model.User
+	handler := AuthMiddleware(mockStorage)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _ = GetCurrentUserFromCtx(r.Context())
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/query", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	require.NotNil(t, user)
+	assert.Equal(t, "1", user.ID)
+
+	user = nil
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/query", nil))
+	assert.Nil(t, user)
+}
+
+func TestCheckOrigin(t *testing.T) {
+	check := CheckOrigin(ParseOrigins(" http://localhost:8000/, https://forum.example "))
+
+	for origin, want := range map[string]bool{
+		"":                       true,
+		"http://localhost:8000":  true,
+		"https://FORUM.example":  true,
+		"https://evil.example":   false,
+		"http://localhost:8001":  false,
+		"https://forum.example.": false,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/query", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		assert.Equal(t, want, check(r), origin)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/query", nil)
+	r.Header.Set("Origin", "https://any.example")
+	assert.True(t, CheckOrigin([]string{"*"})(r))
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+const ClientIPKey = "clientIP"
+
+// ClientIPMiddleware stores the remote address of the request in the context.
+// Put chi's RealIP in front of it when the server runs behind a trusted proxy.
+func ClientIPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+
+		ctx := context.WithValue(r.Context(), ClientIPKey, ip)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func GetClientIPFromCtx(ctx context.Context) string {
+	ip, _ := ctx.Value(ClientIPKey).(string)
+	return ip
+}
@@ -10,6 +10,8 @@ import (
 	mock "github.com/stretchr/testify/mock"
 
 	pgx "github.com/jackc/pgx/v5"
+
+	time "time"
 )
 
 // Storage is an autogenerated mock type for the Storage type
@@ -17,6 +19,34 @@ type Storage struct {
 	mock.Mock
 }
 
+// AddBlock provides a mock function with given fields: ctx, b
+func (_m *Storage) AddBlock(ctx context.Context, b *model.Block) error {
+	ret := _m.Called(ctx, b)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Block) error); ok {
+		r0 = rf(ctx, b)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AddBookmark provides a mock function with given fields: ctx, b
+func (_m *Storage) AddBookmark(ctx context.Context, b *model.Bookmark) error {
+	ret := _m.Called(ctx, b)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Bookmark) error); ok {
+		r0 = rf(ctx, b)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // AddComment provides a mock function with given fields: ctx, comment
 func (_m *Storage) AddComment(ctx context.Context, comment *model.Comment) (*model.Comment, error) {
 	ret := _m.Called(ctx, comment)
@@ -34,8 +64,1024 @@ func (_m *Storage) AddComment(ctx context.Context, comment *model.Comment) (*mod
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, *model.Comment) error); ok {
-		r1 = rf(ctx, comment)
+	if rf, ok := ret.Get(1).(func(context.Context, *model.Comment) error); ok {
+		r1 = rf(ctx, comment)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AddFollowerNotifications provides a mock function with given fields: ctx, n, skip
+func (_m *Storage) AddFollowerNotifications(ctx context.Context, n *model.Notification, skip []string) ([]*model.Notification, error) {
+	ret := _m.Called(ctx, n, skip)
+
+	var r0 []*model.Notification
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Notification, []string) ([]*model.Notification, error)); ok {
+		return rf(ctx, n, skip)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Notification, []string) []*model.Notification); ok {
+		r0 = rf(ctx, n, skip)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Notification)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *model.Notification, []string) error); ok {
+		r1 = rf(ctx, n, skip)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AddLoginEvent provides a mock function with given fields: ctx, event
+func (_m *Storage) AddLoginEvent(ctx context.Context, event *model.LoginEvent) error {
+	ret := _m.Called(ctx, event)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.LoginEvent) error); ok {
+		r0 = rf(ctx, event)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AddMentions provides a mock function with given fields: ctx, mentions
+func (_m *Storage) AddMentions(ctx context.Context, mentions []*model.Mention) error {
+	ret := _m.Called(ctx, mentions)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []*model.Mention) error); ok {
+		r0 = rf(ctx, mentions)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AddMessage provides a mock function with given fields: ctx, m
+func (_m *Storage) AddMessage(ctx context.Context, m *model.Message) (*model.Message, error) {
+	ret := _m.Called(ctx, m)
+
+	var r0 *model.Message
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Message) (*model.Message, error)); ok {
+		return rf(ctx, m)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Message) *model.Message); ok {
+		r0 = rf(ctx, m)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Message)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *model.Message) error); ok {
+		r1 = rf(ctx, m)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AddNotification provides a mock function with given fields: ctx, n
+func (_m *Storage) AddNotification(ctx context.Context, n *model.Notification) (*model.Notification, error) {
+	ret := _m.Called(ctx, n)
+
+	var r0 *model.Notification
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Notification) (*model.Notification, error)); ok {
+		return rf(ctx, n)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Notification) *model.Notification); ok {
+		r0 = rf(ctx, n)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Notification)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *model.Notification) error); ok {
+		r1 = rf(ctx, n)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AddPollVote provides a mock function with given fields: ctx, vote
+func (_m *Storage) AddPollVote(ctx context.Context, vote *model.PollVote) (bool, error) {
+	ret := _m.Called(ctx, vote)
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.PollVote) (bool, error)); ok {
+		return rf(ctx, vote)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *model.PollVote) bool); ok {
+		r0 = rf(ctx, vote)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *model.PollVote) error); ok {
+		r1 = rf(ctx, vote)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AddReaction provides a mock function with given fields: ctx, r
+func (_m *Storage) AddReaction(ctx context.Context, r *model.Reaction) error {
+	ret := _m.Called(ctx, r)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Reaction) error); ok {
+		r0 = rf(ctx, r)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Begin provides a mock function with given fields: ctx
+func (_m *Storage) Begin(ctx context.Context) (pgx.Tx, error) {
+	ret := _m.Called(ctx)
+
+	var r0 pgx.Tx
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (pgx.Tx, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) pgx.Tx); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(pgx.Tx)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// BlockingUserIDs provides a mock function with given fields: ctx, targetID, userIDs
+func (_m *Storage) BlockingUserIDs(ctx context.Context, targetID string, userIDs []string) ([]string, error) {
+	ret := _m.Called(ctx, targetID, userIDs)
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string) ([]string, error)); ok {
+		return rf(ctx, targetID, userIDs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string) []string); ok {
+		r0 = rf(ctx, targetID, userIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, []string) error); ok {
+		r1 = rf(ctx, targetID, userIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Blocks provides a mock function with given fields: ctx, userID
+func (_m *Storage) Blocks(ctx context.Context, userID string) ([]*model.Block, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 []*model.Block
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*model.Block, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*model.Block); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Block)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Bookmarks provides a mock function with given fields: ctx, filter
+func (_m *Storage) Bookmarks(ctx context.Context, filter model.BookmarkFilter) ([]*model.Bookmark, error) {
+	ret := _m.Called(ctx, filter)
+
+	var r0 []*model.Bookmark
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, model.BookmarkFilter) ([]*model.Bookmark, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, model.BookmarkFilter) []*model.Bookmark); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Bookmark)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, model.BookmarkFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Comment provides a mock function with given fields: ctx, id
+func (_m *Storage) Comment(ctx context.Context, id string) (*model.Comment, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 *model.Comment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.Comment, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *model.Comment); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Comment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Comments provides a mock function with given fields: ctx, id, limit, offset
+func (_m *Storage) Comments(ctx context.Context, id string, limit *int, offset *int) ([]*model.Comment, error) {
+	ret := _m.Called(ctx, id, limit, offset)
+
+	var r0 []*model.Comment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *int, *int) ([]*model.Comment, error)); ok {
+		return rf(ctx, id, limit, offset)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, *int, *int) []*model.Comment); ok {
+		r0 = rf(ctx, id, limit, offset)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Comment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, *int, *int) error); ok {
+		r1 = rf(ctx, id, limit, offset)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CommentsByIDs provides a mock function with given fields: ctx, ids
+func (_m *Storage) CommentsByIDs(ctx context.Context, ids []string) ([]*model.Comment, error) {
+	ret := _m.Called(ctx, ids)
+
+	var r0 []*model.Comment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) ([]*model.Comment, error)); ok {
+		return rf(ctx, ids)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []*model.Comment); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Comment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CommentsMentions provides a mock function with given fields: ctx, commentIDs
+func (_m *Storage) CommentsMentions(ctx context.Context, commentIDs []string) ([]*model.Mention, error) {
+	ret := _m.Called(ctx, commentIDs)
+
+	var r0 []*model.Mention
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) ([]*model.Mention, error)); ok {
+		return rf(ctx, commentIDs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []*model.Mention); ok {
+		r0 = rf(ctx, commentIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Mention)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, commentIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Conversation provides a mock function with given fields: ctx, id
+func (_m *Storage) Conversation(ctx context.Context, id string) (*model.Conversation, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 *model.Conversation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.Conversation, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *model.Conversation); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Conversation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ConversationsMembers provides a mock function with given fields: ctx, conversationIDs
+func (_m *Storage) ConversationsMembers(ctx context.Context, conversationIDs []string) ([]*model.ConversationMember, error) {
+	ret := _m.Called(ctx, conversationIDs)
+
+	var r0 []*model.ConversationMember
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) ([]*model.ConversationMember, error)); ok {
+		return rf(ctx, conversationIDs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []*model.ConversationMember); ok {
+		r0 = rf(ctx, conversationIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.ConversationMember)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, conversationIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateConversation provides a mock function with given fields: ctx, c
+func (_m *Storage) CreateConversation(ctx context.Context, c *model.Conversation) (*model.Conversation, error) {
+	ret := _m.Called(ctx, c)
+
+	var r0 *model.Conversation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Conversation) (*model.Conversation, error)); ok {
+		return rf(ctx, c)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Conversation) *model.Conversation); ok {
+		r0 = rf(ctx, c)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Conversation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *model.Conversation) error); ok {
+		r1 = rf(ctx, c)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreatePost provides a mock function with given fields: ctx, post, images, poll
+func (_m *Storage) CreatePost(ctx context.Context, post *model.Post, images []*model.Image, poll *model.Poll) (*model.Post, error) {
+	ret := _m.Called(ctx, post, images, poll)
+
+	var r0 *model.Post
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Post, []*model.Image, *model.Poll) (*model.Post, error)); ok {
+		return rf(ctx, post, images, poll)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Post, []*model.Image, *model.Poll) *model.Post); ok {
+		r0 = rf(ctx, post, images, poll)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Post)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *model.Post, []*model.Image, *model.Poll) error); ok {
+		r1 = rf(ctx, post, images, poll)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateUser provides a mock function with given fields: ctx, tx, user
+func (_m *Storage) CreateUser(ctx context.Context, tx pgx.Tx, user *model.User) (*model.User, error) {
+	ret := _m.Called(ctx, tx, user)
+
+	var r0 *model.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx, *model.User) (*model.User, error)); ok {
+		return rf(ctx, tx, user)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx, *model.User) *model.User); ok {
+		r0 = rf(ctx, tx, user)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, pgx.Tx, *model.User) error); ok {
+		r1 = rf(ctx, tx, user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Feed provides a mock function with given fields: ctx, filter
+func (_m *Storage) Feed(ctx context.Context, filter model.FeedFilter) ([]*model.Post, error) {
+	ret := _m.Called(ctx, filter)
+
+	var r0 []*model.Post
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, model.FeedFilter) ([]*model.Post, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, model.FeedFilter) []*model.Post); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Post)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, model.FeedFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Follow provides a mock function with given fields: ctx, f
+func (_m *Storage) Follow(ctx context.Context, f *model.Follow) error {
+	ret := _m.Called(ctx, f)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Follow) error); ok {
+		r0 = rf(ctx, f)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FollowCount provides a mock function with given fields: ctx, filter
+func (_m *Storage) FollowCount(ctx context.Context, filter model.FollowFilter) (int, error) {
+	ret := _m.Called(ctx, filter)
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, model.FollowFilter) (int, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, model.FollowFilter) int); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, model.FollowFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Follows provides a mock function with given fields: ctx, filter
+func (_m *Storage) Follows(ctx context.Context, filter model.FollowFilter) ([]*model.Follow, error) {
+	ret := _m.Called(ctx, filter)
+
+	var r0 []*model.Follow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, model.FollowFilter) ([]*model.Follow, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, model.FollowFilter) []*model.Follow); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Follow)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, model.FollowFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LastMessages provides a mock function with given fields: ctx, conversationIDs
+func (_m *Storage) LastMessages(ctx context.Context, conversationIDs []string) ([]*model.Message, error) {
+	ret := _m.Called(ctx, conversationIDs)
+
+	var r0 []*model.Message
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) ([]*model.Message, error)); ok {
+		return rf(ctx, conversationIDs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []*model.Message); ok {
+		r0 = rf(ctx, conversationIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Message)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, conversationIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LoginFailures provides a mock function with given fields: ctx, username, ip, since
+func (_m *Storage) LoginFailures(ctx context.Context, username string, ip string, since time.Time) (*model.LoginFailures, error) {
+	ret := _m.Called(ctx, username, ip, since)
+
+	var r0 *model.LoginFailures
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) (*model.LoginFailures, error)); ok {
+		return rf(ctx, username, ip, since)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) *model.LoginFailures); ok {
+		r0 = rf(ctx, username, ip, since)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.LoginFailures)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, time.Time) error); ok {
+		r1 = rf(ctx, username, ip, since)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MarkConversationRead provides a mock function with given fields: ctx, conversationID, userID, messageID
+func (_m *Storage) MarkConversationRead(ctx context.Context, conversationID string, userID string, messageID string) error {
+	ret := _m.Called(ctx, conversationID, userID, messageID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) error); ok {
+		r0 = rf(ctx, conversationID, userID, messageID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MarkNotificationsRead provides a mock function with given fields: ctx, userID, ids
+func (_m *Storage) MarkNotificationsRead(ctx context.Context, userID string, ids []string) error {
+	ret := _m.Called(ctx, userID, ids)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string) error); ok {
+		r0 = rf(ctx, userID, ids)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Messages provides a mock function with given fields: ctx, filter
+func (_m *Storage) Messages(ctx context.Context, filter model.MessageFilter) ([]*model.Message, error) {
+	ret := _m.Called(ctx, filter)
+
+	var r0 []*model.Message
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, model.MessageFilter) ([]*model.Message, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, model.MessageFilter) []*model.Message); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Message)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, model.MessageFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Notifications provides a mock function with given fields: ctx, filter
+func (_m *Storage) Notifications(ctx context.Context, filter model.NotificationFilter) ([]*model.Notification, error) {
+	ret := _m.Called(ctx, filter)
+
+	var r0 []*model.Notification
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, model.NotificationFilter) ([]*model.Notification, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, model.NotificationFilter) []*model.Notification); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Notification)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, model.NotificationFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Poll provides a mock function with given fields: ctx, id
+func (_m *Storage) Poll(ctx context.Context, id string) (*model.Poll, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 *model.Poll
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.Poll, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *model.Poll); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Poll)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PollVote provides a mock function with given fields: ctx, pollID, userID
+func (_m *Storage) PollVote(ctx context.Context, pollID string, userID string) ([]string, error) {
+	ret := _m.Called(ctx, pollID, userID)
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ([]string, error)); ok {
+		return rf(ctx, pollID, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []string); ok {
+		r0 = rf(ctx, pollID, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, pollID, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PollsVotes provides a mock function with given fields: ctx, userID, pollIDs
+func (_m *Storage) PollsVotes(ctx context.Context, userID string, pollIDs []string) ([]*model.PollVote, error) {
+	ret := _m.Called(ctx, userID, pollIDs)
+
+	var r0 []*model.PollVote
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string) ([]*model.PollVote, error)); ok {
+		return rf(ctx, userID, pollIDs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string) []*model.PollVote); ok {
+		r0 = rf(ctx, userID, pollIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.PollVote)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, []string) error); ok {
+		r1 = rf(ctx, userID, pollIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Post provides a mock function with given fields: ctx, id
+func (_m *Storage) Post(ctx context.Context, id string) (*model.Post, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 *model.Post
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.Post, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *model.Post); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Post)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PostImages provides a mock function with given fields: ctx, postID
+func (_m *Storage) PostImages(ctx context.Context, postID string) ([]*model.Image, error) {
+	ret := _m.Called(ctx, postID)
+
+	var r0 []*model.Image
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*model.Image, error)); ok {
+		return rf(ctx, postID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*model.Image); ok {
+		r0 = rf(ctx, postID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Image)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, postID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Posts provides a mock function with given fields: ctx, limit, offset
+func (_m *Storage) Posts(ctx context.Context, limit *int, offset *int) ([]*model.Post, error) {
+	ret := _m.Called(ctx, limit, offset)
+
+	var r0 []*model.Post
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *int, *int) ([]*model.Post, error)); ok {
+		return rf(ctx, limit, offset)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *int, *int) []*model.Post); ok {
+		r0 = rf(ctx, limit, offset)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Post)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *int, *int) error); ok {
+		r1 = rf(ctx, limit, offset)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PostsByIDs provides a mock function with given fields: ctx, ids
+func (_m *Storage) PostsByIDs(ctx context.Context, ids []string) ([]*model.Post, error) {
+	ret := _m.Called(ctx, ids)
+
+	var r0 []*model.Post
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) ([]*model.Post, error)); ok {
+		return rf(ctx, ids)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []*model.Post); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Post)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PostsImages provides a mock function with given fields: ctx, postIDs
+func (_m *Storage) PostsImages(ctx context.Context, postIDs []string) ([]*model.Image, error) {
+	ret := _m.Called(ctx, postIDs)
+
+	var r0 []*model.Image
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) ([]*model.Image, error)); ok {
+		return rf(ctx, postIDs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []*model.Image); ok {
+		r0 = rf(ctx, postIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Image)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, postIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PostsMentions provides a mock function with given fields: ctx, postIDs
+func (_m *Storage) PostsMentions(ctx context.Context, postIDs []string) ([]*model.Mention, error) {
+	ret := _m.Called(ctx, postIDs)
+
+	var r0 []*model.Mention
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) ([]*model.Mention, error)); ok {
+		return rf(ctx, postIDs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []*model.Mention); ok {
+		r0 = rf(ctx, postIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Mention)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, postIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PostsPolls provides a mock function with given fields: ctx, postIDs
+func (_m *Storage) PostsPolls(ctx context.Context, postIDs []string) ([]*model.Poll, error) {
+	ret := _m.Called(ctx, postIDs)
+
+	var r0 []*model.Poll
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) ([]*model.Poll, error)); ok {
+		return rf(ctx, postIDs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []*model.Poll); ok {
+		r0 = rf(ctx, postIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Poll)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, postIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PublishDuePosts provides a mock function with given fields: ctx, now, limit, publishable
+func (_m *Storage) PublishDuePosts(ctx context.Context, now time.Time, limit int, publishable func(*model.Post) bool) ([]*model.Post, error) {
+	ret := _m.Called(ctx, now, limit, publishable)
+
+	var r0 []*model.Post
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int, func(*model.Post) bool) ([]*model.Post, error)); ok {
+		return rf(ctx, now, limit, publishable)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int, func(*model.Post) bool) []*model.Post); ok {
+		r0 = rf(ctx, now, limit, publishable)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Post)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, int, func(*model.Post) bool) error); ok {
+		r1 = rf(ctx, now, limit, publishable)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PublishPost provides a mock function with given fields: ctx, id
+func (_m *Storage) PublishPost(ctx context.Context, id string) (*model.Post, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 *model.Post
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.Post, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *model.Post); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Post)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -43,25 +1089,25 @@ func (_m *Storage) AddComment(ctx context.Context, comment *model.Comment) (*mod
 	return r0, r1
 }
 
-// Begin provides a mock function with given fields: ctx
-func (_m *Storage) Begin(ctx context.Context) (pgx.Tx, error) {
-	ret := _m.Called(ctx)
+// ReactionCounts provides a mock function with given fields: ctx, target, targetIDs, userID
+func (_m *Storage) ReactionCounts(ctx context.Context, target model.ReactionTarget, targetIDs []string, userID string) ([]*model.ReactionCount, error) {
+	ret := _m.Called(ctx, target, targetIDs, userID)
 
-	var r0 pgx.Tx
+	var r0 []*model.ReactionCount
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context) (pgx.Tx, error)); ok {
-		return rf(ctx)
+	if rf, ok := ret.Get(0).(func(context.Context, model.ReactionTarget, []string, string) ([]*model.ReactionCount, error)); ok {
+		return rf(ctx, target, targetIDs, userID)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context) pgx.Tx); ok {
-		r0 = rf(ctx)
+	if rf, ok := ret.Get(0).(func(context.Context, model.ReactionTarget, []string, string) []*model.ReactionCount); ok {
+		r0 = rf(ctx, target, targetIDs, userID)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(pgx.Tx)
+			r0 = ret.Get(0).([]*model.ReactionCount)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
-		r1 = rf(ctx)
+	if rf, ok := ret.Get(1).(func(context.Context, model.ReactionTarget, []string, string) error); ok {
+		r1 = rf(ctx, target, targetIDs, userID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -69,25 +1115,81 @@ func (_m *Storage) Begin(ctx context.Context) (pgx.Tx, error) {
 	return r0, r1
 }
 
-// Comments provides a mock function with given fields: ctx, id, limit, offset
-func (_m *Storage) Comments(ctx context.Context, id string, limit *int, offset *int) ([]*model.Comment, error) {
-	ret := _m.Called(ctx, id, limit, offset)
+// RemoveBlock provides a mock function with given fields: ctx, b
+func (_m *Storage) RemoveBlock(ctx context.Context, b *model.Block) error {
+	ret := _m.Called(ctx, b)
 
-	var r0 []*model.Comment
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Block) error); ok {
+		r0 = rf(ctx, b)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RemoveBookmark provides a mock function with given fields: ctx, b
+func (_m *Storage) RemoveBookmark(ctx context.Context, b *model.Bookmark) error {
+	ret := _m.Called(ctx, b)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Bookmark) error); ok {
+		r0 = rf(ctx, b)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RemoveCommentMentions provides a mock function with given fields: ctx, commentID
+func (_m *Storage) RemoveCommentMentions(ctx context.Context, commentID string) error {
+	ret := _m.Called(ctx, commentID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, commentID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RemoveReaction provides a mock function with given fields: ctx, r
+func (_m *Storage) RemoveReaction(ctx context.Context, r *model.Reaction) error {
+	ret := _m.Called(ctx, r)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Reaction) error); ok {
+		r0 = rf(ctx, r)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SavedPostIDs provides a mock function with given fields: ctx, userID, postIDs
+func (_m *Storage) SavedPostIDs(ctx context.Context, userID string, postIDs []string) ([]string, error) {
+	ret := _m.Called(ctx, userID, postIDs)
+
+	var r0 []string
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, *int, *int) ([]*model.Comment, error)); ok {
-		return rf(ctx, id, limit, offset)
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string) ([]string, error)); ok {
+		return rf(ctx, userID, postIDs)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, *int, *int) []*model.Comment); ok {
-		r0 = rf(ctx, id, limit, offset)
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string) []string); ok {
+		r0 = rf(ctx, userID, postIDs)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]*model.Comment)
+			r0 = ret.Get(0).([]string)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, *int, *int) error); ok {
-		r1 = rf(ctx, id, limit, offset)
+	if rf, ok := ret.Get(1).(func(context.Context, string, []string) error); ok {
+		r1 = rf(ctx, userID, postIDs)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -95,25 +1197,39 @@ func (_m *Storage) Comments(ctx context.Context, id string, limit *int, offset *
 	return r0, r1
 }
 
-// CreatePost provides a mock function with given fields: ctx, post
-func (_m *Storage) CreatePost(ctx context.Context, post *model.Post) (*model.Post, error) {
-	ret := _m.Called(ctx, post)
+// Unfollow provides a mock function with given fields: ctx, f
+func (_m *Storage) Unfollow(ctx context.Context, f *model.Follow) error {
+	ret := _m.Called(ctx, f)
 
-	var r0 *model.Post
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Follow) error); ok {
+		r0 = rf(ctx, f)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UnreadMessageCounts provides a mock function with given fields: ctx, userID, conversationIDs
+func (_m *Storage) UnreadMessageCounts(ctx context.Context, userID string, conversationIDs []string) ([]int, error) {
+	ret := _m.Called(ctx, userID, conversationIDs)
+
+	var r0 []int
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, *model.Post) (*model.Post, error)); ok {
-		return rf(ctx, post)
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string) ([]int, error)); ok {
+		return rf(ctx, userID, conversationIDs)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, *model.Post) *model.Post); ok {
-		r0 = rf(ctx, post)
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string) []int); ok {
+		r0 = rf(ctx, userID, conversationIDs)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*model.Post)
+			r0 = ret.Get(0).([]int)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, *model.Post) error); ok {
-		r1 = rf(ctx, post)
+	if rf, ok := ret.Get(1).(func(context.Context, string, []string) error); ok {
+		r1 = rf(ctx, userID, conversationIDs)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -121,25 +1237,23 @@ func (_m *Storage) CreatePost(ctx context.Context, post *model.Post) (*model.Pos
 	return r0, r1
 }
 
-// CreateUser provides a mock function with given fields: ctx, tx, user
-func (_m *Storage) CreateUser(ctx context.Context, tx pgx.Tx, user *model.User) (*model.User, error) {
-	ret := _m.Called(ctx, tx, user)
+// UnreadNotificationCount provides a mock function with given fields: ctx, userID
+func (_m *Storage) UnreadNotificationCount(ctx context.Context, userID string) (int, error) {
+	ret := _m.Called(ctx, userID)
 
-	var r0 *model.User
+	var r0 int
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx, *model.User) (*model.User, error)); ok {
-		return rf(ctx, tx, user)
+	if rf, ok := ret.Get(0).(func(context.Context, string) (int, error)); ok {
+		return rf(ctx, userID)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx, *model.User) *model.User); ok {
-		r0 = rf(ctx, tx, user)
+	if rf, ok := ret.Get(0).(func(context.Context, string) int); ok {
+		r0 = rf(ctx, userID)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*model.User)
-		}
+		r0 = ret.Get(0).(int)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, pgx.Tx, *model.User) error); ok {
-		r1 = rf(ctx, tx, user)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -147,25 +1261,25 @@ func (_m *Storage) CreateUser(ctx context.Context, tx pgx.Tx, user *model.User)
 	return r0, r1
 }
 
-// Post provides a mock function with given fields: ctx, id
-func (_m *Storage) Post(ctx context.Context, id string) (*model.Post, error) {
-	ret := _m.Called(ctx, id)
+// UpdateComment provides a mock function with given fields: ctx, comment
+func (_m *Storage) UpdateComment(ctx context.Context, comment *model.Comment) (*model.Comment, error) {
+	ret := _m.Called(ctx, comment)
 
-	var r0 *model.Post
+	var r0 *model.Comment
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.Post, error)); ok {
-		return rf(ctx, id)
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Comment) (*model.Comment, error)); ok {
+		return rf(ctx, comment)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) *model.Post); ok {
-		r0 = rf(ctx, id)
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Comment) *model.Comment); ok {
+		r0 = rf(ctx, comment)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*model.Post)
+			r0 = ret.Get(0).(*model.Comment)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, id)
+	if rf, ok := ret.Get(1).(func(context.Context, *model.Comment) error); ok {
+		r1 = rf(ctx, comment)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -173,25 +1287,25 @@ func (_m *Storage) Post(ctx context.Context, id string) (*model.Post, error) {
 	return r0, r1
 }
 
-// Posts provides a mock function with given fields: ctx, limit, offset
-func (_m *Storage) Posts(ctx context.Context, limit *int, offset *int) ([]*model.Post, error) {
-	ret := _m.Called(ctx, limit, offset)
+// UpdateDraft provides a mock function with given fields: ctx, post
+func (_m *Storage) UpdateDraft(ctx context.Context, post *model.Post) (*model.Post, error) {
+	ret := _m.Called(ctx, post)
 
-	var r0 []*model.Post
+	var r0 *model.Post
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, *int, *int) ([]*model.Post, error)); ok {
-		return rf(ctx, limit, offset)
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Post) (*model.Post, error)); ok {
+		return rf(ctx, post)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, *int, *int) []*model.Post); ok {
-		r0 = rf(ctx, limit, offset)
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Post) *model.Post); ok {
+		r0 = rf(ctx, post)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]*model.Post)
+			r0 = ret.Get(0).(*model.Post)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, *int, *int) error); ok {
-		r1 = rf(ctx, limit, offset)
+	if rf, ok := ret.Get(1).(func(context.Context, *model.Post) error); ok {
+		r1 = rf(ctx, post)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -199,6 +1313,20 @@ func (_m *Storage) Posts(ctx context.Context, limit *int, offset *int) ([]*model
 	return r0, r1
 }
 
+// UpdatePassword provides a mock function with given fields: ctx, userID, hash
+func (_m *Storage) UpdatePassword(ctx context.Context, userID string, hash string) error {
+	ret := _m.Called(ctx, userID, hash)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, userID, hash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UpdatePost provides a mock function with given fields: ctx, upd
 func (_m *Storage) UpdatePost(ctx context.Context, upd *model.UpdatePost) (*model.Post, error) {
 	ret := _m.Called(ctx, upd)
@@ -225,6 +1353,32 @@ func (_m *Storage) UpdatePost(ctx context.Context, upd *model.UpdatePost) (*mode
 	return r0, r1
 }
 
+// UpdateUser provides a mock function with given fields: ctx, user
+func (_m *Storage) UpdateUser(ctx context.Context, user *model.User) (*model.User, error) {
+	ret := _m.Called(ctx, user)
+
+	var r0 *model.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.User) (*model.User, error)); ok {
+		return rf(ctx, user)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *model.User) *model.User); ok {
+		r0 = rf(ctx, user)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *model.User) error); ok {
+		r1 = rf(ctx, user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UserByID provides a mock function with given fields: ctx, id
 func (_m *Storage) UserByID(ctx context.Context, id string) (*model.User, error) {
 	ret := _m.Called(ctx, id)
@@ -277,6 +1431,84 @@ func (_m *Storage) UserByUsername(ctx context.Context, username string) (*model.
 	return r0, r1
 }
 
+// UserConversations provides a mock function with given fields: ctx, userID
+func (_m *Storage) UserConversations(ctx context.Context, userID string) ([]*model.Conversation, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 []*model.Conversation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*model.Conversation, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*model.Conversation); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Conversation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UserDrafts provides a mock function with given fields: ctx, userID
+func (_m *Storage) UserDrafts(ctx context.Context, userID string) ([]*model.Post, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 []*model.Post
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*model.Post, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*model.Post); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Post)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UserStats provides a mock function with given fields: ctx, userID
+func (_m *Storage) UserStats(ctx context.Context, userID string) (*model.UserStats, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 *model.UserStats
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.UserStats, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *model.UserStats); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.UserStats)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Users provides a mock function with given fields: ctx
 func (_m *Storage) Users(ctx context.Context) ([]*model.User, error) {
 	ret := _m.Called(ctx)
@@ -303,6 +1535,58 @@ func (_m *Storage) Users(ctx context.Context) ([]*model.User, error) {
 	return r0, r1
 }
 
+// UsersByIDs provides a mock function with given fields: ctx, ids
+func (_m *Storage) UsersByIDs(ctx context.Context, ids []string) ([]*model.User, error) {
+	ret := _m.Called(ctx, ids)
+
+	var r0 []*model.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) ([]*model.User, error)); ok {
+		return rf(ctx, ids)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []*model.User); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UsersByUsernames provides a mock function with given fields: ctx, usernames
+func (_m *Storage) UsersByUsernames(ctx context.Context, usernames []string) ([]*model.User, error) {
+	ret := _m.Called(ctx, usernames)
+
+	var r0 []*model.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) ([]*model.User, error)); ok {
+		return rf(ctx, usernames)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []*model.User); ok {
+		r0 = rf(ctx, usernames)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, usernames)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UsersPost provides a mock function with given fields: ctx, id
 func (_m *Storage) UsersPost(ctx context.Context, id string) ([]*model.Post, error) {
 	ret := _m.Called(ctx, id)
@@ -329,6 +1613,56 @@ func (_m *Storage) UsersPost(ctx context.Context, id string) ([]*model.Post, err
 	return r0, r1
 }
 
+// UsersStats provides a mock function with given fields: ctx, userIDs
+func (_m *Storage) UsersStats(ctx context.Context, userIDs []string) ([]*model.UserStats, error) {
+	ret := _m.Called(ctx, userIDs)
+
+	var r0 []*model.UserStats
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) ([]*model.UserStats, error)); ok {
+		return rf(ctx, userIDs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []*model.UserStats); ok {
+		r0 = rf(ctx, userIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.UserStats)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, userIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// VotePost provides a mock function with given fields: ctx, vote
+func (_m *Storage) VotePost(ctx context.Context, vote *model.Vote) (int, error) {
+	ret := _m.Called(ctx, vote)
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Vote) (int, error)); ok {
+		return rf(ctx, vote)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Vote) int); ok {
+		r0 = rf(ctx, vote)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *model.Vote) error); ok {
+		r1 = rf(ctx, vote)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 type mockConstructorTestingTNewStorage interface {
 	mock.TestingT
 	Cleanup(func())
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+type BlockKind string
+
+const (
+	// BlockKindBlock hides the content of the target and keeps the target
+	// from replying to or mentioning the user.
+	BlockKindBlock BlockKind = "BLOCK"
+	// BlockKindMute only hides the content of the target.
+	BlockKindMute BlockKind = "MUTE"
+)
+
+// Block is a block or a mute of TargetID set by UserID, a user may both
+// block and mute the same target.
+type Block struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"userId"`
+	TargetID  string    `json:"targetId"`
+	Kind      BlockKind `json:"kind"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// Bookmark is a post or a comment saved to the private reading list of a
+// user, exactly one of PostID and CommentID is set.
+type Bookmark struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"userId"`
+	PostID    *string   `json:"postId"`
+	CommentID *string   `json:"commentId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// BookmarkFilter selects a page of a user's bookmarks, newest first.
+type BookmarkFilter struct {
+	UserID string
+	// Type limits the page to posts or comments when set.
+	Type *SavedType
+	// After is the id of the last bookmark of the previous page.
+	After *string
+	Limit int
+}
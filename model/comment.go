@@ -7,8 +7,19 @@ type Comment struct {
 	Content  string     `json:"content"`
 	UserID   string     `json:"userId"`
 	Replies  []*Comment `json:"replies"`
+	Edited   bool       `json:"edited"`
+	// Deleted comments keep their place in the thread, the content is removed.
+	Deleted bool `json:"deleted"`
+	// EventID is set on comments delivered by subscriptions.
+	EventID *string `json:"eventId,omitempty"`
 }
 
+func (c *Comment) SetEventID(id string) {
+	c.EventID = &id
+}
+
+func (Comment) IsSavedItem() {}
+
 type PaginationParams struct {
 	Limit  int
 	Offset int
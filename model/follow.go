@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// Follow puts the posts of FolloweeID into the feed of FollowerID.
+type Follow struct {
+	ID         string    `json:"id"`
+	FollowerID string    `json:"followerId"`
+	FolloweeID string    `json:"followeeId"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// FollowFilter selects a page of follows, newest first. Either FollowerID is
+// set to list the users someone follows or FolloweeID to list their
+// followers.
+type FollowFilter struct {
+	FollowerID string
+	FolloweeID string
+	// After is the id of the last follow of the previous page.
+	After *string
+	Limit int
+}
+
+// FeedFilter selects a page of published posts of the users UserID follows,
+// newest first.
+type FeedFilter struct {
+	UserID string
+	// After is the id of the last post of the previous page.
+	After *string
+	Limit int
+}
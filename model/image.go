@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+type Image struct {
+	ID           string    `json:"id"`
+	PostID       string    `json:"postId"`
+	UserID       string    `json:"userId"`
+	Key          string    `json:"key"`
+	ThumbnailKey string    `json:"thumbnailKey"`
+	ContentType  string    `json:"contentType"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	Size         int64     `json:"size"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+type LoginEvent struct {
+	ID        string    `json:"id"`
+	UserID    *string   `json:"userId"`
+	Username  string    `json:"username"`
+	IP        string    `json:"ip"`
+	Success   bool      `json:"success"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// LoginFailures counts failed logins since the last successful one, separately
+// for a username and for a client ip.
+type LoginFailures struct {
+	ByUsername     int
+	LastByUsername time.Time
+	ByIP           int
+	LastByIP       time.Time
+}
@@ -0,0 +1,10 @@
+package model
+
+// Mention links a post or a comment to a user mentioned in its content with
+// @username.
+type Mention struct {
+	PostID string `json:"postId"`
+	// CommentID is empty for mentions in the content of the post itself.
+	CommentID *string `json:"commentId"`
+	UserID    string  `json:"userId"`
+}
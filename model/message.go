@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// Conversation is a private chat between two or more users, there is at most
+// one conversation for the same set of participants.
+type Conversation struct {
+	ID             string    `json:"id"`
+	ParticipantIDs []string  `json:"participantIds"`
+	CreatedAt      time.Time `json:"createdAt"`
+	// UpdatedAt is the time of the last message.
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ConversationMember holds the read receipt of a participant, every message
+// up to LastReadMessageID has been read.
+type ConversationMember struct {
+	ConversationID    string  `json:"conversationId"`
+	UserID            string  `json:"userId"`
+	LastReadMessageID *string `json:"lastReadMessageId"`
+}
+
+type Message struct {
+	ID             string    `json:"id"`
+	ConversationID string    `json:"conversationId"`
+	UserID         string    `json:"userId"`
+	Content        string    `json:"content"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// MessageFilter selects a page of the messages of a conversation, newest
+// first.
+type MessageFilter struct {
+	ConversationID string
+	// Before is the id of the oldest message of the previous page.
+	Before *string
+	Limit  int
+}
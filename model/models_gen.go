@@ -3,9 +3,17 @@
 package model
 
 import (
+	"fmt"
+	"github.com/99designs/gqlgen/graphql"
+	"io"
+	"strconv"
 	"time"
 )
 
+type SavedItem interface {
+	IsSavedItem()
+}
+
 type AuthResponse struct {
 	AuthToken *AuthToken `json:"authToken"`
 	User      *User      `json:"user"`
@@ -16,11 +24,44 @@ type AuthToken struct {
 	ExpiredAt   time.Time `json:"expiredAt"`
 }
 
+// Creates a draft, or updates a draft or a scheduled post when id is set.
+// Title and content are only checked on publishing.
+type DraftInput struct {
+	ID      *string  `json:"id,omitempty"`
+	Title   string   `json:"title"`
+	Content string   `json:"content"`
+	Tags    []string `json:"tags,omitempty"`
+}
+
+type FollowConnection struct {
+	Edges      []*FollowEdge `json:"edges"`
+	PageInfo   *PageInfo     `json:"pageInfo"`
+	TotalCount int           `json:"totalCount"`
+}
+
+type FollowEdge struct {
+	Cursor     string    `json:"cursor"`
+	Node       *User     `json:"node"`
+	FollowedAt time.Time `json:"followedAt"`
+}
+
 type LoginInput struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 }
 
+// Messages from the newest one, endCursor is the oldest message of the page and
+// hasNextPage tells whether there are older ones.
+type MessageConnection struct {
+	Edges    []*MessageEdge `json:"edges"`
+	PageInfo *PageInfo      `json:"pageInfo"`
+}
+
+type MessageEdge struct {
+	Cursor string   `json:"cursor"`
+	Node   *Message `json:"node"`
+}
+
 type Mutation struct {
 }
 
@@ -30,9 +71,56 @@ type NewComment struct {
 	Content  string  `json:"content"`
 }
 
+// Sends a message to a conversation, or to the conversation with exactly the
+// recipients and the current user when conversationId is not set, it is started
+// if there is none.
+type NewMessage struct {
+	ConversationID *string  `json:"conversationId,omitempty"`
+	RecipientIds   []string `json:"recipientIds,omitempty"`
+	Content        string   `json:"content"`
+}
+
+type NewPoll struct {
+	Question string `json:"question"`
+	// From 2 to 10 distinct options.
+	Options        []string   `json:"options"`
+	MultipleChoice *bool      `json:"multipleChoice,omitempty"`
+	ClosesAt       *time.Time `json:"closesAt,omitempty"`
+}
+
 type NewPost struct {
 	Title   string `json:"title"`
 	Content string `json:"content"`
+	// Up to 10 png, jpeg, gif or webp images, sent as a GraphQL multipart request.
+	Images []*graphql.Upload `json:"images,omitempty"`
+	// Up to 5 tags of lowercase letters, digits and dashes.
+	Tags []string `json:"tags,omitempty"`
+	Poll *NewPoll `json:"poll,omitempty"`
+}
+
+type NotificationConnection struct {
+	Edges    []*NotificationEdge `json:"edges"`
+	PageInfo *PageInfo           `json:"pageInfo"`
+}
+
+type NotificationEdge struct {
+	Cursor string        `json:"cursor"`
+	Node   *Notification `json:"node"`
+}
+
+type PageInfo struct {
+	EndCursor   *string `json:"endCursor,omitempty"`
+	HasNextPage bool    `json:"hasNextPage"`
+}
+
+type PostConnection struct {
+	Edges    []*PostEdge `json:"edges"`
+	PageInfo *PageInfo   `json:"pageInfo"`
+}
+
+type PostEdge struct {
+	Cursor string `json:"cursor"`
+	Node   *Post  `json:"node"`
 }
 
 type Query struct {
@@ -46,10 +134,218 @@ type RegisterInput struct {
 	LastName        string `json:"lastName"`
 }
 
+type SavedConnection struct {
+	Edges    []*SavedEdge `json:"edges"`
+	PageInfo *PageInfo    `json:"pageInfo"`
+}
+
+type SavedEdge struct {
+	Cursor  string    `json:"cursor"`
+	Node    SavedItem `json:"node"`
+	SavedAt time.Time `json:"savedAt"`
+}
+
+type ScoreChange struct {
+	PostID string `json:"postId"`
+	Score  int    `json:"score"`
+}
+
 type Subscription struct {
 }
 
+type UpdateComment struct {
+	ID      string `json:"id"`
+	Content string `json:"content"`
+}
+
 type UpdatePost struct {
 	PostID         string `json:"postId"`
 	EnableComments bool   `json:"enableComments"`
 }
+
+type UpdateProfile struct {
+	FirstName *string `json:"firstName,omitempty"`
+	LastName  *string `json:"lastName,omitempty"`
+	Bio       *string `json:"bio,omitempty"`
+	Website   *string `json:"website,omitempty"`
+	AvatarURL *string `json:"avatarUrl,omitempty"`
+}
+
+type NotificationKind string
+
+const (
+	// Someone replied to your post or comment.
+	NotificationKindReply NotificationKind = "REPLY"
+	// Someone mentioned you with @username.
+	NotificationKindMention NotificationKind = "MENTION"
+	// Your content was changed or removed by someone else.
+	NotificationKindModeration NotificationKind = "MODERATION"
+	// Account security events like a locked account.
+	NotificationKindSecurity NotificationKind = "SECURITY"
+	// A user you follow published a post.
+	NotificationKindNewPost NotificationKind = "NEW_POST"
+)
+
+var AllNotificationKind = []NotificationKind{
+	NotificationKindReply,
+	NotificationKindMention,
+	NotificationKindModeration,
+	NotificationKindSecurity,
+	NotificationKindNewPost,
+}
+
+func (e NotificationKind) IsValid() bool {
+	switch e {
+	case NotificationKindReply, NotificationKindMention, NotificationKindModeration, NotificationKindSecurity, NotificationKindNewPost:
+		return true
+	}
+	return false
+}
+
+func (e NotificationKind) String() string {
+	return string(e)
+}
+
+func (e *NotificationKind) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = NotificationKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid NotificationKind", str)
+	}
+	return nil
+}
+
+func (e NotificationKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type PostStatus string
+
+const (
+	// Only visible to the author in myDrafts.
+	PostStatusDraft PostStatus = "DRAFT"
+	// Published automatically at publishAt.
+	PostStatusScheduled PostStatus = "SCHEDULED"
+	PostStatusPublished PostStatus = "PUBLISHED"
+)
+
+var AllPostStatus = []PostStatus{
+	PostStatusDraft,
+	PostStatusScheduled,
+	PostStatusPublished,
+}
+
+func (e PostStatus) IsValid() bool {
+	switch e {
+	case PostStatusDraft, PostStatusScheduled, PostStatusPublished:
+		return true
+	}
+	return false
+}
+
+func (e PostStatus) String() string {
+	return string(e)
+}
+
+func (e *PostStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = PostStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid PostStatus", str)
+	}
+	return nil
+}
+
+func (e PostStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ReactionTarget string
+
+const (
+	ReactionTargetPost    ReactionTarget = "POST"
+	ReactionTargetComment ReactionTarget = "COMMENT"
+)
+
+var AllReactionTarget = []ReactionTarget{
+	ReactionTargetPost,
+	ReactionTargetComment,
+}
+
+func (e ReactionTarget) IsValid() bool {
+	switch e {
+	case ReactionTargetPost, ReactionTargetComment:
+		return true
+	}
+	return false
+}
+
+func (e ReactionTarget) String() string {
+	return string(e)
+}
+
+func (e *ReactionTarget) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ReactionTarget(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ReactionTarget", str)
+	}
+	return nil
+}
+
+func (e ReactionTarget) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type SavedType string
+
+const (
+	SavedTypePost    SavedType = "POST"
+	SavedTypeComment SavedType = "COMMENT"
+)
+
+var AllSavedType = []SavedType{
+	SavedTypePost,
+	SavedTypeComment,
+}
+
+func (e SavedType) IsValid() bool {
+	switch e {
+	case SavedTypePost, SavedTypeComment:
+		return true
+	}
+	return false
+}
+
+func (e SavedType) String() string {
+	return string(e)
+}
+
+func (e *SavedType) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SavedType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SavedType", str)
+	}
+	return nil
+}
+
+func (e SavedType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
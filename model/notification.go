@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// Notification is an entry of a user's inbox.
+type Notification struct {
+	ID     string           `json:"id"`
+	UserID string           `json:"userId"`
+	Kind   NotificationKind `json:"kind"`
+	// ActorID is the user whose action caused the notification, it is empty
+	// for notifications sent by the forum itself.
+	ActorID   *string   `json:"actorId"`
+	PostID    *string   `json:"postId"`
+	CommentID *string   `json:"commentId"`
+	Message   string    `json:"message"`
+	Read      bool      `json:"read"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// NotificationFilter selects a page of a user's notifications, newest first.
+type NotificationFilter struct {
+	UserID     string
+	UnreadOnly bool
+	// After is the id of the last notification of the previous page.
+	After *string
+	Limit int
+}
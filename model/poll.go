@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// Poll is attached to a post when it is created.
+type Poll struct {
+	ID       string `json:"id"`
+	PostID   string `json:"postId"`
+	Question string `json:"question"`
+	// Multiple allows choosing more than one option.
+	Multiple  bool          `json:"multiple"`
+	ClosesAt  *time.Time    `json:"closesAt"`
+	Options   []*PollOption `json:"options"`
+	CreatedAt time.Time     `json:"createdAt"`
+	// MyVotes are the options chosen by the user the poll is shown to, it is
+	// filled in by the domain.
+	MyVotes []string `json:"-"`
+}
+
+// IsClosed tells whether voting has ended at now.
+func (p *Poll) IsClosed(now time.Time) bool {
+	return p.ClosesAt != nil && !now.Before(*p.ClosesAt)
+}
+
+type PollOption struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+	// Votes is nil when the count is hidden from the user.
+	Votes *int `json:"votes"`
+}
+
+// PollVote holds the options a user has chosen, a user votes once.
+type PollVote struct {
+	PollID    string   `json:"pollId"`
+	UserID    string   `json:"userId"`
+	OptionIDs []string `json:"optionIds"`
+}
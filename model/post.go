@@ -1,5 +1,11 @@
 package model
 
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
 type Post struct {
 	ID              string     `json:"id"`
 	Title           string     `json:"title"`
@@ -7,4 +13,60 @@ type Post struct {
 	CommentsEnabled bool       `json:"commentsEnabled"`
 	Comments        []*Comment `json:"comments"`
 	UserID          string     `json:"userId"`
+	Tags            []string   `json:"tags"`
+	// Score is the sum of all votes.
+	Score  int        `json:"score"`
+	Status PostStatus `json:"status"`
+	// PublishAt is set for scheduled posts.
+	PublishAt *time.Time `json:"publishAt"`
+	// PublishedAt is the time the post was published, drafts are created
+	// before they are published so posts are listed in its order.
+	PublishedAt *time.Time `json:"publishedAt"`
+	// CommentCount is the number of comments that aren't deleted, it is
+	// updated together with the comments.
+	CommentCount int `json:"commentCount"`
+	// LastActivityAt is the time the post was published or last commented.
+	LastActivityAt time.Time `json:"lastActivityAt"`
+}
+
+func (Post) IsSavedItem() {}
+
+// IsPublished tells whether the post is visible to everyone, posts stored
+// before drafts were added have no status and are published.
+func (p *Post) IsPublished() bool {
+	return p.Status == PostStatusPublished || p.Status == ""
+}
+
+// Cursor is the position of a published post in a list ordered by publish
+// time, posts published at the same time are ordered by id.
+func (p *Post) Cursor() string {
+	var at int64
+	if p.PublishedAt != nil {
+		at = p.PublishedAt.UnixNano()
+	}
+	return strconv.FormatInt(at, 10) + ":" + p.ID
+}
+
+// ParsePostCursor returns the publish time and the id of a post cursor, ok
+// is false when it isn't one.
+func ParsePostCursor(cursor string) (publishedAt time.Time, id int64, ok bool) {
+	at, rest, found := strings.Cut(cursor, ":")
+	if !found {
+		return time.Time{}, 0, false
+	}
+	nanos, err := strconv.ParseInt(at, 10, 64)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	if id, err = strconv.ParseInt(rest, 10, 64); err != nil {
+		return time.Time{}, 0, false
+	}
+	return time.Unix(0, nanos), id, true
+}
+
+// Vote of a user for a post, Value is 1 or -1.
+type Vote struct {
+	PostID string `json:"postId"`
+	UserID string `json:"userId"`
+	Value  int    `json:"value"`
 }
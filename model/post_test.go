@@ -0,0 +1,22 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParsePostCursor(t *testing.T) {
+	at := time.Date(2024, 6, 22, 9, 0, 0, 123456789, time.UTC)
+	post := &Post{ID: "42", PublishedAt: &at}
+
+	publishedAt, id, ok := ParsePostCursor(post.Cursor())
+	assert.True(t, ok)
+	assert.True(t, at.Equal(publishedAt))
+	assert.Equal(t, int64(42), id)
+
+	for _, cursor := range []string{"", "42", "x:42", "1:x"} {
+		_, _, ok = ParsePostCursor(cursor)
+		assert.False(t, ok, cursor)
+	}
+}
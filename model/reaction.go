@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// Reaction is an emoji a user left on a post or a comment, exactly one of
+// PostID and CommentID is set. A user can leave several different emojis on
+// the same target.
+type Reaction struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"userId"`
+	PostID    *string   `json:"postId"`
+	CommentID *string   `json:"commentId"`
+	Emoji     string    `json:"emoji"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ReactionCount is the number of users who reacted to a target with an
+// emoji.
+type ReactionCount struct {
+	TargetID string `json:"targetId"`
+	Emoji    string `json:"emoji"`
+	Count    int    `json:"count"`
+	// ReactedByMe tells whether the user the count is shown to is one of
+	// them.
+	ReactedByMe bool `json:"reactedByMe"`
+}
+
+// ReactionsUpdate carries the reactions of a post or a comment after one of
+// them has changed.
+type ReactionsUpdate struct {
+	TargetType ReactionTarget   `json:"targetType"`
+	TargetID   string           `json:"targetId"`
+	Reactions  []*ReactionCount `json:"reactions"`
+}
@@ -2,7 +2,7 @@ package model
 
 import (
 	"github.com/dgrijalva/jwt-go"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/farid21ola/forum/hasher"
 	"os"
 	"time"
 )
@@ -13,26 +13,48 @@ type UserInterface interface {
 }
 
 type User struct {
-	ID        string    `json:"id"`
-	Username  string    `json:"username"`
-	Password  string    `json:"password"`
-	FirstName string    `json:"firstName"`
-	LastName  string    `json:"lastName"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdateAt  time.Time `json:"updateAt"`
+	ID        string `json:"id"`
+	Username  string `json:"username"`
+	Password  string `json:"password"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Bio       string `json:"bio"`
+	Website   string `json:"website"`
+	AvatarURL string `json:"avatarUrl"`
+	// AvatarKeys are the stored files of an uploaded avatar, they are
+	// deleted when the avatar is replaced.
+	AvatarKeys []string  `json:"avatarKeys"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdateAt   time.Time `json:"updateAt"`
+}
+
+type UserStats struct {
+	UserID       string
+	PostCount    int
+	CommentCount int
+	Karma        int
+	// FollowerCount is the number of users following the user,
+	// FollowingCount the number of users the user follows.
+	FollowerCount  int
+	FollowingCount int
 }
 
 func (u *User) HashPassword(password string) error {
-	bytePassword := []byte(password)
-	passwordHash, err := bcrypt.GenerateFromPassword(bytePassword, bcrypt.DefaultCost)
+	passwordHash, err := hasher.Default.Hash(password)
 	if err != nil {
 		return err
 	}
-	u.Password = string(passwordHash)
+	u.Password = passwordHash
 
 	return nil
 }
 
+// PasswordNeedsRehash reports whether the stored hash was made with an
+// outdated algorithm or parameters.
+func (u *User) PasswordNeedsRehash() bool {
+	return hasher.Default.NeedsRehash(u.Password)
+}
+
 func (u *User) GenToken() (*AuthToken, error) {
 	expiredAt := time.Now().Add(time.Hour * 24 * 7) //week
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{
@@ -53,9 +75,8 @@ func (u *User) GenToken() (*AuthToken, error) {
 	}, nil
 }
 
+// ComparePassword takes as long for a wrong password whatever algorithm the
+// stored hash uses, a user without a hash is checked too.
 func (u *User) ComparePassword(password string) error {
-	bytePassword := []byte(password)
-	byteHashPassword := []byte(u.Password)
-	ok := bcrypt.CompareHashAndPassword(byteHashPassword, bytePassword)
-	return ok
+	return hasher.Default.VerifyEvenly(password, u.Password)
 }
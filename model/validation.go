@@ -32,3 +32,33 @@ func (l LoginInput) Validate() (bool, map[string]string) {
 
 	return v.IsValid(), v.Errors
 }
+
+func (u UpdateProfile) Validate() (bool, map[string]string) {
+	v := validator.New()
+
+	if u.FirstName != nil {
+		v.MinLength("firstName", *u.FirstName, 2)
+		v.MaxLength("firstName", *u.FirstName, 255)
+	}
+
+	if u.LastName != nil {
+		v.MinLength("lastName", *u.LastName, 2)
+		v.MaxLength("lastName", *u.LastName, 255)
+	}
+
+	if u.Bio != nil {
+		v.MaxLength("bio", *u.Bio, 500)
+	}
+
+	if u.Website != nil {
+		v.MaxLength("website", *u.Website, 255)
+		v.URL("website", *u.Website)
+	}
+
+	if u.AvatarURL != nil {
+		v.MaxLength("avatarUrl", *u.AvatarURL, 1024)
+		v.URL("avatarUrl", *u.AvatarURL)
+	}
+
+	return v.IsValid(), v.Errors
+}
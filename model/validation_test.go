@@ -2,6 +2,7 @@ package model
 
 import (
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 )
 
@@ -165,3 +166,73 @@ func TestRegisterInput_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateProfile_Validate(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name           string
+		input          UpdateProfile
+		expectedValid  bool
+		expectedErrors map[string]string
+	}{
+		{
+			name:           "Nothing to update",
+			input:          UpdateProfile{},
+			expectedValid:  true,
+			expectedErrors: map[string]string{},
+		},
+		{
+			name: "Valid input",
+			input: UpdateProfile{
+				FirstName: str("John"),
+				LastName:  str("Doe"),
+				Bio:       str("Gopher"),
+				Website:   str("https://example.com"),
+				AvatarURL: str(""),
+			},
+			expectedValid:  true,
+			expectedErrors: map[string]string{},
+		},
+		{
+			name: "FirstName too short",
+			input: UpdateProfile{
+				FirstName: str("J"),
+			},
+			expectedValid: false,
+			expectedErrors: map[string]string{
+				"firstName": "firstName must be at least (2) characters long",
+			},
+		},
+		{
+			name: "Bio too long",
+			input: UpdateProfile{
+				Bio: str(strings.Repeat("a", 501)),
+			},
+			expectedValid: false,
+			expectedErrors: map[string]string{
+				"bio": "bio must be at most (500) characters long",
+			},
+		},
+		{
+			name: "Invalid urls",
+			input: UpdateProfile{
+				Website:   str("example.com"),
+				AvatarURL: str("ftp://example.com/a.png"),
+			},
+			expectedValid: false,
+			expectedErrors: map[string]string{
+				"website":   "website must be a valid http(s) url",
+				"avatarUrl": "avatarUrl must be a valid http(s) url",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, errors := tt.input.Validate()
+			assert.Equal(t, tt.expectedValid, valid)
+			assert.Equal(t, tt.expectedErrors, errors)
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+type subscriber struct {
+	ch   chan Message
+	once sync.Once
+}
+
+func (s *subscriber) close() {
+	s.once.Do(func() { close(s.ch) })
+}
+
+// Memory delivers messages inside one process. Publishing never blocks: a
+// subscriber that can't keep up is handled according to the SlowPolicy.
+type Memory struct {
+	opts   Options
+	topics map[string]map[*subscriber]struct{}
+	logs   map[string][]Message
+	lastID int64
+	closed bool
+	mu     sync.Mutex
+}
+
+func NewMemory(opts Options) *Memory {
+	if opts.BufferSize < 1 {
+		opts.BufferSize = 1
+	}
+	return &Memory{
+		opts:   opts,
+		topics: make(map[string]map[*subscriber]struct{}),
+		logs:   make(map[string][]Message),
+	}
+}
+
+func (m *Memory) Publish(ctx context.Context, topic string, payload []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	m.lastID++
+	msg := Message{ID: m.lastID, Topic: topic, Payload: payload}
+
+	if m.opts.LogSize > 0 {
+		log := append(m.logs[topic], msg)
+		if len(log) > m.opts.LogSize {
+			log = append([]Message(nil), log[len(log)-m.opts.LogSize:]...)
+		}
+		m.logs[topic] = log
+	}
+
+	m.deliver(msg)
+	return nil
+}
+
+func (m *Memory) Subscribe(ctx context.Context, topic string) (<-chan Message, error) {
+	sub := &subscriber{ch: make(chan Message, m.opts.BufferSize)}
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, ErrClosed
+	}
+	if m.topics[topic] == nil {
+		m.topics[topic] = make(map[*subscriber]struct{})
+	}
+	m.topics[topic][sub] = struct{}{}
+	m.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		m.mu.Lock()
+		m.remove(topic, sub)
+		m.mu.Unlock()
+	}()
+
+	return sub.ch, nil
+}
+
+func (m *Memory) Since(ctx context.Context, topic string, id int64) ([]Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var msgs []Message
+	for _, msg := range m.logs[topic] {
+		if msg.ID > id {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs, nil
+}
+
+// Close ends all subscriptions.
+func (m *Memory) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = true
+	for topic, subs := range m.topics {
+		for sub := range subs {
+			m.remove(topic, sub)
+		}
+	}
+}
+
+// forward delivers a message that was logged elsewhere.
+func (m *Memory) forward(msg Message) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.closed {
+		m.deliver(msg)
+	}
+}
+
+// deliver fans msg out without blocking, the caller holds the lock so
+// subscribers get messages in publishing order.
+func (m *Memory) deliver(msg Message) {
+	for sub := range m.topics[msg.Topic] {
+		select {
+		case sub.ch <- msg:
+		default:
+			if m.opts.SlowPolicy == CloseSubscriber {
+				m.remove(msg.Topic, sub)
+			}
+		}
+	}
+}
+
+// remove unsubscribes sub, the caller holds the lock so no publisher can be
+// sending to the channel while it is closed.
+func (m *Memory) remove(topic string, sub *subscriber) {
+	subs := m.topics[topic]
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(m.topics, topic)
+	}
+	sub.close()
+}
@@ -0,0 +1,229 @@
+package pubsub
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func receive(t *testing.T, ch <-chan Message) (Message, bool) {
+	t.Helper()
+	select {
+	case msg, ok := <-ch:
+		return msg, ok
+	case <-time.After(time.Second):
+		t.Fatal("no message received")
+		return Message{}, false
+	}
+}
+
+func assertEmpty(t *testing.T, ch <-chan Message) {
+	t.Helper()
+	select {
+	case msg, ok := <-ch:
+		if ok {
+			t.Fatalf("unexpected message %s", msg.Payload)
+		}
+	default:
+	}
+}
+
+func TestMemory_FanOut(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := NewMemory(DefaultOptions)
+
+	first, err := m.Subscribe(ctx, "comments:1")
+	require.NoError(t, err)
+	second, err := m.Subscribe(ctx, "comments:1")
+	require.NoError(t, err)
+	other, err := m.Subscribe(ctx, "comments:2")
+	require.NoError(t, err)
+
+	require.NoError(t, m.Publish(ctx, "comments:1", []byte(`{"id":"1"}`)))
+
+	for _, ch := range []<-chan Message{first, second} {
+		msg, ok := receive(t, ch)
+		assert.True(t, ok)
+		assert.Equal(t, "comments:1", msg.Topic)
+		assert.JSONEq(t, `{"id":"1"}`, string(msg.Payload))
+	}
+	assertEmpty(t, other)
+}
+
+func TestMemory_Unsubscribe(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(DefaultOptions)
+
+	leaving, cancel := context.WithCancel(ctx)
+	gone, err := m.Subscribe(leaving, "comments:1")
+	require.NoError(t, err)
+	staying, err := m.Subscribe(ctx, "comments:1")
+	require.NoError(t, err)
+
+	cancel()
+	_, ok := receive(t, gone)
+	assert.False(t, ok, "channel must be closed")
+
+	// other subscribers of the topic are not affected
+	require.NoError(t, m.Publish(ctx, "comments:1", []byte(`{}`)))
+	_, ok = receive(t, staying)
+	assert.True(t, ok)
+}
+
+func TestMemory_SlowSubscriber(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     SlowPolicy
+		wantClosed bool
+	}{
+		{name: "Drop messages", policy: DropMessage},
+		{name: "Close subscriber", policy: CloseSubscriber, wantClosed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			m := NewMemory(Options{BufferSize: 2, SlowPolicy: tt.policy})
+
+			slow, err := m.Subscribe(ctx, "topic")
+			require.NoError(t, err)
+			fast, err := m.Subscribe(ctx, "topic")
+			require.NoError(t, err)
+
+			for i := 0; i < 3; i++ {
+				done := make(chan error)
+				go func() { done <- m.Publish(ctx, "topic", []byte(`{}`)) }()
+				select {
+				case err = <-done:
+					require.NoError(t, err)
+				case <-time.After(time.Second):
+					t.Fatal("publish blocked on a slow subscriber")
+				}
+				_, ok := receive(t, fast)
+				assert.True(t, ok)
+			}
+
+			received := 0
+			for range slow {
+				received++
+				if !tt.wantClosed && received == 2 {
+					break
+				}
+			}
+			assert.Equal(t, 2, received)
+			if !tt.wantClosed {
+				assertEmpty(t, slow)
+			}
+		})
+	}
+}
+
+func TestMemory_Close(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(DefaultOptions)
+
+	ch, err := m.Subscribe(ctx, "topic")
+	require.NoError(t, err)
+
+	m.Close()
+	_, ok := receive(t, ch)
+	assert.False(t, ok)
+
+	assert.ErrorIs(t, m.Publish(ctx, "topic", []byte(`{}`)), ErrClosed)
+	_, err = m.Subscribe(ctx, "topic")
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestMemory_Since(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(Options{BufferSize: 1, LogSize: 3})
+
+	for _, topic := range []string{"a", "b", "a", "a", "a"} {
+		require.NoError(t, m.Publish(ctx, topic, []byte(`{}`)))
+	}
+
+	msgs, err := m.Since(ctx, "a", 0)
+	require.NoError(t, err)
+	var ids []int64
+	for _, msg := range msgs {
+		ids = append(ids, msg.ID)
+	}
+	assert.Equal(t, []int64{3, 4, 5}, ids, "only the last LogSize messages are kept")
+
+	msgs, err = m.Since(ctx, "a", 4)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, int64(5), msgs[0].ID)
+
+	msgs, err = m.Since(ctx, "b", 2)
+	require.NoError(t, err)
+	assert.Empty(t, msgs)
+}
+
+func TestResume(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := NewMemory(DefaultOptions)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, m.Publish(ctx, "topic", []byte(`{}`)))
+	}
+
+	ch, err := Resume(ctx, m, "topic", 1)
+	require.NoError(t, err)
+	require.NoError(t, m.Publish(ctx, "topic", []byte(`{}`)))
+
+	for _, want := range []int64{2, 3, 4} {
+		msg, ok := receive(t, ch)
+		require.True(t, ok)
+		assert.Equal(t, want, msg.ID)
+	}
+	assertEmpty(t, ch)
+
+	cancel()
+	for range ch {
+	}
+}
+
+// fakeBroker replays fixed logged messages and passes on the live ones sent
+// to it.
+type fakeBroker struct {
+	logged []Message
+	live   chan Message
+}
+
+func (f *fakeBroker) Publish(ctx context.Context, topic string, payload []byte) error {
+	return nil
+}
+
+func (f *fakeBroker) Subscribe(ctx context.Context, topic string) (<-chan Message, error) {
+	return f.live, nil
+}
+
+func (f *fakeBroker) Since(ctx context.Context, topic string, id int64) ([]Message, error) {
+	return f.logged, nil
+}
+
+func TestResume_OutOfOrder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b := &fakeBroker{logged: []Message{{ID: 2}, {ID: 5}}, live: make(chan Message, 4)}
+
+	ch, err := Resume(ctx, b, "topic", 1)
+	require.NoError(t, err)
+	// 5 is already logged, 4 committed after it and 1 was seen before
+	for _, id := range []int64{5, 4, 1, 6} {
+		b.live <- Message{ID: id}
+	}
+	close(b.live)
+
+	var ids []int64
+	for msg := range ch {
+		ids = append(ids, msg.ID)
+	}
+	assert.Equal(t, []int64{2, 5, 4, 6}, ids)
+}
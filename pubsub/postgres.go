@@ -0,0 +1,197 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"log"
+	"time"
+)
+
+// channel is the NOTIFY channel shared by all topics, topics are part of the
+// notification so that one connection can listen to all of them.
+const channel = "forum_events"
+
+// envelope is the notification of a published message. It carries only the
+// id and the topic because NOTIFY payloads are limited to 8000 bytes, the
+// listeners load the payload from the events table.
+type envelope struct {
+	ID    int64  `json:"i"`
+	Topic string `json:"t"`
+}
+
+// Postgres delivers messages between server instances with LISTEN/NOTIFY.
+// Every instance keeps one dedicated listening connection and fans the
+// notifications out to its local subscribers. The message log is the events
+// table, ids come from its sequence, so concurrent publishers may commit them
+// slightly out of order. Payloads of any size are delivered, they are stored
+// in the log and only the ids are sent through NOTIFY.
+type Postgres struct {
+	pool   *pgxpool.Pool
+	opts   Options
+	local  *Memory
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewPostgres needs a positive LogSize, the events table is trimmed to it on
+// every publish.
+func NewPostgres(pool *pgxpool.Pool, opts Options) (*Postgres, error) {
+	if opts.LogSize < 1 {
+		return nil, ErrInvalidLogSize
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Postgres{
+		pool:   pool,
+		opts:   opts,
+		local:  NewMemory(opts),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go p.listen(ctx)
+	return p, nil
+}
+
+// Publish logs the message and sends it through postgres, local subscribers
+// receive it from the listening connection like everyone else. The
+// notification is only sent when the transaction commits.
+func (p *Postgres) Publish(ctx context.Context, topic string, payload []byte) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	e := envelope{Topic: topic}
+	q := `INSERT INTO "events" (topic, payload) VALUES ($1, $2) RETURNING id`
+	if err = tx.QueryRow(ctx, q, topic, string(payload)).Scan(&e.ID); err != nil {
+		return err
+	}
+
+	q = `DELETE FROM "events" WHERE topic = $1 AND id <= (
+		SELECT id FROM "events" WHERE topic = $1 ORDER BY id DESC OFFSET $2 LIMIT 1)`
+	if _, err = tx.Exec(ctx, q, topic, p.opts.LogSize); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.Exec(ctx, "SELECT pg_notify($1, $2)", channel, string(data)); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (p *Postgres) Subscribe(ctx context.Context, topic string) (<-chan Message, error) {
+	return p.local.Subscribe(ctx, topic)
+}
+
+func (p *Postgres) Since(ctx context.Context, topic string, id int64) ([]Message, error) {
+	var msgs []Message
+
+	q := `SELECT id, payload FROM "events" WHERE topic = $1 AND id > $2 ORDER BY id`
+
+	rows, err := p.pool.Query(ctx, q, topic, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		msg := Message{Topic: topic}
+		if err = rows.Scan(&msg.ID, &msg.Payload); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return msgs, nil
+}
+
+// Close stops listening and ends all subscriptions.
+func (p *Postgres) Close() {
+	p.cancel()
+	<-p.done
+	p.local.Close()
+}
+
+// listen reconnects until the broker is closed. Messages published while the
+// connection is down are lost, the same as for a client that reconnects.
+func (p *Postgres) listen(ctx context.Context) {
+	defer close(p.done)
+
+	delay := time.Second
+	for {
+		err := p.receive(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("pubsub: listen connection failed: %v, reconnecting in %s", err, delay)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		if delay < 30*time.Second {
+			delay *= 2
+		}
+	}
+}
+
+func (p *Postgres) receive(ctx context.Context) error {
+	pooled, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// the connection is left in LISTEN state, so it must not return to the pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err = conn.Exec(ctx, "LISTEN "+channel); err != nil {
+		return err
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var e envelope
+		if err = json.Unmarshal([]byte(n.Payload), &e); err != nil {
+			log.Printf("pubsub: invalid notification: %v", err)
+			continue
+		}
+
+		msg, err := p.load(ctx, e)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// trimmed by later publishes before it was loaded
+			log.Printf("pubsub: event %d of %s is no longer logged", e.ID, e.Topic)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		p.local.forward(msg)
+	}
+}
+
+// load reads the payload of a notified message from the events table.
+func (p *Postgres) load(ctx context.Context, e envelope) (Message, error) {
+	msg := Message{ID: e.ID, Topic: e.Topic}
+
+	q := `SELECT payload FROM "events" WHERE id = $1`
+	err := p.pool.QueryRow(ctx, q, e.ID).Scan(&msg.Payload)
+
+	return msg, err
+}
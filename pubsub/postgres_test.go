@@ -0,0 +1,61 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"github.com/farid21ola/forum/storage/postgres/pgtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Main(m))
+}
+
+func TestNewPostgres_InvalidLogSize(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		_, err := NewPostgres(nil, Options{BufferSize: 1, LogSize: size})
+		assert.ErrorIs(t, err, ErrInvalidLogSize, size)
+	}
+}
+
+func TestPostgres_LargePayload(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	broker, err := NewPostgres(pgtest.Pool(t), Options{BufferSize: 16, LogSize: 100})
+	require.NoError(t, err)
+	defer broker.Close()
+
+	ch, err := broker.Subscribe(ctx, "big")
+	require.NoError(t, err)
+
+	// over the 8000 bytes NOTIFY accepts
+	payload := []byte(fmt.Sprintf(`{"body": %q}`, strings.Repeat("a", 10000)))
+
+	// the listening connection may not be up yet, so publish until the
+	// first message arrives
+	tick := time.NewTicker(100 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		require.NoError(t, broker.Publish(ctx, "big", payload))
+		select {
+		case msg := <-ch:
+			assert.Equal(t, "big", msg.Topic)
+			assert.JSONEq(t, string(payload), string(msg.Payload))
+
+			logged, err := broker.Since(ctx, "big", msg.ID-1)
+			require.NoError(t, err)
+			require.NotEmpty(t, logged)
+			assert.Equal(t, msg.ID, logged[0].ID)
+			return
+		case <-tick.C:
+		case <-ctx.Done():
+			t.Fatal("the message was not delivered")
+		}
+	}
+}
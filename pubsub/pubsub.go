@@ -0,0 +1,106 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrClosed         = errors.New("broker is closed")
+	ErrInvalidLogSize = errors.New("log size must be positive")
+)
+
+// Message is a payload published to a topic. Payloads are json documents so
+// that they can cross process boundaries. IDs increase monotonically.
+type Message struct {
+	ID      int64
+	Topic   string
+	Payload []byte
+}
+
+// Broker fans messages out to the subscribers of a topic and keeps a bounded
+// log of recent messages for clients that reconnect.
+type Broker interface {
+	Publish(ctx context.Context, topic string, payload []byte) error
+	// Subscribe delivers messages published to topic after the call until ctx
+	// is done. The channel is closed when the subscription ends, which also
+	// happens when a slow subscriber is dropped.
+	Subscribe(ctx context.Context, topic string) (<-chan Message, error)
+	// Since returns the logged messages of topic with ids greater than id,
+	// oldest first.
+	Since(ctx context.Context, topic string, id int64) ([]Message, error)
+}
+
+// SlowPolicy decides what happens to a subscriber whose buffer is full.
+type SlowPolicy int
+
+const (
+	// DropMessage skips the message for that subscriber only.
+	DropMessage SlowPolicy = iota
+	// CloseSubscriber ends the subscription so the client can reconnect.
+	CloseSubscriber
+)
+
+type Options struct {
+	// BufferSize is the number of messages kept for every subscriber.
+	BufferSize int
+	SlowPolicy SlowPolicy
+	// LogSize is the number of messages of every topic kept for replay.
+	LogSize int
+}
+
+var DefaultOptions = Options{
+	BufferSize: 16,
+	SlowPolicy: DropMessage,
+	LogSize:    100,
+}
+
+// Resume subscribes to topic and first delivers the logged messages newer
+// than since, so that a client that reconnects gets what it missed as long as
+// it is still in the log. Every message is delivered once.
+func Resume(ctx context.Context, b Broker, topic string, since int64) (<-chan Message, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	// subscribing first leaves no gap between the log and live messages, the
+	// overlap is skipped below
+	live, err := b.Subscribe(ctx, topic)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	missed, err := b.Since(ctx, topic, since)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	ch := make(chan Message)
+	go func() {
+		defer cancel()
+		defer close(ch)
+
+		// ids may commit out of order, so a live message older than the last
+		// logged one can still be new, only the logged ones are skipped
+		seen := make(map[int64]bool, len(missed))
+		for _, msg := range missed {
+			select {
+			case ch <- msg:
+				seen[msg.ID] = true
+			case <-ctx.Done():
+				return
+			}
+		}
+		for msg := range live {
+			if msg.ID <= since || seen[msg.ID] {
+				continue
+			}
+			select {
+			case ch <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const sweepEvery = 1024
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	rule   Rule
+}
+
+// MemoryStore keeps buckets in process memory. It is enough for a single
+// server instance.
+type MemoryStore struct {
+	buckets map[string]*bucket
+	calls   int
+	now     func() time.Time
+	mu      sync.Mutex
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, rule Rule) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	s.calls++
+	if s.calls%sweepEvery == 0 {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rule.Limit), last: now}
+		s.buckets[key] = b
+	}
+	b.rule = rule
+
+	tokens, allowed, retryAfter := take(b.tokens, now.Sub(b.last), rule)
+	b.tokens = tokens
+	b.last = now
+
+	return allowed, retryAfter, nil
+}
+
+// sweep drops buckets that have refilled completely, they behave exactly like
+// missing ones.
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if now.Sub(b.last) >= b.rule.Period {
+			delete(s.buckets, key)
+		}
+	}
+}
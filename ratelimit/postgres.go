@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"sync/atomic"
+	"time"
+)
+
+// PostgresStore keeps buckets in the rate_limits table so that every server
+// instance shares the same limits.
+type PostgresStore struct {
+	DB    *pgxpool.Pool
+	calls atomic.Int64
+}
+
+func NewPostgresStore(db *pgxpool.Pool) *PostgresStore {
+	return &PostgresStore{DB: db}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, rule Rule) (bool, time.Duration, error) {
+	if s.calls.Add(1)%sweepEvery == 0 {
+		if err := s.sweep(ctx); err != nil {
+			return false, 0, err
+		}
+	}
+
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return false, 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	q := `INSERT INTO "rate_limits" (key, tokens) VALUES ($1, $2) ON CONFLICT (key) DO NOTHING`
+	if _, err = tx.Exec(ctx, q, key, float64(rule.Limit)); err != nil {
+		return false, 0, err
+	}
+
+	var tokens, elapsed float64
+	q = `SELECT tokens, EXTRACT(EPOCH FROM now() - updated_at)::float8 FROM "rate_limits" WHERE key = $1 FOR UPDATE`
+	if err = tx.QueryRow(ctx, q, key).Scan(&tokens, &elapsed); err != nil {
+		return false, 0, err
+	}
+
+	tokens, allowed, retryAfter := take(tokens, time.Duration(elapsed*float64(time.Second)), rule)
+
+	// the bucket has refilled completely after a period at the latest
+	q = `UPDATE "rate_limits" SET tokens = $2, updated_at = now(), expires_at = now() + make_interval(secs => $3) WHERE key = $1`
+	if _, err = tx.Exec(ctx, q, key, tokens, rule.Period.Seconds()); err != nil {
+		return false, 0, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return false, 0, err
+	}
+
+	return allowed, retryAfter, nil
+}
+
+// sweep deletes the buckets that have refilled completely, like in the
+// memory store they behave exactly like missing ones.
+func (s *PostgresStore) sweep(ctx context.Context) error {
+	_, err := s.DB.Exec(ctx, `DELETE FROM "rate_limits" WHERE expires_at <= now()`)
+	return err
+}
@@ -0,0 +1,40 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/farid21ola/forum/storage/postgres/pgtest"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Main(m))
+}
+
+func TestPostgresStore_sweep(t *testing.T) {
+	ctx := context.Background()
+	store := NewPostgresStore(pgtest.Pool(t))
+	rule := Rule{Limit: 1, Period: time.Minute}
+
+	_, err := store.DB.Exec(ctx, `INSERT INTO "rate_limits" (key, tokens, expires_at) VALUES ('old', 0, now() - interval '1 second')`)
+	require.NoError(t, err)
+	allowed, _, err := store.Take(ctx, "a", rule)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	// the next call sweeps
+	store.calls.Store(sweepEvery - 1)
+	allowed, _, err = store.Take(ctx, "a", rule)
+	require.NoError(t, err)
+	assert.False(t, allowed, "a bucket that hasn't refilled is kept")
+
+	rows, err := store.DB.Query(ctx, `SELECT key FROM "rate_limits" ORDER BY key`)
+	require.NoError(t, err)
+	keys, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, keys)
+}
@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule describes a token bucket: Limit requests are allowed in a burst and the
+// bucket refills completely over Period.
+type Rule struct {
+	Limit  int
+	Period time.Duration
+}
+
+func (r Rule) rate() float64 {
+	return float64(r.Limit) / r.Period.Seconds()
+}
+
+// Store keeps token buckets. Take removes one token from the bucket identified
+// by key and reports how long to wait when the bucket is empty.
+type Store interface {
+	Take(ctx context.Context, key string, rule Rule) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// LimitError is returned when an action is throttled.
+type LimitError struct {
+	Action     string
+	RetryAfter time.Duration
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("too many %s requests, retry in %d seconds", e.Action, e.RetrySeconds())
+}
+
+// RetrySeconds rounds RetryAfter up to whole seconds, as used in Retry-After headers.
+func (e *LimitError) RetrySeconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+type Limiter struct {
+	store Store
+	rules map[string]Rule
+}
+
+func New(store Store, rules map[string]Rule) *Limiter {
+	return &Limiter{store: store, rules: rules}
+}
+
+// DefaultRules are the limits applied to public mutations when nothing else is configured.
+func DefaultRules() map[string]Rule {
+	return map[string]Rule{
+		"login":      {Limit: 10, Period: time.Minute},
+		"register":   {Limit: 5, Period: time.Hour},
+		"createPost": {Limit: 10, Period: time.Hour},
+		"addComment": {Limit: 30, Period: time.Minute},
+	}
+}
+
+// Allow consumes a token for action on behalf of key (a user id or client ip).
+// Actions without a rule are never limited.
+func (l *Limiter) Allow(ctx context.Context, action, key string) error {
+	rule, ok := l.rules[action]
+	if !ok {
+		return nil
+	}
+
+	allowed, retryAfter, err := l.store.Take(ctx, action+":"+key, rule)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return &LimitError{Action: action, RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+// ParseRules reads rules in the form "login=5/1m,addComment=30/1m" and merges
+// them over base.
+func ParseRules(s string, base map[string]Rule) (map[string]Rule, error) {
+	rules := make(map[string]Rule, len(base))
+	for k, v := range base {
+		rules[k] = v
+	}
+
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		action, spec, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate limit %q", item)
+		}
+		limit, period, ok := strings.Cut(spec, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate limit %q", item)
+		}
+
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid limit in %q", item)
+		}
+		d, err := time.ParseDuration(period)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid period in %q", item)
+		}
+
+		rules[strings.TrimSpace(action)] = Rule{Limit: n, Period: d}
+	}
+
+	return rules, nil
+}
+
+// take refills a bucket holding tokens after elapsed and tries to remove one token.
+func take(tokens float64, elapsed time.Duration, rule Rule) (float64, bool, time.Duration) {
+	tokens = math.Min(float64(rule.Limit), tokens+elapsed.Seconds()*rule.rate())
+	if tokens >= 1 {
+		return tokens - 1, true, 0
+	}
+
+	wait := (1 - tokens) / rule.rate()
+	return tokens, false, time.Duration(wait * float64(time.Second))
+}
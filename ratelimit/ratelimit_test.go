@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	l := New(store, map[string]Rule{
+		"login": {Limit: 2, Period: time.Minute},
+	})
+
+	require.NoError(t, l.Allow(ctx, "login", "ip:1.1.1.1"))
+	require.NoError(t, l.Allow(ctx, "login", "ip:1.1.1.1"))
+
+	err := l.Allow(ctx, "login", "ip:1.1.1.1")
+	var limitErr *LimitError
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, "login", limitErr.Action)
+	assert.Equal(t, 30*time.Second, limitErr.RetryAfter)
+	assert.Equal(t, 30, limitErr.RetrySeconds())
+
+	// other keys and actions have their own buckets
+	assert.NoError(t, l.Allow(ctx, "login", "ip:2.2.2.2"))
+	assert.NoError(t, l.Allow(ctx, "createPost", "ip:1.1.1.1"))
+
+	now = now.Add(30 * time.Second)
+	assert.NoError(t, l.Allow(ctx, "login", "ip:1.1.1.1"))
+	assert.Error(t, l.Allow(ctx, "login", "ip:1.1.1.1"))
+}
+
+func TestMemoryStore_sweep(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	rule := Rule{Limit: 1, Period: time.Minute}
+
+	_, _, err := store.Take(ctx, "a", rule)
+	require.NoError(t, err)
+
+	now = now.Add(time.Minute)
+	store.sweep(now)
+	assert.Empty(t, store.buckets)
+}
+
+func TestParseRules(t *testing.T) {
+	base := map[string]Rule{"login": {Limit: 10, Period: time.Minute}}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]Rule
+		wantErr bool
+	}{
+		{
+			name:  "Empty keeps base",
+			input: "",
+			want:  base,
+		},
+		{
+			name:  "Override and add",
+			input: "login=5/30s, addComment=20/1m",
+			want: map[string]Rule{
+				"login":      {Limit: 5, Period: 30 * time.Second},
+				"addComment": {Limit: 20, Period: time.Minute},
+			},
+		},
+		{
+			name:    "Missing period",
+			input:   "login=5",
+			wantErr: true,
+		},
+		{
+			name:    "Bad limit",
+			input:   "login=x/1m",
+			wantErr: true,
+		},
+		{
+			name:    "Bad period",
+			input:   "login=5/soon",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := ParseRules(tt.input, base)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, rules)
+			}
+		})
+	}
+}
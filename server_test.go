@@ -0,0 +1,476 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/farid21ola/forum/blob"
+	"github.com/farid21ola/forum/pubsub"
+	"github.com/farid21ola/forum/ratelimit"
+	"github.com/farid21ola/forum/storage/inmemory"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const commentAddedQuery = `subscription($postID: ID!) { commentAdded(postID: $postID) { content } }`
+
+type gqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	t.Setenv("JWT_SECRET", "secret")
+
+	dir := t.TempDir()
+	for _, name := range []string{"posts.json", "users.json"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("[]"), 0o644))
+	}
+	blobStore, err := blob.NewLocal(filepath.Join(dir, "media"))
+	require.NoError(t, err)
+
+	s := services{
+		Storage:      inmemory.New(dir),
+		Broker:       pubsub.NewMemory(pubsub.DefaultOptions),
+		LimiterStore: ratelimit.NewMemoryStore(),
+		RateRules:    ratelimit.DefaultRules(),
+		BlobStore:    blobStore,
+		MediaURL:     "/media/",
+		Origins:      []string{"http://localhost:8000"},
+	}
+	srv := httptest.NewServer(newRouter(s, newDomain(s)))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func doQuery(t *testing.T, srv *httptest.Server, token, query string, vars map[string]interface{}, result interface{}) {
+	t.Helper()
+
+	gqlResp := postQuery(t, srv, token, query, vars)
+	require.Empty(t, gqlResp.Errors)
+	require.NoError(t, json.Unmarshal(gqlResp.Data, result))
+}
+
+// postQuery returns the response as it is, errors included.
+func postQuery(t *testing.T, srv *httptest.Server, token, query string, vars map[string]interface{}) gqlResponse {
+	t.Helper()
+
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": vars})
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/query", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var gqlResp gqlResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&gqlResp))
+	return gqlResp
+}
+
+// setup registers a user and creates a post, it returns the token and the
+// post id.
+func setup(t *testing.T, srv *httptest.Server) (string, string) {
+	t.Helper()
+
+	var registered struct {
+		Register struct {
+			AuthToken struct {
+				AccessToken string `json:"accessToken"`
+			} `json:"authToken"`
+		} `json:"register"`
+	}
+	doQuery(t, srv, "", `mutation { register(input: {username: "alice", password: "password1",
+		confirmPassword: "password1", firstName: "Alice", lastName: "Smith"}) { authToken { accessToken } } }`, nil, &registered)
+	token := registered.Register.AuthToken.AccessToken
+
+	var created struct {
+		CreatePost struct {
+			ID string `json:"id"`
+		} `json:"createPost"`
+	}
+	doQuery(t, srv, token, `mutation { createPost(input: {title: "Hello", content: "World"}) { id } }`, nil, &created)
+
+	return token, created.CreatePost.ID
+}
+
+func addComment(t *testing.T, srv *httptest.Server, token, postID, content string) {
+	t.Helper()
+
+	var added interface{}
+	doQuery(t, srv, token, `mutation($postID: ID!, $content: String!) {
+		addComment(input: {postId: $postID, content: $content}) { id } }`,
+		map[string]interface{}{"postID": postID, "content": content}, &added)
+}
+
+// subscription reads the commentAdded events of one transport, next returns
+// false when the server ended the subscription.
+type subscription struct {
+	next  func() (string, bool)
+	close func()
+}
+
+type subscribeFunc func(t *testing.T, srv *httptest.Server, token, postID string) subscription
+
+func contentOf(t *testing.T, data json.RawMessage) string {
+	var event struct {
+		CommentAdded struct {
+			Content string `json:"content"`
+		} `json:"commentAdded"`
+	}
+	require.NoError(t, json.Unmarshal(data, &event))
+	return event.CommentAdded.Content
+}
+
+func streamRequest(t *testing.T, srv *httptest.Server, token, postID, accept string) *http.Response {
+	t.Helper()
+
+	body, err := json.Marshal(map[string]interface{}{
+		"query":     commentAddedQuery,
+		"variables": map[string]interface{}{"postID": postID},
+	})
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/query", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", accept)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	return resp
+}
+
+func subscribeWebsocket(t *testing.T, srv *httptest.Server, token, postID string) subscription {
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/query", nil)
+	require.NoError(t, err)
+
+	init := map[string]interface{}{"type": "connection_init", "payload": map[string]string{}}
+	if token != "" {
+		init["payload"] = map[string]string{"Authorization": "Bearer " + token}
+	}
+	require.NoError(t, conn.WriteJSON(init))
+
+	var msg struct {
+		Type    string `json:"type"`
+		Payload struct {
+			Data json.RawMessage `json:"data"`
+		} `json:"payload"`
+	}
+	require.NoError(t, conn.ReadJSON(&msg))
+	require.Equal(t, "connection_ack", msg.Type)
+
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{
+		"id":   "1",
+		"type": "subscribe",
+		"payload": map[string]interface{}{
+			"query":     commentAddedQuery,
+			"variables": map[string]interface{}{"postID": postID},
+		},
+	}))
+
+	return subscription{
+		next: func() (string, bool) {
+			for {
+				msg.Type = ""
+				if err := conn.ReadJSON(&msg); err != nil {
+					return "", false
+				}
+				switch msg.Type {
+				case "next":
+					return contentOf(t, msg.Payload.Data), true
+				case "complete":
+					return "", false
+				}
+			}
+		},
+		close: func() { conn.Close() },
+	}
+}
+
+func subscribeSSE(t *testing.T, srv *httptest.Server, token, postID string) subscription {
+	resp := streamRequest(t, srv, token, postID, "text/event-stream")
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	scanner := bufio.NewScanner(resp.Body)
+
+	return subscription{
+		next: func() (string, bool) {
+			for scanner.Scan() {
+				line := scanner.Text()
+				if data, ok := strings.CutPrefix(line, "data: "); ok {
+					var r gqlResponse
+					require.NoError(t, json.Unmarshal([]byte(data), &r))
+					return contentOf(t, r.Data), true
+				}
+				if line == "event: complete" {
+					return "", false
+				}
+			}
+			return "", false
+		},
+		close: func() { resp.Body.Close() },
+	}
+}
+
+func subscribeMultipart(t *testing.T, srv *httptest.Server, token, postID string) subscription {
+	resp := streamRequest(t, srv, token, postID, `multipart/mixed;boundary="graphql";subscriptionSpec=1.0,application/json`)
+	assert.True(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "multipart/mixed"))
+
+	const delimiter = "\r\n--graphql"
+	reader := bufio.NewReader(resp.Body)
+	var buf []byte
+
+	// parts are complete once the delimiter after them arrives
+	nextPart := func() ([]byte, bool) {
+		for {
+			if i := bytes.Index(buf, []byte(delimiter)); i >= 0 {
+				part := buf[:i]
+				buf = buf[i+len(delimiter):]
+				return part, true
+			}
+			chunk := make([]byte, 4096)
+			n, err := reader.Read(chunk)
+			buf = append(buf, chunk[:n]...)
+			if err != nil && n == 0 {
+				return nil, false
+			}
+		}
+	}
+
+	return subscription{
+		next: func() (string, bool) {
+			for {
+				part, ok := nextPart()
+				if !ok {
+					return "", false
+				}
+				_, body, found := bytes.Cut(part, []byte("\r\n\r\n"))
+				if !found {
+					continue
+				}
+				var msg struct {
+					Payload *gqlResponse `json:"payload"`
+				}
+				require.NoError(t, json.Unmarshal(body, &msg))
+				if msg.Payload != nil {
+					return contentOf(t, msg.Payload.Data), true
+				}
+			}
+		},
+		close: func() { resp.Body.Close() },
+	}
+}
+
+var transports = []struct {
+	name      string
+	subscribe subscribeFunc
+}{
+	{"websocket", subscribeWebsocket},
+	{"sse", subscribeSSE},
+	{"multipart", subscribeMultipart},
+}
+
+func TestCommentAdded_Transports(t *testing.T) {
+	srv := newTestServer(t)
+	token, postID := setup(t, srv)
+
+	for _, tt := range transports {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := tt.subscribe(t, srv, "", postID)
+			defer sub.close()
+
+			// the subscription is registered asynchronously
+			time.Sleep(100 * time.Millisecond)
+			addComment(t, srv, token, postID, "hello over "+tt.name)
+			addComment(t, srv, token, postID, "second")
+
+			content, ok := sub.next()
+			require.True(t, ok)
+			assert.Equal(t, "hello over "+tt.name, content)
+			content, ok = sub.next()
+			require.True(t, ok)
+			assert.Equal(t, "second", content)
+		})
+	}
+}
+
+func TestCommentAdded_EndsWithSession(t *testing.T) {
+	srv := newTestServer(t)
+	token, postID := setup(t, srv)
+
+	var me struct {
+		Me struct {
+			ID string `json:"id"`
+		} `json:"me"`
+	}
+	doQuery(t, srv, token, `{ me { id } }`, nil, &me)
+
+	for _, tt := range transports {
+		t.Run(tt.name, func(t *testing.T) {
+			short, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{
+				Id:        me.Me.ID,
+				ExpiresAt: time.Now().Add(time.Second).Unix(),
+			}).SignedString([]byte("secret"))
+			require.NoError(t, err)
+
+			sub := tt.subscribe(t, srv, short, postID)
+			defer sub.close()
+
+			ended := make(chan bool)
+			go func() {
+				_, ok := sub.next()
+				ended <- !ok
+			}()
+
+			select {
+			case ok := <-ended:
+				assert.True(t, ok, "no events expected")
+			case <-time.After(5 * time.Second):
+				t.Fatal("subscription outlived the token")
+			}
+		})
+	}
+}
+
+func TestMultipartHeartbeat(t *testing.T) {
+	srv := newTestServer(t)
+	_, postID := setup(t, srv)
+
+	resp := streamRequest(t, srv, "", postID, "multipart/mixed")
+	defer resp.Body.Close()
+
+	// the stream opens with an empty heartbeat part
+	head := make([]byte, 64)
+	n, err := io.ReadAtLeast(resp.Body, head, len("\r\n--graphql\r\n"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(head[:n]), "\r\n--graphql\r\nContent-Type: application/json"), string(head[:n]))
+}
+
+func TestPostActivity(t *testing.T) {
+	srv := newTestServer(t)
+	token, postID := setup(t, srv)
+	start := time.Now().Add(-time.Second)
+
+	addComment(t, srv, token, postID, "first")
+	addComment(t, srv, token, postID, "second")
+	var deleted interface{}
+	doQuery(t, srv, token, `mutation { deleteComment(id: "1") { id } }`, nil, &deleted)
+
+	var result struct {
+		Posts []struct {
+			CommentCount   int       `json:"commentCount"`
+			LastActivityAt time.Time `json:"lastActivityAt"`
+			User           struct {
+				PostCount int `json:"postCount"`
+			} `json:"user"`
+		} `json:"posts"`
+	}
+	doQuery(t, srv, "", `{ posts { commentCount lastActivityAt user { postCount } } }`, nil, &result)
+
+	require.Len(t, result.Posts, 1)
+	assert.Equal(t, 1, result.Posts[0].CommentCount)
+	assert.True(t, result.Posts[0].LastActivityAt.After(start))
+	assert.Equal(t, 1, result.Posts[0].User.PostCount)
+}
+
+func TestErrorCodes(t *testing.T) {
+	srv := newTestServer(t)
+	token, postID := setup(t, srv)
+
+	tests := []struct {
+		name            string
+		query           string
+		expectedCode    string
+		expectedMessage string
+	}{
+		{
+			name:            "Unknown post",
+			query:           `{ post(id: "12345") { id } }`,
+			expectedCode:    "NOT_FOUND",
+			expectedMessage: "post with this id don't exist",
+		},
+		{
+			name:            "Invalid post id",
+			query:           `{ post(id: "abc") { id } }`,
+			expectedCode:    "INVALID_ID",
+			expectedMessage: "invalid id",
+		},
+		{
+			name:            "Unknown user",
+			query:           `{ user(id: "12345") { id } }`,
+			expectedCode:    "NOT_FOUND",
+			expectedMessage: "not found",
+		},
+		{
+			name:            "Comment on an unknown post",
+			query:           `mutation { addComment(input: {postId: "12345", content: "Hi"}) { id } }`,
+			expectedCode:    "NOT_FOUND",
+			expectedMessage: "post with this id don't exist",
+		},
+		{
+			name:            "Reply to an unknown comment",
+			query:           `mutation { addComment(input: {postId: "` + postID + `", parentId: "12345", content: "Hi"}) { id } }`,
+			expectedCode:    "NOT_FOUND",
+			expectedMessage: "parent comment with this id don't exist",
+		},
+		{
+			name: "Taken username",
+			query: `mutation { register(input: {username: "alice", password: "password1",
+				confirmPassword: "password1", firstName: "Alice", lastName: "Smith"}) { authToken { accessToken } } }`,
+			expectedCode:    "CONFLICT",
+			expectedMessage: "username is already in use",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := postQuery(t, srv, token, tt.query, nil)
+			require.Len(t, resp.Errors, 1)
+			assert.Equal(t, tt.expectedMessage, resp.Errors[0].Message)
+			assert.Equal(t, tt.expectedCode, resp.Errors[0].Extensions["code"])
+		})
+	}
+}
+
+func TestUnreadNotificationCount(t *testing.T) {
+	srv := newTestServer(t)
+	token, _ := setup(t, srv)
+
+	var result struct {
+		Users []struct {
+			UnreadNotificationCount *int `json:"unreadNotificationCount"`
+		} `json:"users"`
+	}
+	doQuery(t, srv, token, `{ users { unreadNotificationCount } }`, nil, &result)
+	require.Len(t, result.Users, 1)
+	assert.Equal(t, 0, *result.Users[0].UnreadNotificationCount)
+
+	doQuery(t, srv, "", `{ users { unreadNotificationCount } }`, nil, &result)
+	require.Len(t, result.Users, 1)
+	assert.Nil(t, result.Users[0].UnreadNotificationCount)
+}
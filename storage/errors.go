@@ -0,0 +1,31 @@
+package storage
+
+import "errors"
+
+// Errors every backend returns, wrapped or not, so callers can tell them
+// apart with errors.Is.
+var (
+	// ErrNotFound is returned when a row looked up or changed by its id or
+	// a row it refers to doesn't exist.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a row would break a unique constraint,
+	// e.g. a taken username.
+	ErrConflict = errors.New("already exists")
+	// ErrInvalidID is returned for ids no row can have.
+	ErrInvalidID = errors.New("invalid id")
+)
+
+// Error gives one of the errors above a message for clients, errors.Is
+// still matches Err.
+type Error struct {
+	Err     error
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
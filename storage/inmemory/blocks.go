@@ -0,0 +1,92 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"github.com/farid21ola/forum/model"
+	"time"
+)
+
+const blocksFile = "blocks.json"
+
+func (s *Storage) AddBlock(ctx context.Context, b *model.Block) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.usersExist(b.UserID, b.TargetID); err != nil {
+		return err
+	}
+	if s.block(b) >= 0 {
+		return nil
+	}
+	b.ID = nextID(s.blocks, func(b *model.Block) string { return b.ID })
+	b.CreatedAt = time.Now()
+	s.blocks = append(s.blocks, b)
+
+	if err := s.saveFile(blocksFile, s.blocks); err != nil {
+		return errors.New("something went wrong, try again later")
+	}
+
+	return nil
+}
+
+func (s *Storage) RemoveBlock(ctx context.Context, b *model.Block) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.block(b)
+	if i < 0 {
+		return nil
+	}
+	s.blocks = append(s.blocks[:i], s.blocks[i+1:]...)
+
+	if err := s.saveFile(blocksFile, s.blocks); err != nil {
+		return errors.New("something went wrong, try again later")
+	}
+
+	return nil
+}
+
+// block returns the index of the block of the same kind the user has set for
+// the target of b, or -1.
+func (s *Storage) block(b *model.Block) int {
+	for i, saved := range s.blocks {
+		if saved.UserID == b.UserID && saved.TargetID == b.TargetID && saved.Kind == b.Kind {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Storage) Blocks(ctx context.Context, userID string) ([]*model.Block, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var blocks []*model.Block
+
+	for i := len(s.blocks) - 1; i >= 0; i-- {
+		if b := s.blocks[i]; b.UserID == userID {
+			copied := *b
+			blocks = append(blocks, &copied)
+		}
+	}
+
+	return blocks, nil
+}
+
+func (s *Storage) BlockingUserIDs(ctx context.Context, targetID string, userIDs []string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var ids []string
+
+	wanted := make(map[string]bool, len(userIDs))
+	for _, id := range userIDs {
+		wanted[id] = true
+	}
+	for _, b := range s.blocks {
+		if b.TargetID == targetID && b.Kind == model.BlockKindBlock && wanted[b.UserID] {
+			ids = append(ids, b.UserID)
+		}
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,114 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"github.com/farid21ola/forum/model"
+	"strconv"
+	"time"
+)
+
+const bookmarksFile = "bookmarks.json"
+
+func (s *Storage) AddBookmark(ctx context.Context, b *model.Bookmark) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.usersExist(b.UserID); err != nil {
+		return err
+	}
+	if err := s.targetExists(b.PostID, b.CommentID); err != nil {
+		return err
+	}
+	if s.bookmark(b) >= 0 {
+		return nil
+	}
+	b.ID = nextID(s.bookmarks, func(b *model.Bookmark) string { return b.ID })
+	b.CreatedAt = time.Now()
+	s.bookmarks = append(s.bookmarks, b)
+
+	if err := s.saveFile(bookmarksFile, s.bookmarks); err != nil {
+		return errors.New("something went wrong, try again later")
+	}
+
+	return nil
+}
+
+func (s *Storage) RemoveBookmark(ctx context.Context, b *model.Bookmark) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.bookmark(b)
+	if i < 0 {
+		return nil
+	}
+	s.bookmarks = append(s.bookmarks[:i], s.bookmarks[i+1:]...)
+
+	if err := s.saveFile(bookmarksFile, s.bookmarks); err != nil {
+		return errors.New("something went wrong, try again later")
+	}
+
+	return nil
+}
+
+// bookmark returns the index of the bookmark the user has for the same post
+// or comment as b, or -1.
+func (s *Storage) bookmark(b *model.Bookmark) int {
+	for i, saved := range s.bookmarks {
+		if saved.UserID == b.UserID && equalIDs(saved.PostID, b.PostID) && equalIDs(saved.CommentID, b.CommentID) {
+			return i
+		}
+	}
+	return -1
+}
+
+func equalIDs(a, b *string) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
+
+func (s *Storage) Bookmarks(ctx context.Context, filter model.BookmarkFilter) ([]*model.Bookmark, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var bookmarks []*model.Bookmark
+
+	after := -1
+	if filter.After != nil {
+		after, _ = strconv.Atoi(*filter.After)
+	}
+
+	// newest first, ids grow with insertion order
+	for i := len(s.bookmarks) - 1; i >= 0 && len(bookmarks) < filter.Limit; i-- {
+		b := s.bookmarks[i]
+		if b.UserID != filter.UserID {
+			continue
+		}
+		if filter.Type != nil && (*filter.Type == model.SavedTypePost) != (b.PostID != nil) {
+			continue
+		}
+		if id, _ := strconv.Atoi(b.ID); after >= 0 && id >= after {
+			continue
+		}
+		copied := *b
+		bookmarks = append(bookmarks, &copied)
+	}
+
+	return bookmarks, nil
+}
+
+func (s *Storage) SavedPostIDs(ctx context.Context, userID string, postIDs []string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var saved []string
+
+	wanted := make(map[string]bool, len(postIDs))
+	for _, id := range postIDs {
+		wanted[id] = true
+	}
+	for _, b := range s.bookmarks {
+		if b.UserID == userID && b.PostID != nil && wanted[*b.PostID] {
+			saved = append(saved, *b.PostID)
+		}
+	}
+
+	return saved, nil
+}
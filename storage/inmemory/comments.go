@@ -0,0 +1,94 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/storage"
+	"strconv"
+)
+
+func (s *Storage) Comment(ctx context.Context, id string) (*model.Comment, error) {
+	if err := checkID(id); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if comment := s.comment(id); comment != nil {
+		return comment, nil
+	}
+	return nil, storage.ErrNotFound
+}
+
+func (s *Storage) CommentsByIDs(ctx context.Context, ids []string) ([]*model.Comment, error) {
+	if err := checkIDs(ids); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var comments []*model.Comment
+
+	for _, id := range ids {
+		if comment := s.comment(id); comment != nil {
+			comments = append(comments, comment)
+		}
+	}
+
+	return comments, nil
+}
+
+func (s *Storage) UpdateComment(ctx context.Context, upd *model.Comment) (*model.Comment, error) {
+	if err := checkID(upd.ID); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comment := s.comment(upd.ID)
+	if comment == nil {
+		return nil, storage.ErrNotFound
+	}
+	if upd.Deleted != comment.Deleted {
+		post := s.post(comment.PostID)
+		if upd.Deleted {
+			post.CommentCount--
+		} else {
+			post.CommentCount++
+		}
+	}
+	comment.Content = upd.Content
+	comment.Edited = upd.Edited
+	comment.Deleted = upd.Deleted
+
+	if err := s.saveFile("posts.json", s.posts); err != nil {
+		return nil, errors.New("something went wrong, try again later")
+	}
+
+	return comment, nil
+}
+
+func (s *Storage) comment(id string) *model.Comment {
+	for _, post := range s.posts {
+		for _, comment := range post.Comments {
+			if comment.ID == id {
+				return comment
+			}
+		}
+	}
+	return nil
+}
+
+// nextCommentID keeps comment ids unique across posts so that a comment can
+// be found by its id alone.
+func (s *Storage) nextCommentID() string {
+	last := 0
+	for _, post := range s.posts {
+		for _, comment := range post.Comments {
+			if id, _ := strconv.Atoi(comment.ID); id > last {
+				last = id
+			}
+		}
+	}
+	return strconv.Itoa(last + 1)
+}
@@ -0,0 +1,143 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/storage"
+	"strconv"
+	"time"
+)
+
+func (s *Storage) UserDrafts(ctx context.Context, userID string) ([]*model.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var drafts []*model.Post
+
+	for i := len(s.posts) - 1; i >= 0; i-- {
+		if post := s.posts[i]; post.UserID == userID && !post.IsPublished() {
+			drafts = append(drafts, post)
+		}
+	}
+
+	return drafts, nil
+}
+
+func (s *Storage) UpdateDraft(ctx context.Context, upd *model.Post) (*model.Post, error) {
+	if err := checkID(upd.ID); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post := s.post(upd.ID)
+	if post == nil {
+		return nil, storage.ErrNotFound
+	}
+	if post.IsPublished() {
+		return nil, nil
+	}
+	post.Title = upd.Title
+	post.Content = upd.Content
+	post.Tags = upd.Tags
+	post.Status = upd.Status
+	post.PublishAt = upd.PublishAt
+
+	if err := s.saveFile("posts.json", s.posts); err != nil {
+		return nil, errors.New("something went wrong, try again later")
+	}
+
+	return post, nil
+}
+
+func (s *Storage) PublishPost(ctx context.Context, id string) (*model.Post, error) {
+	if err := checkID(id); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post := s.post(id)
+	if post == nil {
+		return nil, storage.ErrNotFound
+	}
+	if post.IsPublished() {
+		return nil, nil
+	}
+	now := time.Now()
+	post.Status = model.PostStatusPublished
+	post.PublishAt = nil
+	post.PublishedAt = &now
+	post.LastActivityAt = now
+
+	if err := s.saveFile("posts.json", s.posts); err != nil {
+		return nil, errors.New("something went wrong, try again later")
+	}
+
+	return post, nil
+}
+
+func (s *Storage) PublishDuePosts(ctx context.Context, now time.Time, limit int, publishable func(*model.Post) bool) ([]*model.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var published []*model.Post
+	changed := false
+
+	for _, post := range s.posts {
+		if len(published) == limit {
+			break
+		}
+		if post.Status != model.PostStatusScheduled || post.PublishAt.After(now) {
+			continue
+		}
+		changed = true
+		post.PublishAt = nil
+		if !publishable(post) {
+			post.Status = model.PostStatusDraft
+			continue
+		}
+		post.Status = model.PostStatusPublished
+		post.PublishedAt = &now
+		post.LastActivityAt = now
+		published = append(published, post)
+	}
+	if !changed {
+		return nil, nil
+	}
+
+	if err := s.saveFile("posts.json", s.posts); err != nil {
+		return nil, errors.New("something went wrong, try again later")
+	}
+
+	return published, nil
+}
+
+func (s *Storage) publishedPosts() []*model.Post {
+	var posts []*model.Post
+	for _, post := range s.posts {
+		if post.IsPublished() {
+			posts = append(posts, post)
+		}
+	}
+	return posts
+}
+
+// publishedBefore orders posts by publish time, posts published at the same
+// time by id.
+func publishedBefore(a, b *model.Post) bool {
+	if !a.PublishedAt.Equal(*b.PublishedAt) {
+		return a.PublishedAt.Before(*b.PublishedAt)
+	}
+	aID, _ := strconv.Atoi(a.ID)
+	bID, _ := strconv.Atoi(b.ID)
+	return aID < bID
+}
+
+func (s *Storage) post(id string) *model.Post {
+	for _, post := range s.posts {
+		if post.ID == id {
+			return post
+		}
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"github.com/farid21ola/forum/model"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const followsFile = "follows.json"
+
+func (s *Storage) Follow(ctx context.Context, f *model.Follow) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.usersExist(f.FollowerID, f.FolloweeID); err != nil {
+		return err
+	}
+	if s.following[f.FollowerID][f.FolloweeID] {
+		return nil
+	}
+	f.ID = nextID(s.follows, func(f *model.Follow) string { return f.ID })
+	f.CreatedAt = time.Now()
+	s.follows = append(s.follows, f)
+	s.indexFollow(f)
+
+	if err := s.saveFile(followsFile, s.follows); err != nil {
+		return errors.New("something went wrong, try again later")
+	}
+
+	return nil
+}
+
+func (s *Storage) Unfollow(ctx context.Context, f *model.Follow) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.following[f.FollowerID][f.FolloweeID] {
+		return nil
+	}
+	for i, saved := range s.follows {
+		if saved.FollowerID == f.FollowerID && saved.FolloweeID == f.FolloweeID {
+			s.follows = append(s.follows[:i], s.follows[i+1:]...)
+			break
+		}
+	}
+	delete(s.following[f.FollowerID], f.FolloweeID)
+	delete(s.followers[f.FolloweeID], f.FollowerID)
+
+	if err := s.saveFile(followsFile, s.follows); err != nil {
+		return errors.New("something went wrong, try again later")
+	}
+
+	return nil
+}
+
+func (s *Storage) indexFollow(f *model.Follow) {
+	if s.following[f.FollowerID] == nil {
+		s.following[f.FollowerID] = make(map[string]bool)
+	}
+	s.following[f.FollowerID][f.FolloweeID] = true
+	if s.followers[f.FolloweeID] == nil {
+		s.followers[f.FolloweeID] = make(map[string]bool)
+	}
+	s.followers[f.FolloweeID][f.FollowerID] = true
+}
+
+func (s *Storage) Follows(ctx context.Context, filter model.FollowFilter) ([]*model.Follow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var follows []*model.Follow
+
+	after := -1
+	if filter.After != nil {
+		after, _ = strconv.Atoi(*filter.After)
+	}
+
+	// newest first, ids grow with insertion order
+	for i := len(s.follows) - 1; i >= 0 && len(follows) < filter.Limit; i-- {
+		f := s.follows[i]
+		if filter.FollowerID != "" && f.FollowerID != filter.FollowerID ||
+			filter.FolloweeID != "" && f.FolloweeID != filter.FolloweeID {
+			continue
+		}
+		if id, _ := strconv.Atoi(f.ID); after >= 0 && id >= after {
+			continue
+		}
+		copied := *f
+		follows = append(follows, &copied)
+	}
+
+	return follows, nil
+}
+
+func (s *Storage) FollowCount(ctx context.Context, filter model.FollowFilter) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if filter.FollowerID != "" {
+		return len(s.following[filter.FollowerID]), nil
+	}
+	return len(s.followers[filter.FolloweeID]), nil
+}
+
+func (s *Storage) AddFollowerNotifications(ctx context.Context, n *model.Notification, skip []string) ([]*model.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var notifications []*model.Notification
+
+	if n.ActorID == nil {
+		return nil, nil
+	}
+	if err := s.targetExists(n.PostID, n.CommentID); err != nil {
+		return nil, err
+	}
+
+	skipped := make(map[string]bool, len(skip))
+	for _, id := range skip {
+		skipped[id] = true
+	}
+	for _, b := range s.blocks {
+		if b.TargetID == *n.ActorID && b.Kind == model.BlockKindBlock {
+			skipped[b.UserID] = true
+		}
+	}
+
+	var followers []string
+	for id := range s.followers[*n.ActorID] {
+		if !skipped[id] {
+			followers = append(followers, id)
+		}
+	}
+	for _, id := range sortedIDs(followers) {
+		added := *n
+		added.ID = nextID(s.notifications, func(n *model.Notification) string { return n.ID })
+		added.UserID = id
+		added.Read = false
+		added.CreatedAt = time.Now()
+		s.notifications = append(s.notifications, &added)
+		copied := added
+		notifications = append(notifications, &copied)
+	}
+	if len(notifications) == 0 {
+		return nil, nil
+	}
+
+	if err := s.saveFile(notificationsFile, s.notifications); err != nil {
+		return nil, errors.New("something went wrong, try again later")
+	}
+
+	return notifications, nil
+}
+
+// Feed collects the published posts of the followed users after the cursor
+// and orders them newest first.
+func (s *Storage) Feed(ctx context.Context, filter model.FeedFilter) ([]*model.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var posts []*model.Post
+
+	var after *model.Post
+	if filter.After != nil {
+		at, id, ok := model.ParsePostCursor(*filter.After)
+		if !ok {
+			return nil, nil
+		}
+		after = &model.Post{ID: strconv.FormatInt(id, 10), PublishedAt: &at}
+	}
+
+	for userID := range s.following[filter.UserID] {
+		for _, post := range s.postsByUser[userID] {
+			if post.IsPublished() && (after == nil || publishedBefore(post, after)) {
+				posts = append(posts, post)
+			}
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool { return publishedBefore(posts[j], posts[i]) })
+	if len(posts) > filter.Limit {
+		posts = posts[:filter.Limit]
+	}
+
+	return posts, nil
+}
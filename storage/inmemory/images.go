@@ -0,0 +1,58 @@
+package inmemory
+
+import (
+	"context"
+	"github.com/farid21ola/forum/model"
+	"strconv"
+	"time"
+)
+
+const imagesFile = "images.json"
+
+// addPostImage adds an image of a post that is created under the same lock.
+func (s *Storage) addPostImage(image *model.Image) {
+	image.ID = nextID(s.images, func(i *model.Image) string { return i.ID })
+	image.CreatedAt = time.Now()
+	s.images = append(s.images, image)
+}
+
+func (s *Storage) PostImages(ctx context.Context, postID string) ([]*model.Image, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var images []*model.Image
+
+	for _, image := range s.images {
+		if image.PostID == postID {
+			images = append(images, image)
+		}
+	}
+
+	return images, nil
+}
+
+func (s *Storage) PostsImages(ctx context.Context, postIDs []string) ([]*model.Image, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	wanted := make(map[string]bool, len(postIDs))
+	for _, id := range postIDs {
+		wanted[id] = true
+	}
+
+	var images []*model.Image
+	for _, image := range s.images {
+		if wanted[image.PostID] {
+			images = append(images, image)
+		}
+	}
+
+	return images, nil
+}
+
+// nextID continues the numeric ids of a collection kept in insertion order.
+func nextID[T any](items []T, id func(T) string) string {
+	if len(items) == 0 {
+		return "1"
+	}
+	last, _ := strconv.Atoi(id(items[len(items)-1]))
+	return strconv.Itoa(last + 1)
+}
@@ -0,0 +1,77 @@
+package inmemory
+
+import (
+	"context"
+	"github.com/farid21ola/forum/model"
+	"strconv"
+	"time"
+)
+
+func (s *Storage) AddLoginEvent(ctx context.Context, event *model.LoginEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastLoginEventID++
+	event.ID = strconv.Itoa(s.lastLoginEventID)
+	event.CreatedAt = time.Now()
+	s.loginEvents = append(s.loginEvents, event)
+
+	return nil
+}
+
+// LoginFailures drops the events before since, the window of the login
+// policy doesn't change while the process runs so they are never counted
+// again.
+func (s *Storage) LoginFailures(ctx context.Context, username, ip string, since time.Time) (*model.LoginFailures, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLoginEvents(since)
+
+	var failures model.LoginFailures
+	failures.ByUsername, failures.LastByUsername = s.loginFailuresBy(func(e *model.LoginEvent) bool {
+		return e.Username == username
+	}, since, true)
+	// a successful login to any account doesn't clear the failures of the
+	// ip, otherwise logging into an own account between guesses would lift
+	// the limit
+	failures.ByIP, failures.LastByIP = s.loginFailuresBy(func(e *model.LoginEvent) bool {
+		return e.IP == ip
+	}, since, false)
+
+	return &failures, nil
+}
+
+// loginFailuresBy counts matching failures after since, starting over at every
+// successful login when resets is set. Events are kept in chronological order.
+func (s *Storage) loginFailuresBy(match func(e *model.LoginEvent) bool, since time.Time, resets bool) (int, time.Time) {
+	count := 0
+	last := since
+
+	for _, e := range s.loginEvents {
+		if !match(e) || !e.CreatedAt.After(since) {
+			continue
+		}
+		if e.Success {
+			if !resets {
+				continue
+			}
+			count = 0
+			last = since
+			continue
+		}
+		count++
+		last = e.CreatedAt
+	}
+
+	return count, last
+}
+
+func (s *Storage) pruneLoginEvents(since time.Time) {
+	i := 0
+	for i < len(s.loginEvents) && !s.loginEvents[i].CreatedAt.After(since) {
+		i++
+	}
+	if i > 0 {
+		s.loginEvents = append([]*model.LoginEvent(nil), s.loginEvents[i:]...)
+	}
+}
@@ -0,0 +1,144 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"github.com/farid21ola/forum/model"
+	"sort"
+	"strconv"
+)
+
+const mentionsFile = "mentions.json"
+
+func (s *Storage) UsersByUsernames(ctx context.Context, usernames []string) ([]*model.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var users []*model.User
+
+	wanted := make(map[string]bool, len(usernames))
+	for _, username := range usernames {
+		wanted[username] = true
+	}
+	for _, user := range s.users {
+		if wanted[user.Username] {
+			users = append(users, user)
+		}
+	}
+
+	return users, nil
+}
+
+func (s *Storage) AddMentions(ctx context.Context, mentions []*model.Mention) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, m := range mentions {
+		if !s.mentioned(m) {
+			s.mentions = append(s.mentions, m)
+		}
+	}
+
+	if err := s.saveFile(mentionsFile, s.mentions); err != nil {
+		return errors.New("something went wrong, try again later")
+	}
+
+	return nil
+}
+
+// mentioned tells whether the user is already mentioned in the same post or
+// comment as m.
+func (s *Storage) mentioned(m *model.Mention) bool {
+	for _, saved := range s.mentions {
+		if saved.UserID == m.UserID && mentionTarget(saved) == mentionTarget(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// mentionTarget is the comment id for mentions in comments and the post id
+// otherwise, prefixed so the two never match.
+func mentionTarget(m *model.Mention) string {
+	if m.CommentID != nil {
+		return "c" + *m.CommentID
+	}
+	return "p" + m.PostID
+}
+
+func (s *Storage) RemoveCommentMentions(ctx context.Context, commentID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.mentions[:0]
+	for _, m := range s.mentions {
+		if m.CommentID == nil || *m.CommentID != commentID {
+			kept = append(kept, m)
+		}
+	}
+	s.mentions = kept
+
+	if err := s.saveFile(mentionsFile, s.mentions); err != nil {
+		return errors.New("something went wrong, try again later")
+	}
+
+	return nil
+}
+
+func (s *Storage) PostsMentions(ctx context.Context, postIDs []string) ([]*model.Mention, error) {
+	wanted := make(map[string]bool, len(postIDs))
+	for _, id := range postIDs {
+		wanted[id] = true
+	}
+	return s.sortedMentions(func(m *model.Mention) (string, bool) {
+		return m.PostID, m.CommentID == nil && wanted[m.PostID]
+	}), nil
+}
+
+func (s *Storage) CommentsMentions(ctx context.Context, commentIDs []string) ([]*model.Mention, error) {
+	wanted := make(map[string]bool, len(commentIDs))
+	for _, id := range commentIDs {
+		wanted[id] = true
+	}
+	return s.sortedMentions(func(m *model.Mention) (string, bool) {
+		if m.CommentID == nil {
+			return "", false
+		}
+		return *m.CommentID, wanted[*m.CommentID]
+	}), nil
+}
+
+// sortedMentions returns the mentions accepted by keep ordered by the id keep
+// returns for them and by username.
+func (s *Storage) sortedMentions(keep func(*model.Mention) (string, bool)) []*model.Mention {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var mentions []*model.Mention
+
+	type sorted struct {
+		id       int
+		username string
+		mention  *model.Mention
+	}
+	var kept []sorted
+	for _, m := range s.mentions {
+		id, ok := keep(m)
+		user := s.user(m.UserID)
+		if !ok || user == nil {
+			continue
+		}
+		n, _ := strconv.Atoi(id)
+		copied := *m
+		kept = append(kept, sorted{id: n, username: user.Username, mention: &copied})
+	}
+	sort.Slice(kept, func(i, j int) bool {
+		if kept[i].id != kept[j].id {
+			return kept[i].id < kept[j].id
+		}
+		return kept[i].username < kept[j].username
+	})
+	for _, k := range kept {
+		mentions = append(mentions, k.mention)
+	}
+
+	return mentions
+}
@@ -0,0 +1,276 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/storage"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	conversationsFile = "conversations.json"
+	membersFile       = "conversation_members.json"
+	messagesFile      = "messages.json"
+)
+
+func (s *Storage) CreateConversation(ctx context.Context, c *model.Conversation) (*model.Conversation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.usersExist(c.ParticipantIDs...); err != nil {
+		return nil, err
+	}
+	key := participantsKey(c.ParticipantIDs)
+	for _, saved := range s.conversations {
+		if participantsKey(saved.ParticipantIDs) == key {
+			copied := *saved
+			return &copied, nil
+		}
+	}
+
+	c.ID = nextID(s.conversations, func(c *model.Conversation) string { return c.ID })
+	c.ParticipantIDs = sortedIDs(c.ParticipantIDs)
+	c.CreatedAt = time.Now()
+	c.UpdatedAt = c.CreatedAt
+	s.conversations = append(s.conversations, c)
+	for _, userID := range c.ParticipantIDs {
+		s.members = append(s.members, &model.ConversationMember{ConversationID: c.ID, UserID: userID})
+	}
+
+	if err := s.saveFile(conversationsFile, s.conversations); err != nil {
+		return nil, errors.New("something went wrong, try again later")
+	}
+	if err := s.saveFile(membersFile, s.members); err != nil {
+		return nil, errors.New("something went wrong, try again later")
+	}
+
+	copied := *c
+	return &copied, nil
+}
+
+// sortedIDs orders ids by their numbers like ORDER BY user_id does.
+func sortedIDs(ids []string) []string {
+	sorted := append([]string(nil), ids...)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, _ := strconv.Atoi(sorted[i])
+		b, _ := strconv.Atoi(sorted[j])
+		return a < b
+	})
+	return sorted
+}
+
+// participantsKey is the same for every order of the same participants.
+func participantsKey(ids []string) string {
+	sorted := append([]string(nil), ids...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+func (s *Storage) Conversation(ctx context.Context, id string) (*model.Conversation, error) {
+	if err := checkID(id); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if c := s.conversation(id); c != nil {
+		copied := *c
+		return &copied, nil
+	}
+
+	return nil, storage.ErrNotFound
+}
+
+func (s *Storage) conversation(id string) *model.Conversation {
+	for _, c := range s.conversations {
+		if c.ID == id {
+			return c
+		}
+	}
+	return nil
+}
+
+func (s *Storage) UserConversations(ctx context.Context, userID string) ([]*model.Conversation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var conversations []*model.Conversation
+
+	for _, c := range s.conversations {
+		for _, id := range c.ParticipantIDs {
+			if id == userID {
+				copied := *c
+				conversations = append(conversations, &copied)
+				break
+			}
+		}
+	}
+	sort.SliceStable(conversations, func(i, j int) bool {
+		return conversations[i].UpdatedAt.After(conversations[j].UpdatedAt)
+	})
+
+	return conversations, nil
+}
+
+func (s *Storage) ConversationsMembers(ctx context.Context, conversationIDs []string) ([]*model.ConversationMember, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var members []*model.ConversationMember
+
+	wanted := make(map[string]bool, len(conversationIDs))
+	for _, id := range conversationIDs {
+		wanted[id] = true
+	}
+	for _, m := range s.members {
+		if wanted[m.ConversationID] {
+			copied := *m
+			members = append(members, &copied)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].ConversationID != members[j].ConversationID {
+			a, _ := strconv.Atoi(members[i].ConversationID)
+			b, _ := strconv.Atoi(members[j].ConversationID)
+			return a < b
+		}
+		a, _ := strconv.Atoi(members[i].UserID)
+		b, _ := strconv.Atoi(members[j].UserID)
+		return a < b
+	})
+
+	return members, nil
+}
+
+func (s *Storage) AddMessage(ctx context.Context, m *model.Message) (*model.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conversation(m.ConversationID) == nil {
+		return nil, storage.ErrNotFound
+	}
+	if err := s.usersExist(m.UserID); err != nil {
+		return nil, err
+	}
+	m.ID = nextID(s.messages, func(m *model.Message) string { return m.ID })
+	m.CreatedAt = time.Now()
+	s.messages = append(s.messages, m)
+	s.conversation(m.ConversationID).UpdatedAt = m.CreatedAt
+
+	if err := s.saveFile(messagesFile, s.messages); err != nil {
+		return nil, errors.New("something went wrong, try again later")
+	}
+	if err := s.saveFile(conversationsFile, s.conversations); err != nil {
+		return nil, errors.New("something went wrong, try again later")
+	}
+
+	copied := *m
+	return &copied, nil
+}
+
+func (s *Storage) Messages(ctx context.Context, filter model.MessageFilter) ([]*model.Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var messages []*model.Message
+
+	before := -1
+	if filter.Before != nil {
+		before, _ = strconv.Atoi(*filter.Before)
+	}
+
+	// newest first, ids grow with insertion order
+	for i := len(s.messages) - 1; i >= 0 && len(messages) < filter.Limit; i-- {
+		m := s.messages[i]
+		if m.ConversationID != filter.ConversationID {
+			continue
+		}
+		if id, _ := strconv.Atoi(m.ID); before >= 0 && id >= before {
+			continue
+		}
+		copied := *m
+		messages = append(messages, &copied)
+	}
+
+	return messages, nil
+}
+
+func (s *Storage) LastMessages(ctx context.Context, conversationIDs []string) ([]*model.Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var messages []*model.Message
+
+	wanted := make(map[string]bool, len(conversationIDs))
+	for _, id := range conversationIDs {
+		wanted[id] = true
+	}
+	// newest first, the first message of a conversation is its last one
+	for i := len(s.messages) - 1; i >= 0 && len(wanted) > 0; i-- {
+		m := s.messages[i]
+		if !wanted[m.ConversationID] {
+			continue
+		}
+		delete(wanted, m.ConversationID)
+		copied := *m
+		messages = append(messages, &copied)
+	}
+
+	return messages, nil
+}
+
+func (s *Storage) MarkConversationRead(ctx context.Context, conversationID, userID, messageID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	read, _ := strconv.Atoi(messageID)
+	for _, m := range s.members {
+		if m.ConversationID != conversationID || m.UserID != userID {
+			continue
+		}
+		if m.LastReadMessageID != nil {
+			if last, _ := strconv.Atoi(*m.LastReadMessageID); last >= read {
+				return nil
+			}
+		}
+		m.LastReadMessageID = &messageID
+	}
+
+	if err := s.saveFile(membersFile, s.members); err != nil {
+		return errors.New("something went wrong, try again later")
+	}
+
+	return nil
+}
+
+func (s *Storage) UnreadMessageCounts(ctx context.Context, userID string, conversationIDs []string) ([]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// only the conversations of the user are counted, lastRead holds them
+	lastRead := make(map[string]int, len(conversationIDs))
+	for _, id := range conversationIDs {
+		for _, m := range s.members {
+			if m.ConversationID != id || m.UserID != userID {
+				continue
+			}
+			lastRead[id] = 0
+			if m.LastReadMessageID != nil {
+				lastRead[id], _ = strconv.Atoi(*m.LastReadMessageID)
+			}
+		}
+	}
+	unread := make(map[string]int, len(lastRead))
+	for _, m := range s.messages {
+		read, ok := lastRead[m.ConversationID]
+		if id, _ := strconv.Atoi(m.ID); ok && m.UserID != userID && id > read {
+			unread[m.ConversationID]++
+		}
+	}
+
+	counts := make([]int, len(conversationIDs))
+	for i, id := range conversationIDs {
+		counts[i] = unread[id]
+	}
+	return counts, nil
+}
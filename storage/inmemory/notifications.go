@@ -0,0 +1,94 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"github.com/farid21ola/forum/model"
+	"strconv"
+	"time"
+)
+
+const notificationsFile = "notifications.json"
+
+func (s *Storage) AddNotification(ctx context.Context, n *model.Notification) (*model.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.usersExist(n.UserID); err != nil {
+		return nil, err
+	}
+	if err := s.targetExists(n.PostID, n.CommentID); err != nil {
+		return nil, err
+	}
+	n.ID = nextID(s.notifications, func(n *model.Notification) string { return n.ID })
+	n.Read = false
+	n.CreatedAt = time.Now()
+	s.notifications = append(s.notifications, n)
+
+	if err := s.saveFile(notificationsFile, s.notifications); err != nil {
+		return nil, errors.New("something went wrong, try again later")
+	}
+
+	return n, nil
+}
+
+func (s *Storage) Notifications(ctx context.Context, filter model.NotificationFilter) ([]*model.Notification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var notifications []*model.Notification
+
+	after := -1
+	if filter.After != nil {
+		after, _ = strconv.Atoi(*filter.After)
+	}
+
+	// newest first, ids grow with insertion order
+	for i := len(s.notifications) - 1; i >= 0 && len(notifications) < filter.Limit; i-- {
+		n := s.notifications[i]
+		if n.UserID != filter.UserID || filter.UnreadOnly && n.Read {
+			continue
+		}
+		if id, _ := strconv.Atoi(n.ID); after >= 0 && id >= after {
+			continue
+		}
+		copied := *n
+		notifications = append(notifications, &copied)
+	}
+
+	return notifications, nil
+}
+
+func (s *Storage) MarkNotificationsRead(ctx context.Context, userID string, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	marked := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		marked[id] = true
+	}
+	for _, n := range s.notifications {
+		if n.UserID == userID && (ids == nil || marked[n.ID]) {
+			n.Read = true
+		}
+	}
+
+	if err := s.saveFile(notificationsFile, s.notifications); err != nil {
+		return errors.New("something went wrong, try again later")
+	}
+
+	return nil
+}
+
+func (s *Storage) UnreadNotificationCount(ctx context.Context, userID string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var count int
+
+	for _, n := range s.notifications {
+		if n.UserID == userID && !n.Read {
+			count++
+		}
+	}
+
+	return count, nil
+}
@@ -0,0 +1,158 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/storage"
+	"strconv"
+	"time"
+)
+
+const (
+	pollsFile     = "polls.json"
+	pollVotesFile = "poll_votes.json"
+)
+
+// addPoll adds the poll of a post that is created under the same lock.
+func (s *Storage) addPoll(poll *model.Poll) {
+
+	poll.ID = nextID(s.polls, func(p *model.Poll) string { return p.ID })
+	poll.CreatedAt = time.Now()
+	// option ids are unique across polls like the ids of the other backend
+	optionID := 0
+	for _, p := range s.polls {
+		for _, option := range p.Options {
+			if id, _ := strconv.Atoi(option.ID); id > optionID {
+				optionID = id
+			}
+		}
+	}
+	for _, option := range poll.Options {
+		optionID++
+		option.ID = strconv.Itoa(optionID)
+		votes := 0
+		option.Votes = &votes
+	}
+	s.polls = append(s.polls, poll)
+}
+
+// copyPoll copies the options too, their counts change with votes.
+func copyPoll(p *model.Poll) *model.Poll {
+	copied := *p
+	copied.Options = make([]*model.PollOption, 0, len(p.Options))
+	for _, option := range p.Options {
+		o := *option
+		if option.Votes != nil {
+			votes := *option.Votes
+			o.Votes = &votes
+		}
+		copied.Options = append(copied.Options, &o)
+	}
+	return &copied
+}
+
+func (s *Storage) Poll(ctx context.Context, id string) (*model.Poll, error) {
+	if err := checkID(id); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, p := range s.polls {
+		if p.ID == id {
+			return copyPoll(p), nil
+		}
+	}
+
+	return nil, storage.ErrNotFound
+}
+
+func (s *Storage) PostsPolls(ctx context.Context, postIDs []string) ([]*model.Poll, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	wanted := make(map[string]bool, len(postIDs))
+	for _, id := range postIDs {
+		wanted[id] = true
+	}
+
+	var polls []*model.Poll
+	for _, p := range s.polls {
+		if wanted[p.PostID] {
+			polls = append(polls, copyPoll(p))
+		}
+	}
+
+	return polls, nil
+}
+
+func (s *Storage) AddPollVote(ctx context.Context, vote *model.PollVote) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, v := range s.pollVotes {
+		if v.PollID == vote.PollID && v.UserID == vote.UserID {
+			return false, nil
+		}
+	}
+	var poll *model.Poll
+	for _, p := range s.polls {
+		if p.ID == vote.PollID {
+			poll = p
+		}
+	}
+	if poll == nil {
+		return false, storage.ErrNotFound
+	}
+
+	chosen := make(map[string]bool, len(vote.OptionIDs))
+	for _, id := range vote.OptionIDs {
+		chosen[id] = true
+	}
+	for _, option := range poll.Options {
+		if chosen[option.ID] {
+			*option.Votes++
+		}
+	}
+	s.pollVotes = append(s.pollVotes, vote)
+
+	if err := s.saveFile(pollVotesFile, s.pollVotes); err != nil {
+		return false, errors.New("something went wrong, try again later")
+	}
+	if err := s.saveFile(pollsFile, s.polls); err != nil {
+		return false, errors.New("something went wrong, try again later")
+	}
+
+	return true, nil
+}
+
+func (s *Storage) PollVote(ctx context.Context, pollID, userID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, v := range s.pollVotes {
+		if v.PollID == pollID && v.UserID == userID {
+			return append([]string(nil), v.OptionIDs...), nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *Storage) PollsVotes(ctx context.Context, userID string, pollIDs []string) ([]*model.PollVote, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	wanted := make(map[string]bool, len(pollIDs))
+	for _, id := range pollIDs {
+		wanted[id] = true
+	}
+
+	var votes []*model.PollVote
+	for _, v := range s.pollVotes {
+		if v.UserID == userID && wanted[v.PollID] {
+			votes = append(votes, &model.PollVote{PollID: v.PollID, UserID: v.UserID, OptionIDs: append([]string(nil), v.OptionIDs...)})
+		}
+	}
+
+	return votes, nil
+}
@@ -0,0 +1,108 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"github.com/farid21ola/forum/model"
+	"time"
+)
+
+const reactionsFile = "reactions.json"
+
+func (s *Storage) AddReaction(ctx context.Context, r *model.Reaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.usersExist(r.UserID); err != nil {
+		return err
+	}
+	if err := s.targetExists(r.PostID, r.CommentID); err != nil {
+		return err
+	}
+	if s.reaction(r) >= 0 {
+		return nil
+	}
+	r.ID = nextID(s.reactions, func(r *model.Reaction) string { return r.ID })
+	r.CreatedAt = time.Now()
+	s.reactions = append(s.reactions, r)
+
+	if err := s.saveFile(reactionsFile, s.reactions); err != nil {
+		return errors.New("something went wrong, try again later")
+	}
+
+	return nil
+}
+
+func (s *Storage) RemoveReaction(ctx context.Context, r *model.Reaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.reaction(r)
+	if i < 0 {
+		return nil
+	}
+	s.reactions = append(s.reactions[:i], s.reactions[i+1:]...)
+
+	if err := s.saveFile(reactionsFile, s.reactions); err != nil {
+		return errors.New("something went wrong, try again later")
+	}
+
+	return nil
+}
+
+// reaction returns the index of the reaction the user left on the same target
+// with the same emoji as r, or -1.
+func (s *Storage) reaction(r *model.Reaction) int {
+	for i, saved := range s.reactions {
+		if saved.UserID == r.UserID && saved.Emoji == r.Emoji &&
+			equalIDs(saved.PostID, r.PostID) && equalIDs(saved.CommentID, r.CommentID) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Storage) ReactionCounts(ctx context.Context, target model.ReactionTarget, targetIDs []string, userID string) ([]*model.ReactionCount, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	wanted := make(map[string]bool, len(targetIDs))
+	for _, id := range targetIDs {
+		wanted[id] = true
+	}
+
+	// reactions are kept in insertion order, so the first one of an emoji
+	// decides its place
+	counts := make(map[string]map[string]*model.ReactionCount)
+	byTarget := make(map[string][]*model.ReactionCount)
+	for _, r := range s.reactions {
+		id := r.PostID
+		if target == model.ReactionTargetComment {
+			id = r.CommentID
+		}
+		if id == nil || !wanted[*id] {
+			continue
+		}
+		if counts[*id] == nil {
+			counts[*id] = make(map[string]*model.ReactionCount)
+		}
+		count := counts[*id][r.Emoji]
+		if count == nil {
+			count = &model.ReactionCount{TargetID: *id, Emoji: r.Emoji}
+			counts[*id][r.Emoji] = count
+			byTarget[*id] = append(byTarget[*id], count)
+		}
+		count.Count++
+		if r.UserID == userID {
+			count.ReactedByMe = true
+		}
+	}
+
+	var result []*model.ReactionCount
+	for _, id := range targetIDs {
+		result = append(result, byTarget[id]...)
+		// a target asked for twice is counted once
+		delete(byTarget, id)
+	}
+	return result, nil
+}
@@ -6,20 +6,44 @@ import (
 	"errors"
 	"fmt"
 	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/storage"
 	"github.com/jackc/pgx/v5"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"sync"
+	"time"
 )
 
 type Storage struct {
 	basePath string
 	posts    []*model.Post
 	users    []*model.User
-	mu       sync.RWMutex
+	images   []*model.Image
+	votes    []*model.Vote
+	// notifications are kept in insertion order
+	notifications []*model.Notification
+	mentions      []*model.Mention
+	bookmarks     []*model.Bookmark
+	follows       []*model.Follow
+	blocks        []*model.Block
+	conversations []*model.Conversation
+	members       []*model.ConversationMember
+	messages      []*model.Message
+	polls         []*model.Poll
+	pollVotes     []*model.PollVote
+	reactions     []*model.Reaction
+	// indexes for follow lookups and the feed, rebuilt on start
+	followers   map[string]map[string]bool
+	following   map[string]map[string]bool
+	postsByUser map[string][]*model.Post
+	// login events are not written to disk, they only matter while the process runs
+	loginEvents      []*model.LoginEvent
+	lastLoginEventID int
+	mu               sync.RWMutex
 }
 
 func New(filePath string) *Storage {
@@ -37,30 +61,153 @@ func New(filePath string) *Storage {
 		log.Fatalf("can't initialize inMemory storage %s", err)
 	}
 
-	return &Storage{
+	var images []*model.Image
+	err = readOptionalJSONFile(filepath.Join(filePath, imagesFile), &images)
+	if err != nil {
+		log.Fatalf("can't initialize inMemory storage %s", err)
+	}
+
+	var votes []*model.Vote
+	err = readOptionalJSONFile(filepath.Join(filePath, votesFile), &votes)
+	if err != nil {
+		log.Fatalf("can't initialize inMemory storage %s", err)
+	}
+
+	var notifications []*model.Notification
+	err = readOptionalJSONFile(filepath.Join(filePath, notificationsFile), &notifications)
+	if err != nil {
+		log.Fatalf("can't initialize inMemory storage %s", err)
+	}
+
+	var mentions []*model.Mention
+	err = readOptionalJSONFile(filepath.Join(filePath, mentionsFile), &mentions)
+	if err != nil {
+		log.Fatalf("can't initialize inMemory storage %s", err)
+	}
+
+	var bookmarks []*model.Bookmark
+	err = readOptionalJSONFile(filepath.Join(filePath, bookmarksFile), &bookmarks)
+	if err != nil {
+		log.Fatalf("can't initialize inMemory storage %s", err)
+	}
+
+	var follows []*model.Follow
+	err = readOptionalJSONFile(filepath.Join(filePath, followsFile), &follows)
+	if err != nil {
+		log.Fatalf("can't initialize inMemory storage %s", err)
+	}
+
+	var blocks []*model.Block
+	err = readOptionalJSONFile(filepath.Join(filePath, blocksFile), &blocks)
+	if err != nil {
+		log.Fatalf("can't initialize inMemory storage %s", err)
+	}
+
+	var conversations []*model.Conversation
+	err = readOptionalJSONFile(filepath.Join(filePath, conversationsFile), &conversations)
+	if err != nil {
+		log.Fatalf("can't initialize inMemory storage %s", err)
+	}
+
+	var members []*model.ConversationMember
+	err = readOptionalJSONFile(filepath.Join(filePath, membersFile), &members)
+	if err != nil {
+		log.Fatalf("can't initialize inMemory storage %s", err)
+	}
+
+	var messages []*model.Message
+	err = readOptionalJSONFile(filepath.Join(filePath, messagesFile), &messages)
+	if err != nil {
+		log.Fatalf("can't initialize inMemory storage %s", err)
+	}
+
+	var polls []*model.Poll
+	err = readOptionalJSONFile(filepath.Join(filePath, pollsFile), &polls)
+	if err != nil {
+		log.Fatalf("can't initialize inMemory storage %s", err)
+	}
+
+	var pollVotes []*model.PollVote
+	err = readOptionalJSONFile(filepath.Join(filePath, pollVotesFile), &pollVotes)
+	if err != nil {
+		log.Fatalf("can't initialize inMemory storage %s", err)
+	}
+
+	var reactions []*model.Reaction
+	err = readOptionalJSONFile(filepath.Join(filePath, reactionsFile), &reactions)
+	if err != nil {
+		log.Fatalf("can't initialize inMemory storage %s", err)
+	}
+
+	// posts saved before tags, drafts and comment counts were added
+	loadedAt := time.Now()
+	for _, post := range posts {
+		post.CommentCount = 0
+		for _, comment := range post.Comments {
+			if !comment.Deleted {
+				post.CommentCount++
+			}
+		}
+		if post.LastActivityAt.IsZero() {
+			post.LastActivityAt = loadedAt
+		}
+		if post.Tags == nil {
+			post.Tags = []string{}
+		}
+		if post.Status == "" {
+			post.Status = model.PostStatusPublished
+		}
+		// the order of the posts published before is kept by their ids
+		if post.IsPublished() && post.PublishedAt == nil {
+			post.PublishedAt = &loadedAt
+		}
+	}
+
+	s := &Storage{
 		basePath: filePath,
 		posts:    posts,
 		users:    users,
+		images:   images,
+		votes:    votes,
+
+		notifications: notifications,
+		mentions:      mentions,
+		bookmarks:     bookmarks,
+		follows:       follows,
+		blocks:        blocks,
+		conversations: conversations,
+		members:       members,
+		messages:      messages,
+		polls:         polls,
+		pollVotes:     pollVotes,
+		reactions:     reactions,
+
+		followers:   make(map[string]map[string]bool),
+		following:   make(map[string]map[string]bool),
+		postsByUser: make(map[string][]*model.Post),
+	}
+	for _, f := range follows {
+		s.indexFollow(f)
+	}
+	for _, post := range posts {
+		s.postsByUser[post.UserID] = append(s.postsByUser[post.UserID], post)
 	}
+
+	return s
 }
 
 func (s *Storage) Posts(ctx context.Context, limit, offset *int) ([]*model.Post, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	var posts []*model.Post
-	for i, post := range s.posts {
-		if i+*offset > len(posts) {
-			break
-		}
-		if i >= *limit-1 {
-			break
-		}
-		posts = append(posts, post)
-	}
-	return posts, nil
+	posts := s.publishedPosts()
+	sort.SliceStable(posts, func(i, j int) bool { return publishedBefore(posts[i], posts[j]) })
+	return page(posts, limit, offset), nil
 }
 
 func (s *Storage) Post(ctx context.Context, id string) (*model.Post, error) {
+	if err := checkID(id); err != nil {
+		return nil, err
+	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	for _, p := range s.posts {
@@ -68,25 +215,40 @@ func (s *Storage) Post(ctx context.Context, id string) (*model.Post, error) {
 			return p, nil
 		}
 	}
-	return nil, errors.New("post with id not exists")
+	return nil, storage.ErrNotFound
+}
+
+func (s *Storage) PostsByIDs(ctx context.Context, ids []string) ([]*model.Post, error) {
+	if err := checkIDs(ids); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var posts []*model.Post
+
+	for _, id := range ids {
+		if post := s.post(id); post != nil {
+			posts = append(posts, post)
+		}
+	}
+
+	return posts, nil
 }
 
 func (s *Storage) User(ctx context.Context, id string) (*model.User, error) {
+	if err := checkID(id); err != nil {
+		return nil, err
+	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	user := new(model.User)
 
 	for _, u := range s.users {
 		if u.ID == id {
-			user = u
+			return u, nil
 		}
 	}
 
-	if user == nil {
-		return nil, errors.New("user with id not exists")
-	}
-
-	return user, nil
+	return nil, storage.ErrNotFound
 }
 
 func (s *Storage) Users(ctx context.Context) ([]*model.User, error) {
@@ -96,22 +258,59 @@ func (s *Storage) Users(ctx context.Context) ([]*model.User, error) {
 }
 
 func (s *Storage) Comments(ctx context.Context, postId string, limit, offset *int) ([]*model.Comment, error) {
+	if err := checkID(postId); err != nil {
+		return nil, err
+	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	for _, post := range s.posts {
-		if post.ID == postId {
-			return post.Comments, nil
+	if post := s.post(postId); post != nil {
+		return page(post.Comments, limit, offset), nil
+	}
+	return nil, nil
+}
+
+// page returns the items LIMIT and OFFSET would, nil means no limit or no
+// offset.
+func page[T any](items []T, limit, offset *int) []T {
+	if offset != nil && *offset > 0 {
+		if *offset >= len(items) {
+			return nil
 		}
+		items = items[*offset:]
 	}
-	return nil, errors.New("post with id not exists")
+	if limit != nil && *limit >= 0 && *limit < len(items) {
+		items = items[:*limit]
+	}
+	return items
+}
+
+// checkID rejects the ids postgres can't store, so both storages fail the
+// same way.
+func checkID(id string) error {
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return storage.ErrInvalidID
+	}
+	return nil
+}
+
+func checkIDs(ids []string) error {
+	for _, id := range ids {
+		if err := checkID(id); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (s *Storage) UsersPost(ctx context.Context, userId string) ([]*model.Post, error) {
+	if err := checkID(userId); err != nil {
+		return nil, err
+	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	var p []*model.Post
 
-	for _, post := range s.posts {
+	for _, post := range s.publishedPosts() {
 		if post.UserID == userId {
 			p = append(p, post)
 		}
@@ -121,32 +320,140 @@ func (s *Storage) UsersPost(ctx context.Context, userId string) ([]*model.Post,
 }
 
 func (s *Storage) UserByID(ctx context.Context, id string) (*model.User, error) {
+	if err := checkID(id); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if user := s.user(id); user != nil {
+		return user, nil
+	}
+	return nil, storage.ErrNotFound
+}
+
+func (s *Storage) user(id string) *model.User {
 	for _, user := range s.users {
 		if user.ID == id {
-			return user, nil
+			return user
+		}
+	}
+	return nil
+}
+
+// usersExist returns storage.ErrNotFound when one of the ids is not a user,
+// like the foreign keys of the other backend.
+func (s *Storage) usersExist(ids ...string) error {
+	for _, id := range ids {
+		if s.user(id) == nil {
+			return storage.ErrNotFound
+		}
+	}
+	return nil
+}
+
+// targetExists checks the post or the comment a bookmark, a reaction or a
+// notification points to.
+func (s *Storage) targetExists(postID, commentID *string) error {
+	if postID != nil && s.post(*postID) == nil || commentID != nil && s.comment(*commentID) == nil {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Storage) UsersByIDs(ctx context.Context, ids []string) ([]*model.User, error) {
+	if err := checkIDs(ids); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var users []*model.User
+
+	for _, id := range ids {
+		for _, user := range s.users {
+			if user.ID == id {
+				users = append(users, user)
+				break
+			}
 		}
 	}
-	return nil, errors.New("user with id not exists")
+
+	return users, nil
+}
+
+func (s *Storage) UserStats(ctx context.Context, userID string) (*model.UserStats, error) {
+	if err := checkID(userID); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.userStats(userID), nil
+}
+
+func (s *Storage) UsersStats(ctx context.Context, userIDs []string) ([]*model.UserStats, error) {
+	if err := checkIDs(userIDs); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := make([]*model.UserStats, 0, len(userIDs))
+	for _, id := range userIDs {
+		stats = append(stats, s.userStats(id))
+	}
+	return stats, nil
+}
+
+func (s *Storage) userStats(userID string) *model.UserStats {
+	stats := model.UserStats{UserID: userID}
+
+	for _, post := range s.posts {
+		if post.UserID == userID && post.IsPublished() {
+			stats.PostCount++
+		}
+		for _, comment := range post.Comments {
+			if comment.UserID == userID {
+				stats.CommentCount++
+			} else if post.UserID == userID {
+				stats.Karma++
+			}
+		}
+	}
+	stats.FollowerCount = len(s.followers[userID])
+	stats.FollowingCount = len(s.following[userID])
+
+	return &stats
 }
 
 func (s *Storage) UserByUsername(ctx context.Context, username string) (*model.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	for _, user := range s.users {
 		if user.Username == username {
 			return user, nil
 		}
 	}
-	return nil, errors.New("user with username not exists")
+	return nil, storage.ErrNotFound
 }
 
 func (s *Storage) CreateUser(ctx context.Context, tx pgx.Tx, user *model.User) (*model.User, error) {
 	s.mu.Lock()
 
+	for _, u := range s.users {
+		if u.Username == user.Username {
+			s.mu.Unlock()
+			return nil, storage.ErrConflict
+		}
+	}
+
 	if len(s.users) == 0 {
 		user.ID = "1"
 	} else {
 		id, _ := strconv.Atoi(s.users[len(s.users)-1].ID)
 		user.ID = strconv.Itoa(id + 1)
 	}
+	user.CreatedAt = time.Now()
+	user.UpdateAt = user.CreatedAt
 
 	s.users = append(s.users, user)
 
@@ -157,7 +464,59 @@ func (s *Storage) CreateUser(ctx context.Context, tx pgx.Tx, user *model.User) (
 
 	return user, nil
 }
+func (s *Storage) UpdateUser(ctx context.Context, upd *model.User) (*model.User, error) {
+	if err := checkID(upd.ID); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	for _, user := range s.users {
+		if user.ID == upd.ID {
+			user.FirstName = upd.FirstName
+			user.LastName = upd.LastName
+			user.Bio = upd.Bio
+			user.Website = upd.Website
+			user.AvatarURL = upd.AvatarURL
+			user.AvatarKeys = upd.AvatarKeys
+			user.UpdateAt = time.Now()
+
+			err := s.save(true)
+			if err != nil {
+				return nil, errors.New("something went wrong, try again later")
+			}
+
+			return user, nil
+		}
+	}
+	s.mu.Unlock()
+	return nil, storage.ErrNotFound
+}
+
+func (s *Storage) UpdatePassword(ctx context.Context, userID, hash string) error {
+	if err := checkID(userID); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	for _, user := range s.users {
+		if user.ID == userID {
+			user.Password = hash
+			user.UpdateAt = time.Now()
+
+			err := s.save(true)
+			if err != nil {
+				return errors.New("something went wrong, try again later")
+			}
+
+			return nil
+		}
+	}
+	s.mu.Unlock()
+	return storage.ErrNotFound
+}
+
 func (s *Storage) UpdatePost(ctx context.Context, upd *model.UpdatePost) (*model.Post, error) {
+	if err := checkID(upd.PostID); err != nil {
+		return nil, err
+	}
 	s.mu.Lock()
 	for i, post := range s.posts {
 		if post.ID == upd.PostID {
@@ -171,20 +530,21 @@ func (s *Storage) UpdatePost(ctx context.Context, upd *model.UpdatePost) (*model
 			return post, nil
 		}
 	}
-	return nil, errors.New("post with id dont exist")
+	s.mu.Unlock()
+	return nil, storage.ErrNotFound
 }
 func (s *Storage) AddComment(ctx context.Context, comment *model.Comment) (*model.Comment, error) {
+	if err := checkID(comment.PostID); err != nil {
+		return nil, err
+	}
 	s.mu.Lock()
 	for i, post := range s.posts {
 		if post.ID == comment.PostID {
-			if len(s.posts[i].Comments) == 0 {
-				comment.ID = "1"
-			} else {
-				id, _ := strconv.Atoi(s.posts[i].Comments[len(s.posts[i].Comments)-1].ID)
-				comment.ID = strconv.Itoa(id + 1)
-			}
+			comment.ID = s.nextCommentID()
 
 			s.posts[i].Comments = append(s.posts[i].Comments, comment)
+			s.posts[i].CommentCount++
+			s.posts[i].LastActivityAt = time.Now()
 			err := s.save(false)
 			if err != nil {
 				return nil, errors.New("something went wrong, try again later")
@@ -192,10 +552,16 @@ func (s *Storage) AddComment(ctx context.Context, comment *model.Comment) (*mode
 			return comment, nil
 		}
 	}
-	return nil, errors.New("post with id dont exist")
+	s.mu.Unlock()
+	return nil, storage.ErrNotFound
 }
-func (s *Storage) CreatePost(ctx context.Context, post *model.Post) (*model.Post, error) {
+func (s *Storage) CreatePost(ctx context.Context, post *model.Post, images []*model.Image, poll *model.Poll) (*model.Post, error) {
 	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.usersExist(post.UserID); err != nil {
+		return nil, err
+	}
 
 	if len(s.posts) == 0 {
 		post.ID = "1"
@@ -205,11 +571,41 @@ func (s *Storage) CreatePost(ctx context.Context, post *model.Post) (*model.Post
 	}
 
 	post.CommentsEnabled = true
+	if post.Tags == nil {
+		post.Tags = []string{}
+	}
+	if post.Status == "" {
+		post.Status = model.PostStatusPublished
+	}
+	now := time.Now()
+	post.LastActivityAt = now
+	if post.IsPublished() {
+		post.PublishedAt = &now
+	}
 	s.posts = append(s.posts, post)
-	err := s.save(false)
-	if err != nil {
+	s.postsByUser[post.UserID] = append(s.postsByUser[post.UserID], post)
+	for _, image := range images {
+		image.PostID = post.ID
+		s.addPostImage(image)
+	}
+	if poll != nil {
+		poll.PostID = post.ID
+		s.addPoll(poll)
+	}
+
+	if err := s.saveFile("posts.json", s.posts); err != nil {
 		return nil, errors.New("something went wrong, try again later")
 	}
+	if len(images) > 0 {
+		if err := s.saveFile(imagesFile, s.images); err != nil {
+			return nil, errors.New("something went wrong, try again later")
+		}
+	}
+	if poll != nil {
+		if err := s.saveFile(pollsFile, s.polls); err != nil {
+			return nil, errors.New("something went wrong, try again later")
+		}
+	}
 	return post, nil
 }
 
@@ -247,6 +643,29 @@ func (s *Storage) save(isUser bool) error {
 	return nil
 }
 
+// saveFile writes v as json to a file in the storage directory, the caller
+// must hold the lock.
+func (s *Storage) saveFile(name string, v interface{}) error {
+	file, err := os.Create(filepath.Join(s.basePath, name))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// readOptionalJSONFile is readJSONFile for files added after the storage
+// directory was created, a missing file leaves dataType untouched.
+func readOptionalJSONFile(filePath string, dataType interface{}) error {
+	if _, err := os.Stat(filePath); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return readJSONFile(filePath, dataType)
+}
+
 func readJSONFile(filePath string, dataType interface{}) error {
 	file, err := os.Open(filePath)
 	if err != nil {
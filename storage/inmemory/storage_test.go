@@ -0,0 +1,51 @@
+package inmemory
+
+import (
+	"context"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/storage"
+	"github.com/farid21ola/forum/storage/storagetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newStorage(t *testing.T) *Storage {
+	dir := t.TempDir()
+	for _, file := range []string{"posts.json", "users.json"} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte("[]"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return New(dir)
+}
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return newStorage(t)
+	})
+}
+
+func TestStorage_PruneLoginEvents(t *testing.T) {
+	ctx := context.Background()
+	s := newStorage(t)
+
+	old := &model.LoginEvent{Username: "alice", IP: "10.0.0.1"}
+	require.NoError(t, s.AddLoginEvent(ctx, old))
+	since := time.Now()
+	time.Sleep(time.Millisecond)
+	recent := &model.LoginEvent{Username: "alice", IP: "10.0.0.1"}
+	require.NoError(t, s.AddLoginEvent(ctx, recent))
+
+	failures, err := s.LoginFailures(ctx, "alice", "10.0.0.1", since)
+	require.NoError(t, err)
+	assert.Equal(t, 1, failures.ByUsername)
+	assert.Equal(t, []*model.LoginEvent{recent}, s.loginEvents, "events before the window are dropped")
+
+	next := &model.LoginEvent{Username: "alice", IP: "10.0.0.1"}
+	require.NoError(t, s.AddLoginEvent(ctx, next))
+	assert.Equal(t, "3", next.ID, "ids are not reused after pruning")
+}
@@ -0,0 +1,55 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/storage"
+)
+
+const votesFile = "votes.json"
+
+func (s *Storage) VotePost(ctx context.Context, vote *model.Vote) (int, error) {
+	if err := checkID(vote.PostID); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var post *model.Post
+	for _, p := range s.posts {
+		if p.ID == vote.PostID {
+			post = p
+		}
+	}
+	if post == nil {
+		return 0, storage.ErrNotFound
+	}
+
+	votes := s.votes[:0:0]
+	for _, v := range s.votes {
+		if v.PostID != vote.PostID || v.UserID != vote.UserID {
+			votes = append(votes, v)
+		}
+	}
+	if vote.Value != 0 {
+		votes = append(votes, vote)
+	}
+	s.votes = votes
+
+	post.Score = 0
+	for _, v := range s.votes {
+		if v.PostID == post.ID {
+			post.Score += v.Value
+		}
+	}
+
+	if err := s.saveFile(votesFile, s.votes); err != nil {
+		return 0, errors.New("something went wrong, try again later")
+	}
+	if err := s.saveFile("posts.json", s.posts); err != nil {
+		return 0, errors.New("something went wrong, try again later")
+	}
+
+	return post.Score, nil
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"github.com/farid21ola/forum/model"
+)
+
+func (s *Storage) AddBlock(ctx context.Context, b *model.Block) error {
+	q := `INSERT INTO "blocks" (user_id, target_id, kind) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
+
+	_, err := s.DB.Exec(ctx, q, b.UserID, b.TargetID, string(b.Kind))
+	return mapError(err)
+}
+
+func (s *Storage) RemoveBlock(ctx context.Context, b *model.Block) error {
+	q := `DELETE FROM "blocks" WHERE user_id = $1 AND target_id = $2 AND kind = $3`
+
+	_, err := s.DB.Exec(ctx, q, b.UserID, b.TargetID, string(b.Kind))
+	return mapError(err)
+}
+
+func (s *Storage) Blocks(ctx context.Context, userID string) ([]*model.Block, error) {
+	var blocks []*model.Block
+
+	q := `SELECT id, user_id, target_id, kind, created_at FROM "blocks" WHERE user_id = $1 ORDER BY id DESC`
+
+	rows, err := s.DB.Query(ctx, q, userID)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b model.Block
+		var kind string
+		if err = rows.Scan(&b.ID, &b.UserID, &b.TargetID, &kind, &b.CreatedAt); err != nil {
+			return nil, err
+		}
+		b.Kind = model.BlockKind(kind)
+		blocks = append(blocks, &b)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, mapError(err)
+	}
+
+	return blocks, nil
+}
+
+func (s *Storage) BlockingUserIDs(ctx context.Context, targetID string, userIDs []string) ([]string, error) {
+	var ids []string
+
+	q := `SELECT user_id FROM "blocks" WHERE target_id = $1 AND kind = 'BLOCK' AND user_id = ANY($2)`
+
+	rows, err := s.DB.Query(ctx, q, targetID, userIDs)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, mapError(err)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"github.com/farid21ola/forum/model"
+)
+
+func (s *Storage) AddBookmark(ctx context.Context, b *model.Bookmark) error {
+	q := `INSERT INTO "bookmarks" (user_id, post_id, comment_id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
+
+	_, err := s.DB.Exec(ctx, q, b.UserID, b.PostID, b.CommentID)
+	return mapError(err)
+}
+
+func (s *Storage) RemoveBookmark(ctx context.Context, b *model.Bookmark) error {
+	q := `DELETE FROM "bookmarks" WHERE user_id = $1
+		AND post_id IS NOT DISTINCT FROM $2::bigint AND comment_id IS NOT DISTINCT FROM $3::bigint`
+
+	_, err := s.DB.Exec(ctx, q, b.UserID, b.PostID, b.CommentID)
+	return mapError(err)
+}
+
+func (s *Storage) Bookmarks(ctx context.Context, filter model.BookmarkFilter) ([]*model.Bookmark, error) {
+	var bookmarks []*model.Bookmark
+
+	// the cursor is validated by the domain, a malformed one matches nothing
+	after, ok := parseCursor(filter.After)
+	if !ok {
+		return nil, nil
+	}
+	var posts *bool
+	if filter.Type != nil {
+		isPost := *filter.Type == model.SavedTypePost
+		posts = &isPost
+	}
+
+	q := `SELECT id, user_id, post_id, comment_id, created_at FROM "bookmarks"
+		WHERE user_id = $1 AND ($2::boolean IS NULL OR (post_id IS NOT NULL) = $2)
+		AND ($3::bigint IS NULL OR id < $3)
+		ORDER BY id DESC LIMIT $4`
+
+	rows, err := s.DB.Query(ctx, q, filter.UserID, posts, after, filter.Limit)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b model.Bookmark
+		if err = rows.Scan(&b.ID, &b.UserID, &b.PostID, &b.CommentID, &b.CreatedAt); err != nil {
+			return nil, err
+		}
+		bookmarks = append(bookmarks, &b)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, mapError(err)
+	}
+
+	return bookmarks, nil
+}
+
+func (s *Storage) SavedPostIDs(ctx context.Context, userID string, postIDs []string) ([]string, error) {
+	var saved []string
+
+	q := `SELECT post_id FROM "bookmarks" WHERE user_id = $1 AND post_id = ANY($2)`
+
+	rows, err := s.DB.Query(ctx, q, userID, postIDs)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		saved = append(saved, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, mapError(err)
+	}
+
+	return saved, nil
+}
@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/storage"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+func (s *Storage) UserDrafts(ctx context.Context, userID string) ([]*model.Post, error) {
+	q := `SELECT ` + postColumns + ` FROM "posts" WHERE user_id = $1 AND status <> 'PUBLISHED' ORDER BY id DESC`
+
+	return s.queryPosts(ctx, q, userID)
+}
+
+func (s *Storage) UpdateDraft(ctx context.Context, post *model.Post) (*model.Post, error) {
+	var updated model.Post
+	q := `UPDATE "posts" SET title = $1, content = $2, tags = $3, status = $4, publish_at = $5
+		WHERE "id" = $6 AND status <> 'PUBLISHED' RETURNING ` + postColumns
+
+	err := scanPost(s.DB.QueryRow(ctx, q, post.Title, post.Content, post.Tags, string(post.Status), post.PublishAt, post.ID), &updated)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, s.postExists(ctx, post.ID)
+	}
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	return &updated, nil
+}
+
+func (s *Storage) PublishPost(ctx context.Context, id string) (*model.Post, error) {
+	var post model.Post
+	q := `UPDATE "posts" SET status = 'PUBLISHED', publish_at = NULL, published_at = NOW(), last_activity_at = NOW()
+		WHERE "id" = $1 AND status <> 'PUBLISHED' RETURNING ` + postColumns
+
+	err := scanPost(s.DB.QueryRow(ctx, q, id), &post)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, s.postExists(ctx, id)
+	}
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	return &post, nil
+}
+
+// postExists tells a published post, for which drafts methods return nil,
+// from a missing one.
+func (s *Storage) postExists(ctx context.Context, id string) error {
+	var exists bool
+
+	q := `SELECT EXISTS (SELECT 1 FROM "posts" WHERE id = $1)`
+	if err := s.DB.QueryRow(ctx, q, id).Scan(&exists); err != nil {
+		return mapError(err)
+	}
+	if !exists {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+// PublishDuePosts locks due posts with SKIP LOCKED, so instances running the
+// scheduler at the same time pick different posts, and a post that has been
+// published or turned back into a draft no longer matches once the
+// transaction commits.
+func (s *Storage) PublishDuePosts(ctx context.Context, now time.Time, limit int, publishable func(*model.Post) bool) ([]*model.Post, error) {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	q := `SELECT ` + postColumns + ` FROM "posts" WHERE status = 'SCHEDULED' AND publish_at <= $1
+		ORDER BY publish_at LIMIT $2 FOR UPDATE SKIP LOCKED`
+	due, err := scanPosts(tx.Query(ctx, q, now, limit))
+	if err != nil {
+		return nil, err
+	}
+
+	var ids, invalid []string
+	for _, post := range due {
+		if publishable(post) {
+			ids = append(ids, post.ID)
+		} else {
+			invalid = append(invalid, post.ID)
+		}
+	}
+
+	if len(invalid) > 0 {
+		q = `UPDATE "posts" SET status = 'DRAFT', publish_at = NULL WHERE id = ANY($1::bigint[])`
+		if _, err = tx.Exec(ctx, q, invalid); err != nil {
+			return nil, mapError(err)
+		}
+	}
+
+	var published []*model.Post
+	if len(ids) > 0 {
+		q = `UPDATE "posts" SET status = 'PUBLISHED', publish_at = NULL, published_at = $2, last_activity_at = $2
+			WHERE id = ANY($1::bigint[]) RETURNING ` + postColumns
+		if published, err = scanPosts(tx.Query(ctx, q, ids, now)); err != nil {
+			return nil, err
+		}
+	}
+
+	return published, mapError(tx.Commit(ctx))
+}
+
+func (s *Storage) queryPosts(ctx context.Context, q string, args ...any) ([]*model.Post, error) {
+	return scanPosts(s.DB.Query(ctx, q, args...))
+}
+
+func scanPosts(rows pgx.Rows, err error) ([]*model.Post, error) {
+	var posts []*model.Post
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var post model.Post
+		if err = scanPost(rows, &post); err != nil {
+			return nil, err
+		}
+		posts = append(posts, &post)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, mapError(err)
+	}
+
+	return posts, nil
+}
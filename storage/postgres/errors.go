@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"errors"
+	"github.com/farid21ola/forum/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SQLSTATE codes mapped onto storage errors.
+const (
+	uniqueViolation           = "23505"
+	foreignKeyViolation       = "23503"
+	invalidTextRepresentation = "22P02"
+	numericValueOutOfRange    = "22003"
+)
+
+// mapError turns pgx errors into the errors of the storage package, other
+// errors are returned as they are.
+func mapError(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return storage.ErrNotFound
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	switch pgErr.Code {
+	case uniqueViolation:
+		return storage.ErrConflict
+	case foreignKeyViolation:
+		return storage.ErrNotFound
+	case invalidTextRepresentation, numericValueOutOfRange:
+		return storage.ErrInvalidID
+	}
+	return err
+}
@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"context"
+	"github.com/farid21ola/forum/model"
+	"strconv"
+	"time"
+)
+
+func (s *Storage) Follow(ctx context.Context, f *model.Follow) error {
+	q := `INSERT INTO "follows" (follower_id, followee_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+
+	_, err := s.DB.Exec(ctx, q, f.FollowerID, f.FolloweeID)
+	return mapError(err)
+}
+
+func (s *Storage) Unfollow(ctx context.Context, f *model.Follow) error {
+	q := `DELETE FROM "follows" WHERE follower_id = $1 AND followee_id = $2`
+
+	_, err := s.DB.Exec(ctx, q, f.FollowerID, f.FolloweeID)
+	return mapError(err)
+}
+
+func (s *Storage) Follows(ctx context.Context, filter model.FollowFilter) ([]*model.Follow, error) {
+	var follows []*model.Follow
+
+	// the cursor is validated by the domain, a malformed one matches nothing
+	after, ok := parseCursor(filter.After)
+	if !ok {
+		return nil, nil
+	}
+
+	q := `SELECT id, follower_id, followee_id, created_at FROM "follows"
+		WHERE ($1::bigint IS NULL OR follower_id = $1) AND ($2::bigint IS NULL OR followee_id = $2)
+		AND ($3::bigint IS NULL OR id < $3)
+		ORDER BY id DESC LIMIT $4`
+
+	rows, err := s.DB.Query(ctx, q, nullableID(filter.FollowerID), nullableID(filter.FolloweeID), after, filter.Limit)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var f model.Follow
+		if err = rows.Scan(&f.ID, &f.FollowerID, &f.FolloweeID, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		follows = append(follows, &f)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, mapError(err)
+	}
+
+	return follows, nil
+}
+
+func (s *Storage) FollowCount(ctx context.Context, filter model.FollowFilter) (int, error) {
+	var count int
+
+	q := `SELECT COUNT(*) FROM "follows"
+		WHERE ($1::bigint IS NULL OR follower_id = $1) AND ($2::bigint IS NULL OR followee_id = $2)`
+
+	err := s.DB.QueryRow(ctx, q, nullableID(filter.FollowerID), nullableID(filter.FolloweeID)).Scan(&count)
+	return count, mapError(err)
+}
+
+// AddFollowerNotifications inserts the notifications of all followers with
+// one statement, so publishing a post doesn't take a round trip per follower.
+func (s *Storage) AddFollowerNotifications(ctx context.Context, n *model.Notification, skip []string) ([]*model.Notification, error) {
+	var notifications []*model.Notification
+
+	q := `INSERT INTO "notifications" (user_id, kind, actor_id, post_id, comment_id, message)
+		SELECT f.follower_id, $2, $1, $3, $4, $5 FROM "follows" f
+		WHERE f.followee_id = $1 AND NOT f.follower_id = ANY($6::bigint[])
+		AND NOT EXISTS (SELECT 1 FROM "blocks" b
+			WHERE b.user_id = f.follower_id AND b.target_id = $1 AND b.kind = 'BLOCK')
+		ORDER BY f.follower_id
+		RETURNING ` + notificationColumns
+
+	if skip == nil {
+		skip = []string{}
+	}
+	rows, err := s.DB.Query(ctx, q, n.ActorID, string(n.Kind), n.PostID, n.CommentID, n.Message, skip)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var added model.Notification
+		var kind string
+		err = rows.Scan(&added.ID, &added.UserID, &kind, &added.ActorID, &added.PostID, &added.CommentID, &added.Message, &added.Read, &added.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		added.Kind = model.NotificationKind(kind)
+		notifications = append(notifications, &added)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, mapError(err)
+	}
+
+	return notifications, nil
+}
+
+// Feed joins the followed users with their posts when the feed is read, the
+// posts are not copied to the followers.
+func (s *Storage) Feed(ctx context.Context, filter model.FeedFilter) ([]*model.Post, error) {
+	var afterTime *time.Time
+	var afterID *int64
+	if filter.After != nil {
+		at, id, ok := model.ParsePostCursor(*filter.After)
+		if !ok {
+			return nil, nil
+		}
+		afterTime, afterID = &at, &id
+	}
+
+	q := `SELECT ` + postColumns + ` FROM "posts"
+		WHERE user_id IN (SELECT followee_id FROM "follows" WHERE follower_id = $1)
+		AND status = 'PUBLISHED' AND ($2::timestamptz IS NULL OR (published_at, id) < ($2, $3::bigint))
+		ORDER BY published_at DESC, id DESC LIMIT $4`
+
+	return s.queryPosts(ctx, q, filter.UserID, afterTime, afterID, filter.Limit)
+}
+
+// parseCursor converts the id cursor of a page, ok is false when it isn't a
+// number.
+func parseCursor(cursor *string) (after *int64, ok bool) {
+	if cursor == nil {
+		return nil, true
+	}
+	id, err := strconv.ParseInt(*cursor, 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	return &id, true
+}
+
+func nullableID(id string) *string {
+	if id == "" {
+		return nil
+	}
+	return &id
+}
@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"context"
+	"github.com/farid21ola/forum/model"
+	"github.com/jackc/pgx/v5"
+)
+
+const imageColumns = `id, post_id, user_id, key, thumbnail_key, content_type, width, height, size, created_at`
+
+// addPostImage inserts an image of a post that is created in the same
+// transaction.
+func addPostImage(ctx context.Context, tx pgx.Tx, image *model.Image) error {
+	q := `INSERT INTO "post_images" (post_id, user_id, key, thumbnail_key, content_type, width, height, size)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at`
+
+	err := tx.QueryRow(ctx, q, image.PostID, image.UserID, image.Key, image.ThumbnailKey,
+		image.ContentType, image.Width, image.Height, image.Size,
+	).Scan(&image.ID, &image.CreatedAt)
+	return mapError(err)
+}
+
+func (s *Storage) PostImages(ctx context.Context, postID string) ([]*model.Image, error) {
+	return s.queryImages(ctx, `post_id = $1`, postID)
+}
+
+func (s *Storage) PostsImages(ctx context.Context, postIDs []string) ([]*model.Image, error) {
+	return s.queryImages(ctx, `post_id = ANY($1::bigint[])`, postIDs)
+}
+
+func (s *Storage) queryImages(ctx context.Context, where string, arg any) ([]*model.Image, error) {
+	var images []*model.Image
+
+	q := `SELECT ` + imageColumns + ` FROM "post_images" WHERE ` + where + ` ORDER BY id`
+
+	rows, err := s.DB.Query(ctx, q, arg)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var image model.Image
+		err = rows.Scan(&image.ID, &image.PostID, &image.UserID, &image.Key, &image.ThumbnailKey,
+			&image.ContentType, &image.Width, &image.Height, &image.Size, &image.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, &image)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, mapError(err)
+	}
+
+	return images, nil
+}
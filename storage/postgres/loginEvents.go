@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/farid21ola/forum/model"
+	"time"
+)
+
+func (s *Storage) AddLoginEvent(ctx context.Context, event *model.LoginEvent) error {
+	q := `INSERT INTO "login_events" (user_id, username, ip, success) VALUES ($1, $2, $3, $4) RETURNING id, created_at`
+
+	return s.DB.QueryRow(ctx, q, event.UserID, event.Username, event.IP, event.Success).Scan(&event.ID, &event.CreatedAt)
+}
+
+// pruneLoginEventsEvery is how many login checks pass between deletions of
+// the events before the window.
+const pruneLoginEventsEvery = 100
+
+func (s *Storage) LoginFailures(ctx context.Context, username, ip string, since time.Time) (*model.LoginFailures, error) {
+	var failures model.LoginFailures
+	var err error
+
+	// the window of the login policy doesn't change while the process runs,
+	// older events are never counted again
+	if s.loginChecks.Add(1)%pruneLoginEventsEvery == 0 {
+		if _, err = s.DB.Exec(ctx, `DELETE FROM "login_events" WHERE created_at <= $1`, since); err != nil {
+			return nil, mapError(err)
+		}
+	}
+
+	failures.ByUsername, failures.LastByUsername, err = s.loginFailuresBy(ctx, "username", username, since, true)
+	if err != nil {
+		return nil, err
+	}
+	// a successful login to any account doesn't clear the failures of the
+	// ip, otherwise logging into an own account between guesses would lift
+	// the limit
+	failures.ByIP, failures.LastByIP, err = s.loginFailuresBy(ctx, "ip", ip, since, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &failures, nil
+}
+
+// loginFailuresBy counts failures for the value of field that happened after
+// since and, when resets is set, after the last successful login with the
+// same value.
+func (s *Storage) loginFailuresBy(ctx context.Context, field, value string, since time.Time, resets bool) (int, time.Time, error) {
+	var count int
+	var last time.Time
+
+	q := fmt.Sprintf(`SELECT count(*), COALESCE(max(created_at), $2) FROM "login_events"
+		WHERE %[1]s = $1 AND NOT success AND created_at > $2`, field)
+	if resets {
+		q += fmt.Sprintf(`
+		AND created_at > COALESCE((SELECT max(created_at) FROM "login_events" WHERE %[1]s = $1 AND success), $2)`, field)
+	}
+
+	if err := s.DB.QueryRow(ctx, q, value, since).Scan(&count, &last); err != nil {
+		return 0, time.Time{}, err
+	}
+
+	return count, last, nil
+}
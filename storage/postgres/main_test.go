@@ -0,0 +1,18 @@
+package postgres
+
+import (
+	"github.com/farid21ola/forum/storage/postgres/pgtest"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Main(m))
+}
+
+// newTestStorage returns a storage on an empty test database, the tables are
+// truncated before every test.
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+	return New(pgtest.Pool(t))
+}
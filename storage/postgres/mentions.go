@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"github.com/farid21ola/forum/model"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Storage) AddMentions(ctx context.Context, mentions []*model.Mention) error {
+	batch := &pgx.Batch{}
+	for _, m := range mentions {
+		batch.Queue(`INSERT INTO "mentions" (post_id, comment_id, user_id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`,
+			m.PostID, m.CommentID, m.UserID)
+	}
+
+	return mapError(s.DB.SendBatch(ctx, batch).Close())
+}
+
+func (s *Storage) RemoveCommentMentions(ctx context.Context, commentID string) error {
+	_, err := s.DB.Exec(ctx, `DELETE FROM "mentions" WHERE comment_id = $1`, commentID)
+	return mapError(err)
+}
+
+func (s *Storage) PostsMentions(ctx context.Context, postIDs []string) ([]*model.Mention, error) {
+	q := `SELECT m.post_id, m.comment_id, m.user_id FROM "mentions" m JOIN "users" u ON u.id = m.user_id
+		WHERE m.post_id = ANY($1::bigint[]) AND m.comment_id IS NULL ORDER BY m.post_id, u.username`
+
+	return s.queryMentions(ctx, q, postIDs)
+}
+
+func (s *Storage) CommentsMentions(ctx context.Context, commentIDs []string) ([]*model.Mention, error) {
+	q := `SELECT m.post_id, m.comment_id, m.user_id FROM "mentions" m JOIN "users" u ON u.id = m.user_id
+		WHERE m.comment_id = ANY($1::bigint[]) ORDER BY m.comment_id, u.username`
+
+	return s.queryMentions(ctx, q, commentIDs)
+}
+
+func (s *Storage) queryMentions(ctx context.Context, q string, args ...interface{}) ([]*model.Mention, error) {
+	var mentions []*model.Mention
+
+	rows, err := s.DB.Query(ctx, q, args...)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m model.Mention
+		if err = rows.Scan(&m.PostID, &m.CommentID, &m.UserID); err != nil {
+			return nil, err
+		}
+		mentions = append(mentions, &m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, mapError(err)
+	}
+
+	return mentions, nil
+}
@@ -0,0 +1,239 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/farid21ola/forum/model"
+	"github.com/jackc/pgx/v5"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const conversationColumns = `id, created_at, updated_at,
+	array(SELECT user_id::text FROM "conversation_members" m WHERE m.conversation_id = c.id ORDER BY user_id)`
+
+func scanConversation(row pgx.Row, c *model.Conversation) error {
+	return row.Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt, &c.ParticipantIDs)
+}
+
+func (s *Storage) CreateConversation(ctx context.Context, c *model.Conversation) (*model.Conversation, error) {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	key := participantsKey(c.ParticipantIDs)
+	// the same order as the members of a stored conversation
+	created := model.Conversation{ParticipantIDs: sortedIDs(c.ParticipantIDs)}
+	q := `INSERT INTO "conversations" (participants_key) VALUES ($1)
+		ON CONFLICT (participants_key) DO NOTHING RETURNING id, created_at, updated_at`
+	err = tx.QueryRow(ctx, q, key).Scan(&created.ID, &created.CreatedAt, &created.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// the conversation exists, a concurrent insert has been committed by now
+		var existing model.Conversation
+		q = `SELECT ` + conversationColumns + ` FROM "conversations" c WHERE participants_key = $1`
+		if err = scanConversation(s.DB.QueryRow(ctx, q, key), &existing); err != nil {
+			return nil, mapError(err)
+		}
+		return &existing, nil
+	}
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	q = `INSERT INTO "conversation_members" (conversation_id, user_id) SELECT $1, unnest($2::bigint[])`
+	if _, err = tx.Exec(ctx, q, created.ID, c.ParticipantIDs); err != nil {
+		return nil, mapError(err)
+	}
+
+	return &created, tx.Commit(ctx)
+}
+
+// sortedIDs orders ids by their numbers like ORDER BY user_id does.
+func sortedIDs(ids []string) []string {
+	sorted := append([]string(nil), ids...)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, _ := strconv.Atoi(sorted[i])
+		b, _ := strconv.Atoi(sorted[j])
+		return a < b
+	})
+	return sorted
+}
+
+// participantsKey is the same for every order of the same participants.
+func participantsKey(ids []string) string {
+	sorted := append([]string(nil), ids...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+func (s *Storage) Conversation(ctx context.Context, id string) (*model.Conversation, error) {
+	var c model.Conversation
+
+	q := `SELECT ` + conversationColumns + ` FROM "conversations" c WHERE id = $1`
+	if err := scanConversation(s.DB.QueryRow(ctx, q, id), &c); err != nil {
+		return nil, mapError(err)
+	}
+
+	return &c, nil
+}
+
+func (s *Storage) UserConversations(ctx context.Context, userID string) ([]*model.Conversation, error) {
+	var conversations []*model.Conversation
+
+	q := `SELECT ` + conversationColumns + ` FROM "conversations" c
+		WHERE id IN (SELECT conversation_id FROM "conversation_members" WHERE user_id = $1)
+		ORDER BY updated_at DESC, id DESC`
+
+	rows, err := s.DB.Query(ctx, q, userID)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c model.Conversation
+		if err = scanConversation(rows, &c); err != nil {
+			return nil, err
+		}
+		conversations = append(conversations, &c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, mapError(err)
+	}
+
+	return conversations, nil
+}
+
+func (s *Storage) ConversationsMembers(ctx context.Context, conversationIDs []string) ([]*model.ConversationMember, error) {
+	var members []*model.ConversationMember
+
+	q := `SELECT conversation_id, user_id, last_read_message_id FROM "conversation_members"
+		WHERE conversation_id = ANY($1::bigint[]) ORDER BY conversation_id, user_id`
+
+	rows, err := s.DB.Query(ctx, q, conversationIDs)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m model.ConversationMember
+		if err = rows.Scan(&m.ConversationID, &m.UserID, &m.LastReadMessageID); err != nil {
+			return nil, err
+		}
+		members = append(members, &m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, mapError(err)
+	}
+
+	return members, nil
+}
+
+func (s *Storage) AddMessage(ctx context.Context, m *model.Message) (*model.Message, error) {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	q := `INSERT INTO "messages" (conversation_id, user_id, content) VALUES ($1, $2, $3) RETURNING id, created_at`
+	if err = tx.QueryRow(ctx, q, m.ConversationID, m.UserID, m.Content).Scan(&m.ID, &m.CreatedAt); err != nil {
+		return nil, mapError(err)
+	}
+
+	q = `UPDATE "conversations" SET updated_at = $2 WHERE id = $1`
+	if _, err = tx.Exec(ctx, q, m.ConversationID, m.CreatedAt); err != nil {
+		return nil, mapError(err)
+	}
+
+	return m, tx.Commit(ctx)
+}
+
+func (s *Storage) Messages(ctx context.Context, filter model.MessageFilter) ([]*model.Message, error) {
+	// the cursor is validated by the domain, a malformed one matches nothing
+	before, ok := parseCursor(filter.Before)
+	if !ok {
+		return nil, nil
+	}
+
+	q := `SELECT id, conversation_id, user_id, content, created_at FROM "messages"
+		WHERE conversation_id = $1 AND ($2::bigint IS NULL OR id < $2)
+		ORDER BY id DESC LIMIT $3`
+
+	return s.queryMessages(ctx, q, filter.ConversationID, before, filter.Limit)
+}
+
+func (s *Storage) LastMessages(ctx context.Context, conversationIDs []string) ([]*model.Message, error) {
+	q := `SELECT DISTINCT ON (conversation_id) id, conversation_id, user_id, content, created_at FROM "messages"
+		WHERE conversation_id = ANY($1::bigint[])
+		ORDER BY conversation_id, id DESC`
+
+	return s.queryMessages(ctx, q, conversationIDs)
+}
+
+func (s *Storage) queryMessages(ctx context.Context, q string, args ...interface{}) ([]*model.Message, error) {
+	var messages []*model.Message
+
+	rows, err := s.DB.Query(ctx, q, args...)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m model.Message
+		if err = rows.Scan(&m.ID, &m.ConversationID, &m.UserID, &m.Content, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		messages = append(messages, &m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, mapError(err)
+	}
+
+	return messages, nil
+}
+
+func (s *Storage) MarkConversationRead(ctx context.Context, conversationID, userID, messageID string) error {
+	q := `UPDATE "conversation_members" SET last_read_message_id = GREATEST(last_read_message_id, $3::bigint)
+		WHERE conversation_id = $1 AND user_id = $2`
+
+	_, err := s.DB.Exec(ctx, q, conversationID, userID, messageID)
+	return mapError(err)
+}
+
+func (s *Storage) UnreadMessageCounts(ctx context.Context, userID string, conversationIDs []string) ([]int, error) {
+	var counts []int
+
+	q := `SELECT (SELECT count(*) FROM "messages" msg JOIN "conversation_members" m ON m.conversation_id = msg.conversation_id
+			WHERE msg.conversation_id = c.id AND m.user_id = $1 AND msg.user_id <> $1
+			AND msg.id > COALESCE(m.last_read_message_id, 0))
+		FROM unnest($2::bigint[]) WITH ORDINALITY AS c(id, n) ORDER BY c.n`
+
+	rows, err := s.DB.Query(ctx, q, userID, conversationIDs)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var count int
+		if err = rows.Scan(&count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, mapError(err)
+	}
+
+	return counts, nil
+}
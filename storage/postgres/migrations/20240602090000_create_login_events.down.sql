@@ -0,0 +1 @@
+DROP TABLE login_events;
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE login_events (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT REFERENCES users (id) ON DELETE SET NULL,
+    username TEXT NOT NULL,
+    ip TEXT NOT NULL,
+    success BOOLEAN NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL
+);
+
+CREATE INDEX login_events_username_idx ON login_events (username, created_at);
+CREATE INDEX login_events_ip_idx ON login_events (ip, created_at);
\ No newline at end of file
@@ -0,0 +1,7 @@
+DROP TRIGGER users_set_updated_at ON users;
+DROP FUNCTION set_updated_at();
+
+ALTER TABLE users
+    DROP COLUMN bio,
+    DROP COLUMN website,
+    DROP COLUMN avatar_url;
\ No newline at end of file
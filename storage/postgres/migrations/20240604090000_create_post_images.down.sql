@@ -0,0 +1 @@
+DROP TABLE post_images;
\ No newline at end of file
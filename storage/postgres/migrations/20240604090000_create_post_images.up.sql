@@ -0,0 +1,14 @@
+CREATE TABLE post_images (
+    id BIGSERIAL PRIMARY KEY,
+    post_id BIGINT REFERENCES posts (id) ON DELETE CASCADE NOT NULL,
+    user_id BIGINT REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    thumbnail_key VARCHAR(255) NOT NULL,
+    content_type VARCHAR(50) NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    size BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL
+);
+
+CREATE INDEX post_images_post_id_idx ON post_images (post_id);
\ No newline at end of file
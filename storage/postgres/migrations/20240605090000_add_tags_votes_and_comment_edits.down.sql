@@ -0,0 +1,9 @@
+DROP TABLE post_votes;
+
+ALTER TABLE comments
+    DROP COLUMN deleted,
+    DROP COLUMN edited;
+
+ALTER TABLE posts
+    DROP COLUMN score,
+    DROP COLUMN tags;
\ No newline at end of file
@@ -0,0 +1,15 @@
+ALTER TABLE posts
+    ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN score INT NOT NULL DEFAULT 0;
+
+ALTER TABLE comments
+    ADD COLUMN edited BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN deleted BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE post_votes (
+    post_id BIGINT REFERENCES posts (id) ON DELETE CASCADE NOT NULL,
+    user_id BIGINT REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    value SMALLINT NOT NULL CHECK (value IN (-1, 1)),
+
+    PRIMARY KEY (post_id, user_id)
+);
\ No newline at end of file
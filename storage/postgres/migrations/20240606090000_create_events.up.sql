@@ -0,0 +1,8 @@
+CREATE TABLE events (
+    id BIGSERIAL PRIMARY KEY,
+    topic VARCHAR(255) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL
+);
+
+CREATE INDEX events_topic_id_idx ON events (topic, id);
\ No newline at end of file
@@ -0,0 +1,14 @@
+CREATE TABLE notifications (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    actor_id BIGINT REFERENCES users (id) ON DELETE SET NULL,
+    post_id BIGINT REFERENCES posts (id) ON DELETE CASCADE,
+    comment_id BIGINT REFERENCES comments (id) ON DELETE CASCADE,
+    message TEXT NOT NULL,
+    read BOOLEAN DEFAULT FALSE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL
+);
+
+CREATE INDEX notifications_user_id_id_idx ON notifications (user_id, id);
+CREATE INDEX notifications_unread_idx ON notifications (user_id) WHERE NOT read;
\ No newline at end of file
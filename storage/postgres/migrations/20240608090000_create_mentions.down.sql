@@ -0,0 +1 @@
+DROP TABLE mentions;
\ No newline at end of file
@@ -0,0 +1,9 @@
+CREATE TABLE mentions (
+    id BIGSERIAL PRIMARY KEY,
+    post_id BIGINT REFERENCES posts (id) ON DELETE CASCADE NOT NULL,
+    comment_id BIGINT REFERENCES comments (id) ON DELETE CASCADE,
+    user_id BIGINT REFERENCES users (id) ON DELETE CASCADE NOT NULL
+);
+
+CREATE INDEX mentions_post_id_idx ON mentions (post_id) WHERE comment_id IS NULL;
+CREATE INDEX mentions_comment_id_idx ON mentions (comment_id);
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP INDEX posts_scheduled_idx;
+
+ALTER TABLE posts
+    DROP COLUMN status,
+    DROP COLUMN publish_at;
\ No newline at end of file
@@ -0,0 +1,5 @@
+ALTER TABLE posts
+    ADD COLUMN status VARCHAR(20) DEFAULT 'PUBLISHED' NOT NULL,
+    ADD COLUMN publish_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX posts_scheduled_idx ON posts (publish_at) WHERE status = 'SCHEDULED';
\ No newline at end of file
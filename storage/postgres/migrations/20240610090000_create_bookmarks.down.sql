@@ -0,0 +1 @@
+DROP TABLE bookmarks;
\ No newline at end of file
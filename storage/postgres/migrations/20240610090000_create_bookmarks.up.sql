@@ -0,0 +1,11 @@
+CREATE TABLE bookmarks (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    post_id BIGINT REFERENCES posts (id) ON DELETE CASCADE,
+    comment_id BIGINT REFERENCES comments (id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL,
+    CHECK ((post_id IS NULL) <> (comment_id IS NULL))
+);
+
+CREATE UNIQUE INDEX bookmarks_user_post_idx ON bookmarks (user_id, post_id) WHERE post_id IS NOT NULL;
+CREATE UNIQUE INDEX bookmarks_user_comment_idx ON bookmarks (user_id, comment_id) WHERE comment_id IS NOT NULL;
\ No newline at end of file
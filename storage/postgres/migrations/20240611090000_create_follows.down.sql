@@ -0,0 +1,2 @@
+DROP INDEX posts_user_published_idx;
+DROP TABLE follows;
\ No newline at end of file
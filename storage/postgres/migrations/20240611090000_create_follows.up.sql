@@ -0,0 +1,12 @@
+CREATE TABLE follows (
+    id BIGSERIAL PRIMARY KEY,
+    follower_id BIGINT REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    followee_id BIGINT REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL,
+    UNIQUE (follower_id, followee_id),
+    CHECK (follower_id <> followee_id)
+);
+
+CREATE INDEX follows_followee_id_idx ON follows (followee_id, id);
+-- the feed reads the newest published posts of every followed user
+CREATE INDEX posts_user_published_idx ON posts (user_id, id) WHERE status = 'PUBLISHED';
\ No newline at end of file
@@ -0,0 +1,10 @@
+CREATE TABLE blocks (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    target_id BIGINT REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    kind VARCHAR(10) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL,
+    UNIQUE (user_id, target_id, kind)
+);
+
+CREATE INDEX blocks_target_id_idx ON blocks (target_id) WHERE kind = 'BLOCK';
\ No newline at end of file
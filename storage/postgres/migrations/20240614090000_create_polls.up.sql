@@ -0,0 +1,24 @@
+CREATE TABLE polls (
+    id BIGSERIAL PRIMARY KEY,
+    post_id BIGINT REFERENCES posts (id) ON DELETE CASCADE NOT NULL UNIQUE,
+    question TEXT NOT NULL,
+    multiple BOOLEAN DEFAULT FALSE NOT NULL,
+    closes_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL
+);
+
+CREATE TABLE poll_options (
+    id BIGSERIAL PRIMARY KEY,
+    poll_id BIGINT REFERENCES polls (id) ON DELETE CASCADE NOT NULL,
+    text TEXT NOT NULL,
+    votes INT DEFAULT 0 NOT NULL
+);
+
+CREATE INDEX poll_options_poll_id_idx ON poll_options (poll_id, id);
+
+CREATE TABLE poll_votes (
+    poll_id BIGINT REFERENCES polls (id) ON DELETE CASCADE NOT NULL,
+    user_id BIGINT REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    option_ids BIGINT[] NOT NULL,
+    PRIMARY KEY (poll_id, user_id)
+);
\ No newline at end of file
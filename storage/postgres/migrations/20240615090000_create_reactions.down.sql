@@ -0,0 +1 @@
+DROP TABLE reactions;
\ No newline at end of file
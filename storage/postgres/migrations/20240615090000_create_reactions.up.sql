@@ -0,0 +1,12 @@
+CREATE TABLE reactions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    post_id BIGINT REFERENCES posts (id) ON DELETE CASCADE,
+    comment_id BIGINT REFERENCES comments (id) ON DELETE CASCADE,
+    emoji TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL,
+    CHECK ((post_id IS NULL) <> (comment_id IS NULL))
+);
+
+CREATE UNIQUE INDEX reactions_post_user_emoji_idx ON reactions (post_id, user_id, emoji) WHERE post_id IS NOT NULL;
+CREATE UNIQUE INDEX reactions_comment_user_emoji_idx ON reactions (comment_id, user_id, emoji) WHERE comment_id IS NOT NULL;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE posts
+    DROP COLUMN comment_count,
+    DROP COLUMN last_activity_at;
\ No newline at end of file
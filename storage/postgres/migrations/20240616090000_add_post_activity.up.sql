@@ -0,0 +1,5 @@
+ALTER TABLE posts
+    ADD COLUMN comment_count INT DEFAULT 0 NOT NULL,
+    ADD COLUMN last_activity_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL;
+
+UPDATE posts p SET comment_count = (SELECT count(*) FROM comments c WHERE c.post_id = p.id AND NOT c.deleted);
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP INDEX comments_post_id_idx;
+DROP INDEX comments_parent_id_idx;
+DROP INDEX comments_user_id_idx;
\ No newline at end of file
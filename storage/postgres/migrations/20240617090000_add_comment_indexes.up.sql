@@ -0,0 +1,3 @@
+CREATE INDEX comments_post_id_idx ON comments (post_id, id);
+CREATE INDEX comments_parent_id_idx ON comments (parent_id);
+CREATE INDEX comments_user_id_idx ON comments (user_id);
\ No newline at end of file
@@ -0,0 +1,4 @@
+DROP INDEX mentions_post_id_user_id_idx;
+DROP INDEX mentions_comment_id_user_id_idx;
+CREATE INDEX mentions_post_id_idx ON mentions (post_id) WHERE comment_id IS NULL;
+CREATE INDEX mentions_comment_id_idx ON mentions (comment_id);
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- a user is mentioned once in the same post or comment
+DELETE FROM mentions a USING mentions b
+WHERE a.id > b.id AND a.post_id = b.post_id AND a.user_id = b.user_id
+AND a.comment_id IS NOT DISTINCT FROM b.comment_id;
+
+DROP INDEX mentions_post_id_idx;
+DROP INDEX mentions_comment_id_idx;
+CREATE UNIQUE INDEX mentions_post_id_user_id_idx ON mentions (post_id, user_id) WHERE comment_id IS NULL;
+CREATE UNIQUE INDEX mentions_comment_id_user_id_idx ON mentions (comment_id, user_id) WHERE comment_id IS NOT NULL;
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP INDEX rate_limits_expires_at_idx;
+ALTER TABLE rate_limits DROP COLUMN expires_at;
\ No newline at end of file
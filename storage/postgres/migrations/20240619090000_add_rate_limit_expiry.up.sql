@@ -0,0 +1,3 @@
+ALTER TABLE rate_limits ADD COLUMN expires_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL;
+
+CREATE INDEX rate_limits_expires_at_idx ON rate_limits (expires_at);
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN avatar_keys;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN avatar_keys TEXT[] DEFAULT '{}' NOT NULL;
\ No newline at end of file
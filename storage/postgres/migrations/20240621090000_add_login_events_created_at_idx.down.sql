@@ -0,0 +1 @@
+DROP INDEX login_events_created_at_idx;
\ No newline at end of file
@@ -0,0 +1 @@
+CREATE INDEX login_events_created_at_idx ON login_events (created_at);
\ No newline at end of file
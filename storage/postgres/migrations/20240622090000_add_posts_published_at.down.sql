@@ -0,0 +1,3 @@
+DROP INDEX posts_published_at_idx;
+
+ALTER TABLE posts DROP COLUMN published_at;
\ No newline at end of file
@@ -0,0 +1,6 @@
+ALTER TABLE posts ADD COLUMN published_at TIMESTAMP WITH TIME ZONE;
+
+-- the order of the posts published before is kept by their ids
+UPDATE posts SET published_at = NOW() WHERE status = 'PUBLISHED';
+
+CREATE INDEX posts_published_at_idx ON posts (published_at, id) WHERE status = 'PUBLISHED';
\ No newline at end of file
@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"context"
+	"github.com/farid21ola/forum/model"
+	"strconv"
+)
+
+const notificationColumns = `id, user_id, kind, actor_id, post_id, comment_id, message, read, created_at`
+
+func (s *Storage) AddNotification(ctx context.Context, n *model.Notification) (*model.Notification, error) {
+	q := `INSERT INTO "notifications" (user_id, kind, actor_id, post_id, comment_id, message)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, read, created_at`
+
+	err := s.DB.QueryRow(ctx, q, n.UserID, string(n.Kind), n.ActorID, n.PostID, n.CommentID, n.Message).
+		Scan(&n.ID, &n.Read, &n.CreatedAt)
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	return n, nil
+}
+
+func (s *Storage) Notifications(ctx context.Context, filter model.NotificationFilter) ([]*model.Notification, error) {
+	var notifications []*model.Notification
+
+	// the cursor is validated by the domain, a malformed one matches nothing
+	after, ok := parseCursor(filter.After)
+	if !ok {
+		return nil, nil
+	}
+
+	q := `SELECT ` + notificationColumns + ` FROM "notifications"
+		WHERE user_id = $1 AND (NOT $2 OR NOT read) AND ($3::bigint IS NULL OR id < $3)
+		ORDER BY id DESC LIMIT $4`
+
+	rows, err := s.DB.Query(ctx, q, filter.UserID, filter.UnreadOnly, after, filter.Limit)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var n model.Notification
+		var kind string
+		err = rows.Scan(&n.ID, &n.UserID, &kind, &n.ActorID, &n.PostID, &n.CommentID, &n.Message, &n.Read, &n.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		n.Kind = model.NotificationKind(kind)
+		notifications = append(notifications, &n)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, mapError(err)
+	}
+
+	return notifications, nil
+}
+
+func (s *Storage) MarkNotificationsRead(ctx context.Context, userID string, ids []string) error {
+	if ids == nil {
+		q := `UPDATE "notifications" SET read = TRUE WHERE user_id = $1 AND NOT read`
+		_, err := s.DB.Exec(ctx, q, userID)
+		return mapError(err)
+	}
+
+	// ids that are not numbers can't belong to a notification
+	var numeric []int64
+	for _, id := range ids {
+		if n, err := strconv.ParseInt(id, 10, 64); err == nil {
+			numeric = append(numeric, n)
+		}
+	}
+	if len(numeric) == 0 {
+		return nil
+	}
+
+	q := `UPDATE "notifications" SET read = TRUE WHERE user_id = $1 AND id = ANY($2) AND NOT read`
+	_, err := s.DB.Exec(ctx, q, userID, numeric)
+	return mapError(err)
+}
+
+func (s *Storage) UnreadNotificationCount(ctx context.Context, userID string) (int, error) {
+	var count int
+
+	q := `SELECT count(*) FROM "notifications" WHERE user_id = $1 AND NOT read`
+	if err := s.DB.QueryRow(ctx, q, userID).Scan(&count); err != nil {
+		return 0, mapError(err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,237 @@
+// Package pgtest runs the tests of a package against a throwaway PostgreSQL
+// database with the migrations of storage/postgres applied.
+package pgtest
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pool is connected to the database created for the tests of the package, it
+// is nil when there is no server and the tests are skipped.
+var pool *pgxpool.Pool
+
+// Main is called from TestMain. It uses the server of DB_URL or starts one
+// from the binaries on PATH or in PG_BIN, creates a database for the package
+// and drops it after the tests. Without a server the postgres tests are
+// skipped, unless CI or PG_REQUIRED is set and the package fails instead.
+func Main(m *testing.M) int {
+	ctx := context.Background()
+
+	url := os.Getenv("DB_URL")
+	if url == "" {
+		var stop func()
+		var err error
+		url, stop, err = startServer(ctx)
+		if err != nil {
+			if os.Getenv("CI") != "" || os.Getenv("PG_REQUIRED") != "" {
+				log.Printf("%s: postgres is required but can't be started: %v", testPackage(), err)
+				return 1
+			}
+			log.Printf("%s: postgres tests are skipped, set DB_URL or put initdb and pg_ctl on PATH: %v", testPackage(), err)
+			return m.Run()
+		}
+		defer stop()
+	}
+
+	p, drop, err := createDatabase(ctx, url)
+	if err != nil {
+		log.Printf("error create test database: %v", err)
+		return 1
+	}
+	defer drop()
+
+	if err = applyMigrations(ctx, p, migrationsDir()); err != nil {
+		log.Printf("error apply migrations: %v", err)
+		return 1
+	}
+	pool = p
+
+	return m.Run()
+}
+
+// testPackage names the package under test after its test binary.
+func testPackage() string {
+	return strings.TrimSuffix(filepath.Base(os.Args[0]), ".test")
+}
+
+// Pool returns the pool of the test database with all tables truncated, the
+// test is skipped when there is no server.
+func Pool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	if pool == nil {
+		t.Skip("no postgres server, set DB_URL or put initdb and pg_ctl on PATH")
+	}
+
+	ctx := context.Background()
+	rows, err := pool.Query(ctx, `SELECT tablename FROM pg_tables WHERE schemaname = 'public'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, table := range tables {
+		tables[i] = `"` + table + `"`
+	}
+	_, err = pool.Exec(ctx, `TRUNCATE `+strings.Join(tables, ", ")+` RESTART IDENTITY CASCADE`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pool
+}
+
+// startServer initializes a cluster in a temporary directory and starts it on
+// a free port, stop shuts the server down and removes the directory.
+func startServer(ctx context.Context) (string, func(), error) {
+	initdb, err := lookPath("initdb")
+	if err != nil {
+		return "", nil, err
+	}
+	pgCtl, err := lookPath("pg_ctl")
+	if err != nil {
+		return "", nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "forum-postgres")
+	if err != nil {
+		return "", nil, err
+	}
+	data := filepath.Join(dir, "data")
+
+	out, err := exec.CommandContext(ctx, initdb, "-D", data, "-U", "postgres", "-A", "trust", "-N").CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("initdb: %v: %s", err, out)
+	}
+
+	port, err := freePort()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, err
+	}
+
+	options := fmt.Sprintf("-p %d -k %s -c listen_addresses=127.0.0.1 -F", port, dir)
+	out, err = exec.CommandContext(ctx, pgCtl, "-D", data, "-l", filepath.Join(dir, "postgres.log"), "-o", options, "-w", "start").CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("pg_ctl start: %v: %s", err, out)
+	}
+
+	stop := func() {
+		if out, err := exec.Command(pgCtl, "-D", data, "-m", "immediate", "-w", "stop").CombinedOutput(); err != nil {
+			log.Printf("error stop postgres: %v: %s", err, out)
+		}
+		os.RemoveAll(dir)
+	}
+
+	return fmt.Sprintf("postgres://postgres@127.0.0.1:%d/postgres?sslmode=disable", port), stop, nil
+}
+
+// lookPath finds a postgres binary in PG_BIN, on PATH or in the directory
+// Debian and Ubuntu packages install it to.
+func lookPath(name string) (string, error) {
+	if dir := os.Getenv("PG_BIN"); dir != "" {
+		return exec.LookPath(filepath.Join(dir, name))
+	}
+	if path, err := exec.LookPath(name); err == nil {
+		return path, nil
+	}
+	paths, _ := filepath.Glob(filepath.Join("/usr/lib/postgresql/*/bin", name))
+	if len(paths) == 0 {
+		return "", fmt.Errorf("%s not found", name)
+	}
+	return paths[len(paths)-1], nil
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// createDatabase creates an empty database on the server of url, drop closes
+// the pool and removes the database.
+func createDatabase(ctx context.Context, url string) (*pgxpool.Pool, func(), error) {
+	admin, err := pgx.Connect(ctx, url)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	name := fmt.Sprintf("forum_test_%d", time.Now().UnixNano())
+	if _, err = admin.Exec(ctx, `CREATE DATABASE `+name); err != nil {
+		admin.Close(ctx)
+		return nil, nil, err
+	}
+
+	drop := func() {
+		if _, err := admin.Exec(ctx, `DROP DATABASE `+name); err != nil {
+			log.Printf("error drop test database %s: %v", name, err)
+		}
+		admin.Close(ctx)
+	}
+
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		drop()
+		return nil, nil, err
+	}
+	config.ConnConfig.Database = name
+	p, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		drop()
+		return nil, nil, err
+	}
+
+	return p, func() {
+		p.Close()
+		drop()
+	}, nil
+}
+
+// migrationsDir is storage/postgres/migrations next to this file, so the
+// package can be used from tests of any package.
+func migrationsDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "migrations")
+}
+
+// applyMigrations runs the up migrations of dir in the order of their names,
+// the way golang-migrate does in docker-compose.
+func applyMigrations(ctx context.Context, p *pgxpool.Pool, dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.up.sql"))
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no migrations in %s", dir)
+	}
+
+	for _, file := range files {
+		migration, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if _, err = p.Exec(ctx, string(migration)); err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(file), err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/storage"
+	"github.com/jackc/pgx/v5"
+)
+
+// createPoll inserts the poll of a post that is created in the same
+// transaction.
+func createPoll(ctx context.Context, tx pgx.Tx, poll *model.Poll) error {
+	q := `INSERT INTO "polls" (post_id, question, multiple, closes_at) VALUES ($1, $2, $3, $4) RETURNING id, created_at`
+	err := tx.QueryRow(ctx, q, poll.PostID, poll.Question, poll.Multiple, poll.ClosesAt).Scan(&poll.ID, &poll.CreatedAt)
+	if err != nil {
+		return mapError(err)
+	}
+
+	for _, option := range poll.Options {
+		q = `INSERT INTO "poll_options" (poll_id, text) VALUES ($1, $2) RETURNING id, votes`
+		if err = tx.QueryRow(ctx, q, poll.ID, option.Text).Scan(&option.ID, &option.Votes); err != nil {
+			return mapError(err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) Poll(ctx context.Context, id string) (*model.Poll, error) {
+	polls, err := s.queryPolls(ctx, `id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(polls) == 0 {
+		return nil, storage.ErrNotFound
+	}
+	return polls[0], nil
+}
+
+func (s *Storage) PostsPolls(ctx context.Context, postIDs []string) ([]*model.Poll, error) {
+	return s.queryPolls(ctx, `post_id = ANY($1)`, postIDs)
+}
+
+// queryPolls loads the options of all the polls with a second query.
+func (s *Storage) queryPolls(ctx context.Context, where string, arg any) ([]*model.Poll, error) {
+	var polls []*model.Poll
+
+	q := `SELECT id, post_id, question, multiple, closes_at, created_at FROM "polls" WHERE ` + where + ` ORDER BY id`
+	rows, err := s.DB.Query(ctx, q, arg)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	byID := make(map[string]*model.Poll)
+	ids := []string{}
+	for rows.Next() {
+		var poll model.Poll
+		if err = rows.Scan(&poll.ID, &poll.PostID, &poll.Question, &poll.Multiple, &poll.ClosesAt, &poll.CreatedAt); err != nil {
+			return nil, err
+		}
+		polls = append(polls, &poll)
+		byID[poll.ID] = &poll
+		ids = append(ids, poll.ID)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, mapError(err)
+	}
+	if len(polls) == 0 {
+		return nil, nil
+	}
+
+	q = `SELECT poll_id, id, text, votes FROM "poll_options" WHERE poll_id = ANY($1::bigint[]) ORDER BY id`
+	rows, err = s.DB.Query(ctx, q, ids)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pollID string
+		var option model.PollOption
+		if err = rows.Scan(&pollID, &option.ID, &option.Text, &option.Votes); err != nil {
+			return nil, err
+		}
+		byID[pollID].Options = append(byID[pollID].Options, &option)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, mapError(err)
+	}
+
+	return polls, nil
+}
+
+func (s *Storage) AddPollVote(ctx context.Context, vote *model.PollVote) (bool, error) {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	q := `INSERT INTO "poll_votes" (poll_id, user_id, option_ids) VALUES ($1, $2, $3::bigint[]) ON CONFLICT DO NOTHING`
+	tag, err := tx.Exec(ctx, q, vote.PollID, vote.UserID, vote.OptionIDs)
+	if err != nil {
+		return false, mapError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	q = `UPDATE "poll_options" SET votes = votes + 1 WHERE poll_id = $1 AND id = ANY($2::bigint[])`
+	if _, err = tx.Exec(ctx, q, vote.PollID, vote.OptionIDs); err != nil {
+		return false, mapError(err)
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+func (s *Storage) PollVote(ctx context.Context, pollID, userID string) ([]string, error) {
+	var optionIDs []string
+
+	q := `SELECT option_ids::text[] FROM "poll_votes" WHERE poll_id = $1 AND user_id = $2`
+	err := s.DB.QueryRow(ctx, q, pollID, userID).Scan(&optionIDs)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	return optionIDs, nil
+}
+
+func (s *Storage) PollsVotes(ctx context.Context, userID string, pollIDs []string) ([]*model.PollVote, error) {
+	var votes []*model.PollVote
+
+	q := `SELECT poll_id, user_id, option_ids::text[] FROM "poll_votes" WHERE user_id = $1 AND poll_id = ANY($2::bigint[])`
+	rows, err := s.DB.Query(ctx, q, userID, pollIDs)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var vote model.PollVote
+		if err = rows.Scan(&vote.PollID, &vote.UserID, &vote.OptionIDs); err != nil {
+			return nil, err
+		}
+		votes = append(votes, &vote)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, mapError(err)
+	}
+
+	return votes, nil
+}
@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"context"
+	"github.com/farid21ola/forum/model"
+)
+
+func (s *Storage) AddReaction(ctx context.Context, r *model.Reaction) error {
+	q := `INSERT INTO "reactions" (user_id, post_id, comment_id, emoji) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING`
+
+	_, err := s.DB.Exec(ctx, q, r.UserID, r.PostID, r.CommentID, r.Emoji)
+	return mapError(err)
+}
+
+func (s *Storage) RemoveReaction(ctx context.Context, r *model.Reaction) error {
+	q := `DELETE FROM "reactions" WHERE user_id = $1
+		AND post_id IS NOT DISTINCT FROM $2::bigint AND comment_id IS NOT DISTINCT FROM $3::bigint AND emoji = $4`
+
+	_, err := s.DB.Exec(ctx, q, r.UserID, r.PostID, r.CommentID, r.Emoji)
+	return mapError(err)
+}
+
+func (s *Storage) ReactionCounts(ctx context.Context, target model.ReactionTarget, targetIDs []string, userID string) ([]*model.ReactionCount, error) {
+	var counts []*model.ReactionCount
+
+	column := "post_id"
+	if target == model.ReactionTargetComment {
+		column = "comment_id"
+	}
+	q := `SELECT ` + column + `, emoji, count(*), COALESCE(bool_or(user_id = $2), false) FROM "reactions"
+		WHERE ` + column + ` = ANY($1::bigint[])
+		GROUP BY ` + column + `, emoji ORDER BY ` + column + `, min(id)`
+
+	rows, err := s.DB.Query(ctx, q, targetIDs, nullableID(userID))
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c model.ReactionCount
+		if err = rows.Scan(&c.TargetID, &c.Emoji, &c.Count, &c.ReactedByMe); err != nil {
+			return nil, err
+		}
+		counts = append(counts, &c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, mapError(err)
+	}
+
+	return counts, nil
+}
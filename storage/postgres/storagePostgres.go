@@ -2,16 +2,20 @@ package postgres
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/storage"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"log"
+	"sync/atomic"
 )
 
 type Storage struct {
 	DB *pgxpool.Pool
+	// loginChecks counts LoginFailures calls to delete old login events
+	// from time to time
+	loginChecks atomic.Int64
 }
 
 type QueryLoggerTracer struct {
@@ -36,66 +40,57 @@ func (s *Storage) Begin(ctx context.Context) (pgx.Tx, error) {
 }
 
 func (s *Storage) Posts(ctx context.Context, limit, offset *int) ([]*model.Post, error) {
-	var posts []*model.Post
+	q := `SELECT ` + postColumns + ` FROM "posts" WHERE status = 'PUBLISHED' ORDER BY published_at, id LIMIT $1 OFFSET $2`
 
-	q := `SELECT id, title, content, comments_enabled, user_id FROM "posts"`
-
-	if limit != nil {
-		q += fmt.Sprintf(` LIMIT %d `, *limit)
-	}
-	if offset != nil {
-		q += fmt.Sprintf(` OFFSET %d `, *offset)
-	}
+	return s.queryPosts(ctx, q, limit, offset)
+}
 
-	rows, err := s.DB.Query(ctx, q)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+func (s *Storage) Post(ctx context.Context, id string) (*model.Post, error) {
+	var post model.Post
 
-	for rows.Next() {
-		var post model.Post
-		if err = rows.Scan(&post.ID, &post.Title, &post.Content, &post.CommentsEnabled, &post.UserID); err != nil {
-			return nil, err
-		}
-		posts = append(posts, &post)
-	}
+	q := `SELECT ` + postColumns + ` FROM "posts" WHERE "id" = $1`
 
-	if err = rows.Err(); err != nil {
-		return nil, err
+	if err := scanPost(s.DB.QueryRow(ctx, q, id), &post); err != nil {
+		return nil, mapError(err)
 	}
 
-	return posts, nil
+	return &post, nil
 }
 
-func (s *Storage) Post(ctx context.Context, id string) (*model.Post, error) {
-	var post model.Post
+func (s *Storage) PostsByIDs(ctx context.Context, ids []string) ([]*model.Post, error) {
+	q := `SELECT ` + postColumns + ` FROM "posts" WHERE id = ANY($1)`
 
-	q := `SELECT id, title, content, comments_enabled, user_id FROM "posts" WHERE "id" = $1`
+	return s.queryPosts(ctx, q, ids)
+}
 
-	err := s.DB.QueryRow(ctx, q, id).Scan(
-		&post.ID, &post.Title, &post.Content, &post.CommentsEnabled, &post.UserID,
+const postColumns = `id, title, content, comments_enabled, user_id, tags, score, status, publish_at, published_at, comment_count, last_activity_at`
+
+func scanPost(row pgx.Row, post *model.Post) error {
+	var status string
+	err := row.Scan(
+		&post.ID, &post.Title, &post.Content, &post.CommentsEnabled, &post.UserID, &post.Tags, &post.Score,
+		&status, &post.PublishAt, &post.PublishedAt, &post.CommentCount, &post.LastActivityAt,
 	)
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, nil
-		}
-		return nil, err
-	}
+	post.Status = model.PostStatus(status)
+	return err
+}
 
-	return &post, nil
+const userColumns = `id, username, first_name, last_name, password, bio, website, avatar_url, avatar_keys, created_at, updated_at`
+
+func scanUser(row pgx.Row, user *model.User) error {
+	return row.Scan(
+		&user.ID, &user.Username, &user.FirstName, &user.LastName, &user.Password,
+		&user.Bio, &user.Website, &user.AvatarURL, &user.AvatarKeys, &user.CreatedAt, &user.UpdateAt,
+	)
 }
 
 func (s *Storage) UserByField(ctx context.Context, field, value string) (*model.User, error) {
 	var user model.User
 
-	q := fmt.Sprintf(`SELECT id, username, first_name, last_name, password FROM users WHERE %s = $1`, field)
+	q := fmt.Sprintf(`SELECT %s FROM users WHERE %s = $1`, userColumns, field)
 
-	if err := s.DB.QueryRow(ctx, q, value).Scan(&user.ID, &user.Username, &user.FirstName, &user.LastName, &user.Password); err != nil {
-		if err == pgx.ErrNoRows {
-			return nil, err
-		}
-		return nil, err
+	if err := scanUser(s.DB.QueryRow(ctx, q, value), &user); err != nil {
+		return nil, mapError(err)
 	}
 	return &user, nil
 }
@@ -109,141 +104,293 @@ func (s *Storage) UserByUsername(ctx context.Context, username string) (*model.U
 }
 
 func (s *Storage) Users(ctx context.Context) ([]*model.User, error) {
-	var users []*model.User
+	q := `SELECT ` + userColumns + ` FROM "users"`
 
-	q := `SELECT id, username, first_name, last_name FROM "users"`
+	return s.queryUsers(ctx, q)
+}
 
-	rows, err := s.DB.Query(ctx, q)
+func (s *Storage) UsersByIDs(ctx context.Context, ids []string) ([]*model.User, error) {
+	q := `SELECT ` + userColumns + ` FROM "users" WHERE id = ANY($1)`
+
+	return s.queryUsers(ctx, q, ids)
+}
+
+func (s *Storage) UsersByUsernames(ctx context.Context, usernames []string) ([]*model.User, error) {
+	q := `SELECT ` + userColumns + ` FROM "users" WHERE username = ANY($1)`
+
+	return s.queryUsers(ctx, q, usernames)
+}
+
+func (s *Storage) queryUsers(ctx context.Context, q string, args ...any) ([]*model.User, error) {
+	var users []*model.User
+
+	rows, err := s.DB.Query(ctx, q, args...)
 	if err != nil {
-		return nil, err
+		return nil, mapError(err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var user model.User
-		if err = rows.Scan(&user.ID, &user.Username, &user.FirstName, &user.LastName); err != nil {
+		if err = scanUser(rows, &user); err != nil {
 			return nil, err
 		}
 		users = append(users, &user)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, mapError(err)
 	}
 
 	return users, nil
 }
 
-func (s *Storage) UsersPost(ctx context.Context, userId string) ([]*model.Post, error) {
-	var posts []*model.Post
+func (s *Storage) UpdateUser(ctx context.Context, user *model.User) (*model.User, error) {
+	var updated model.User
+
+	q := `UPDATE "users" SET first_name = $1, last_name = $2, bio = $3, website = $4, avatar_url = $5, avatar_keys = $6
+		WHERE id = $7 RETURNING ` + userColumns
+
+	keys := user.AvatarKeys
+	if keys == nil {
+		keys = []string{}
+	}
+	err := scanUser(s.DB.QueryRow(ctx, q, user.FirstName, user.LastName, user.Bio, user.Website, user.AvatarURL, keys, user.ID), &updated)
+	if err != nil {
+		return nil, mapError(err)
+	}
 
-	q := `SELECT id, title, content, comments_enabled, user_id FROM "posts"`
+	return &updated, nil
+}
 
-	rows, err := s.DB.Query(ctx, q)
+func (s *Storage) UserStats(ctx context.Context, userID string) (*model.UserStats, error) {
+	stats, err := s.UsersStats(ctx, []string{userID})
 	if err != nil {
 		return nil, err
 	}
+
+	return stats[0], nil
+}
+
+func (s *Storage) UsersStats(ctx context.Context, userIDs []string) ([]*model.UserStats, error) {
+	var stats []*model.UserStats
+
+	q := `SELECT u.id,
+		(SELECT count(*) FROM "posts" WHERE user_id = u.id AND status = 'PUBLISHED'),
+		(SELECT count(*) FROM "comments" WHERE user_id = u.id),
+		(SELECT count(*) FROM "comments" c JOIN "posts" p ON p.id = c.post_id WHERE p.user_id = u.id AND c.user_id <> u.id),
+		(SELECT count(*) FROM "follows" WHERE followee_id = u.id),
+		(SELECT count(*) FROM "follows" WHERE follower_id = u.id)
+		FROM unnest($1::bigint[]) WITH ORDINALITY AS u(id, n) ORDER BY u.n`
+
+	rows, err := s.DB.Query(ctx, q, userIDs)
+	if err != nil {
+		return nil, mapError(err)
+	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var post model.Post
-		if err = rows.Scan(&post.ID, &post.Title, &post.Content, &post.CommentsEnabled, &post.UserID); err != nil {
+		var st model.UserStats
+		err = rows.Scan(&st.UserID, &st.PostCount, &st.CommentCount, &st.Karma, &st.FollowerCount, &st.FollowingCount)
+		if err != nil {
 			return nil, err
 		}
-		if post.UserID == userId {
-			posts = append(posts, &post)
-		}
-
+		stats = append(stats, &st)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, mapError(err)
 	}
 
-	return posts, nil
+	return stats, nil
+}
+
+func (s *Storage) UsersPost(ctx context.Context, userId string) ([]*model.Post, error) {
+	q := `SELECT ` + postColumns + ` FROM "posts" WHERE user_id = $1 AND status = 'PUBLISHED' ORDER BY id`
+
+	return s.queryPosts(ctx, q, userId)
 }
 
 func (s *Storage) CreateUser(ctx context.Context, tx pgx.Tx, user *model.User) (*model.User, error) {
-	q := `INSERT INTO "users"(username, first_name, last_name, password) VALUES ($1, $2, $3, $4) RETURNING id`
-	err := tx.QueryRow(ctx, q, user.Username, user.FirstName, user.LastName, user.Password).Scan(&user.ID)
+	q := `INSERT INTO "users"(username, first_name, last_name, password) VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at`
+	err := tx.QueryRow(ctx, q, user.Username, user.FirstName, user.LastName, user.Password).Scan(&user.ID, &user.CreatedAt, &user.UpdateAt)
 	if err != nil {
-		return nil, err
+		return nil, mapError(err)
 	}
-	return user, err
+	return user, nil
 }
 
-func (s *Storage) Comments(ctx context.Context, postId string, limit, offset *int) ([]*model.Comment, error) {
-	var comments []*model.Comment
-
-	q := `SELECT id, content, post_id, parent_id, user_id FROM "comments"`
+func (s *Storage) UpdatePassword(ctx context.Context, userID, hash string) error {
+	q := `UPDATE "users" SET password = $1 WHERE id = $2`
 
-	if limit != nil {
-		q += fmt.Sprintf(` LIMIT %d `, *limit)
+	tag, err := s.DB.Exec(ctx, q, hash, userID)
+	if err != nil {
+		return mapError(err)
 	}
-	if offset != nil {
-		q += fmt.Sprintf(` OFFSET %d `, *offset)
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
 	}
 
-	rows, err := s.DB.Query(ctx, q)
+	return nil
+}
+
+func (s *Storage) Comments(ctx context.Context, postId string, limit, offset *int) ([]*model.Comment, error) {
+	q := `SELECT ` + commentColumns + ` FROM "comments" WHERE post_id = $1 ORDER BY id LIMIT $2 OFFSET $3`
+
+	return s.queryComments(ctx, q, postId, limit, offset)
+}
+
+func (s *Storage) CommentsByIDs(ctx context.Context, ids []string) ([]*model.Comment, error) {
+	q := `SELECT ` + commentColumns + ` FROM "comments" WHERE id = ANY($1)`
+
+	return s.queryComments(ctx, q, ids)
+}
+
+func (s *Storage) queryComments(ctx context.Context, q string, args ...any) ([]*model.Comment, error) {
+	var comments []*model.Comment
+
+	rows, err := s.DB.Query(ctx, q, args...)
 	if err != nil {
-		return nil, err
+		return nil, mapError(err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var comment model.Comment
-		if err = rows.Scan(&comment.ID, &comment.Content, &comment.PostID, &comment.ParentID, &comment.UserID); err != nil {
+		if err = scanComment(rows, &comment); err != nil {
 			return nil, err
 		}
-		if comment.PostID == postId {
-			comments = append(comments, &comment)
-		}
-
+		comments = append(comments, &comment)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, mapError(err)
 	}
 
 	return comments, nil
 }
 
-func (s *Storage) CreatePost(ctx context.Context, post *model.Post) (*model.Post, error) {
-	q := `INSERT INTO "posts" (title, content, user_id) VALUES ($1,$2,$3) RETURNING *`
+const commentColumns = `id, content, post_id, parent_id, user_id, edited, deleted`
 
-	err := s.DB.QueryRow(ctx, q, post.Title, post.Content, post.UserID).Scan(
-		&post.ID, &post.Title, &post.Content, &post.CommentsEnabled, &post.UserID,
+func scanComment(row pgx.Row, comment *model.Comment) error {
+	return row.Scan(
+		&comment.ID, &comment.Content, &comment.PostID, &comment.ParentID, &comment.UserID, &comment.Edited, &comment.Deleted,
 	)
+}
+
+func (s *Storage) Comment(ctx context.Context, id string) (*model.Comment, error) {
+	var comment model.Comment
+
+	q := `SELECT ` + commentColumns + ` FROM "comments" WHERE "id" = $1`
+
+	if err := scanComment(s.DB.QueryRow(ctx, q, id), &comment); err != nil {
+		return nil, mapError(err)
+	}
+
+	return &comment, nil
+}
+
+func (s *Storage) UpdateComment(ctx context.Context, comment *model.Comment) (*model.Comment, error) {
+	var updated model.Comment
+
+	tx, err := s.DB.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback(ctx)
+
+	var deleted bool
+	err = tx.QueryRow(ctx, `SELECT deleted FROM "comments" WHERE "id" = $1 FOR UPDATE`, comment.ID).Scan(&deleted)
+	if err != nil {
+		return nil, mapError(err)
+	}
 
-	return post, nil
+	q := `UPDATE "comments" SET content = $1, edited = $2, deleted = $3 WHERE "id" = $4 RETURNING ` + commentColumns
+
+	err = scanComment(tx.QueryRow(ctx, q, comment.Content, comment.Edited, comment.Deleted, comment.ID), &updated)
+	if err != nil {
+		return nil, err
+	}
+
+	if deleted != updated.Deleted {
+		change := 1
+		if updated.Deleted {
+			change = -1
+		}
+		q = `UPDATE "posts" SET comment_count = comment_count + $1 WHERE "id" = $2`
+		if _, err = tx.Exec(ctx, q, change, updated.PostID); err != nil {
+			return nil, err
+		}
+	}
+
+	return &updated, tx.Commit(ctx)
+}
+
+func (s *Storage) CreatePost(ctx context.Context, post *model.Post, images []*model.Image, poll *model.Poll) (*model.Post, error) {
+	if post.Tags == nil {
+		post.Tags = []string{}
+	}
+	if post.Status == "" {
+		post.Status = model.PostStatusPublished
+	}
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	q := `INSERT INTO "posts" (title, content, user_id, tags, status, publish_at, published_at)
+		VALUES ($1,$2,$3,$4,$5,$6, CASE WHEN $7::boolean THEN NOW() END) RETURNING ` + postColumns
+
+	err = scanPost(tx.QueryRow(ctx, q, post.Title, post.Content, post.UserID, post.Tags, string(post.Status), post.PublishAt, post.IsPublished()), post)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	for _, image := range images {
+		image.PostID = post.ID
+		if err = addPostImage(ctx, tx, image); err != nil {
+			return nil, err
+		}
+	}
+	if poll != nil {
+		poll.PostID = post.ID
+		if err = createPoll(ctx, tx, poll); err != nil {
+			return nil, err
+		}
+	}
+
+	return post, tx.Commit(ctx)
 }
 
 func (s *Storage) AddComment(ctx context.Context, comment *model.Comment) (*model.Comment, error) {
-	//var newComment *models.Comment
-	q := `INSERT INTO "comments" (content, post_id, parent_id, user_id) VALUES ($1,$2,$3,$4) RETURNING *`
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
 
-	err := s.DB.QueryRow(ctx, q, comment.Content, comment.PostID, comment.ParentID, comment.UserID).Scan(
-		&comment.ID, &comment.Content, &comment.PostID, &comment.ParentID, &comment.UserID,
-	)
+	q := `INSERT INTO "comments" (content, post_id, parent_id, user_id) VALUES ($1,$2,$3,$4) RETURNING ` + commentColumns
+
+	err = scanComment(tx.QueryRow(ctx, q, comment.Content, comment.PostID, comment.ParentID, comment.UserID), comment)
 	if err != nil {
+		return nil, mapError(err)
+	}
+
+	q = `UPDATE "posts" SET comment_count = comment_count + 1, last_activity_at = NOW() WHERE "id" = $1`
+	if _, err = tx.Exec(ctx, q, comment.PostID); err != nil {
 		return nil, err
 	}
 
-	return comment, nil
+	return comment, tx.Commit(ctx)
 }
 
 func (s *Storage) UpdatePost(ctx context.Context, upd *model.UpdatePost) (*model.Post, error) {
 	var post model.Post
-	q := `UPDATE "posts" SET comments_enabled = $1 WHERE "id" = $2 RETURNING id, title, content, comments_enabled, user_id`
+	q := `UPDATE "posts" SET comments_enabled = $1 WHERE "id" = $2 RETURNING ` + postColumns
 
-	err := s.DB.QueryRow(ctx, q, upd.EnableComments, upd.PostID).Scan(
-		&post.ID, &post.Title, &post.Content, &post.CommentsEnabled, &post.UserID,
-	)
+	err := scanPost(s.DB.QueryRow(ctx, q, upd.EnableComments, upd.PostID), &post)
 	if err != nil {
-		return nil, err
+		return nil, mapError(err)
 	}
 
 	return &post, nil
@@ -0,0 +1,746 @@
+package postgres
+
+import (
+	"context"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func createTestUser(t *testing.T, s *Storage, username string) *model.User {
+	t.Helper()
+	ctx := context.Background()
+
+	tx, err := s.Begin(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback(ctx)
+
+	user, err := s.CreateUser(ctx, tx, &model.User{Username: username, FirstName: "First", LastName: "Last", Password: "hash"})
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit(ctx))
+
+	return user
+}
+
+func createTestPost(t *testing.T, s *Storage, userID string, status model.PostStatus) *model.Post {
+	t.Helper()
+
+	post, err := s.CreatePost(context.Background(), &model.Post{Title: "Title", Content: "Content", UserID: userID, Status: status}, nil, nil)
+	require.NoError(t, err)
+
+	return post
+}
+
+func addTestComment(t *testing.T, s *Storage, postID, userID, content string) *model.Comment {
+	t.Helper()
+
+	comment, err := s.AddComment(context.Background(), &model.Comment{PostID: postID, UserID: userID, Content: content})
+	require.NoError(t, err)
+
+	return comment
+}
+
+func commentContents(comments []*model.Comment) []string {
+	contents := []string{}
+	for _, c := range comments {
+		contents = append(contents, c.Content)
+	}
+	return contents
+}
+
+func TestStorage_Comments(t *testing.T) {
+	s := newTestStorage(t)
+	user := createTestUser(t, s, "alice")
+	post := createTestPost(t, s, user.ID, model.PostStatusPublished)
+	other := createTestPost(t, s, user.ID, model.PostStatusPublished)
+
+	// comments of the two posts are interleaved, so a page of the whole
+	// table differs from a page of one post
+	for _, content := range []string{"1", "2", "3", "4", "5"} {
+		addTestComment(t, s, post.ID, user.ID, content)
+		addTestComment(t, s, other.ID, user.ID, "other "+content)
+	}
+
+	limit := func(n int) *int { return &n }
+
+	tests := []struct {
+		name     string
+		limit    *int
+		offset   *int
+		expected []string
+	}{
+		{
+			name:     "Without limit",
+			expected: []string{"1", "2", "3", "4", "5"},
+		},
+		{
+			name:     "First page",
+			limit:    limit(2),
+			offset:   limit(0),
+			expected: []string{"1", "2"},
+		},
+		{
+			name:     "Second page",
+			limit:    limit(2),
+			offset:   limit(2),
+			expected: []string{"3", "4"},
+		},
+		{
+			name:     "Last page",
+			limit:    limit(2),
+			offset:   limit(4),
+			expected: []string{"5"},
+		},
+		{
+			name:     "After the last comment",
+			limit:    limit(2),
+			offset:   limit(6),
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comments, err := s.Comments(context.Background(), post.ID, tt.limit, tt.offset)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, commentContents(comments))
+		})
+	}
+}
+
+func TestStorage_UsersPost(t *testing.T) {
+	s := newTestStorage(t)
+	alice := createTestUser(t, s, "alice")
+	bob := createTestUser(t, s, "bob")
+
+	first := createTestPost(t, s, alice.ID, model.PostStatusPublished)
+	createTestPost(t, s, bob.ID, model.PostStatusPublished)
+	createTestPost(t, s, alice.ID, model.PostStatusDraft)
+	second := createTestPost(t, s, alice.ID, model.PostStatusPublished)
+
+	posts, err := s.UsersPost(context.Background(), alice.ID)
+	require.NoError(t, err)
+	require.Len(t, posts, 2)
+	assert.Equal(t, first.ID, posts[0].ID)
+	assert.Equal(t, second.ID, posts[1].ID)
+
+	posts, err = s.UsersPost(context.Background(), "12345")
+	require.NoError(t, err)
+	assert.Empty(t, posts)
+}
+
+func postIDs(posts []*model.Post) []string {
+	ids := []string{}
+	for _, p := range posts {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+func TestStorage_Posts(t *testing.T) {
+	s := newTestStorage(t)
+	user := createTestUser(t, s, "alice")
+	first := createTestPost(t, s, user.ID, model.PostStatusPublished)
+	createTestPost(t, s, user.ID, model.PostStatusDraft)
+	second := createTestPost(t, s, user.ID, model.PostStatusPublished)
+	third := createTestPost(t, s, user.ID, model.PostStatusPublished)
+
+	limit := func(n int) *int { return &n }
+
+	tests := []struct {
+		name     string
+		limit    *int
+		offset   *int
+		expected []string
+	}{
+		{
+			name:     "Without limit",
+			expected: []string{first.ID, second.ID, third.ID},
+		},
+		{
+			name:     "First page",
+			limit:    limit(2),
+			offset:   limit(0),
+			expected: []string{first.ID, second.ID},
+		},
+		{
+			name:     "Last page",
+			limit:    limit(2),
+			offset:   limit(2),
+			expected: []string{third.ID},
+		},
+		{
+			name:     "After the last post",
+			limit:    limit(2),
+			offset:   limit(4),
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			posts, err := s.Posts(context.Background(), tt.limit, tt.offset)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, postIDs(posts))
+		})
+	}
+}
+
+func TestStorage_Post(t *testing.T) {
+	s := newTestStorage(t)
+	user := createTestUser(t, s, "alice")
+	post := createTestPost(t, s, user.ID, model.PostStatusDraft)
+
+	tests := []struct {
+		name          string
+		id            string
+		expected      *model.Post
+		expectedError error
+	}{
+		{
+			name:     "Existing post",
+			id:       post.ID,
+			expected: post,
+		},
+		{
+			name:          "Unknown id",
+			id:            "12345",
+			expectedError: storage.ErrNotFound,
+		},
+		{
+			name:          "Invalid id",
+			id:            "abc",
+			expectedError: storage.ErrInvalidID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Post(context.Background(), tt.id)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, got)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestStorage_UserByField(t *testing.T) {
+	s := newTestStorage(t)
+	user := createTestUser(t, s, "alice")
+	createTestUser(t, s, "bob")
+
+	tests := []struct {
+		name          string
+		get           func(ctx context.Context) (*model.User, error)
+		expectedError error
+	}{
+		{
+			name: "By id",
+			get:  func(ctx context.Context) (*model.User, error) { return s.UserByID(ctx, user.ID) },
+		},
+		{
+			name: "By username",
+			get:  func(ctx context.Context) (*model.User, error) { return s.UserByUsername(ctx, "alice") },
+		},
+		{
+			name: "By field",
+			get:  func(ctx context.Context) (*model.User, error) { return s.UserByField(ctx, "username", "alice") },
+		},
+		{
+			name:          "Unknown id",
+			get:           func(ctx context.Context) (*model.User, error) { return s.UserByID(ctx, "12345") },
+			expectedError: storage.ErrNotFound,
+		},
+		{
+			name:          "Unknown username",
+			get:           func(ctx context.Context) (*model.User, error) { return s.UserByUsername(ctx, "carol") },
+			expectedError: storage.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.get(context.Background())
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, got)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, user.ID, got.ID)
+			assert.Equal(t, "alice", got.Username)
+			assert.Equal(t, "hash", got.Password)
+		})
+	}
+}
+
+func usernames(users []*model.User) []string {
+	names := []string{}
+	for _, u := range users {
+		names = append(names, u.Username)
+	}
+	return names
+}
+
+func TestStorage_Users(t *testing.T) {
+	s := newTestStorage(t)
+	alice := createTestUser(t, s, "alice")
+	bob := createTestUser(t, s, "bob")
+	createTestUser(t, s, "carol")
+
+	tests := []struct {
+		name     string
+		get      func(ctx context.Context) ([]*model.User, error)
+		expected []string
+	}{
+		{
+			name:     "All users",
+			get:      s.Users,
+			expected: []string{"alice", "bob", "carol"},
+		},
+		{
+			name: "By ids",
+			get: func(ctx context.Context) ([]*model.User, error) {
+				return s.UsersByIDs(ctx, []string{bob.ID, "12345", alice.ID})
+			},
+			expected: []string{"alice", "bob"},
+		},
+		{
+			name: "By unknown ids",
+			get: func(ctx context.Context) ([]*model.User, error) {
+				return s.UsersByIDs(ctx, []string{"12345"})
+			},
+			expected: []string{},
+		},
+		{
+			name: "By usernames",
+			get: func(ctx context.Context) ([]*model.User, error) {
+				return s.UsersByUsernames(ctx, []string{"carol", "dave", "alice"})
+			},
+			expected: []string{"alice", "carol"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, err := tt.get(context.Background())
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.expected, usernames(users))
+		})
+	}
+}
+
+func TestStorage_UpdateUser(t *testing.T) {
+	s := newTestStorage(t)
+	user := createTestUser(t, s, "alice")
+
+	tests := []struct {
+		name          string
+		user          *model.User
+		expectedError error
+	}{
+		{
+			name: "Existing user",
+			user: &model.User{ID: user.ID, FirstName: "Alice", LastName: "Smith", Bio: "Hi", Website: "https://example.com"},
+		},
+		{
+			name:          "Unknown id",
+			user:          &model.User{ID: "12345", FirstName: "Alice"},
+			expectedError: storage.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, err := s.UpdateUser(context.Background(), tt.user)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "alice", updated.Username)
+			assert.Equal(t, "Alice", updated.FirstName)
+			assert.Equal(t, "Smith", updated.LastName)
+			assert.Equal(t, "Hi", updated.Bio)
+			assert.Equal(t, "https://example.com", updated.Website)
+			assert.False(t, updated.UpdateAt.Before(user.UpdateAt))
+		})
+	}
+}
+
+func TestStorage_UsersStats(t *testing.T) {
+	s := newTestStorage(t)
+	alice := createTestUser(t, s, "alice")
+	bob := createTestUser(t, s, "bob")
+
+	post := createTestPost(t, s, alice.ID, model.PostStatusPublished)
+	createTestPost(t, s, alice.ID, model.PostStatusDraft)
+	addTestComment(t, s, post.ID, alice.ID, "own")
+	addTestComment(t, s, post.ID, bob.ID, "first")
+	addTestComment(t, s, post.ID, bob.ID, "second")
+
+	stats, err := s.UsersStats(context.Background(), []string{bob.ID, "12345", alice.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []*model.UserStats{
+		{UserID: bob.ID, CommentCount: 2},
+		{UserID: "12345"},
+		{UserID: alice.ID, PostCount: 1, CommentCount: 1, Karma: 2},
+	}, stats)
+
+	st, err := s.UserStats(context.Background(), alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, stats[2], st)
+
+	_, err = s.UserStats(context.Background(), "abc")
+	assert.ErrorIs(t, err, storage.ErrInvalidID)
+}
+
+func TestStorage_CreateUser(t *testing.T) {
+	s := newTestStorage(t)
+	createTestUser(t, s, "alice")
+
+	tests := []struct {
+		name          string
+		username      string
+		expectedError error
+	}{
+		{
+			name:     "New username",
+			username: "bob",
+		},
+		{
+			name:          "Taken username",
+			username:      "alice",
+			expectedError: storage.ErrConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			tx, err := s.Begin(ctx)
+			require.NoError(t, err)
+			defer tx.Rollback(ctx)
+
+			user, err := s.CreateUser(ctx, tx, &model.User{Username: tt.username, FirstName: "First", LastName: "Last"})
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, user.ID)
+			assert.False(t, user.CreatedAt.IsZero())
+			require.NoError(t, tx.Commit(ctx))
+
+			stored, err := s.UserByUsername(ctx, tt.username)
+			require.NoError(t, err)
+			assert.Equal(t, user.ID, stored.ID)
+		})
+	}
+
+	// a user created in a transaction that is rolled back isn't stored
+	ctx := context.Background()
+	tx, err := s.Begin(ctx)
+	require.NoError(t, err)
+	_, err = s.CreateUser(ctx, tx, &model.User{Username: "carol"})
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback(ctx))
+
+	_, err = s.UserByUsername(ctx, "carol")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func TestStorage_UpdatePassword(t *testing.T) {
+	s := newTestStorage(t)
+	user := createTestUser(t, s, "alice")
+
+	tests := []struct {
+		name          string
+		userID        string
+		expectedError error
+	}{
+		{
+			name:   "Existing user",
+			userID: user.ID,
+		},
+		{
+			name:          "Unknown id",
+			userID:        "12345",
+			expectedError: storage.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.UpdatePassword(context.Background(), tt.userID, "new hash")
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+
+			stored, err := s.UserByID(context.Background(), tt.userID)
+			require.NoError(t, err)
+			assert.Equal(t, "new hash", stored.Password)
+		})
+	}
+}
+
+func TestStorage_Comment(t *testing.T) {
+	s := newTestStorage(t)
+	user := createTestUser(t, s, "alice")
+	post := createTestPost(t, s, user.ID, model.PostStatusPublished)
+	parent := addTestComment(t, s, post.ID, user.ID, "parent")
+	reply, err := s.AddComment(context.Background(), &model.Comment{PostID: post.ID, ParentID: &parent.ID, UserID: user.ID, Content: "reply"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		id            string
+		expected      *model.Comment
+		expectedError error
+	}{
+		{
+			name:     "Comment",
+			id:       parent.ID,
+			expected: parent,
+		},
+		{
+			name:     "Reply",
+			id:       reply.ID,
+			expected: reply,
+		},
+		{
+			name:          "Unknown id",
+			id:            "12345",
+			expectedError: storage.ErrNotFound,
+		},
+		{
+			name:          "Invalid id",
+			id:            "abc",
+			expectedError: storage.ErrInvalidID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Comment(context.Background(), tt.id)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, got)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestStorage_UpdateComment(t *testing.T) {
+	s := newTestStorage(t)
+	user := createTestUser(t, s, "alice")
+	post := createTestPost(t, s, user.ID, model.PostStatusPublished)
+	comment := addTestComment(t, s, post.ID, user.ID, "first")
+	addTestComment(t, s, post.ID, user.ID, "second")
+
+	// the steps run in order on the same comment
+	tests := []struct {
+		name          string
+		update        *model.Comment
+		expectedCount int
+		expectedError error
+	}{
+		{
+			name:          "Edit",
+			update:        &model.Comment{ID: comment.ID, Content: "edited", Edited: true},
+			expectedCount: 2,
+		},
+		{
+			name:          "Delete",
+			update:        &model.Comment{ID: comment.ID, Content: "", Edited: true, Deleted: true},
+			expectedCount: 1,
+		},
+		{
+			name:          "Delete again",
+			update:        &model.Comment{ID: comment.ID, Content: "", Edited: true, Deleted: true},
+			expectedCount: 1,
+		},
+		{
+			name:          "Restore",
+			update:        &model.Comment{ID: comment.ID, Content: "restored", Edited: true},
+			expectedCount: 2,
+		},
+		{
+			name:          "Unknown id",
+			update:        &model.Comment{ID: "12345", Content: "edited"},
+			expectedCount: 2,
+			expectedError: storage.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, err := s.UpdateComment(context.Background(), tt.update)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.update.Content, updated.Content)
+				assert.Equal(t, tt.update.Deleted, updated.Deleted)
+				assert.Equal(t, post.ID, updated.PostID)
+			}
+
+			stored, err := s.Post(context.Background(), post.ID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCount, stored.CommentCount)
+		})
+	}
+}
+
+func TestStorage_CreatePost(t *testing.T) {
+	s := newTestStorage(t)
+	user := createTestUser(t, s, "alice")
+
+	tests := []struct {
+		name          string
+		post          *model.Post
+		expected      *model.Post
+		expectedError error
+	}{
+		{
+			name:     "Defaults",
+			post:     &model.Post{Title: "Title", Content: "Content", UserID: user.ID},
+			expected: &model.Post{ID: "1", Title: "Title", Content: "Content", CommentsEnabled: true, UserID: user.ID, Tags: []string{}, Status: model.PostStatusPublished},
+		},
+		{
+			name:     "Draft with tags",
+			post:     &model.Post{Title: "Title", Content: "Content", UserID: user.ID, Tags: []string{"go"}, Status: model.PostStatusDraft},
+			expected: &model.Post{ID: "2", Title: "Title", Content: "Content", CommentsEnabled: true, UserID: user.ID, Tags: []string{"go"}, Status: model.PostStatusDraft},
+		},
+		{
+			name:          "Unknown user",
+			post:          &model.Post{Title: "Title", Content: "Content", UserID: "12345"},
+			expectedError: storage.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post, err := s.CreatePost(context.Background(), tt.post, nil, nil)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.False(t, post.LastActivityAt.IsZero())
+			post.LastActivityAt = tt.expected.LastActivityAt
+			assert.Equal(t, tt.expected, post)
+		})
+	}
+}
+
+func TestStorage_AddComment(t *testing.T) {
+	s := newTestStorage(t)
+	user := createTestUser(t, s, "alice")
+	post := createTestPost(t, s, user.ID, model.PostStatusPublished)
+
+	tests := []struct {
+		name          string
+		comment       *model.Comment
+		expectedCount int
+		expectedError error
+	}{
+		{
+			name:          "First comment",
+			comment:       &model.Comment{PostID: post.ID, UserID: user.ID, Content: "first"},
+			expectedCount: 1,
+		},
+		{
+			name:          "Second comment",
+			comment:       &model.Comment{PostID: post.ID, UserID: user.ID, Content: "second"},
+			expectedCount: 2,
+		},
+		{
+			name:          "Unknown post",
+			comment:       &model.Comment{PostID: "12345", UserID: user.ID, Content: "lost"},
+			expectedCount: 2,
+			expectedError: storage.ErrNotFound,
+		},
+		{
+			name:          "Unknown user",
+			comment:       &model.Comment{PostID: post.ID, UserID: "12345", Content: "lost"},
+			expectedCount: 2,
+			expectedError: storage.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, err := s.Post(context.Background(), post.ID)
+			require.NoError(t, err)
+
+			comment, err := s.AddComment(context.Background(), tt.comment)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				require.NoError(t, err)
+				assert.NotEmpty(t, comment.ID)
+				assert.False(t, comment.Edited)
+			}
+
+			after, err := s.Post(context.Background(), post.ID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCount, after.CommentCount)
+			if tt.expectedError == nil {
+				assert.True(t, after.LastActivityAt.After(before.LastActivityAt))
+			} else {
+				assert.Equal(t, before.LastActivityAt, after.LastActivityAt)
+			}
+		})
+	}
+}
+
+func TestStorage_UpdatePost(t *testing.T) {
+	s := newTestStorage(t)
+	user := createTestUser(t, s, "alice")
+	post := createTestPost(t, s, user.ID, model.PostStatusPublished)
+
+	tests := []struct {
+		name          string
+		update        *model.UpdatePost
+		expectedError error
+	}{
+		{
+			name:   "Disable comments",
+			update: &model.UpdatePost{PostID: post.ID, EnableComments: false},
+		},
+		{
+			name:   "Enable comments",
+			update: &model.UpdatePost{PostID: post.ID, EnableComments: true},
+		},
+		{
+			name:          "Unknown id",
+			update:        &model.UpdatePost{PostID: "12345"},
+			expectedError: storage.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, err := s.UpdatePost(context.Background(), tt.update)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.update.EnableComments, updated.CommentsEnabled)
+			assert.Equal(t, post.Title, updated.Title)
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package postgres
+
+import (
+	"github.com/farid21ola/forum/storage"
+	"github.com/farid21ola/forum/storage/storagetest"
+	"testing"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return newTestStorage(t)
+	})
+}
@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"context"
+	"github.com/farid21ola/forum/model"
+)
+
+func (s *Storage) VotePost(ctx context.Context, vote *model.Vote) (int, error) {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if vote.Value == 0 {
+		q := `DELETE FROM "post_votes" WHERE post_id = $1 AND user_id = $2`
+		_, err = tx.Exec(ctx, q, vote.PostID, vote.UserID)
+	} else {
+		q := `INSERT INTO "post_votes" (post_id, user_id, value) VALUES ($1, $2, $3)
+			ON CONFLICT (post_id, user_id) DO UPDATE SET value = EXCLUDED.value`
+		_, err = tx.Exec(ctx, q, vote.PostID, vote.UserID, vote.Value)
+	}
+	if err != nil {
+		return 0, mapError(err)
+	}
+
+	// the score is recounted instead of adjusted so it can't drift from the votes
+	var score int
+	q := `UPDATE "posts" SET score = (SELECT COALESCE(SUM(value), 0) FROM "post_votes" WHERE post_id = $1)
+		WHERE id = $1 RETURNING score`
+	if err = tx.QueryRow(ctx, q, vote.PostID).Scan(&score); err != nil {
+		return 0, mapError(err)
+	}
+
+	return score, tx.Commit(ctx)
+}
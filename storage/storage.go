@@ -4,21 +4,147 @@ import (
 	"context"
 	"github.com/farid21ola/forum/model"
 	"github.com/jackc/pgx/v5"
+	"time"
 )
 
+// Storage returns ErrNotFound when a single row looked up or changed by its
+// id doesn't exist, ErrConflict for taken unique values and ErrInvalidID for
+// malformed ids. Lists leave missing rows out.
+//
 //go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=Storage --output=./mocks
 type Storage interface {
 	Begin(ctx context.Context) (pgx.Tx, error)
 	UserByID(ctx context.Context, id string) (*model.User, error)
 	UserByUsername(ctx context.Context, username string) (*model.User, error)
 	Users(ctx context.Context) ([]*model.User, error)
+	UsersByIDs(ctx context.Context, ids []string) ([]*model.User, error)
+	// UsersByUsernames skips usernames that don't exist.
+	UsersByUsernames(ctx context.Context, usernames []string) ([]*model.User, error)
+	UserStats(ctx context.Context, userID string) (*model.UserStats, error)
+	// UsersStats returns the stats of several users in the order of userIDs.
+	UsersStats(ctx context.Context, userIDs []string) ([]*model.UserStats, error)
 	UsersPost(ctx context.Context, id string) ([]*model.Post, error)
 	Posts(ctx context.Context, limit, offset *int) ([]*model.Post, error)
 	Post(ctx context.Context, id string) (*model.Post, error)
+	// PostsByIDs and CommentsByIDs skip ids that don't exist.
+	PostsByIDs(ctx context.Context, ids []string) ([]*model.Post, error)
 	Comments(ctx context.Context, id string, limit, offset *int) ([]*model.Comment, error)
+	Comment(ctx context.Context, id string) (*model.Comment, error)
+	CommentsByIDs(ctx context.Context, ids []string) ([]*model.Comment, error)
 
 	CreateUser(ctx context.Context, tx pgx.Tx, user *model.User) (*model.User, error)
-	CreatePost(ctx context.Context, post *model.Post) (*model.Post, error)
+	UpdateUser(ctx context.Context, user *model.User) (*model.User, error)
+	UpdatePassword(ctx context.Context, userID, hash string) error
+	// CreatePost stores the post together with its images and its poll, so a
+	// post is never left without them. poll may be nil.
+	CreatePost(ctx context.Context, post *model.Post, images []*model.Image, poll *model.Poll) (*model.Post, error)
 	UpdatePost(ctx context.Context, upd *model.UpdatePost) (*model.Post, error)
+	// UserDrafts returns the drafts and scheduled posts of the user.
+	UserDrafts(ctx context.Context, userID string) ([]*model.Post, error)
+	// UpdateDraft saves the title, content, tags, status and publish time of an
+	// unpublished post, it returns nil when the post has been published.
+	UpdateDraft(ctx context.Context, post *model.Post) (*model.Post, error)
+	// PublishPost returns nil when the post is already published.
+	PublishPost(ctx context.Context, id string) (*model.Post, error)
+	// PublishDuePosts publishes up to limit scheduled posts due at now and
+	// returns them. Due posts that publishable rejects become drafts again.
+	// Concurrent calls never return the same post.
+	PublishDuePosts(ctx context.Context, now time.Time, limit int, publishable func(*model.Post) bool) ([]*model.Post, error)
 	AddComment(ctx context.Context, comment *model.Comment) (*model.Comment, error)
+	UpdateComment(ctx context.Context, comment *model.Comment) (*model.Comment, error)
+	// VotePost stores the vote, a zero value removes it, and returns the new
+	// score of the post.
+	VotePost(ctx context.Context, vote *model.Vote) (int, error)
+	PostImages(ctx context.Context, postID string) ([]*model.Image, error)
+	// PostsImages returns the images of all the posts in the order they were
+	// added.
+	PostsImages(ctx context.Context, postIDs []string) ([]*model.Image, error)
+	AddLoginEvent(ctx context.Context, event *model.LoginEvent) error
+	LoginFailures(ctx context.Context, username, ip string, since time.Time) (*model.LoginFailures, error)
+	// AddMentions skips the mentions that are already stored.
+	AddMentions(ctx context.Context, mentions []*model.Mention) error
+	// RemoveCommentMentions removes the mentions in the content of a comment.
+	RemoveCommentMentions(ctx context.Context, commentID string) error
+	// PostsMentions returns the mentions in the content of the posts, mentions
+	// in their comments are not included. PostsMentions and CommentsMentions
+	// order the mentions by post or comment id and username.
+	PostsMentions(ctx context.Context, postIDs []string) ([]*model.Mention, error)
+	CommentsMentions(ctx context.Context, commentIDs []string) ([]*model.Mention, error)
+	AddNotification(ctx context.Context, n *model.Notification) (*model.Notification, error)
+	Notifications(ctx context.Context, filter model.NotificationFilter) ([]*model.Notification, error)
+	// MarkNotificationsRead marks all notifications of the user as read when
+	// ids is nil.
+	MarkNotificationsRead(ctx context.Context, userID string, ids []string) error
+	UnreadNotificationCount(ctx context.Context, userID string) (int, error)
+	// AddBookmark does nothing when the post or comment is already saved.
+	AddBookmark(ctx context.Context, b *model.Bookmark) error
+	// RemoveBookmark deletes the bookmark of the user for b.PostID or
+	// b.CommentID.
+	RemoveBookmark(ctx context.Context, b *model.Bookmark) error
+	Bookmarks(ctx context.Context, filter model.BookmarkFilter) ([]*model.Bookmark, error)
+	// SavedPostIDs returns the ids of the given posts the user has saved.
+	SavedPostIDs(ctx context.Context, userID string, postIDs []string) ([]string, error)
+	// Follow does nothing when the user already follows the followee.
+	Follow(ctx context.Context, f *model.Follow) error
+	Unfollow(ctx context.Context, f *model.Follow) error
+	Follows(ctx context.Context, filter model.FollowFilter) ([]*model.Follow, error)
+	// FollowCount counts the follows matching the follower or the followee
+	// of the filter.
+	FollowCount(ctx context.Context, filter model.FollowFilter) (int, error)
+	// AddFollowerNotifications adds a copy of n for every follower of its
+	// actor, except the users in skip and the ones who block the actor. The
+	// stored notifications are returned.
+	AddFollowerNotifications(ctx context.Context, n *model.Notification, skip []string) ([]*model.Notification, error)
+	Feed(ctx context.Context, filter model.FeedFilter) ([]*model.Post, error)
+	// AddBlock does nothing when the target is already blocked or muted the
+	// same way.
+	AddBlock(ctx context.Context, b *model.Block) error
+	RemoveBlock(ctx context.Context, b *model.Block) error
+	// Blocks returns the blocks and mutes set by the user, newest first.
+	Blocks(ctx context.Context, userID string) ([]*model.Block, error)
+	// BlockingUserIDs returns the ids of the given users who have blocked
+	// targetID.
+	BlockingUserIDs(ctx context.Context, targetID string, userIDs []string) ([]string, error)
+	// CreateConversation returns the existing conversation when there is one
+	// with the same participants.
+	CreateConversation(ctx context.Context, c *model.Conversation) (*model.Conversation, error)
+	Conversation(ctx context.Context, id string) (*model.Conversation, error)
+	// UserConversations returns the conversations of the user with the most
+	// recent messages first.
+	UserConversations(ctx context.Context, userID string) ([]*model.Conversation, error)
+	// ConversationsMembers returns the members of several conversations
+	// ordered by conversation and user id.
+	ConversationsMembers(ctx context.Context, conversationIDs []string) ([]*model.ConversationMember, error)
+	AddMessage(ctx context.Context, m *model.Message) (*model.Message, error)
+	Messages(ctx context.Context, filter model.MessageFilter) ([]*model.Message, error)
+	// LastMessages returns the newest message of each of the conversations
+	// that have one.
+	LastMessages(ctx context.Context, conversationIDs []string) ([]*model.Message, error)
+	// MarkConversationRead moves the read receipt of the user up to
+	// messageID, it never moves back.
+	MarkConversationRead(ctx context.Context, conversationID, userID, messageID string) error
+	// UnreadMessageCounts counts the messages of other participants after the
+	// read receipt of the user in the order of conversationIDs.
+	UnreadMessageCounts(ctx context.Context, userID string, conversationIDs []string) ([]int, error)
+	// Poll and PostsPolls return polls with the vote count of every option.
+	Poll(ctx context.Context, id string) (*model.Poll, error)
+	// PostsPolls skips the posts that have no poll.
+	PostsPolls(ctx context.Context, postIDs []string) ([]*model.Poll, error)
+	// AddPollVote stores the vote and counts it, it returns false when the
+	// user has already voted in the poll.
+	AddPollVote(ctx context.Context, vote *model.PollVote) (bool, error)
+	// PollVote returns the options the user has chosen, nil when the user
+	// hasn't voted.
+	PollVote(ctx context.Context, pollID, userID string) ([]string, error)
+	// PollsVotes returns the votes of the user in the polls, polls the user
+	// hasn't voted in are skipped.
+	PollsVotes(ctx context.Context, userID string, pollIDs []string) ([]*model.PollVote, error)
+	// AddReaction does nothing when the user has already reacted to the
+	// target with the same emoji.
+	AddReaction(ctx context.Context, r *model.Reaction) error
+	RemoveReaction(ctx context.Context, r *model.Reaction) error
+	// ReactionCounts counts the reactions of the posts or the comments with
+	// the given ids by emoji, in the order the emojis were first used on each
+	// target. ReactedByMe is set for the reactions of userID.
+	ReactionCounts(ctx context.Context, target model.ReactionTarget, targetIDs []string, userID string) ([]*model.ReactionCount, error)
 }
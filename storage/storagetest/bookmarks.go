@@ -0,0 +1,102 @@
+package storagetest
+
+import (
+	"context"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// bookmarkTargets names what the bookmarks point to, "post <id>" or
+// "comment <id>".
+func bookmarkTargets(bookmarks []*model.Bookmark) []string {
+	targets := []string{}
+	for _, b := range bookmarks {
+		if b.PostID != nil {
+			targets = append(targets, "post "+*b.PostID)
+		} else {
+			targets = append(targets, "comment "+*b.CommentID)
+		}
+	}
+	return targets
+}
+
+func testBookmarks(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+	first := createPost(t, s, alice.ID, "First", model.PostStatusPublished)
+	second := createPost(t, s, alice.ID, "Second", model.PostStatusPublished)
+	comment := addComment(t, s, first.ID, bob.ID, "Content")
+
+	for _, b := range []*model.Bookmark{
+		{UserID: alice.ID, PostID: &first.ID},
+		{UserID: alice.ID, CommentID: &comment.ID},
+		{UserID: alice.ID, PostID: &second.ID},
+		{UserID: alice.ID, PostID: &first.ID},
+		{UserID: bob.ID, PostID: &first.ID},
+	} {
+		require.NoError(t, s.AddBookmark(ctx, b))
+	}
+
+	page, err := s.Bookmarks(ctx, model.BookmarkFilter{UserID: alice.ID, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+
+	postType, commentType := model.SavedTypePost, model.SavedTypeComment
+	tests := []struct {
+		name     string
+		filter   model.BookmarkFilter
+		expected []string
+	}{
+		{
+			name:     "All",
+			filter:   model.BookmarkFilter{UserID: alice.ID, Limit: 10},
+			expected: []string{"post " + second.ID, "comment " + comment.ID, "post " + first.ID},
+		},
+		{
+			name:     "First page",
+			filter:   model.BookmarkFilter{UserID: alice.ID, Limit: 1},
+			expected: []string{"post " + second.ID},
+		},
+		{
+			name:     "Next page",
+			filter:   model.BookmarkFilter{UserID: alice.ID, After: &page[0].ID, Limit: 10},
+			expected: []string{"comment " + comment.ID, "post " + first.ID},
+		},
+		{
+			name:     "Posts",
+			filter:   model.BookmarkFilter{UserID: alice.ID, Type: &postType, Limit: 10},
+			expected: []string{"post " + second.ID, "post " + first.ID},
+		},
+		{
+			name:     "Comments",
+			filter:   model.BookmarkFilter{UserID: alice.ID, Type: &commentType, Limit: 10},
+			expected: []string{"comment " + comment.ID},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bookmarks, err := s.Bookmarks(ctx, tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, bookmarkTargets(bookmarks))
+		})
+	}
+
+	saved, err := s.SavedPostIDs(ctx, alice.ID, []string{first.ID, second.ID, unknownID})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{first.ID, second.ID}, saved)
+
+	require.NoError(t, s.RemoveBookmark(ctx, &model.Bookmark{UserID: alice.ID, PostID: &first.ID}))
+	require.NoError(t, s.RemoveBookmark(ctx, &model.Bookmark{UserID: alice.ID, CommentID: &comment.ID}))
+	bookmarks, err := s.Bookmarks(ctx, model.BookmarkFilter{UserID: alice.ID, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"post " + second.ID}, bookmarkTargets(bookmarks))
+
+	saved, err = s.SavedPostIDs(ctx, bob.ID, []string{first.ID, second.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []string{first.ID}, saved)
+}
@@ -0,0 +1,108 @@
+package storagetest
+
+import (
+	"context"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func schedulePost(t *testing.T, s storage.Storage, userID, title string, at time.Time) *model.Post {
+	t.Helper()
+
+	post := createPost(t, s, userID, title, model.PostStatusDraft)
+	post.Status = model.PostStatusScheduled
+	post.PublishAt = &at
+	scheduled, err := s.UpdateDraft(context.Background(), post)
+	require.NoError(t, err)
+	return scheduled
+}
+
+// testDrafts checks that drafts stay out of the published posts until they
+// are published once, and are then listed by the time they were published.
+func testDrafts(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	user := mustCreateUser(t, s, "alice")
+	draft := createPost(t, s, user.ID, "Draft", model.PostStatusDraft)
+	assert.Nil(t, draft.PublishedAt)
+	published := createPost(t, s, user.ID, "Published", model.PostStatusPublished)
+	require.NotNil(t, published.PublishedAt)
+	at := time.Now().Add(time.Hour)
+	scheduled := schedulePost(t, s, user.ID, "Scheduled", at)
+
+	assert.Equal(t, model.PostStatusScheduled, scheduled.Status)
+	require.NotNil(t, scheduled.PublishAt)
+	assert.WithinDuration(t, at, *scheduled.PublishAt, time.Millisecond)
+
+	drafts, err := s.UserDrafts(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Scheduled", "Draft"}, postTitles(drafts))
+
+	draft.Title = "Edited"
+	edited, err := s.UpdateDraft(ctx, draft)
+	require.NoError(t, err)
+	assert.Equal(t, "Edited", edited.Title)
+
+	post, err := s.PublishPost(ctx, draft.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.PostStatusPublished, post.Status)
+	assert.Nil(t, post.PublishAt)
+	require.NotNil(t, post.PublishedAt)
+	assert.False(t, post.PublishedAt.Before(*published.PublishedAt))
+
+	post, err = s.PublishPost(ctx, draft.ID)
+	require.NoError(t, err)
+	assert.Nil(t, post)
+	post, err = s.UpdateDraft(ctx, published)
+	require.NoError(t, err)
+	assert.Nil(t, post)
+
+	posts, err := s.Posts(ctx, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Published", "Edited"}, postTitles(posts))
+}
+
+// testPublishDuePosts checks that only due posts are published, that
+// rejected ones become drafts and that limit is kept.
+func testPublishDuePosts(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	now := time.Now()
+	user := mustCreateUser(t, s, "alice")
+	schedulePost(t, s, user.ID, "Due", now.Add(-time.Minute))
+	invalid := schedulePost(t, s, user.ID, "Invalid", now.Add(-time.Minute))
+	later := schedulePost(t, s, user.ID, "Later", now.Add(time.Hour))
+	publishable := func(p *model.Post) bool { return p.Title != "Invalid" }
+
+	published, err := s.PublishDuePosts(ctx, now, 10, publishable)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Due"}, postTitles(published))
+	assert.Equal(t, model.PostStatusPublished, published[0].Status)
+	assert.Nil(t, published[0].PublishAt)
+
+	post, err := s.Post(ctx, invalid.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.PostStatusDraft, post.Status)
+	assert.Nil(t, post.PublishAt)
+	post, err = s.Post(ctx, later.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.PostStatusScheduled, post.Status)
+
+	published, err = s.PublishDuePosts(ctx, now, 10, publishable)
+	require.NoError(t, err)
+	assert.Empty(t, published)
+
+	schedulePost(t, s, user.ID, "First", now.Add(-time.Minute))
+	schedulePost(t, s, user.ID, "Second", now.Add(-time.Minute))
+	for i := 0; i < 2; i++ {
+		published, err = s.PublishDuePosts(ctx, now, 1, publishable)
+		require.NoError(t, err)
+		assert.Len(t, published, 1)
+	}
+
+	posts, err := s.Posts(ctx, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Due", "First", "Second"}, postTitles(posts))
+}
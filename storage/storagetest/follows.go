@@ -0,0 +1,210 @@
+package storagetest
+
+import (
+	"context"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func testFollows(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+	carol := mustCreateUser(t, s, "carol")
+
+	for _, f := range []*model.Follow{
+		{FollowerID: alice.ID, FolloweeID: bob.ID},
+		{FollowerID: alice.ID, FolloweeID: carol.ID},
+		{FollowerID: bob.ID, FolloweeID: carol.ID},
+		{FollowerID: alice.ID, FolloweeID: bob.ID},
+	} {
+		require.NoError(t, s.Follow(ctx, f))
+	}
+
+	following, err := s.Follows(ctx, model.FollowFilter{FollowerID: alice.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, following, 2)
+	assert.Equal(t, carol.ID, following[0].FolloweeID)
+	assert.Equal(t, bob.ID, following[1].FolloweeID)
+
+	page, err := s.Follows(ctx, model.FollowFilter{FollowerID: alice.ID, After: &following[0].ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, bob.ID, page[0].FolloweeID)
+
+	followers, err := s.Follows(ctx, model.FollowFilter{FolloweeID: carol.ID, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, followers, 1)
+	assert.Equal(t, bob.ID, followers[0].FollowerID)
+
+	count, err := s.FollowCount(ctx, model.FollowFilter{FolloweeID: carol.ID})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	count, err = s.FollowCount(ctx, model.FollowFilter{FollowerID: alice.ID})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	require.NoError(t, s.Unfollow(ctx, &model.Follow{FollowerID: alice.ID, FolloweeID: carol.ID}))
+	count, err = s.FollowCount(ctx, model.FollowFilter{FolloweeID: carol.ID})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+// testFollowerNotifications checks that followers get one notification each,
+// except the skipped ones and the ones blocking the author.
+func testFollowerNotifications(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+	carol := mustCreateUser(t, s, "carol")
+	dave := mustCreateUser(t, s, "dave")
+	erin := mustCreateUser(t, s, "erin")
+	for _, follower := range []*model.User{bob, carol, dave, erin} {
+		require.NoError(t, s.Follow(ctx, &model.Follow{FollowerID: follower.ID, FolloweeID: alice.ID}))
+	}
+	require.NoError(t, s.AddBlock(ctx, &model.Block{UserID: dave.ID, TargetID: alice.ID, Kind: model.BlockKindBlock}))
+	// a mute still lets the notification through
+	require.NoError(t, s.AddBlock(ctx, &model.Block{UserID: erin.ID, TargetID: alice.ID, Kind: model.BlockKindMute}))
+	post := createPost(t, s, alice.ID, "Hello", model.PostStatusPublished)
+
+	n := &model.Notification{Kind: model.NotificationKindNewPost, ActorID: &alice.ID, PostID: &post.ID, Message: "new post"}
+	added, err := s.AddFollowerNotifications(ctx, n, []string{carol.ID})
+	require.NoError(t, err)
+	require.Len(t, added, 2)
+
+	recipients := []string{}
+	for _, a := range added {
+		recipients = append(recipients, a.UserID)
+		assert.NotEmpty(t, a.ID)
+		assert.Equal(t, model.NotificationKindNewPost, a.Kind)
+		assert.Equal(t, &post.ID, a.PostID)
+		assert.False(t, a.Read)
+	}
+	assert.ElementsMatch(t, []string{bob.ID, erin.ID}, recipients)
+
+	for _, user := range []*model.User{bob, carol, dave, erin} {
+		notifications, err := s.Notifications(ctx, model.NotificationFilter{UserID: user.ID, Limit: 10})
+		require.NoError(t, err)
+		if user == bob || user == erin {
+			require.Len(t, notifications, 1, user.Username)
+			assert.Equal(t, "new post", notifications[0].Message)
+		} else {
+			assert.Empty(t, notifications, user.Username)
+		}
+	}
+
+	none, err := s.AddFollowerNotifications(ctx, &model.Notification{
+		Kind: model.NotificationKindNewPost, ActorID: &bob.ID, PostID: &post.ID, Message: "no followers",
+	}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, none)
+}
+
+// testFeed checks that the feed merges the published posts of the followed
+// users newest first, by the time they were published.
+func testFeed(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+	carol := mustCreateUser(t, s, "carol")
+	require.NoError(t, s.Follow(ctx, &model.Follow{FollowerID: alice.ID, FolloweeID: bob.ID}))
+	require.NoError(t, s.Follow(ctx, &model.Follow{FollowerID: alice.ID, FolloweeID: carol.ID}))
+
+	createPost(t, s, bob.ID, "bob 1", model.PostStatusPublished)
+	middle := createPost(t, s, carol.ID, "carol 1", model.PostStatusPublished)
+	draft := createPost(t, s, bob.ID, "bob draft", model.PostStatusDraft)
+	createPost(t, s, alice.ID, "alice 1", model.PostStatusPublished)
+	createPost(t, s, bob.ID, "bob 2", model.PostStatusPublished)
+	_, err := s.PublishPost(ctx, draft.ID)
+	require.NoError(t, err)
+	after := middle.Cursor()
+
+	tests := []struct {
+		name     string
+		filter   model.FeedFilter
+		expected []string
+	}{
+		{
+			name:     "All",
+			filter:   model.FeedFilter{UserID: alice.ID, Limit: 10},
+			expected: []string{"bob draft", "bob 2", "carol 1", "bob 1"},
+		},
+		{
+			name:     "First page",
+			filter:   model.FeedFilter{UserID: alice.ID, Limit: 2},
+			expected: []string{"bob draft", "bob 2"},
+		},
+		{
+			name:     "Next page",
+			filter:   model.FeedFilter{UserID: alice.ID, After: &after, Limit: 2},
+			expected: []string{"bob 1"},
+		},
+		{
+			name:     "Invalid cursor",
+			filter:   model.FeedFilter{UserID: alice.ID, After: &middle.ID, Limit: 2},
+			expected: []string{},
+		},
+		{
+			name:     "Nobody followed",
+			filter:   model.FeedFilter{UserID: carol.ID, Limit: 10},
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			posts, err := s.Feed(ctx, tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, postTitles(posts))
+		})
+	}
+
+	require.NoError(t, s.Unfollow(ctx, &model.Follow{FollowerID: alice.ID, FolloweeID: bob.ID}))
+	posts, err := s.Feed(ctx, model.FeedFilter{UserID: alice.ID, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"carol 1"}, postTitles(posts))
+}
+
+func testBlocks(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+	carol := mustCreateUser(t, s, "carol")
+
+	for _, b := range []*model.Block{
+		{UserID: alice.ID, TargetID: bob.ID, Kind: model.BlockKindBlock},
+		{UserID: alice.ID, TargetID: carol.ID, Kind: model.BlockKindMute},
+		{UserID: carol.ID, TargetID: bob.ID, Kind: model.BlockKindBlock},
+		{UserID: alice.ID, TargetID: bob.ID, Kind: model.BlockKindBlock},
+	} {
+		require.NoError(t, s.AddBlock(ctx, b))
+	}
+
+	blocks, err := s.Blocks(ctx, alice.ID)
+	require.NoError(t, err)
+	require.Len(t, blocks, 2)
+	assert.Equal(t, carol.ID, blocks[0].TargetID)
+	assert.Equal(t, model.BlockKindMute, blocks[0].Kind)
+	assert.Equal(t, bob.ID, blocks[1].TargetID)
+	assert.Equal(t, model.BlockKindBlock, blocks[1].Kind)
+
+	ids, err := s.BlockingUserIDs(ctx, bob.ID, []string{alice.ID, carol.ID, unknownID})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{alice.ID, carol.ID}, ids)
+
+	// a mute doesn't hide the muting user from the target
+	ids, err = s.BlockingUserIDs(ctx, carol.ID, []string{alice.ID})
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+
+	require.NoError(t, s.RemoveBlock(ctx, &model.Block{UserID: alice.ID, TargetID: bob.ID, Kind: model.BlockKindBlock}))
+	ids, err = s.BlockingUserIDs(ctx, bob.ID, []string{alice.ID})
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+	blocks, err = s.Blocks(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.Len(t, blocks, 1)
+}
@@ -0,0 +1,53 @@
+package storagetest
+
+import (
+	"context"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// testImages checks that images are stored with their post and that a failed
+// post leaves no images behind.
+func testImages(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	alice := mustCreateUser(t, s, "alice")
+	image := func(key string) *model.Image {
+		return &model.Image{UserID: alice.ID, Key: key, ThumbnailKey: key + "_thumb", ContentType: "image/png", Width: 16, Height: 16, Size: 1}
+	}
+
+	images := []*model.Image{image("posts/a.png"), image("posts/b.png")}
+	post, err := s.CreatePost(ctx, &model.Post{Title: "Title", Content: "Content", UserID: alice.ID}, images, nil)
+	require.NoError(t, err)
+
+	stored, err := s.PostImages(ctx, post.ID)
+	require.NoError(t, err)
+	require.Len(t, stored, 2)
+	for i, img := range stored {
+		assert.Equal(t, post.ID, img.PostID)
+		assert.Equal(t, images[i].Key, img.Key)
+		assert.NotEmpty(t, img.ID)
+	}
+
+	_, err = s.CreatePost(ctx, &model.Post{Title: "Title", Content: "Content", UserID: unknownID},
+		[]*model.Image{image("posts/c.png")}, nil)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	other := createPost(t, s, alice.ID, "Other", model.PostStatusPublished)
+	none, err := s.PostImages(ctx, other.ID)
+	require.NoError(t, err)
+	assert.Empty(t, none)
+
+	third, err := s.CreatePost(ctx, &model.Post{Title: "Third", Content: "Content", UserID: alice.ID},
+		[]*model.Image{image("posts/d.png")}, nil)
+	require.NoError(t, err)
+	all, err := s.PostsImages(ctx, []string{third.ID, other.ID, post.ID})
+	require.NoError(t, err)
+	keys := make([]string, 0, len(all))
+	for _, img := range all {
+		keys = append(keys, img.Key)
+	}
+	assert.Equal(t, []string{"posts/a.png", "posts/b.png", "posts/d.png"}, keys)
+}
@@ -0,0 +1,43 @@
+package storagetest
+
+import (
+	"context"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// testLoginFailures checks that a successful login clears the failures of
+// the username but not the failures of the ip.
+func testLoginFailures(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	since := time.Now().Add(-time.Hour)
+
+	for _, e := range []*model.LoginEvent{
+		{Username: "alice", IP: "10.0.0.1"},
+		{Username: "bob", IP: "10.0.0.1"},
+		{Username: "mallory", IP: "10.0.0.1", Success: true},
+		{Username: "alice", IP: "10.0.0.1"},
+		{Username: "alice", IP: "10.0.0.2"},
+	} {
+		require.NoError(t, s.AddLoginEvent(ctx, e))
+	}
+
+	failures, err := s.LoginFailures(ctx, "alice", "10.0.0.1", since)
+	require.NoError(t, err)
+	assert.Equal(t, 3, failures.ByUsername)
+	assert.Equal(t, 3, failures.ByIP, "a login to another account doesn't clear the ip")
+
+	require.NoError(t, s.AddLoginEvent(ctx, &model.LoginEvent{Username: "alice", IP: "10.0.0.2", Success: true}))
+	failures, err = s.LoginFailures(ctx, "alice", "10.0.0.1", since)
+	require.NoError(t, err)
+	assert.Equal(t, 0, failures.ByUsername)
+	assert.Equal(t, 3, failures.ByIP)
+
+	failures, err = s.LoginFailures(ctx, "alice", "10.0.0.1", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 0, failures.ByIP, "failures before the window are not counted")
+}
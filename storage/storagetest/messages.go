@@ -0,0 +1,171 @@
+package storagetest
+
+import (
+	"context"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func messageContents(messages []*model.Message) []string {
+	contents := []string{}
+	for _, m := range messages {
+		contents = append(contents, m.Content)
+	}
+	return contents
+}
+
+func conversationIDs(conversations []*model.Conversation) []string {
+	ids := []string{}
+	for _, c := range conversations {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
+
+// testConversations checks that the same participants share one
+// conversation and that conversations with new messages come first.
+func testConversations(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+	carol := mustCreateUser(t, s, "carol")
+
+	direct, err := s.CreateConversation(ctx, &model.Conversation{ParticipantIDs: []string{bob.ID, alice.ID}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{alice.ID, bob.ID}, direct.ParticipantIDs)
+
+	same, err := s.CreateConversation(ctx, &model.Conversation{ParticipantIDs: []string{alice.ID, bob.ID}})
+	require.NoError(t, err)
+	assert.Equal(t, direct.ID, same.ID)
+
+	group, err := s.CreateConversation(ctx, &model.Conversation{ParticipantIDs: []string{carol.ID, alice.ID, bob.ID}})
+	require.NoError(t, err)
+	assert.NotEqual(t, direct.ID, group.ID)
+
+	stored, err := s.Conversation(ctx, group.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{alice.ID, bob.ID, carol.ID}, stored.ParticipantIDs)
+
+	members, err := s.ConversationsMembers(ctx, []string{group.ID, direct.ID, unknownID})
+	require.NoError(t, err)
+	require.Len(t, members, 5)
+	expected := []*model.ConversationMember{
+		{ConversationID: direct.ID, UserID: alice.ID},
+		{ConversationID: direct.ID, UserID: bob.ID},
+		{ConversationID: group.ID, UserID: alice.ID},
+		{ConversationID: group.ID, UserID: bob.ID},
+		{ConversationID: group.ID, UserID: carol.ID},
+	}
+	assert.Equal(t, expected, members)
+
+	last, err := s.LastMessages(ctx, []string{direct.ID, group.ID})
+	require.NoError(t, err)
+	assert.Empty(t, last)
+
+	addMessage(t, s, direct.ID, alice.ID, "direct")
+	addMessage(t, s, group.ID, carol.ID, "group")
+	conversations, err := s.UserConversations(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{group.ID, direct.ID}, conversationIDs(conversations))
+
+	addMessage(t, s, direct.ID, bob.ID, "direct again")
+	conversations, err = s.UserConversations(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{direct.ID, group.ID}, conversationIDs(conversations))
+
+	conversations, err = s.UserConversations(ctx, carol.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{group.ID}, conversationIDs(conversations))
+
+	last, err = s.LastMessages(ctx, []string{direct.ID, group.ID, unknownID})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"direct again", "group"}, messageContents(last))
+}
+
+func addMessage(t *testing.T, s storage.Storage, conversationID, userID, content string) *model.Message {
+	t.Helper()
+
+	m, err := s.AddMessage(context.Background(), &model.Message{ConversationID: conversationID, UserID: userID, Content: content})
+	require.NoError(t, err)
+	return m
+}
+
+// testMessages checks that messages are paged newest first and that read
+// receipts only move forward.
+func testMessages(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+	carol := mustCreateUser(t, s, "carol")
+	c, err := s.CreateConversation(ctx, &model.Conversation{ParticipantIDs: []string{alice.ID, bob.ID}})
+	require.NoError(t, err)
+
+	first := addMessage(t, s, c.ID, alice.ID, "1")
+	second := addMessage(t, s, c.ID, bob.ID, "2")
+	third := addMessage(t, s, c.ID, alice.ID, "3")
+	addMessage(t, s, c.ID, bob.ID, "4")
+
+	tests := []struct {
+		name     string
+		filter   model.MessageFilter
+		expected []string
+	}{
+		{
+			name:     "All",
+			filter:   model.MessageFilter{ConversationID: c.ID, Limit: 10},
+			expected: []string{"4", "3", "2", "1"},
+		},
+		{
+			name:     "Last page",
+			filter:   model.MessageFilter{ConversationID: c.ID, Limit: 2},
+			expected: []string{"4", "3"},
+		},
+		{
+			name:     "Earlier page",
+			filter:   model.MessageFilter{ConversationID: c.ID, Before: &third.ID, Limit: 10},
+			expected: []string{"2", "1"},
+		},
+		{
+			name:     "Unknown conversation",
+			filter:   model.MessageFilter{ConversationID: unknownID, Limit: 10},
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, err := s.Messages(ctx, tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, messageContents(messages))
+		})
+	}
+
+	other, err := s.CreateConversation(ctx, &model.Conversation{ParticipantIDs: []string{bob.ID, carol.ID}})
+	require.NoError(t, err)
+	addMessage(t, s, other.ID, carol.ID, "other")
+
+	// alice isn't a member of the other conversation, nothing is unread there
+	counts, err := s.UnreadMessageCounts(ctx, alice.ID, []string{c.ID, other.ID, unknownID})
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 0, 0}, counts)
+
+	require.NoError(t, s.MarkConversationRead(ctx, c.ID, alice.ID, second.ID))
+	require.NoError(t, s.MarkConversationRead(ctx, c.ID, alice.ID, first.ID))
+	counts, err = s.UnreadMessageCounts(ctx, alice.ID, []string{c.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1}, counts)
+
+	members, err := s.ConversationsMembers(ctx, []string{c.ID})
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+	require.NotNil(t, members[0].LastReadMessageID)
+	assert.Equal(t, second.ID, *members[0].LastReadMessageID)
+	assert.Nil(t, members[1].LastReadMessageID)
+
+	counts, err = s.UnreadMessageCounts(ctx, bob.ID, []string{other.ID, c.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, counts)
+}
@@ -0,0 +1,137 @@
+package storagetest
+
+import (
+	"context"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func notificationMessages(notifications []*model.Notification) []string {
+	messages := []string{}
+	for _, n := range notifications {
+		messages = append(messages, n.Message)
+	}
+	return messages
+}
+
+// testNotifications checks that the inbox of a user is paged newest first and
+// that read notifications are counted out.
+func testNotifications(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+	post := createPost(t, s, alice.ID, "Title", model.PostStatusPublished)
+
+	var ids []string
+	for _, message := range []string{"1", "2", "3"} {
+		n, err := s.AddNotification(ctx, &model.Notification{
+			UserID: alice.ID, Kind: model.NotificationKindMention, ActorID: &bob.ID, PostID: &post.ID, Message: message,
+		})
+		require.NoError(t, err)
+		assert.False(t, n.Read)
+		ids = append(ids, n.ID)
+	}
+	_, err := s.AddNotification(ctx, &model.Notification{UserID: bob.ID, Kind: model.NotificationKindSecurity, Message: "bob"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		filter   model.NotificationFilter
+		expected []string
+	}{
+		{
+			name:     "All",
+			filter:   model.NotificationFilter{UserID: alice.ID, Limit: 10},
+			expected: []string{"3", "2", "1"},
+		},
+		{
+			name:     "First page",
+			filter:   model.NotificationFilter{UserID: alice.ID, Limit: 2},
+			expected: []string{"3", "2"},
+		},
+		{
+			name:     "Next page",
+			filter:   model.NotificationFilter{UserID: alice.ID, After: &ids[1], Limit: 2},
+			expected: []string{"1"},
+		},
+		{
+			name:     "Malformed cursor",
+			filter:   model.NotificationFilter{UserID: alice.ID, After: strPtr("abc"), Limit: 2},
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifications, err := s.Notifications(ctx, tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, notificationMessages(notifications))
+		})
+	}
+
+	require.NoError(t, s.MarkNotificationsRead(ctx, alice.ID, []string{ids[0], "abc"}))
+	count, err := s.UnreadNotificationCount(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	unread, err := s.Notifications(ctx, model.NotificationFilter{UserID: alice.ID, UnreadOnly: true, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"3", "2"}, notificationMessages(unread))
+
+	require.NoError(t, s.MarkNotificationsRead(ctx, alice.ID, nil))
+	count, err = s.UnreadNotificationCount(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+	count, err = s.UnreadNotificationCount(ctx, bob.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+// testMentions checks that mentions in comments are kept apart from the ones
+// in the post.
+func testMentions(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+	carol := mustCreateUser(t, s, "carol")
+	post := createPost(t, s, alice.ID, "Title", model.PostStatusPublished)
+	other := createPost(t, s, alice.ID, "Other", model.PostStatusPublished)
+	comment := addComment(t, s, post.ID, bob.ID, "Content")
+
+	err := s.AddMentions(ctx, []*model.Mention{
+		{PostID: post.ID, UserID: carol.ID},
+		{PostID: post.ID, UserID: bob.ID},
+		{PostID: post.ID, CommentID: &comment.ID, UserID: alice.ID},
+	})
+	require.NoError(t, err)
+
+	// mentioning the same user again in the same content does nothing
+	err = s.AddMentions(ctx, []*model.Mention{
+		{PostID: post.ID, UserID: bob.ID},
+		{PostID: other.ID, UserID: bob.ID},
+		{PostID: post.ID, CommentID: &comment.ID, UserID: alice.ID},
+	})
+	require.NoError(t, err)
+
+	mentions, err := s.PostsMentions(ctx, []string{other.ID, post.ID, unknownID})
+	require.NoError(t, err)
+	assert.Equal(t, []*model.Mention{
+		{PostID: post.ID, UserID: bob.ID},
+		{PostID: post.ID, UserID: carol.ID},
+		{PostID: other.ID, UserID: bob.ID},
+	}, mentions)
+
+	mentions, err = s.CommentsMentions(ctx, []string{comment.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []*model.Mention{{PostID: post.ID, CommentID: &comment.ID, UserID: alice.ID}}, mentions)
+
+	require.NoError(t, s.RemoveCommentMentions(ctx, comment.ID))
+	mentions, err = s.CommentsMentions(ctx, []string{comment.ID})
+	require.NoError(t, err)
+	assert.Empty(t, mentions)
+	mentions, err = s.PostsMentions(ctx, []string{post.ID})
+	require.NoError(t, err)
+	assert.Len(t, mentions, 2, "only the mentions of the comment are removed")
+}
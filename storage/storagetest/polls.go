@@ -0,0 +1,97 @@
+package storagetest
+
+import (
+	"context"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func optionVotes(poll *model.Poll) []int {
+	votes := []int{}
+	for _, option := range poll.Options {
+		votes = append(votes, *option.Votes)
+	}
+	return votes
+}
+
+// testPolls checks that every user votes once and that the votes are
+// counted per option.
+func testPolls(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+	other := createPost(t, s, alice.ID, "Other", model.PostStatusPublished)
+
+	poll := &model.Poll{Question: "Which?", Multiple: true, Options: []*model.PollOption{{Text: "A"}, {Text: "B"}}}
+	post, err := s.CreatePost(ctx, &model.Post{Title: "Title", Content: "Content", UserID: alice.ID}, nil, poll)
+	require.NoError(t, err)
+	assert.Equal(t, post.ID, poll.PostID)
+	require.Len(t, poll.Options, 2)
+	assert.Equal(t, []int{0, 0}, optionVotes(poll))
+	a, b := poll.Options[0].ID, poll.Options[1].ID
+	assert.NotEqual(t, a, b)
+
+	none, err := s.PostsPolls(ctx, []string{other.ID})
+	require.NoError(t, err)
+	assert.Empty(t, none)
+
+	tests := []struct {
+		name          string
+		vote          *model.PollVote
+		expectedAdded bool
+	}{
+		{
+			name:          "Vote",
+			vote:          &model.PollVote{PollID: poll.ID, UserID: alice.ID, OptionIDs: []string{a}},
+			expectedAdded: true,
+		},
+		{
+			name:          "Vote again",
+			vote:          &model.PollVote{PollID: poll.ID, UserID: alice.ID, OptionIDs: []string{b}},
+			expectedAdded: false,
+		},
+		{
+			name:          "Several options",
+			vote:          &model.PollVote{PollID: poll.ID, UserID: bob.ID, OptionIDs: []string{a, b}},
+			expectedAdded: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, err := s.AddPollVote(ctx, tt.vote)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedAdded, added)
+		})
+	}
+
+	polls, err := s.PostsPolls(ctx, []string{other.ID, post.ID})
+	require.NoError(t, err)
+	require.Len(t, polls, 1)
+	stored := polls[0]
+	assert.Equal(t, poll.ID, stored.ID)
+	assert.Equal(t, "Which?", stored.Question)
+	assert.True(t, stored.Multiple)
+	assert.Equal(t, []int{2, 1}, optionVotes(stored))
+
+	stored, err = s.Poll(ctx, poll.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 1}, optionVotes(stored))
+
+	chosen, err := s.PollVote(ctx, poll.ID, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{a}, chosen)
+	chosen, err = s.PollVote(ctx, poll.ID, unknownID)
+	require.NoError(t, err)
+	assert.Nil(t, chosen)
+
+	votes, err := s.PollsVotes(ctx, bob.ID, []string{poll.ID, unknownID})
+	require.NoError(t, err)
+	assert.Equal(t, []*model.PollVote{{PollID: poll.ID, UserID: bob.ID, OptionIDs: []string{a, b}}}, votes)
+	votes, err = s.PollsVotes(ctx, unknownID, []string{poll.ID})
+	require.NoError(t, err)
+	assert.Empty(t, votes)
+}
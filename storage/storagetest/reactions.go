@@ -0,0 +1,53 @@
+package storagetest
+
+import (
+	"context"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// testReactions checks that reactions are counted once per user and emoji,
+// in the order the emojis were first used.
+func testReactions(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+	first := createPost(t, s, alice.ID, "First", model.PostStatusPublished)
+	second := createPost(t, s, alice.ID, "Second", model.PostStatusPublished)
+	comment := addComment(t, s, first.ID, bob.ID, "Content")
+
+	for _, r := range []*model.Reaction{
+		{UserID: alice.ID, PostID: &first.ID, Emoji: "👍"},
+		{UserID: bob.ID, PostID: &first.ID, Emoji: "❤️"},
+		{UserID: bob.ID, PostID: &first.ID, Emoji: "👍"},
+		{UserID: alice.ID, PostID: &first.ID, Emoji: "👍"},
+		{UserID: bob.ID, PostID: &second.ID, Emoji: "❤️"},
+		{UserID: alice.ID, CommentID: &comment.ID, Emoji: "👍"},
+	} {
+		require.NoError(t, s.AddReaction(ctx, r))
+	}
+
+	counts, err := s.ReactionCounts(ctx, model.ReactionTargetPost, []string{first.ID, second.ID, unknownID}, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []*model.ReactionCount{
+		{TargetID: first.ID, Emoji: "👍", Count: 2, ReactedByMe: true},
+		{TargetID: first.ID, Emoji: "❤️", Count: 1},
+		{TargetID: second.ID, Emoji: "❤️", Count: 1},
+	}, counts)
+
+	counts, err = s.ReactionCounts(ctx, model.ReactionTargetComment, []string{comment.ID}, "")
+	require.NoError(t, err)
+	assert.Equal(t, []*model.ReactionCount{{TargetID: comment.ID, Emoji: "👍", Count: 1}}, counts)
+
+	require.NoError(t, s.RemoveReaction(ctx, &model.Reaction{UserID: bob.ID, PostID: &first.ID, Emoji: "👍"}))
+	require.NoError(t, s.RemoveReaction(ctx, &model.Reaction{UserID: bob.ID, PostID: &second.ID, Emoji: "❤️"}))
+	counts, err = s.ReactionCounts(ctx, model.ReactionTargetPost, []string{first.ID, second.ID}, bob.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []*model.ReactionCount{
+		{TargetID: first.ID, Emoji: "👍", Count: 1},
+		{TargetID: first.ID, Emoji: "❤️", Count: 1, ReactedByMe: true},
+	}, counts)
+}
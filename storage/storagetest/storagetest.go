@@ -0,0 +1,660 @@
+// Package storagetest checks that a storage.Storage backend behaves the way
+// the domain expects. Every backend runs the same suite, so they can't drift
+// apart.
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"github.com/farid21ola/forum/model"
+	"github.com/farid21ola/forum/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+)
+
+// unknownID is a well formed id no test creates.
+const unknownID = "12345"
+
+// Run runs the suite, newStorage returns an empty storage for every test.
+func Run(t *testing.T, newStorage func(t *testing.T) storage.Storage) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s storage.Storage)
+	}{
+		{name: "NotFound", test: testNotFound},
+		{name: "Posts", test: testPosts},
+		{name: "Comments", test: testComments},
+		{name: "Users", test: testUsers},
+		{name: "UsersStats", test: testUsersStats},
+		{name: "UniqueUsername", test: testUniqueUsername},
+		{name: "CommentCount", test: testCommentCount},
+		{name: "Concurrency", test: testConcurrency},
+		{name: "Drafts", test: testDrafts},
+		{name: "PublishDuePosts", test: testPublishDuePosts},
+		{name: "Notifications", test: testNotifications},
+		{name: "Mentions", test: testMentions},
+		{name: "Bookmarks", test: testBookmarks},
+		{name: "Follows", test: testFollows},
+		{name: "FollowerNotifications", test: testFollowerNotifications},
+		{name: "Feed", test: testFeed},
+		{name: "Blocks", test: testBlocks},
+		{name: "Conversations", test: testConversations},
+		{name: "Messages", test: testMessages},
+		{name: "Images", test: testImages},
+		{name: "Polls", test: testPolls},
+		{name: "Reactions", test: testReactions},
+		{name: "LoginFailures", test: testLoginFailures},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStorage(t))
+		})
+	}
+}
+
+func createUser(ctx context.Context, s storage.Storage, username string) (*model.User, error) {
+	tx, err := s.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tx != nil {
+		defer tx.Rollback(ctx)
+	}
+
+	user, err := s.CreateUser(ctx, tx, &model.User{Username: username, FirstName: "First", LastName: "Last", Password: "hash"})
+	if err != nil {
+		return nil, err
+	}
+
+	if tx != nil {
+		return user, tx.Commit(ctx)
+	}
+	return user, nil
+}
+
+func mustCreateUser(t *testing.T, s storage.Storage, username string) *model.User {
+	t.Helper()
+
+	user, err := createUser(context.Background(), s, username)
+	require.NoError(t, err)
+	return user
+}
+
+func createPost(t *testing.T, s storage.Storage, userID, title string, status model.PostStatus) *model.Post {
+	t.Helper()
+
+	post, err := s.CreatePost(context.Background(), &model.Post{Title: title, Content: "Content", UserID: userID, Status: status}, nil, nil)
+	require.NoError(t, err)
+	return post
+}
+
+func addComment(t *testing.T, s storage.Storage, postID, userID, content string) *model.Comment {
+	t.Helper()
+
+	comment, err := s.AddComment(context.Background(), &model.Comment{PostID: postID, UserID: userID, Content: content})
+	require.NoError(t, err)
+	return comment
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+// testNotFound checks that single rows that don't exist give
+// storage.ErrNotFound and that lists leave them out.
+func testNotFound(t *testing.T, s storage.Storage) {
+	user := mustCreateUser(t, s, "alice")
+	post := createPost(t, s, user.ID, "Title", model.PostStatusPublished)
+
+	tests := []struct {
+		name          string
+		call          func(ctx context.Context) (interface{}, error)
+		expectedError error
+	}{
+		{
+			name:          "Post",
+			call:          func(ctx context.Context) (interface{}, error) { return s.Post(ctx, unknownID) },
+			expectedError: storage.ErrNotFound,
+		},
+		{
+			name:          "Post by an invalid id",
+			call:          func(ctx context.Context) (interface{}, error) { return s.Post(ctx, "abc") },
+			expectedError: storage.ErrInvalidID,
+		},
+		{
+			name:          "Comment",
+			call:          func(ctx context.Context) (interface{}, error) { return s.Comment(ctx, unknownID) },
+			expectedError: storage.ErrNotFound,
+		},
+		{
+			name:          "Comment by an invalid id",
+			call:          func(ctx context.Context) (interface{}, error) { return s.Comment(ctx, "abc") },
+			expectedError: storage.ErrInvalidID,
+		},
+		{
+			name: "Comments of an unknown post",
+			call: func(ctx context.Context) (interface{}, error) { return s.Comments(ctx, unknownID, nil, nil) },
+		},
+		{
+			name: "Posts of an unknown user",
+			call: func(ctx context.Context) (interface{}, error) { return s.UsersPost(ctx, unknownID) },
+		},
+		{
+			name: "Users by unknown ids",
+			call: func(ctx context.Context) (interface{}, error) { return s.UsersByIDs(ctx, []string{unknownID}) },
+		},
+		{
+			name: "Posts by unknown ids",
+			call: func(ctx context.Context) (interface{}, error) { return s.PostsByIDs(ctx, []string{unknownID}) },
+		},
+		{
+			name: "Comments by unknown ids",
+			call: func(ctx context.Context) (interface{}, error) { return s.CommentsByIDs(ctx, []string{unknownID}) },
+		},
+		{
+			name: "Users by unknown usernames",
+			call: func(ctx context.Context) (interface{}, error) { return s.UsersByUsernames(ctx, []string{"bob"}) },
+		},
+		{
+			name:          "User by id",
+			call:          func(ctx context.Context) (interface{}, error) { return s.UserByID(ctx, unknownID) },
+			expectedError: storage.ErrNotFound,
+		},
+		{
+			name:          "User by an invalid id",
+			call:          func(ctx context.Context) (interface{}, error) { return s.UserByID(ctx, "abc") },
+			expectedError: storage.ErrInvalidID,
+		},
+		{
+			name:          "User by username",
+			call:          func(ctx context.Context) (interface{}, error) { return s.UserByUsername(ctx, "bob") },
+			expectedError: storage.ErrNotFound,
+		},
+		{
+			name: "Update user",
+			call: func(ctx context.Context) (interface{}, error) {
+				return s.UpdateUser(ctx, &model.User{ID: unknownID, FirstName: "First"})
+			},
+			expectedError: storage.ErrNotFound,
+		},
+		{
+			name:          "Update password",
+			call:          func(ctx context.Context) (interface{}, error) { return nil, s.UpdatePassword(ctx, unknownID, "hash") },
+			expectedError: storage.ErrNotFound,
+		},
+		{
+			name: "Update post",
+			call: func(ctx context.Context) (interface{}, error) {
+				return s.UpdatePost(ctx, &model.UpdatePost{PostID: unknownID})
+			},
+			expectedError: storage.ErrNotFound,
+		},
+		{
+			name: "Update comment",
+			call: func(ctx context.Context) (interface{}, error) {
+				return s.UpdateComment(ctx, &model.Comment{ID: unknownID, Content: "Content"})
+			},
+			expectedError: storage.ErrNotFound,
+		},
+		{
+			name: "Comment of an unknown post",
+			call: func(ctx context.Context) (interface{}, error) {
+				return s.AddComment(ctx, &model.Comment{PostID: unknownID, UserID: user.ID, Content: "Content"})
+			},
+			expectedError: storage.ErrNotFound,
+		},
+		{
+			name: "Update draft",
+			call: func(ctx context.Context) (interface{}, error) {
+				return s.UpdateDraft(ctx, &model.Post{ID: unknownID, Title: "Title", Status: model.PostStatusDraft})
+			},
+			expectedError: storage.ErrNotFound,
+		},
+		{
+			name: "Update a published post as a draft",
+			call: func(ctx context.Context) (interface{}, error) {
+				return s.UpdateDraft(ctx, &model.Post{ID: post.ID, Title: "Title", Status: model.PostStatusDraft})
+			},
+		},
+		{
+			name: "Bookmark an unknown post",
+			call: func(ctx context.Context) (interface{}, error) {
+				return nil, s.AddBookmark(ctx, &model.Bookmark{UserID: user.ID, PostID: strPtr(unknownID)})
+			},
+			expectedError: storage.ErrNotFound,
+		},
+		{
+			name: "React to an unknown comment",
+			call: func(ctx context.Context) (interface{}, error) {
+				return nil, s.AddReaction(ctx, &model.Reaction{UserID: user.ID, CommentID: strPtr(unknownID), Emoji: "👍"})
+			},
+			expectedError: storage.ErrNotFound,
+		},
+		{
+			name: "Follow an unknown user",
+			call: func(ctx context.Context) (interface{}, error) {
+				return nil, s.Follow(ctx, &model.Follow{FollowerID: user.ID, FolloweeID: unknownID})
+			},
+			expectedError: storage.ErrNotFound,
+		},
+		{
+			name: "Block an unknown user",
+			call: func(ctx context.Context) (interface{}, error) {
+				return nil, s.AddBlock(ctx, &model.Block{UserID: user.ID, TargetID: unknownID, Kind: model.BlockKindBlock})
+			},
+			expectedError: storage.ErrNotFound,
+		},
+		{
+			name: "Notify an unknown user",
+			call: func(ctx context.Context) (interface{}, error) {
+				return s.AddNotification(ctx, &model.Notification{UserID: unknownID, Kind: model.NotificationKindSecurity})
+			},
+			expectedError: storage.ErrNotFound,
+		},
+		{
+			name: "Conversation with an unknown user",
+			call: func(ctx context.Context) (interface{}, error) {
+				return s.CreateConversation(ctx, &model.Conversation{ParticipantIDs: []string{user.ID, unknownID}})
+			},
+			expectedError: storage.ErrNotFound,
+		},
+		{
+			name: "Message to an unknown conversation",
+			call: func(ctx context.Context) (interface{}, error) {
+				return s.AddMessage(ctx, &model.Message{ConversationID: unknownID, UserID: user.ID, Content: "Hi"})
+			},
+			expectedError: storage.ErrNotFound,
+		},
+		{
+			name: "Post with a poll of an unknown user",
+			call: func(ctx context.Context) (interface{}, error) {
+				return s.CreatePost(ctx, &model.Post{Title: "Title", Content: "Content", UserID: unknownID}, nil,
+					&model.Poll{Question: "Which?", Options: []*model.PollOption{{Text: "A"}}})
+			},
+			expectedError: storage.ErrNotFound,
+		},
+		{
+			name:          "Unknown poll",
+			call:          func(ctx context.Context) (interface{}, error) { return s.Poll(ctx, unknownID) },
+			expectedError: storage.ErrNotFound,
+		},
+		{
+			name:          "Unknown conversation",
+			call:          func(ctx context.Context) (interface{}, error) { return s.Conversation(ctx, unknownID) },
+			expectedError: storage.ErrNotFound,
+		},
+		{
+			name:          "Publish post",
+			call:          func(ctx context.Context) (interface{}, error) { return s.PublishPost(ctx, unknownID) },
+			expectedError: storage.ErrNotFound,
+		},
+		{
+			name:          "Publish post by an invalid id",
+			call:          func(ctx context.Context) (interface{}, error) { return s.PublishPost(ctx, "abc") },
+			expectedError: storage.ErrInvalidID,
+		},
+		{
+			name: "Publish a published post",
+			call: func(ctx context.Context) (interface{}, error) { return s.PublishPost(ctx, post.ID) },
+		},
+		{
+			name: "Vote for an unknown post",
+			call: func(ctx context.Context) (interface{}, error) {
+				return s.VotePost(ctx, &model.Vote{PostID: unknownID, UserID: user.ID, Value: 1})
+			},
+			expectedError: storage.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.call(context.Background())
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				require.NoError(t, err)
+				assert.Empty(t, got)
+			}
+		})
+	}
+
+	// failed calls leave the storage usable
+	stored, err := s.Post(context.Background(), post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, post.ID, stored.ID)
+	assert.Equal(t, 0, stored.CommentCount)
+}
+
+func postTitles(posts []*model.Post) []string {
+	titles := []string{}
+	for _, p := range posts {
+		titles = append(titles, p.Title)
+	}
+	return titles
+}
+
+func commentContents(comments []*model.Comment) []string {
+	contents := []string{}
+	for _, c := range comments {
+		contents = append(contents, c.Content)
+	}
+	return contents
+}
+
+// pages are the pages of five items "1" to "5" both Posts and Comments return.
+var pages = []struct {
+	name     string
+	limit    *int
+	offset   *int
+	expected []string
+}{
+	{
+		name:     "Without limit",
+		expected: []string{"1", "2", "3", "4", "5"},
+	},
+	{
+		name:     "Without offset",
+		limit:    intPtr(2),
+		expected: []string{"1", "2"},
+	},
+	{
+		name:     "Without limit with offset",
+		offset:   intPtr(3),
+		expected: []string{"4", "5"},
+	},
+	{
+		name:     "First page",
+		limit:    intPtr(2),
+		offset:   intPtr(0),
+		expected: []string{"1", "2"},
+	},
+	{
+		name:     "Second page",
+		limit:    intPtr(2),
+		offset:   intPtr(2),
+		expected: []string{"3", "4"},
+	},
+	{
+		name:     "Last page",
+		limit:    intPtr(2),
+		offset:   intPtr(4),
+		expected: []string{"5"},
+	},
+	{
+		name:     "After the last item",
+		limit:    intPtr(2),
+		offset:   intPtr(6),
+		expected: []string{},
+	},
+	{
+		name:     "Zero limit",
+		limit:    intPtr(0),
+		offset:   intPtr(0),
+		expected: []string{},
+	},
+}
+
+// testPosts checks that published posts are paged in the order they were
+// created.
+func testPosts(t *testing.T, s storage.Storage) {
+	user := mustCreateUser(t, s, "alice")
+	for _, title := range []string{"1", "2", "3", "4", "5"} {
+		createPost(t, s, user.ID, title, model.PostStatusPublished)
+		createPost(t, s, user.ID, "draft "+title, model.PostStatusDraft)
+	}
+
+	for _, tt := range pages {
+		t.Run(tt.name, func(t *testing.T) {
+			posts, err := s.Posts(context.Background(), tt.limit, tt.offset)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, postTitles(posts))
+		})
+	}
+
+	posts, err := s.UsersPost(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "2", "3", "4", "5"}, postTitles(posts))
+
+	posts, err = s.PostsByIDs(context.Background(), []string{posts[4].ID, unknownID, posts[0].ID})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1", "5"}, postTitles(posts))
+}
+
+// testComments checks that the comments of a post are paged in the order
+// they were added.
+func testComments(t *testing.T, s storage.Storage) {
+	user := mustCreateUser(t, s, "alice")
+	post := createPost(t, s, user.ID, "Title", model.PostStatusPublished)
+	other := createPost(t, s, user.ID, "Other", model.PostStatusPublished)
+
+	// comments of the two posts are interleaved, so a page of all comments
+	// differs from a page of one post
+	for _, content := range []string{"1", "2", "3", "4", "5"} {
+		addComment(t, s, post.ID, user.ID, content)
+		addComment(t, s, other.ID, user.ID, "other "+content)
+	}
+
+	for _, tt := range pages {
+		t.Run(tt.name, func(t *testing.T) {
+			comments, err := s.Comments(context.Background(), post.ID, tt.limit, tt.offset)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, commentContents(comments))
+		})
+	}
+
+	parent := addComment(t, s, post.ID, user.ID, "parent")
+	reply, err := s.AddComment(context.Background(), &model.Comment{PostID: post.ID, ParentID: &parent.ID, UserID: user.ID, Content: "reply"})
+	require.NoError(t, err)
+
+	stored, err := s.Comment(context.Background(), reply.ID)
+	require.NoError(t, err)
+	assert.Equal(t, post.ID, stored.PostID)
+	require.NotNil(t, stored.ParentID)
+	assert.Equal(t, parent.ID, *stored.ParentID)
+	assert.Equal(t, "reply", stored.Content)
+
+	comments, err := s.CommentsByIDs(context.Background(), []string{reply.ID, unknownID, parent.ID})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"parent", "reply"}, commentContents(comments))
+}
+
+func usernames(users []*model.User) []string {
+	names := []string{}
+	for _, u := range users {
+		names = append(names, u.Username)
+	}
+	return names
+}
+
+func testUsers(t *testing.T, s storage.Storage) {
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+	mustCreateUser(t, s, "carol")
+
+	users, err := s.Users(context.Background())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"alice", "bob", "carol"}, usernames(users))
+
+	users, err = s.UsersByIDs(context.Background(), []string{bob.ID, unknownID, alice.ID})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"alice", "bob"}, usernames(users))
+
+	users, err = s.UsersByUsernames(context.Background(), []string{"carol", "dave", "alice"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"alice", "carol"}, usernames(users))
+
+	user, err := s.UserByUsername(context.Background(), "bob")
+	require.NoError(t, err)
+	assert.Equal(t, bob.ID, user.ID)
+
+	updated, err := s.UpdateUser(context.Background(), &model.User{
+		ID: alice.ID, FirstName: "Alice", LastName: "Smith", Bio: "Hi", AvatarKeys: []string{"avatars/a.png", "avatars/a_thumb.png"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", updated.Username)
+	assert.Equal(t, "Smith", updated.LastName)
+	assert.Equal(t, []string{"avatars/a.png", "avatars/a_thumb.png"}, updated.AvatarKeys)
+
+	require.NoError(t, s.UpdatePassword(context.Background(), alice.ID, "new hash"))
+	user, err = s.UserByID(context.Background(), alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", user.FirstName)
+	assert.Equal(t, "Hi", user.Bio)
+	assert.Equal(t, "new hash", user.Password)
+}
+
+func testUsersStats(t *testing.T, s storage.Storage) {
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+
+	post := createPost(t, s, alice.ID, "Title", model.PostStatusPublished)
+	createPost(t, s, alice.ID, "Draft", model.PostStatusDraft)
+	addComment(t, s, post.ID, alice.ID, "own")
+	addComment(t, s, post.ID, bob.ID, "first")
+	addComment(t, s, post.ID, bob.ID, "second")
+
+	stats, err := s.UsersStats(context.Background(), []string{bob.ID, unknownID, alice.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []*model.UserStats{
+		{UserID: bob.ID, CommentCount: 2},
+		{UserID: unknownID},
+		{UserID: alice.ID, PostCount: 1, CommentCount: 1, Karma: 2},
+	}, stats)
+
+	st, err := s.UserStats(context.Background(), alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, stats[2], st)
+}
+
+func testUniqueUsername(t *testing.T, s storage.Storage) {
+	mustCreateUser(t, s, "alice")
+
+	_, err := createUser(context.Background(), s, "alice")
+	assert.ErrorIs(t, err, storage.ErrConflict)
+
+	users, err := s.Users(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+
+	// the storage is still usable after the failure
+	mustCreateUser(t, s, "bob")
+}
+
+// testCommentCount checks that the comment count of a post follows added,
+// deleted and restored comments.
+func testCommentCount(t *testing.T, s storage.Storage) {
+	user := mustCreateUser(t, s, "alice")
+	post := createPost(t, s, user.ID, "Title", model.PostStatusPublished)
+	comment := addComment(t, s, post.ID, user.ID, "first")
+	addComment(t, s, post.ID, user.ID, "second")
+
+	// the steps run in order on the same comment
+	tests := []struct {
+		name          string
+		update        *model.Comment
+		expectedCount int
+	}{
+		{
+			name:          "Edit",
+			update:        &model.Comment{ID: comment.ID, Content: "edited", Edited: true},
+			expectedCount: 2,
+		},
+		{
+			name:          "Delete",
+			update:        &model.Comment{ID: comment.ID, Edited: true, Deleted: true},
+			expectedCount: 1,
+		},
+		{
+			name:          "Delete again",
+			update:        &model.Comment{ID: comment.ID, Edited: true, Deleted: true},
+			expectedCount: 1,
+		},
+		{
+			name:          "Restore",
+			update:        &model.Comment{ID: comment.ID, Content: "restored", Edited: true},
+			expectedCount: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, err := s.UpdateComment(context.Background(), tt.update)
+			require.NoError(t, err)
+			assert.Equal(t, tt.update.Content, updated.Content)
+			assert.Equal(t, tt.update.Deleted, updated.Deleted)
+			assert.Equal(t, post.ID, updated.PostID)
+
+			stored, err := s.Post(context.Background(), post.ID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCount, stored.CommentCount)
+		})
+	}
+}
+
+// testConcurrency creates users, posts and comments from many goroutines and
+// checks that nothing is lost and every id is given out once.
+func testConcurrency(t *testing.T, s storage.Storage) {
+	const n = 20
+	ctx := context.Background()
+	author := mustCreateUser(t, s, "author")
+	post := createPost(t, s, author.ID, "Title", model.PostStatusPublished)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	ids := make(map[string]bool)
+	add := func(kind, id string) {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.False(t, ids[kind+id], "%s id %s is given out twice", kind, id)
+		ids[kind+id] = true
+	}
+
+	for i := 0; i < n; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			user, err := createUser(ctx, s, fmt.Sprintf("user%d", i))
+			if assert.NoError(t, err) {
+				add("user", user.ID)
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			p, err := s.CreatePost(ctx, &model.Post{Title: fmt.Sprintf("post %d", i), Content: "Content", UserID: author.ID}, nil, nil)
+			if assert.NoError(t, err) {
+				add("post", p.ID)
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			c, err := s.AddComment(ctx, &model.Comment{PostID: post.ID, UserID: author.ID, Content: fmt.Sprintf("comment %d", i)})
+			if assert.NoError(t, err) {
+				add("comment", c.ID)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	users, err := s.Users(ctx)
+	require.NoError(t, err)
+	assert.Len(t, users, n+1)
+
+	posts, err := s.Posts(ctx, nil, nil)
+	require.NoError(t, err)
+	assert.Len(t, posts, n+1)
+
+	comments, err := s.Comments(ctx, post.ID, nil, nil)
+	require.NoError(t, err)
+	assert.Len(t, comments, n)
+
+	stored, err := s.Post(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, n, stored.CommentCount)
+}
@@ -0,0 +1,20 @@
+package validator
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+func (v *Validator) MaxLength(field, value string, low int) bool {
+	if _, ok := v.Errors[field]; ok {
+		return false
+	}
+
+	if utf8.RuneCountInString(value) > low {
+		v.Errors[field] = fmt.Sprintf("%s must be at most (%d) characters long", field, low)
+
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,71 @@
+package validator
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestValidator_MaxLength(t *testing.T) {
+	tests := []struct {
+		name          string
+		field         string
+		value         string
+		low           int
+		initialErrors map[string]string
+		expectedValid bool
+		expectedError string
+	}{
+		{
+			name:          "Value longer than maximum length",
+			field:         "bio",
+			value:         "abcdefg",
+			low:           6,
+			initialErrors: map[string]string{},
+			expectedValid: false,
+			expectedError: "bio must be at most (6) characters long",
+		},
+		{
+			name:          "Value meets maximum length",
+			field:         "bio",
+			value:         "abcdef",
+			low:           6,
+			initialErrors: map[string]string{},
+			expectedValid: true,
+			expectedError: "",
+		},
+		{
+			name:          "Multibyte characters are counted once",
+			field:         "bio",
+			value:         "привет",
+			low:           6,
+			initialErrors: map[string]string{},
+			expectedValid: true,
+			expectedError: "",
+		},
+		{
+			name:          "Field already has an error",
+			field:         "bio",
+			value:         "abc",
+			low:           6,
+			initialErrors: map[string]string{"bio": "some other error"},
+			expectedValid: false,
+			expectedError: "some other error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New()
+			v.Errors = tt.initialErrors
+
+			result := v.MaxLength(tt.field, tt.value, tt.low)
+			assert.Equal(t, tt.expectedValid, result)
+
+			if tt.expectedError == "" {
+				assert.Empty(t, v.Errors)
+			} else {
+				assert.Equal(t, tt.expectedError, v.Errors[tt.field])
+			}
+		})
+	}
+}
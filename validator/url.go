@@ -0,0 +1,26 @@
+package validator
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// URL accepts empty values, combine it with Required when the field is mandatory.
+func (v *Validator) URL(field, value string) bool {
+	if _, ok := v.Errors[field]; ok {
+		return false
+	}
+
+	if value == "" {
+		return true
+	}
+
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.Errors[field] = fmt.Sprintf("%s must be a valid http(s) url", field)
+
+		return false
+	}
+
+	return true
+}
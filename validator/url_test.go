@@ -0,0 +1,74 @@
+package validator
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestValidator_URL(t *testing.T) {
+	tests := []struct {
+		name          string
+		field         string
+		value         string
+		initialErrors map[string]string
+		expectedValid bool
+		expectedError string
+	}{
+		{
+			name:          "Valid https url",
+			field:         "website",
+			value:         "https://example.com/me",
+			initialErrors: map[string]string{},
+			expectedValid: true,
+			expectedError: "",
+		},
+		{
+			name:          "Empty value",
+			field:         "website",
+			value:         "",
+			initialErrors: map[string]string{},
+			expectedValid: true,
+			expectedError: "",
+		},
+		{
+			name:          "Unsupported scheme",
+			field:         "website",
+			value:         "javascript:alert(1)",
+			initialErrors: map[string]string{},
+			expectedValid: false,
+			expectedError: "website must be a valid http(s) url",
+		},
+		{
+			name:          "Missing host",
+			field:         "website",
+			value:         "http://",
+			initialErrors: map[string]string{},
+			expectedValid: false,
+			expectedError: "website must be a valid http(s) url",
+		},
+		{
+			name:          "Field already has an error",
+			field:         "website",
+			value:         "https://example.com",
+			initialErrors: map[string]string{"website": "some other error"},
+			expectedValid: false,
+			expectedError: "some other error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New()
+			v.Errors = tt.initialErrors
+
+			result := v.URL(tt.field, tt.value)
+			assert.Equal(t, tt.expectedValid, result)
+
+			if tt.expectedError == "" {
+				assert.Empty(t, v.Errors)
+			} else {
+				assert.Equal(t, tt.expectedError, v.Errors[tt.field])
+			}
+		})
+	}
+}